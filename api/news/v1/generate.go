@@ -0,0 +1,4 @@
+// Package newsv1 holds the generated gRPC API of the syncer.
+package newsv1
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative news.proto
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/alert"
+	"inshorts.com/inshorts-news-data-syncer/pkg/search"
+)
+
+// runAlerts manages the saved queries that syncs run with --alerts
+// percolate written articles against, e.g.
+//
+//	alerts add --id 42 --subscriber user-7 --category politics election results
+//	alerts list
+//	alerts remove --id 42
+func runAlerts(args []string) int {
+	if len(args) == 0 {
+		exitWithConfigError(errors.New("expected add, remove or list"), "invalid configuration")
+	}
+	action, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("alerts "+action, flag.ExitOnError)
+	index := fs.String("index", indexName, "article index whose alerts are managed")
+	var run func(ctx context.Context, p *alert.Percolator) error
+	switch action {
+	case "add":
+		id := fs.String("id", "", "subscription id, replacing an existing subscription with it")
+		subscriber := fs.String("subscriber", "", "who is alerted, passed on with every match")
+		name := fs.String("name", "", "optional name of the subscription")
+		category := fs.String("category", "", "only match these comma separated categories")
+		source := fs.String("source", "", "only match articles from these comma separated sources")
+		near := fs.String("near", "", "only match articles within a distance of a point: lat,lon,distance e.g. 28.6,77.2,50km")
+		terms := parseInterspersed(fs, args)
+
+		q := search.Query{Text: strings.Join(terms, " ")}
+		if *category != "" {
+			q.Categories = strings.Split(*category, ",")
+		}
+		if *source != "" {
+			q.Sources = strings.Split(*source, ",")
+		}
+		if *near != "" {
+			var err error
+			if q.Near, err = search.ParseNear(*near); err != nil {
+				exitWithConfigError(err, "invalid --near")
+			}
+		}
+		if *id == "" || *subscriber == "" {
+			exitWithConfigError(errors.New("--id and --subscriber are required"), "invalid configuration")
+		}
+		sub := alert.Subscription{
+			ID:         *id,
+			Subscriber: *subscriber,
+			Name:       *name,
+			Query:      q.Body()["query"].(map[string]interface{}),
+		}
+		run = func(ctx context.Context, p *alert.Percolator) error {
+			if err := p.Prepare(ctx); err != nil {
+				return err
+			}
+			return p.Save(ctx, sub)
+		}
+	case "remove":
+		id := fs.String("id", "", "id of the subscription to remove")
+		fs.Parse(args)
+		if *id == "" {
+			exitWithConfigError(errors.New("--id is required"), "invalid configuration")
+		}
+		run = func(ctx context.Context, p *alert.Percolator) error {
+			return p.Delete(ctx, *id)
+		}
+	case "list":
+		asJSON := fs.Bool("json", false, "print the subscriptions with their queries as json")
+		fs.Parse(args)
+		run = func(ctx context.Context, p *alert.Percolator) error {
+			subs, err := p.List(ctx)
+			if err != nil {
+				return err
+			}
+			if *asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(subs)
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tSUBSCRIBER\tNAME")
+			for _, s := range subs {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", s.ID, s.Subscriber, s.Name)
+			}
+			return w.Flush()
+		}
+	default:
+		exitWithConfigError(fmt.Errorf("unknown action %q, expected add, remove or list", action), "invalid configuration")
+	}
+
+	es, err := newElasticsearchClient("")
+	if err != nil {
+		exitWithConfigError(err, "failed to create elasticsearch client")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := run(ctx, alert.NewPercolator(es, *index)); err != nil {
+		log.Error().Caller().Err(err).Msgf("alerts %s failed", action)
+		return exitFailure
+	}
+	return exitSuccess
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/sink"
+)
+
+// runAlias manages the read and write aliases of the news indices, e.g.
+// to switch readers to a reindexed index in one atomic step:
+//
+//	alias promote news-v3 --read-alias news --write-alias news-write
+//	alias create news-v3 --read-alias news-staging
+//	alias remove news-v2 --alias news-staging
+//	alias list --index 'news-*'
+func runAlias(args []string) int {
+	if len(args) == 0 {
+		exitWithConfigError(errors.New("expected create, promote, remove or list"), "invalid configuration")
+	}
+	action, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("alias "+action, flag.ExitOnError)
+	var run func(ctx context.Context, es *elasticsearch.Client) error
+	switch action {
+	case "create", "promote":
+		read := fs.String("read-alias", "", "alias readers search through")
+		write := fs.String("write-alias", "", "alias syncs write through, the index becomes its write index")
+		index := onlyArg(fs, args, "index")
+		if *read == "" && *write == "" {
+			exitWithConfigError(errors.New("--read-alias or --write-alias is required"), "invalid configuration")
+		}
+		if *read == *write {
+			exitWithConfigError(errors.New("--read-alias and --write-alias must differ"), "invalid configuration")
+		}
+		update := sink.AliasUpdate{Index: index, Read: *read, Write: *write, Move: action == "promote"}
+		run = func(ctx context.Context, es *elasticsearch.Client) error {
+			moved, err := sink.UpdateAliases(ctx, es, update)
+			if err != nil {
+				return err
+			}
+			if len(moved) > 0 {
+				fmt.Printf("promoted %s, moved from %s\n", index, strings.Join(moved, ", "))
+			}
+			return nil
+		}
+	case "remove":
+		name := fs.String("alias", "", "alias to remove from the index")
+		index := onlyArg(fs, args, "index")
+		if *name == "" {
+			exitWithConfigError(errors.New("--alias is required"), "invalid configuration")
+		}
+		run = func(ctx context.Context, es *elasticsearch.Client) error {
+			return sink.RemoveAlias(ctx, es, index, *name)
+		}
+	case "list":
+		pattern := fs.String("index", "", "only list the aliases of indices matching this pattern, e.g. news-*")
+		asJSON := fs.Bool("json", false, "print the aliases as json")
+		fs.Parse(args)
+		run = func(ctx context.Context, es *elasticsearch.Client) error {
+			aliases, err := sink.ListAliases(ctx, es, *pattern)
+			if err != nil {
+				return err
+			}
+			if *asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(aliases)
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ALIAS\tINDEX\tWRITE\tFILTERED")
+			for _, a := range aliases {
+				fmt.Fprintf(w, "%s\t%s\t%t\t%t\n", a.Name, a.Index, a.Write, a.Filtered)
+			}
+			return w.Flush()
+		}
+	default:
+		exitWithConfigError(fmt.Errorf("unknown action %q, expected create, promote, remove or list", action), "invalid configuration")
+	}
+
+	es, err := newElasticsearchClient("")
+	if err != nil {
+		exitWithConfigError(err, "failed to create elasticsearch client")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := run(ctx, es); err != nil {
+		log.Error().Caller().Err(err).Msgf("alias %s failed", action)
+		return exitFailure
+	}
+	return exitSuccess
+}
+
+// onlyArg parses args with fs and returns its single positional argument,
+// named what in the error when there isn't exactly one.
+func onlyArg(fs *flag.FlagSet, args []string, what string) string {
+	positional := parseInterspersed(fs, args)
+	if len(positional) != 1 {
+		exitWithConfigError(fmt.Errorf("expected one %s, got %d", what, len(positional)), "invalid configuration")
+	}
+	return positional[0]
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	syncpkg "inshorts.com/inshorts-news-data-syncer/pkg/sync"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// sliceSize is the length of one backfill slice, such as 1month.
+type sliceSize struct {
+	n    int
+	unit string
+}
+
+var slicePattern = regexp.MustCompile(`^(\d*)(d|w|month|y)$`)
+
+// jobNamePattern is what --coordinate accepts, as the name becomes part of
+// task document IDs.
+var jobNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// parseSlice parses --slice: a count and one of d, w, month or y.
+func parseSlice(value string) (sliceSize, error) {
+	m := slicePattern.FindStringSubmatch(value)
+	if m == nil {
+		return sliceSize{}, fmt.Errorf("invalid slice %q, expected e.g. 1d, 1w, 1month or 1y", value)
+	}
+	n := 1
+	if m[1] != "" {
+		n, _ = strconv.Atoi(m[1])
+	}
+	if n <= 0 {
+		return sliceSize{}, fmt.Errorf("invalid slice %q", value)
+	}
+	return sliceSize{n: n, unit: m[2]}, nil
+}
+
+// next returns the start of the slice following the one starting at t.
+func (s sliceSize) next(t time.Time) time.Time {
+	switch s.unit {
+	case "d":
+		return t.AddDate(0, 0, s.n)
+	case "w":
+		return t.AddDate(0, 0, 7*s.n)
+	case "month":
+		return t.AddDate(0, s.n, 0)
+	default:
+		return t.AddDate(s.n, 0, 0)
+	}
+}
+
+// layout is the date suffix of the index of a slice: monthly slices go
+// to news-2019.01, yearly ones to news-2019 and shorter ones to
+// news-2019.01.07.
+func (s sliceSize) layout() string {
+	switch s.unit {
+	case "month":
+		return "2006.01"
+	case "y":
+		return "2006"
+	default:
+		return "2006.01.02"
+	}
+}
+
+// backfillCheckpoint records the slices of a backfill that completed, so
+// a rerun with the same range resumes after them.
+type backfillCheckpoint struct {
+	From      string   `json:"from"`
+	To        string   `json:"to"`
+	Slice     string   `json:"slice"`
+	Completed []string `json:"completed"`
+}
+
+// loadCheckpoint reads path, returning an empty checkpoint when it
+// doesn't exist yet.
+func loadCheckpoint(path string) (backfillCheckpoint, error) {
+	var cp backfillCheckpoint
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, err
+	}
+	err = json.Unmarshal(data, &cp)
+	return cp, err
+}
+
+// save writes the checkpoint through a temporary file, so a crash never
+// leaves a truncated one behind.
+func (cp backfillCheckpoint) save(path string) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// runBackfill loads historical articles in date slices, each into its
+// own date partitioned index, e.g.
+//
+//	syncer backfill --from 2019-01-01 --to 2024-01-01 --slice 1month --source export.json
+//
+// syncs the articles of January 2019 into inshorts-news-2019.01 and so
+// on. A source containing {from} and {to} is fetched once per slice with
+// the slice bounds substituted; any other source is read per slice and
+// filtered to it. Completed slices are recorded in --checkpoint and
+// skipped when the backfill is restarted, or with --coordinate shared out
+// as task documents between every instance running the same job. Every
+// other option of a sync applies to each slice.
+func runBackfill(args []string) int {
+	from := flag.String("from", "", "first day of the backfill, e.g. 2019-01-01")
+	to := flag.String("to", "", "day the backfill stops before, e.g. 2024-01-01")
+	sliceFlag := flag.String("slice", "1month", "length of each slice: 1d, 1w, 1month or 1y, with any count")
+	checkpoint := flag.String("checkpoint", "backfill-checkpoint.json", "file recording completed slices")
+	coordinate := flag.String("coordinate", "", "share the backfill with every instance started with the same job name, instead of --checkpoint: slices are recorded as task documents in elasticsearch that each instance claims, works on and marks done, and a rerun resumes the job")
+	taskTTL := flag.Duration("task-ttl", 5*time.Minute, "with --coordinate, time after which the slice of an instance that stopped renewing its claim is claimed by another")
+	taskMaxAttempts := flag.Int("task-max-attempts", 3, "with --coordinate, times a slice is claimed before it is abandoned for failing, 0 for no limit")
+	cfg := parseFlags(args)
+
+	start, err := time.Parse(time.DateOnly, *from)
+	if err != nil {
+		exitWithConfigError(err, "invalid --from")
+	}
+	end, err := time.Parse(time.DateOnly, *to)
+	if err != nil {
+		exitWithConfigError(err, "invalid --to")
+	}
+	if !start.Before(end) {
+		exitWithConfigError(errors.New("--from must be before --to"), "invalid configuration")
+	}
+	size, err := parseSlice(*sliceFlag)
+	if err != nil {
+		exitWithConfigError(err, "invalid --slice")
+	}
+	if cfg.daemon() || cfg.jobs != "" {
+		exitWithConfigError(errors.New("backfill runs once and can't be combined with --jobs, --schedule, --ingest or --grpc-addr"), "invalid configuration")
+	}
+	if err := cfg.validate(); err != nil {
+		exitWithConfigError(err, "invalid configuration")
+	}
+	cfg = cfg.scoped()
+	utils.AddDateLayouts(cfg.dateLayouts...)
+	applyMemoryLimit(cfg)
+
+	if *coordinate != "" {
+		if !jobNamePattern.MatchString(*coordinate) {
+			exitWithConfigError(fmt.Errorf("invalid job name %q, expected letters, digits, '.', '_' or '-'", *coordinate), "invalid --coordinate")
+		}
+		if *taskTTL < 3*time.Second {
+			exitWithConfigError(errors.New("--task-ttl must be at least 3s"), "invalid configuration")
+		}
+		if *taskMaxAttempts < 0 {
+			exitWithConfigError(errors.New("--task-max-attempts must not be negative"), "invalid configuration")
+		}
+	}
+	var cp backfillCheckpoint
+	if *coordinate == "" {
+		if cp, err = loadCheckpoint(*checkpoint); err != nil {
+			exitWithConfigError(err, "invalid --checkpoint")
+		}
+		if cp.From == "" {
+			cp = backfillCheckpoint{From: *from, To: *to, Slice: *sliceFlag}
+		} else if cp.From != *from || cp.To != *to || cp.Slice != *sliceFlag {
+			exitWithConfigError(fmt.Errorf("%s belongs to the backfill of %s to %s by %s, remove it to start another", *checkpoint, cp.From, cp.To, cp.Slice), "invalid --checkpoint")
+		}
+	}
+
+	es, err := cfg.elasticsearchClient("")
+	if err != nil {
+		exitWithConfigError(err, "failed to create elasticsearch client")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.livenessAddr != "" {
+		stopLiveness := serveLiveness(cfg.livenessAddr)
+		defer stopLiveness()
+	}
+
+	type backfillSlice struct{ start, end time.Time }
+	var names []string
+	bounds := map[string]backfillSlice{}
+	for sliceStart := start; sliceStart.Before(end); sliceStart = size.next(sliceStart) {
+		sliceEnd := size.next(sliceStart)
+		if sliceEnd.After(end) {
+			sliceEnd = end
+		}
+		name := sliceStart.Format(time.DateOnly)
+		names = append(names, name)
+		bounds[name] = backfillSlice{sliceStart, sliceEnd}
+	}
+	runSlice := func(ctx context.Context, name string) bool {
+		b := bounds[name]
+		sliceCfg := cfg
+		sliceCfg.index = cfg.index + "-" + b.start.Format(size.layout())
+		sliceCfg.source = strings.NewReplacer(
+			"{from}", name,
+			"{to}", b.end.Format(time.DateOnly),
+		).Replace(cfg.source)
+		return runNamedJob(ctx, sliceCfg, "backfill "+name, es, b.start, b.end)
+	}
+
+	if *coordinate != "" {
+		queue := syncpkg.NewTaskQueue(es, *coordinate, *taskTTL, *taskMaxAttempts)
+		if err := queue.Plan(ctx, fmt.Sprintf("%s..%s by %s", *from, *to, *sliceFlag), names); err != nil {
+			exitWithConfigError(err, "invalid --coordinate")
+		}
+		err := queue.Work(ctx, func(ctx context.Context, name string) error {
+			if !runSlice(ctx, name) {
+				return errors.New("sync failed")
+			}
+			return nil
+		})
+		if err != nil {
+			log.Error().Caller().Err(err).Msgf("backfill job %s stopped, rerun to resume it", *coordinate)
+			return exitFailure
+		}
+		log.Info().Caller().Msgf("backfill of %s to %s completed", *from, *to)
+		return exitSuccess
+	}
+
+	for _, name := range names {
+		if slices.Contains(cp.Completed, name) {
+			log.Info().Caller().Msgf("skipping completed slice %s", name)
+			continue
+		}
+		if ctx.Err() != nil {
+			return exitFailure
+		}
+		if !runSlice(ctx, name) {
+			log.Error().Caller().Msgf("slice %s failed, rerun to resume from it", name)
+			return exitFailure
+		}
+
+		cp.Completed = append(cp.Completed, name)
+		if err := cp.save(*checkpoint); err != nil {
+			log.Error().Caller().Err(err).Msgf("failed to write checkpoint %s", *checkpoint)
+			return exitFailure
+		}
+	}
+	log.Info().Caller().Msgf("backfill of %s to %s completed", *from, *to)
+	return exitSuccess
+}
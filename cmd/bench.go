@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/pkg/sink"
+	"inshorts.com/inshorts-news-data-syncer/pkg/source"
+)
+
+// benchResult is the outcome of indexing the synthetic articles with one
+// combination of settings.
+type benchResult struct {
+	batchSize, workers int
+	compress           bool
+	elapsed            time.Duration
+	failed             int
+	// latencies are those of the bulk requests, sorted.
+	latencies []time.Duration
+}
+
+func (r benchResult) percentile(p float64) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	return r.latencies[int(p*float64(len(r.latencies)-1))]
+}
+
+// runBench indexes synthetic articles into a scratch index of the target
+// cluster once per combination of batch size, worker count and request
+// compression, and prints the throughput and bulk latency of each, e.g.
+//
+//	bench --docs 50000 --batch-sizes 500,2000,5000 --workers 1,4,8 --compression off,on
+//
+// The scratch index is recreated for every combination and deleted at
+// the end.
+func runBench(args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	target := fs.String("target", "", "elasticsearch address to benchmark (default ES_URL)")
+	index := fs.String("index", indexName+"-bench", "scratch index, deleted and recreated for each combination")
+	docs := fs.Int("docs", 10000, "synthetic articles indexed per combination")
+	seed := fs.Uint64("seed", 1, "seed of the synthetic articles")
+	batchSizes := fs.String("batch-sizes", "500,1000,5000", "comma separated bulk batch sizes")
+	workers := fs.String("workers", "1,2,4", "comma separated numbers of concurrent bulk requests")
+	compression := fs.String("compression", "off,on", "comma separated gzip request compression settings, on and/or off")
+	fs.Parse(args)
+
+	sizes, err := parsePositiveInts(*batchSizes)
+	if err != nil {
+		exitWithConfigError(err, "invalid --batch-sizes")
+	}
+	counts, err := parsePositiveInts(*workers)
+	if err != nil {
+		exitWithConfigError(err, "invalid --workers")
+	}
+	var compress []bool
+	for _, v := range strings.Split(*compression, ",") {
+		switch strings.TrimSpace(v) {
+		case "off":
+			compress = append(compress, false)
+		case "on":
+			compress = append(compress, true)
+		default:
+			exitWithConfigError(fmt.Errorf("unknown setting %q, expected on or off", v), "invalid --compression")
+		}
+	}
+	if *docs < 1 {
+		exitWithConfigError(errors.New("--docs must be positive"), "invalid configuration")
+	}
+	if *index == "" {
+		exitWithConfigError(errors.New("--index must not be empty"), "invalid configuration")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	src, err := source.Open(ctx, fmt.Sprintf("generate:?count=%d&seed=%d", *docs, *seed))
+	if err != nil {
+		exitWithConfigError(err, "failed to generate articles")
+	}
+	articles, err := source.ReadAll(ctx, src)
+	src.Close()
+	if err != nil {
+		exitWithConfigError(err, "failed to generate articles")
+	}
+
+	clients := make(map[bool]*elasticsearch.Client)
+	for _, c := range compress {
+		esCfg := elasticsearchConfig(*target)
+		esCfg.CompressRequestBody = c
+		if clients[c], err = elasticsearch.NewClient(esCfg); err != nil {
+			exitWithConfigError(err, "failed to create elasticsearch client")
+		}
+	}
+	defer deleteIndex(clients[compress[0]], *index)
+
+	var results []benchResult
+	for _, c := range compress {
+		for _, size := range sizes {
+			for _, n := range counts {
+				log.Info().Caller().Msgf("benchmarking batch size %d, %d workers, compression %t", size, n, c)
+				r, err := benchOnce(ctx, clients[c], *index, articles, size, n)
+				if err != nil {
+					log.Error().Caller().Err(err).Msg("benchmark failed")
+					return exitFailure
+				}
+				r.compress = c
+				results = append(results, r)
+			}
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "BATCH\tWORKERS\tGZIP\tDOCS/S\tP50\tP99\tMAX\tFAILED\t")
+	for _, r := range results {
+		fmt.Fprintf(w, "%d\t%d\t%t\t%.0f\t%s\t%s\t%s\t%d\t\n",
+			r.batchSize, r.workers, r.compress,
+			float64(len(articles))/r.elapsed.Seconds(),
+			r.percentile(0.5).Round(time.Millisecond),
+			r.percentile(0.99).Round(time.Millisecond),
+			r.percentile(1).Round(time.Millisecond),
+			r.failed)
+	}
+	w.Flush()
+	return exitSuccess
+}
+
+// benchOnce recreates index and writes articles to it in batches of
+// size from workers goroutines.
+func benchOnce(ctx context.Context, es *elasticsearch.Client, index string, articles []model.Article, size, workers int) (benchResult, error) {
+	r := benchResult{batchSize: size, workers: workers}
+	if err := deleteIndex(es, index); err != nil {
+		return r, err
+	}
+	out := sink.NewElasticsearch(es, index)
+	if err := out.Prepare(ctx, sink.IndexOptions{}); err != nil {
+		return r, err
+	}
+
+	batches := make(chan []model.Article)
+	var mu sync.Mutex
+	var firstErr error
+	var wg sync.WaitGroup
+	start := time.Now()
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				began := time.Now()
+				err := out.WriteBatch(ctx, batch)
+				took := time.Since(began)
+
+				mu.Lock()
+				r.latencies = append(r.latencies, took)
+				var partial *sink.PartialError
+				switch {
+				case errors.As(err, &partial):
+					r.failed += partial.Failed
+				case err != nil:
+					r.failed += len(batch)
+					if firstErr == nil {
+						firstErr = err
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < len(articles); i += size {
+		batches <- articles[i:min(i+size, len(articles))]
+	}
+	close(batches)
+	wg.Wait()
+	r.elapsed = time.Since(start)
+	slices.Sort(r.latencies)
+	return r, firstErr
+}
+
+// deleteIndex deletes index, which may not exist.
+func deleteIndex(es *elasticsearch.Client, index string) error {
+	res, err := es.Indices.Delete([]string{index}, es.Indices.Delete.WithIgnoreUnavailable(true))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("deleting %s: %s", index, res.Status())
+	}
+	return nil
+}
+
+// parsePositiveInts parses a comma separated list of positive integers.
+func parsePositiveInts(list string) ([]int, error) {
+	var values []int
+	for _, v := range strings.Split(list, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("%q is not a positive number", v)
+		}
+		values = append(values, n)
+	}
+	return values, nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/sink"
+)
+
+// runCCR sets up cross-cluster replication of the news indices, run
+// against the follower cluster, e.g. in the DR region:
+//
+//	ccr setup --target https://dr:9200 --remote primary --seeds es1:9300,es2:9300 news
+//	ccr setup --remote primary --registered --auto-follow 'news-*'
+//	ccr pause news
+//	ccr resume news
+//	ccr status
+//
+// Indices default to the news index.
+func runCCR(args []string) int {
+	if len(args) == 0 {
+		exitWithConfigError(errors.New("expected setup, pause, resume or status"), "invalid configuration")
+	}
+	action, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("ccr "+action, flag.ExitOnError)
+	target := fs.String("target", "", "elasticsearch address of the follower cluster (default ES_URL)")
+	var indices []string
+	var run func(ctx context.Context, es *elasticsearch.Client) error
+	switch action {
+	case "setup":
+		remote := sink.RemoteCluster{}
+		fs.StringVar(&remote.Name, "remote", "", "name the leader cluster is registered under")
+		seeds := fs.String("seeds", "", "comma separated transport addresses of the leader cluster, e.g. es1:9300,es2:9300")
+		fs.StringVar(&remote.Proxy, "proxy", "", "address of a proxy in front of the leader cluster, instead of --seeds")
+		autoFollow := fs.String("auto-follow", "", "comma separated patterns of leader indices to follow as they are created, e.g. news-*")
+		registered := fs.Bool("registered", false, "the remote cluster is registered already")
+		indices = ccrIndices(fs, args)
+		remote.Seeds = splitList(*seeds)
+		switch {
+		case remote.Name == "":
+			exitWithConfigError(errors.New("--remote is required"), "invalid configuration")
+		case len(remote.Seeds) > 0 && remote.Proxy != "":
+			exitWithConfigError(errors.New("--seeds and --proxy are mutually exclusive"), "invalid configuration")
+		case !*registered && len(remote.Seeds) == 0 && remote.Proxy == "":
+			exitWithConfigError(errors.New("--seeds or --proxy is required unless --registered"), "invalid configuration")
+		}
+		run = func(ctx context.Context, es *elasticsearch.Client) error {
+			if !*registered {
+				if err := sink.RegisterRemoteCluster(ctx, es, remote); err != nil {
+					return err
+				}
+			}
+			if patterns := splitList(*autoFollow); len(patterns) > 0 {
+				if err := sink.AutoFollow(ctx, es, remote.Name+"-news", remote.Name, patterns); err != nil {
+					return err
+				}
+			}
+			for _, index := range indices {
+				if err := sink.Follow(ctx, es, remote.Name, index); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case "pause", "resume":
+		indices = ccrIndices(fs, args)
+		toggle := sink.PauseFollow
+		if action == "resume" {
+			toggle = sink.ResumeFollow
+		}
+		run = func(ctx context.Context, es *elasticsearch.Client) error {
+			for _, index := range indices {
+				if err := toggle(ctx, es, index); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case "status":
+		indices = ccrIndices(fs, args)
+		run = func(ctx context.Context, es *elasticsearch.Client) error {
+			followers, err := sink.Followers(ctx, es, indices)
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "INDEX\tREMOTE\tLEADER\tSTATUS")
+			for _, f := range followers {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", f.Index, f.Remote, f.Leader, f.Status)
+			}
+			return w.Flush()
+		}
+	default:
+		exitWithConfigError(fmt.Errorf("unknown action %q, expected setup, pause, resume or status", action), "invalid configuration")
+	}
+
+	es, err := newElasticsearchClient(*target)
+	if err != nil {
+		exitWithConfigError(err, "failed to create elasticsearch client")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := run(ctx, es); err != nil {
+		log.Error().Caller().Err(err).Msgf("ccr %s failed", action)
+		return exitFailure
+	}
+	return exitSuccess
+}
+
+// ccrIndices parses args with fs and returns the indices they name, the
+// news index when none.
+func ccrIndices(fs *flag.FlagSet, args []string) []string {
+	if indices := parseInterspersed(fs, args); len(indices) > 0 {
+		return indices
+	}
+	return []string{indexName}
+}
+
+// splitList splits a comma separated list, dropping empty entries.
+func splitList(list string) []string {
+	var entries []string
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/enrich"
+	"inshorts.com/inshorts-news-data-syncer/pkg/sink"
+)
+
+// runCleanup deletes the articles of an index past the retention of
+// their category or past their license, e.g.
+//
+//	cleanup --retention sports=30d,politics=365d,*=180d --archive-index news-archive
+//	cleanup --tenant brand-a --retention *=90d
+//	cleanup --purge-expired
+//
+// Syncs do the same after every run with --retention, or with license
+// windows in --source-registry.
+func runCleanup(args []string) int {
+	fs := flag.NewFlagSet("cleanup", flag.ExitOnError)
+	index := fs.String("index", indexName, "index to clean up")
+	spec := fs.String("retention", "", `days articles are kept by category, "*" for other categories, e.g. sports=30d,politics=365d,*=180d`)
+	archive := fs.String("archive-index", "", "index articles expired by --retention are copied to before they are deleted (empty deletes them)")
+	purge := fs.Bool("purge-expired", false, "delete the articles whose license ran out, going by their expires_at")
+	dryRun := fs.Bool("dry-run", false, "only log how many articles would expire")
+	tenant := fs.String("tenant", "", "only expire the articles of this tenant, in its <tenant>-<index>")
+	fs.Parse(args)
+
+	if *spec == "" && !*purge {
+		exitWithConfigError(errors.New("--retention or --purge-expired is required"), "invalid configuration")
+	}
+	x := expiry{licenses: *purge, tenant: *tenant, dryRun: *dryRun}
+	if *spec != "" {
+		var err error
+		if x.retention, err = sink.ParseRetention(*spec); err != nil {
+			exitWithConfigError(err, "invalid --retention")
+		}
+	} else if *archive != "" {
+		exitWithConfigError(errors.New("--archive-index needs --retention"), "invalid configuration")
+	}
+	if *archive == *index {
+		exitWithConfigError(errors.New("--archive-index must differ from --index"), "invalid configuration")
+	}
+	if err := checkTenant(*tenant); err != nil {
+		exitWithConfigError(err, "invalid configuration")
+	}
+	scope := config{index: *index, archiveIndex: *archive, tenant: *tenant}.scoped()
+	x.archive = scope.archiveIndex
+	es, err := newElasticsearchClient("")
+	if err != nil {
+		exitWithConfigError(err, "failed to create elasticsearch client")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	enrichers, err := enrich.FromEnv()
+	if err != nil {
+		exitWithConfigError(err, "error while configuring enrichers")
+	}
+	expire, err := newExpirer(es, []string{scope.index}, x, enrich.IndexOptionsFor(enrichers))
+	if err != nil {
+		log.Error().Caller().Err(err).Msg("cleanup failed")
+		return exitFailure
+	}
+	expired, err := expire(ctx)
+	if err != nil {
+		log.Error().Caller().Err(err).Msg("cleanup failed")
+		return exitFailure
+	}
+	if *dryRun {
+		log.Info().Caller().Msgf("dry run of the cleanup of %s finished, %d articles would expire", scope.index, expired)
+		return exitSuccess
+	}
+	log.Info().Caller().Msgf("cleanup of %s finished, %d articles expired", scope.index, expired)
+	return exitSuccess
+}
+
+// expiry is what an expirer deletes.
+type expiry struct {
+	// retention expires articles by category, unless nil, copying them
+	// to archive first unless it's empty.
+	retention sink.Retention
+	archive   string
+	// licenses purges the articles past their expires_at.
+	licenses bool
+	// tenant restricts expiry to the articles of a tenant.
+	tenant string
+	dryRun bool
+}
+
+// expirer returns the Syncer.Expire of --retention and of the license
+// windows of --source-registry, expiring the articles of the index and
+// of its category routes on every elasticsearch sink. It is nil when
+// neither is configured.
+func expirer(cfg config, es *elasticsearch.Client, opts sink.IndexOptions) func(context.Context) (int, error) {
+	x := expiry{archive: cfg.archiveIndex, tenant: cfg.tenant}
+	if cfg.retention != "" {
+		// validate parsed them
+		x.retention, _ = sink.ParseRetention(cfg.retention)
+	}
+	_, sources := cfg.access()
+	x.licenses = sources.Licensed()
+	if x.retention == nil && !x.licenses {
+		return nil
+	}
+	indices := []string{cfg.index}
+	if cfg.categoryRoutes != "" {
+		indices = append(indices, cfg.categoryRoutesOf().Indices()...)
+	}
+
+	var expires []func(context.Context) (int, error)
+	for _, spec := range cfg.sinkSpecs() {
+		if spec.kind != "elasticsearch" {
+			continue
+		}
+		client := es
+		if spec.target != "" {
+			var err error
+			if client, err = cfg.elasticsearchClient(spec.target); err != nil {
+				continue
+			}
+		}
+		expire, err := newExpirer(client, indices, x, opts)
+		if err != nil {
+			exitWithConfigError(err, "error while configuring the expiry of articles")
+		}
+		expires = append(expires, expire)
+	}
+	if len(expires) == 0 {
+		return nil
+	}
+	return func(ctx context.Context) (int, error) {
+		total := 0
+		for _, expire := range expires {
+			expired, err := expire(ctx)
+			total += expired
+			if err != nil {
+				return total, err
+			}
+		}
+		return total, nil
+	}
+}
+
+// newExpirer returns a function expiring the articles of indices on the
+// cluster of es as x says. The archive index is created with the article
+// mapping for opts on first use.
+func newExpirer(es *elasticsearch.Client, indices []string, x expiry, opts sink.IndexOptions) (func(context.Context) (int, error), error) {
+	version, err := clusterVersion(es)
+	if err != nil {
+		return nil, err
+	}
+	opts.Version = version
+
+	prepared := false
+	return func(ctx context.Context) (int, error) {
+		if x.retention != nil && x.archive != "" && !x.dryRun && !prepared {
+			if err := sink.NewElasticsearch(es, x.archive).Prepare(ctx, opts); err != nil {
+				return 0, err
+			}
+			prepared = true
+		}
+		total := 0
+		for _, index := range indices {
+			target := sink.NewElasticsearch(es, index).WithTenant(x.tenant)
+			if x.licenses {
+				purged, err := target.PurgeExpired(ctx, x.dryRun)
+				total += purged
+				if err != nil {
+					return total, err
+				}
+			}
+			if x.retention != nil {
+				expired, err := target.Expire(ctx, x.retention, x.archive, x.dryRun)
+				total += expired
+				if err != nil {
+					return total, err
+				}
+			}
+		}
+		return total, nil
+	}, nil
+}
@@ -0,0 +1,635 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"inshorts.com/inshorts-news-data-syncer/pkg/sink"
+	"inshorts.com/inshorts-news-data-syncer/pkg/source"
+	syncpkg "inshorts.com/inshorts-news-data-syncer/pkg/sync"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// config holds the command line options. Optional stages are still
+// configured through the environment by the code that owns them.
+type config struct {
+	// source is the URI articles are synced from: a file path, file:// or http(s)://.
+	source string
+	// jobs is a JSON file of datasets synced concurrently, each overriding
+	// some of the options below, see loadJobs.
+	jobs string
+	// index is the index, collection, table or topic synced into.
+	index string
+	// tenant is the brand synced for, prefixing the index names and
+	// tagging and scoping its articles, see scoped. Empty for none.
+	tenant string
+	// fieldMap is a JSON file mapping article fields to paths in the
+	// source objects, for inputs that use other field names.
+	fieldMap string
+	// sourceRegistry is a JSON file configuring news sources, see
+	// syncpkg.SourceRegistry.
+	sourceRegistry string
+	// accessFields are the document level security fields of every
+	// article, see syncpkg.ParseAccessFields.
+	accessFields string
+	// idStrategy lists how article IDs are derived, see
+	// syncpkg.ParseIDStrategies.
+	idStrategy string
+	// sample and limit sync a deterministic subset of the source, see
+	// syncpkg.Syncer.SampleRate.
+	sample string
+	limit  int
+	// shard is the "i/n" part of the source this instance syncs, see
+	// syncpkg.Syncer.Shard.
+	shard string
+	// keepExtra keeps source fields the model lacks under "extra".
+	keepExtra bool
+	// categoryRoutes is a JSON file of per category indices and
+	// enrichment, see syncpkg.CategoryRoutes.
+	categoryRoutes string
+	// validateInput validates every input object against the shipped
+	// article schema, or schemaFile when set, which implies it.
+	validateInput bool
+	schemaFile    string
+	// sink is a comma separated list of destinations, each kind[=target],
+	// e.g. "elasticsearch,opensearch=https://new-cluster:9200". Articles
+	// are written to all of them.
+	sink string
+	// output is the file written by file based sinks, "-" for stdout.
+	// A target given in --sink takes precedence.
+	output string
+
+	// deadLetter is a file rejected articles are appended to, empty for
+	// none.
+	deadLetter string
+	// dumpBatches is a directory the bulk request bodies are written to,
+	// empty for none.
+	dumpBatches string
+	// prune soft deletes articles of the elasticsearch index that a full
+	// sync didn't write.
+	prune bool
+	// history is the index versions overwritten in elasticsearch are
+	// archived to, empty for none.
+	history string
+	// retention expires articles of the elasticsearch index after every
+	// run, see sink.ParseRetention, copying them to archiveIndex first
+	// unless it's empty.
+	retention    string
+	archiveIndex string
+	// related stores the related articles of every synced article, found
+	// with more_like_this or kNN, see sink.Elasticsearch.Relate.
+	related     string
+	relatedSize int
+	// rollover makes --index a write alias of the elasticsearch sink,
+	// rolled over on these conditions, see sink.ParseRolloverConditions.
+	rollover string
+
+	// alerts is a comma separated list of where articles matching saved
+	// queries are reported: log, webhook=<url> or kafka[=<topic>]. Empty
+	// disables alerting.
+	alerts string
+
+	// notify is a comma separated list of where run summaries are posted:
+	// webhook[=<url>] or email[=<recipients>]. notifyOn is always or
+	// failure.
+	notify   string
+	notifyOn string
+
+	// routingField routes documents to shards by its value in
+	// elasticsearch and opensearch, e.g. "source_name".
+	routingField string
+
+	// shards, replicas, refreshInterval and codec are applied when the
+	// elasticsearch or opensearch index is created. replicas is -1 for
+	// the cluster default.
+	shards          int
+	replicas        int
+	refreshInterval string
+	codec           string
+	// languages adds language subfields to the text fields.
+	languages string
+	// synonyms is a file of synonym rules expanded at search time.
+	synonyms string
+
+	// compress gzips request bodies sent to elasticsearch and opensearch,
+	// which pays off when the cluster is in another region.
+	compress bool
+	// retries counts the retryable responses of the clients that write,
+	// for the run reports. Every job of --jobs has its own.
+	retries *sink.RetryStats
+
+	// pipeline overlaps reading, enrichment and writing.
+	pipeline bool
+	// maxMemory is the memory budget of the process in bytes, zero for
+	// none.
+	maxMemory int64
+
+	// maxDocsPerSec and maxBytesPerSec throttle writes to each sink. Zero
+	// means unlimited.
+	maxDocsPerSec  float64
+	maxBytesPerSec int64
+
+	// healthInterval gates writes to elasticsearch on cluster health,
+	// checked at most this often. Zero disables the checks.
+	healthInterval time.Duration
+
+	// force writes even when elasticsearch would run past its flood stage
+	// disk watermark.
+	force bool
+
+	// sourceTimezone is the IANA zone of source timestamps without one,
+	// e.g. "Asia/Kolkata". Empty means UTC.
+	sourceTimezone string
+	// onBadDate is the syncpkg.DatePolicy for unparseable publication dates.
+	onBadDate string
+	// dateLayouts are extra Go time layouts accepted for publication_date.
+	dateLayouts []string
+
+	// schedule is a standard 5 field cron expression for periodic syncs.
+	schedule string
+	// httpAddr is the listen address of the admin API in daemon mode.
+	httpAddr string
+
+	// grpcAddr optionally serves the gRPC API in daemon mode.
+	grpcAddr string
+
+	// ingest enables the /ingest endpoint for pushed articles in daemon mode.
+	ingest              bool
+	ingestFlushSize     int
+	ingestFlushInterval time.Duration
+
+	// maxRuntime bounds every sync, in job as well as daemon mode. Zero
+	// means no deadline.
+	maxRuntime time.Duration
+	// livenessAddr optionally serves /healthz and /metrics while a job
+	// mode sync runs.
+	livenessAddr string
+
+	// lock takes a distributed lock per index before syncing.
+	lock    bool
+	lockTTL time.Duration
+}
+
+// daemon reports whether the binary should keep running instead of
+// syncing once and exiting.
+func (c config) daemon() bool {
+	return c.schedule != "" || c.ingest || c.grpcAddr != ""
+}
+
+// parseFlags parses the command line options in args, normally
+// os.Args[1:].
+func parseFlags(args []string) config {
+	var cfg config
+	flag.StringVar(&cfg.source, "source", defaultSource, "articles to sync: a json file path or a file://, http:// or https:// uri")
+	flag.StringVar(&cfg.jobs, "jobs", "", "json file of jobs synced concurrently in one run, each with a name and optionally its own source, index, sink, output, field_map, schema_file, category_routes, source_registry, id_strategy, source_timezone and tenant")
+	flag.StringVar(&cfg.index, "index", indexName, "index to sync into, also the meilisearch and typesense collection and the default kafka topic")
+	flag.StringVar(&cfg.tenant, "tenant", "", "brand to sync for when several share a deployment: prefixes --index and the other index names with <tenant>-, tags every article with a tenant field and scopes --prune and --retention to the tenant's articles")
+	flag.StringVar(&cfg.fieldMap, "field-map", "", `json file mapping article fields to paths in the source objects, e.g. {"title": "$.headline", "publication_date": "$.meta.pub_date"}`)
+	flag.StringVar(&cfg.sourceRegistry, "source-registry", "", `json file configuring news sources by name: trust score, default category, enabled flag and max_articles_per_run`)
+	flag.StringVar(&cfg.accessFields, "access-fields", "", `document level security fields set on every article under "access", e.g. visibility=public,region=in, for search roles to filter on; sources of --source-registry can override them with their own "access"`)
+	flag.StringVar(&cfg.idStrategy, "id-strategy", string(syncpkg.IDInput), "comma separated ways to derive article ids, tried in order: input id, sha256 of the canonical url, uuid5 of title and publication date or a random uuid (auto), e.g. input,url")
+	flag.StringVar(&cfg.sample, "sample", "", `sync only this share of the articles, e.g. 1% or 0.01, picked by id hash so every run picks the same ones`)
+	flag.IntVar(&cfg.limit, "limit", 0, "sync at most this many articles, the same ones every run (0 disables)")
+	flag.StringVar(&cfg.shard, "shard", "", `sync only shard i of n, counting from 0, e.g. 2/4 on the third of four instances reading the same input; articles are split by id hash so the shards together sync every article once`)
+	flag.StringVar(&cfg.categoryRoutes, "category-routes", "", `json file routing categories to their own index and enrichment, e.g. [{"category": "sports", "index": "news-sports", "skip_enrichers": ["llm_summary"]}, {"category": "finance", "enrichers": ["entities"]}]`)
+	flag.BoolVar(&cfg.validateInput, "validate-input", false, "validate every input object against the json schema of the article format and fail on the first that doesn't match, naming its line and fields")
+	flag.StringVar(&cfg.schemaFile, "schema-file", "", "json schema file input objects are validated against instead of the article format, e.g. of the format a --field-map reads; implies --validate-input")
+	flag.BoolVar(&cfg.keepExtra, "keep-extra-fields", false, `keep source fields the article model doesn't know in an "extra" object, mapped dynamically`)
+	flag.StringVar(&cfg.sink, "sink", "elasticsearch", `comma separated destinations to write articles to, each elasticsearch, opensearch, meilisearch, typesense, kafka, postgres, sqlite or ndjson with an optional "=address", "=dsn" or "=file"`)
+	flag.StringVar(&cfg.output, "output", "-", `output file of file based sinks, "-" for stdout`)
+	flag.StringVar(&cfg.deadLetter, "dead-letter", "", "ndjson file rejected articles are appended to with the reason, for retry-dlq; jobs append to <file>.<job>")
+	flag.StringVar(&cfg.dumpBatches, "debug-dump-batches", "", "directory the exact ndjson body of every elasticsearch and opensearch bulk request is written to, one file per batch")
+	flag.BoolVar(&cfg.prune, "prune", false, "after a full sync, mark articles of the elasticsearch index gone from the source deleted instead of removing them; the <index>-live alias leaves them out and syncing them again restores them")
+	flag.StringVar(&cfg.retention, "retention", "", `after every run, delete articles of the elasticsearch index published longer ago than the days kept for their category, "*" for other categories, e.g. sports=30d,politics=365d,*=180d`)
+	flag.StringVar(&cfg.archiveIndex, "archive-index", "", "index articles expired by --retention are copied to before they are deleted")
+	flag.StringVar(&cfg.related, "related", "", `after every run, store the ids of the most related articles of each synced article in its related_ids, found with mlt (more_like_this on title and description) or knn (nearest embeddings, needs the embedding enricher)`)
+	flag.IntVar(&cfg.relatedSize, "related-size", 5, "number of related articles --related stores per article")
+	flag.StringVar(&cfg.rollover, "rollover", "", "write to --index as an elasticsearch write alias, bootstrapped with <index>-000001 and rolled over to a new index before a run once one of these conditions is met, e.g. max_docs=10000000,max_size=50gb,max_age=30d")
+	flag.StringVar(&cfg.history, "history-index", "", "archive the current version of every document the elasticsearch sink overwrites into this index first, e.g. news-history")
+	flag.StringVar(&cfg.alerts, "alerts", "", `percolate written articles against the saved alert queries and report matches to log, webhook=<url> and/or kafka[=<topic>], comma separated`)
+	flag.StringVar(&cfg.notify, "notify", "", "post a summary of every run to webhook[=<url>], a slack compatible incoming webhook, NOTIFY_WEBHOOK_URL by default, and/or mail it to email[=<a@x.com;b@y.com>] through SMTP_ADDR, NOTIFY_EMAIL_TO by default; comma separated")
+	flag.StringVar(&cfg.notifyOn, "notify-on", "always", "runs --notify reports: always or failure")
+	flag.StringVar(&cfg.routingField, "routing-field", "", "route documents to shards by this field in elasticsearch and opensearch, e.g. source_name or category; changing it on an existing index duplicates documents")
+	flag.IntVar(&cfg.shards, "shards", 0, "number_of_shards of a newly created index (0 keeps the cluster default)")
+	flag.IntVar(&cfg.replicas, "replicas", -1, "number_of_replicas of a newly created index (-1 keeps the cluster default)")
+	flag.StringVar(&cfg.refreshInterval, "refresh-interval", "", `refresh_interval of a newly created index, e.g. 30s or -1 to disable refreshes`)
+	flag.StringVar(&cfg.codec, "index-codec", "", "stored fields codec of a newly created index: default or best_compression")
+	flag.StringVar(&cfg.languages, "languages", "", "comma separated language analyzers to also index title, description and llm_summary with, e.g. hindi,spanish")
+	flag.StringVar(&cfg.synonyms, "synonyms", "", `file of synonym rules expanded when searching, one per line such as "PM, Prime Minister"; kept in sync on elasticsearch, applied at index creation on opensearch`)
+	flag.BoolVar(&cfg.compress, "compress-requests", false, "gzip bulk and other request bodies sent to elasticsearch and opensearch, trading cpu for bandwidth")
+	flag.BoolVar(&cfg.pipeline, "pipeline", false, "read, enrich and write batches concurrently so slow enrichment doesn't stall reads or bulk requests; --limit then keeps the first sampled articles; not supported with the run wide relevance normalisation or story clustering")
+	flag.Func("max-memory", "memory budget of a --pipeline run, e.g. 512MB: reads pause and bulk batches shrink as the heap nears it instead of the container getting oom killed (0 disables)", func(value string) error {
+		size, err := utils.ParseByteSize(value)
+		cfg.maxMemory = size
+		return err
+	})
+	flag.Float64Var(&cfg.maxDocsPerSec, "max-docs-per-sec", 0, "maximum documents written to each sink per second (0 disables)")
+	flag.Func("max-bytes-per-sec", "maximum bytes written to each sink per second, e.g. 5MB (0 disables)", func(value string) error {
+		size, err := utils.ParseByteSize(value)
+		cfg.maxBytesPerSec = size
+		return err
+	})
+	flag.DurationVar(&cfg.healthInterval, "health-check-interval", 0, "pause writes to elasticsearch while the cluster is red or its write queues are saturated, checking this often, e.g. 30s (0 disables)")
+	flag.BoolVar(&cfg.force, "force", false, "sync even when the data would push an elasticsearch node past its flood stage disk watermark")
+	flag.StringVar(&cfg.sourceTimezone, "source-timezone", "", `time zone of source timestamps without an offset, e.g. "Asia/Kolkata" (default UTC)`)
+	flag.StringVar(&cfg.onBadDate, "on-bad-date", string(syncpkg.DateFail), "what to do with articles whose publication_date can't be parsed: fail the run, skip the article, omit the date or use the time of the sync (now)")
+	flag.Func("date-layout", `extra Go time layout accepted for publication_date, e.g. "02/01/2006 15:04", may be repeated`, func(layout string) error {
+		cfg.dateLayouts = append(cfg.dateLayouts, layout)
+		return nil
+	})
+	flag.StringVar(&cfg.schedule, "schedule", "", `cron expression to run syncs as a daemon, e.g. "0 */2 * * *"`)
+	flag.StringVar(&cfg.httpAddr, "http-addr", ":8080", "listen address of the admin api in daemon mode")
+	flag.StringVar(&cfg.grpcAddr, "grpc-addr", "", "listen address of the grpc ingestion and search api, e.g. :9090 (empty disables)")
+	flag.BoolVar(&cfg.ingest, "ingest", false, "accept pushed articles on POST /ingest, and over grpc with --grpc-addr, in daemon mode")
+	flag.IntVar(&cfg.ingestFlushSize, "ingest-flush-size", sink.DefaultBulkSize, "number of buffered ingested articles that triggers a bulk flush")
+	flag.DurationVar(&cfg.ingestFlushInterval, "ingest-flush-interval", 5*time.Second, "maximum time ingested articles are buffered before a flush")
+	flag.DurationVar(&cfg.maxRuntime, "max-runtime", 0, "deadline for each sync run, e.g. 30m (0 disables)")
+	flag.StringVar(&cfg.livenessAddr, "liveness-addr", "", "listen address of the liveness and metrics endpoints in job mode, e.g. :8081")
+	flag.BoolVar(&cfg.lock, "lock", false, "take a distributed lock in elasticsearch so only one sync runs per index")
+	flag.DurationVar(&cfg.lockTTL, "lock-ttl", 5*time.Minute, "time after which a lock that is no longer renewed can be taken over")
+	flag.CommandLine.Parse(args)
+	cfg.retries = new(sink.RetryStats)
+	return cfg
+}
+
+// tenantName guards --tenant, which becomes part of index names.
+var tenantName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// checkTenant returns an error when tenant can't prefix index names.
+func checkTenant(tenant string) error {
+	if tenant != "" && !tenantName.MatchString(tenant) {
+		return fmt.Errorf("invalid --tenant %q, expected lowercase letters, digits, - and _", tenant)
+	}
+	return nil
+}
+
+// tenantIndex returns index prefixed with the tenant, unchanged without
+// one or when empty.
+func (c config) tenantIndex(index string) string {
+	if c.tenant == "" || index == "" {
+		return index
+	}
+	return c.tenant + "-" + index
+}
+
+// scoped returns c with the index names of the tenant. It's applied
+// once, after validate; category route indices are prefixed as they're
+// loaded, see categoryRoutesOf.
+func (c config) scoped() config {
+	c.index = c.tenantIndex(c.index)
+	c.history = c.tenantIndex(c.history)
+	c.archiveIndex = c.tenantIndex(c.archiveIndex)
+	return c
+}
+
+// categoryRoutesOf loads --category-routes, already checked by validate,
+// with the route indices of the tenant.
+func (c config) categoryRoutesOf() syncpkg.CategoryRoutes {
+	routes, _ := syncpkg.LoadCategoryRoutes(c.categoryRoutes)
+	for i := range routes {
+		routes[i].Index = c.tenantIndex(routes[i].Index)
+	}
+	return routes
+}
+
+// access returns the parsed --access-fields and --source-registry,
+// already checked by validate.
+func (c config) access() (map[string]string, syncpkg.SourceRegistry) {
+	var access map[string]string
+	if c.accessFields != "" {
+		access, _ = syncpkg.ParseAccessFields(c.accessFields)
+	}
+	var sources syncpkg.SourceRegistry
+	if c.sourceRegistry != "" {
+		sources, _ = syncpkg.LoadSourceRegistry(c.sourceRegistry)
+	}
+	return access, sources
+}
+
+// location returns the zone of --source-timezone, nil when unset.
+func (c config) location() (*time.Location, error) {
+	if c.sourceTimezone == "" {
+		return nil, nil
+	}
+	return time.LoadLocation(c.sourceTimezone)
+}
+
+// indexSettings returns the settings of newly created indices.
+func (c config) indexSettings() sink.IndexSettings {
+	settings := sink.IndexSettings{
+		Shards:          c.shards,
+		RefreshInterval: c.refreshInterval,
+		Codec:           c.codec,
+	}
+	if c.replicas >= 0 {
+		replicas := c.replicas
+		settings.Replicas = &replicas
+	}
+	// Checked by validate
+	settings.Languages, _ = sink.ParseLanguages(c.languages)
+	if c.synonyms != "" {
+		settings.Synonyms, _ = sink.LoadSynonyms(c.synonyms)
+	}
+	if c.accessFields != "" || c.sourceRegistry != "" {
+		access, sources := c.access()
+		settings.AccessFields = syncpkg.AccessFieldNames(access, sources)
+	}
+	return settings
+}
+
+// validate checks option values that flag parsing alone can't catch.
+func (c config) validate() error {
+	if c.jobs != "" {
+		return c.validateJobs()
+	}
+	if c.index == "" {
+		return errors.New("--index must not be empty")
+	}
+	if err := checkTenant(c.tenant); err != nil {
+		return err
+	}
+	if c.accessFields != "" {
+		if _, err := syncpkg.ParseAccessFields(c.accessFields); err != nil {
+			return fmt.Errorf("invalid --access-fields: %w", err)
+		}
+	}
+	if c.dumpBatches != "" {
+		if err := os.MkdirAll(c.dumpBatches, 0o755); err != nil {
+			return fmt.Errorf("invalid --debug-dump-batches: %w", err)
+		}
+	}
+	if c.prune && (c.ingest || c.grpcAddr != "") {
+		return errors.New("--prune needs full syncs and can't be combined with --ingest or --grpc-addr")
+	}
+	if c.related != "" {
+		if c.related != sink.RelatedMoreLikeThis && c.related != sink.RelatedKNN {
+			return fmt.Errorf("invalid --related %q, expected %s or %s", c.related, sink.RelatedMoreLikeThis, sink.RelatedKNN)
+		}
+		if c.relatedSize < 1 {
+			return errors.New("--related-size must be positive")
+		}
+		if c.ingest || c.grpcAddr != "" {
+			return errors.New("--related applies to synced runs and can't be combined with --ingest or --grpc-addr")
+		}
+	}
+	if c.rollover != "" {
+		if _, err := sink.ParseRolloverConditions(c.rollover); err != nil {
+			return fmt.Errorf("invalid --rollover: %w", err)
+		}
+	}
+	if c.retention != "" {
+		if _, err := sink.ParseRetention(c.retention); err != nil {
+			return fmt.Errorf("invalid --retention: %w", err)
+		}
+		if c.archiveIndex == c.index {
+			return errors.New("--archive-index must differ from --index")
+		}
+	} else if c.archiveIndex != "" {
+		return errors.New("--archive-index needs --retention")
+	}
+	if c.categoryRoutes != "" {
+		routes, err := syncpkg.LoadCategoryRoutes(c.categoryRoutes)
+		if err != nil {
+			return fmt.Errorf("invalid --category-routes: %w", err)
+		}
+		if c.ingest || c.grpcAddr != "" {
+			return errors.New("--category-routes applies to synced sources and can't be combined with --ingest or --grpc-addr")
+		}
+		if slices.Contains(routes.Indices(), c.index) {
+			return fmt.Errorf("category routes can't route to --index %s itself", c.index)
+		}
+	}
+	if c.history != "" && c.history == c.index {
+		return errors.New("--history-index must differ from --index")
+	}
+	if c.schedule != "" {
+		if _, err := cron.ParseStandard(c.schedule); err != nil {
+			return fmt.Errorf("invalid --schedule %q: %w", c.schedule, err)
+		}
+	}
+	kinds := make(map[string]bool)
+	for _, spec := range c.sinkSpecs() {
+		switch spec.kind {
+		case "elasticsearch", "opensearch", "meilisearch", "kafka", "ndjson":
+		case "typesense":
+			if os.Getenv("TYPESENSE_API_KEY") == "" {
+				return errors.New("--sink=typesense needs TYPESENSE_API_KEY")
+			}
+		case "sqlite":
+			if spec.output(c) == "-" {
+				return errors.New("--sink=sqlite needs a database file, set --output or use sqlite=<file>")
+			}
+		case "postgres":
+			if spec.target == "" && os.Getenv("POSTGRES_DSN") == "" {
+				return errors.New("--sink=postgres needs a dsn, set POSTGRES_DSN or use postgres=<dsn>")
+			}
+		default:
+			return fmt.Errorf("unknown --sink %q, expected elasticsearch, opensearch, meilisearch, typesense, kafka, postgres, sqlite or ndjson", spec.kind)
+		}
+		kinds[spec.kind] = true
+	}
+	// The lock is stored with the elasticsearch client, which refuses to
+	// talk to OpenSearch.
+	if c.lock && kinds["opensearch"] && !kinds["elasticsearch"] {
+		return errors.New("--lock requires an elasticsearch sink when writing to opensearch")
+	}
+	if c.rollover != "" && !kinds["elasticsearch"] {
+		return errors.New("--rollover requires an elasticsearch sink")
+	}
+	if c.fieldMap != "" {
+		if _, err := source.LoadFieldMapping(c.fieldMap); err != nil {
+			return fmt.Errorf("invalid --field-map: %w", err)
+		}
+	}
+	if c.schemaFile != "" {
+		if _, err := source.LoadSchema(c.schemaFile); err != nil {
+			return fmt.Errorf("invalid --schema-file: %w", err)
+		}
+	} else if c.validateInput && c.fieldMap != "" {
+		// The shipped schema describes the input before it is mapped
+		return errors.New("--validate-input with --field-map needs a --schema-file describing the mapped input")
+	}
+	if _, err := c.location(); err != nil {
+		return fmt.Errorf("invalid --source-timezone: %w", err)
+	}
+	if c.sourceRegistry != "" {
+		if _, err := syncpkg.LoadSourceRegistry(c.sourceRegistry); err != nil {
+			return fmt.Errorf("invalid --source-registry: %w", err)
+		}
+	}
+	if _, err := syncpkg.ParseIDStrategies(c.idStrategy); err != nil {
+		return fmt.Errorf("invalid --id-strategy: %w", err)
+	}
+	if c.sample != "" {
+		if _, err := syncpkg.ParseSampleRate(c.sample); err != nil {
+			return fmt.Errorf("invalid --sample: %w", err)
+		}
+	}
+	if c.limit < 0 {
+		return errors.New("--limit must not be negative")
+	}
+	if c.shard != "" {
+		if _, err := syncpkg.ParseShard(c.shard); err != nil {
+			return fmt.Errorf("invalid --shard: %w", err)
+		}
+		if c.ingest || c.grpcAddr != "" {
+			return errors.New("--shard splits a source between instances and can't be combined with --ingest or --grpc-addr")
+		}
+	}
+	if _, err := syncpkg.ParseDatePolicy(c.onBadDate); err != nil {
+		return fmt.Errorf("invalid --on-bad-date: %w", err)
+	}
+	for _, spec := range c.alertSpecs() {
+		switch spec.kind {
+		case "log", "kafka":
+		case "webhook":
+			if spec.target == "" {
+				return errors.New("--alerts=webhook needs a url, use webhook=<url>")
+			}
+		default:
+			return fmt.Errorf("unknown --alerts %q, expected log, webhook or kafka", spec.kind)
+		}
+	}
+	for _, spec := range c.notifySpecs() {
+		switch spec.kind {
+		case "webhook":
+			if spec.target == "" && os.Getenv("NOTIFY_WEBHOOK_URL") == "" {
+				return errors.New("--notify=webhook needs a url, use webhook=<url> or set NOTIFY_WEBHOOK_URL")
+			}
+		case "email":
+			if len(emailRecipients(spec.target)) == 0 {
+				return errors.New("--notify=email needs recipients, use email=<a@x.com;b@y.com> or set NOTIFY_EMAIL_TO")
+			}
+			if os.Getenv("SMTP_ADDR") == "" || os.Getenv("SMTP_FROM") == "" {
+				return errors.New("--notify=email needs SMTP_ADDR and SMTP_FROM")
+			}
+		default:
+			return fmt.Errorf("unknown --notify %q, expected webhook or email", spec.kind)
+		}
+	}
+	if c.notifyOn != "always" && c.notifyOn != "failure" {
+		return fmt.Errorf("invalid --notify-on %q, expected always or failure", c.notifyOn)
+	}
+	switch c.routingField {
+	case "", "source_name", "category", "country", "state", "city", "location_name", "author", "tags":
+	default:
+		return fmt.Errorf("unsupported --routing-field %q", c.routingField)
+	}
+	if c.shards < 0 {
+		return errors.New("--shards must not be negative")
+	}
+	if c.replicas < -1 {
+		return errors.New("--replicas must be -1 or more")
+	}
+	if c.refreshInterval != "" && c.refreshInterval != "-1" {
+		if _, err := time.ParseDuration(c.refreshInterval); err != nil {
+			return fmt.Errorf("invalid --refresh-interval: %w", err)
+		}
+	}
+	if _, err := sink.ParseLanguages(c.languages); err != nil {
+		return fmt.Errorf("invalid --languages: %w", err)
+	}
+	if c.synonyms != "" {
+		if _, err := sink.LoadSynonyms(c.synonyms); err != nil {
+			return fmt.Errorf("invalid --synonyms: %w", err)
+		}
+	}
+	switch c.codec {
+	case "", "default", "best_compression":
+	default:
+		return fmt.Errorf("unknown --index-codec %q, expected default or best_compression", c.codec)
+	}
+	if c.maxMemory < 0 {
+		return errors.New("--max-memory must not be negative")
+	}
+	if c.maxMemory > 0 && !c.pipeline {
+		return errors.New("--max-memory requires --pipeline, without it the whole source is read into memory before writing")
+	}
+	if c.maxDocsPerSec < 0 {
+		return errors.New("--max-docs-per-sec must not be negative")
+	}
+	if c.healthInterval < 0 {
+		return errors.New("--health-check-interval must not be negative")
+	}
+	if c.ingestFlushSize < 1 {
+		return errors.New("--ingest-flush-size must be positive")
+	}
+	if c.ingestFlushInterval <= 0 {
+		return errors.New("--ingest-flush-interval must be positive")
+	}
+	if c.lock && c.lockTTL < 3*time.Second {
+		return errors.New("--lock-ttl must be at least 3s")
+	}
+	if c.maxRuntime < 0 {
+		return errors.New("--max-runtime must not be negative")
+	}
+	return nil
+}
+
+// sinkSpec is one destination of --sink or --alerts.
+type sinkSpec struct {
+	kind string
+	// target optionally overrides the address or output file of the sink.
+	target string
+}
+
+// output returns the file a file based sink writes to.
+func (s sinkSpec) output(c config) string {
+	if s.target != "" {
+		return s.target
+	}
+	return c.output
+}
+
+// alertSpecs returns the notifiers of --alerts, which share the
+// kind[=target] syntax of --sink.
+func (c config) alertSpecs() []sinkSpec {
+	if c.alerts == "" {
+		return nil
+	}
+	return parseSpecs(c.alerts)
+}
+
+// notifySpecs returns the notifiers of --notify.
+func (c config) notifySpecs() []sinkSpec {
+	if c.notify == "" {
+		return nil
+	}
+	return parseSpecs(c.notify)
+}
+
+// emailRecipients returns the semicolon separated addresses of an email
+// notifier, or the comma separated ones of NOTIFY_EMAIL_TO.
+func emailRecipients(target string) []string {
+	sep := ";"
+	if target == "" {
+		target, sep = os.Getenv("NOTIFY_EMAIL_TO"), ","
+	}
+	var recipients []string
+	for _, to := range strings.Split(target, sep) {
+		if to = strings.TrimSpace(to); to != "" {
+			recipients = append(recipients, to)
+		}
+	}
+	return recipients
+}
+
+func (c config) sinkSpecs() []sinkSpec {
+	return parseSpecs(c.sink)
+}
+
+func parseSpecs(list string) []sinkSpec {
+	var specs []sinkSpec
+	for _, raw := range strings.Split(list, ",") {
+		kind, target, _ := strings.Cut(strings.TrimSpace(raw), "=")
+		specs = append(specs, sinkSpec{kind: kind, target: target})
+	}
+	return specs
+}
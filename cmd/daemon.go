@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"inshorts.com/inshorts-news-data-syncer/pkg/sink"
+	syncpkg "inshorts.com/inshorts-news-data-syncer/pkg/sync"
+)
+
+// daemon runs syncs on a cron schedule or on demand, serves the admin API
+// and optionally accepts pushed articles.
+type daemon struct {
+	ctx    context.Context
+	syncer *syncpkg.Syncer
+	ingest *ingestBuffer
+	runs   sync.WaitGroup
+	// maxRuntime bounds each sync, zero meaning no deadline.
+	maxRuntime time.Duration
+
+	mu         sync.Mutex
+	running    bool
+	startedAt  time.Time
+	lastReport *syncpkg.Report
+}
+
+// runDaemon blocks until ctx is cancelled, then waits for an in-flight
+// sync to finish before returning.
+func runDaemon(ctx context.Context, cfg config, s *syncpkg.Syncer, es *elasticsearch.Client) error {
+	// Background work is stopped through this context when the admin API fails.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	d := &daemon{ctx: ctx, syncer: s, maxRuntime: cfg.maxRuntime}
+
+	scheduler := cron.New()
+	if cfg.schedule != "" {
+		if _, err := scheduler.AddFunc(cfg.schedule, func() {
+			if !d.trigger("schedule") {
+				log.Warn().Caller().Msg("skipping scheduled sync, previous run still in progress")
+			}
+		}); err != nil {
+			return err
+		}
+	}
+
+	if cfg.ingest {
+		d.ingest = newIngestBuffer(cfg, s)
+		d.runs.Add(1)
+		go func() {
+			defer d.runs.Done()
+			d.ingest.run(ctx)
+		}()
+	}
+
+	var grpcServer *grpc.Server
+	var grpcListener net.Listener
+	if cfg.grpcAddr != "" {
+		search, err := newSearchServer(es, cfg.index)
+		if err != nil {
+			return err
+		}
+		if grpcListener, err = net.Listen("tcp", cfg.grpcAddr); err != nil {
+			return err
+		}
+		grpcServer = newGRPCServer(search, d.ingest)
+	}
+
+	server := &http.Server{Addr: cfg.httpAddr, Handler: d.routes(), ReadHeaderTimeout: 5 * time.Second}
+
+	serverErr := make(chan error, 2)
+	go func() {
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	if grpcServer != nil {
+		go func() {
+			if err := grpcServer.Serve(grpcListener); err != nil {
+				serverErr <- err
+			}
+		}()
+		log.Info().Caller().Msgf("grpc api on %s", cfg.grpcAddr)
+	}
+
+	scheduler.Start()
+	log.Info().Caller().Msgf("daemon started with schedule %q, admin api on %s", cfg.schedule, cfg.httpAddr)
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-serverErr:
+	}
+
+	log.Info().Caller().Msg("daemon stopping, waiting for running syncs and ingest flushes to finish")
+	scheduler.Stop()
+	cancel()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if shutdownErr := server.Shutdown(shutdownCtx); err == nil {
+		err = shutdownErr
+	}
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
+	d.runs.Wait()
+	return err
+}
+
+func (d *daemon) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /sync", d.handleSync)
+	mux.HandleFunc("GET /status", d.handleStatus)
+	mux.HandleFunc("GET /healthz", d.handleHealth)
+	mux.HandleFunc("GET /readyz", d.handleReady)
+	mux.HandleFunc("GET /metrics", handleMetrics)
+	if d.ingest != nil {
+		mux.HandleFunc("POST /ingest", d.ingest.handleIngest)
+	}
+	return mux
+}
+
+// trigger starts a sync in the background unless one is already in
+// progress, which protects against overlapping runs. It reports whether
+// a run was started.
+func (d *daemon) trigger(reason string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.running {
+		return false
+	}
+	d.running = true
+	d.startedAt = time.Now().UTC()
+
+	d.runs.Add(1)
+	go func() {
+		defer d.runs.Done()
+		log.Info().Caller().Msgf("sync triggered by %s", reason)
+
+		ctx := d.ctx
+		if d.maxRuntime > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, d.maxRuntime)
+			defer cancel()
+		}
+		report := d.syncer.Run(ctx)
+		report.Log()
+		logTransportMetrics()
+
+		d.mu.Lock()
+		d.running = false
+		d.lastReport = report
+		d.mu.Unlock()
+	}()
+	return true
+}
+
+func (d *daemon) handleSync(w http.ResponseWriter, _ *http.Request) {
+	if !d.trigger("api") {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "a sync is already running"})
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "started"})
+}
+
+func (d *daemon) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	d.mu.Lock()
+	body := struct {
+		Running    bool            `json:"running"`
+		StartedAt  *time.Time      `json:"started_at,omitempty"`
+		LastReport *syncpkg.Report `json:"last_report,omitempty"`
+	}{Running: d.running, LastReport: d.lastReport}
+	if d.running {
+		startedAt := d.startedAt
+		body.StartedAt = &startedAt
+	}
+	d.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, body)
+}
+
+// handleHealth reports liveness: the process is up and serving.
+func (d *daemon) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReady reports readiness: the sink's destination is reachable.
+func (d *daemon) handleReady(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if pinger, ok := d.syncer.Sink.(sink.Pinger); ok {
+		if err := pinger.Ping(ctx); err != nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable", "error": err.Error()})
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Error().Caller().Err(err).Msg("failed to write http response")
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"inshorts.com/inshorts-news-data-syncer/pkg/sink"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// checkStatus is the outcome of a doctor check.
+type checkStatus string
+
+const (
+	checkOK   checkStatus = "OK"
+	checkWarn checkStatus = "WARN"
+	checkFail checkStatus = "FAIL"
+	checkSkip checkStatus = "SKIP"
+)
+
+// checkResult is a doctor check, with a hint on how to fix it when it
+// didn't pass.
+type checkResult struct {
+	name   string
+	status checkStatus
+	detail string
+	hint   string
+}
+
+// runDoctor checks that a sync into the cluster can work before a long
+// one is attempted: connectivity, TLS trust, authentication, version,
+// privileges on the index and disk space, e.g.
+//
+//	doctor --index inshorts-news
+//
+// It exits with exitFailure when any check fails.
+func runDoctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	target := fs.String("target", "", "elasticsearch address to check (default ES_URL)")
+	index := fs.String("index", indexName, "index the sync writes to")
+	fs.Parse(args)
+
+	address := *target
+	if address == "" {
+		address = utils.GetEnv("ES_URL", defaultESAddress)
+	}
+	es, err := newElasticsearchClient(address)
+	if err != nil {
+		exitWithConfigError(err, "failed to create elasticsearch client")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	d := &doctor{es: es, address: address, index: *index}
+	results := []checkResult{d.tlsTrust(ctx)}
+	connected := d.connectivity(ctx)
+	results = append(results, connected)
+	if connected.status != checkOK {
+		results = append(results, d.skipped("version", "authentication", "privileges", "cluster health", "disk space")...)
+	} else {
+		results = append(results, d.version(), d.authentication(ctx), d.privileges(ctx), d.health(ctx), d.diskSpace(ctx))
+	}
+
+	code := exitSuccess
+	for _, r := range results {
+		fmt.Printf("%-4s  %-15s %s\n", r.status, r.name, r.detail)
+		if r.hint != "" {
+			fmt.Printf("      %-15s -> %s\n", "", r.hint)
+		}
+		if r.status == checkFail {
+			code = exitFailure
+		}
+	}
+	return code
+}
+
+type doctor struct {
+	es      *elasticsearch.Client
+	address string
+	index   string
+	// cluster is the version of the cluster, set by connectivity.
+	cluster sink.Version
+}
+
+func (d *doctor) skipped(names ...string) []checkResult {
+	results := make([]checkResult, len(names))
+	for i, name := range names {
+		results[i] = checkResult{name: name, status: checkSkip, detail: "the cluster isn't reachable"}
+	}
+	return results
+}
+
+// tlsTrust verifies the certificate of an https address. The clients
+// don't verify it, so an untrusted one only warns.
+func (d *doctor) tlsTrust(ctx context.Context) checkResult {
+	r := checkResult{name: "tls trust"}
+	u, err := url.Parse(d.address)
+	if err != nil {
+		r.status, r.detail, r.hint = checkFail, err.Error(), "set ES_URL to a url such as https://host:9200"
+		return r
+	}
+	if u.Scheme != "https" {
+		r.status, r.detail = checkWarn, "plain http, credentials and articles travel unencrypted"
+		r.hint = "use an https address unless the cluster is on a trusted network"
+		return r
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "443")
+	}
+	dialer := &tls.Dialer{Config: &tls.Config{ServerName: u.Hostname()}}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		r.status, r.detail = checkWarn, err.Error()
+		r.hint = "the syncer skips certificate verification, add the cluster CA to the system trust store to verify it"
+		return r
+	}
+	state := conn.(*tls.Conn).ConnectionState()
+	conn.Close()
+	cert := state.PeerCertificates[0]
+	r.status, r.detail = checkOK, fmt.Sprintf("certificate of %s trusted, expires %s", cert.Subject.CommonName, cert.NotAfter.Format("2006-01-02"))
+	if until := time.Until(cert.NotAfter); until < 30*24*time.Hour {
+		r.status, r.hint = checkWarn, "the certificate expires within 30 days"
+	}
+	return r
+}
+
+func (d *doctor) connectivity(ctx context.Context) checkResult {
+	r := checkResult{name: "connectivity"}
+	res, err := d.es.Info(d.es.Info.WithContext(ctx))
+	if err != nil {
+		r.status, r.detail = checkFail, err.Error()
+		r.hint = fmt.Sprintf("check that %s is reachable from here and ES_URL is right", d.address)
+		return r
+	}
+	defer res.Body.Close()
+	switch {
+	case res.StatusCode == 401:
+		r.status, r.detail = checkFail, "authentication failed: "+res.Status()
+		r.hint = "check ES_USERNAME and ES_PASSWORD, or ES_API_KEY"
+		return r
+	case res.IsError():
+		r.status, r.detail = checkFail, res.String()
+		return r
+	}
+	var info struct {
+		Version struct {
+			Number       string `json:"number"`
+			BuildFlavor  string `json:"build_flavor"`
+			Distribution string `json:"distribution"`
+		} `json:"version"`
+		ClusterName string `json:"cluster_name"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		r.status, r.detail = checkFail, err.Error()
+		return r
+	}
+	version, err := sink.ParseVersion(info.Version.Number, info.Version.BuildFlavor, info.Version.Distribution)
+	if err != nil {
+		r.status, r.detail = checkFail, err.Error()
+		return r
+	}
+	d.cluster = version
+	r.status, r.detail = checkOK, fmt.Sprintf("reached cluster %s at %s", info.ClusterName, d.address)
+	return r
+}
+
+func (d *doctor) version() checkResult {
+	r := checkResult{name: "version", status: checkOK, detail: "elasticsearch " + d.cluster.String()}
+	if err := d.cluster.Supported(); err != nil {
+		r.status, r.detail = checkFail, err.Error()
+	}
+	return r
+}
+
+func (d *doctor) authentication(ctx context.Context) checkResult {
+	r := checkResult{name: "authentication"}
+	res, err := d.es.Security.Authenticate(d.es.Security.Authenticate.WithContext(ctx))
+	if err != nil {
+		r.status, r.detail = checkFail, err.Error()
+		return r
+	}
+	defer res.Body.Close()
+	if res.StatusCode == 401 {
+		r.status, r.detail, r.hint = checkFail, res.Status(), "check ES_USERNAME and ES_PASSWORD, or ES_API_KEY"
+		return r
+	}
+	if res.IsError() {
+		// Security is disabled on the cluster
+		r.status, r.detail = checkWarn, "security isn't enabled, anyone reaching the cluster can write to it"
+		return r
+	}
+	var user struct {
+		Username string   `json:"username"`
+		Roles    []string `json:"roles"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&user); err != nil {
+		r.status, r.detail = checkFail, err.Error()
+		return r
+	}
+	r.status, r.detail = checkOK, fmt.Sprintf("authenticated as %s, roles %s", user.Username, strings.Join(user.Roles, ", "))
+	return r
+}
+
+// privileges checks the index privileges a sync uses: creating the index
+// and its mapping, writing and reading documents.
+func (d *doctor) privileges(ctx context.Context) checkResult {
+	r := checkResult{name: "privileges"}
+	needed := []string{"write", "read", "view_index_metadata", "manage"}
+	exists, err := d.es.Indices.Exists([]string{d.index}, d.es.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		r.status, r.detail = checkFail, err.Error()
+		return r
+	}
+	exists.Body.Close()
+	if exists.StatusCode == 404 {
+		needed = append(needed, "create_index")
+	}
+
+	query, _ := json.Marshal(map[string]interface{}{
+		"cluster": []string{"monitor"},
+		"index":   []map[string]interface{}{{"names": []string{d.index}, "privileges": needed}},
+	})
+	res, err := d.es.Security.HasPrivileges(bytes.NewReader(query), d.es.Security.HasPrivileges.WithContext(ctx))
+	if err != nil {
+		r.status, r.detail = checkFail, err.Error()
+		return r
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		r.status, r.detail = checkWarn, "couldn't check privileges: "+res.Status()
+		return r
+	}
+	var granted struct {
+		Cluster map[string]bool            `json:"cluster"`
+		Index   map[string]map[string]bool `json:"index"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&granted); err != nil {
+		r.status, r.detail = checkFail, err.Error()
+		return r
+	}
+	var missing []string
+	for _, p := range needed {
+		if !granted.Index[d.index][p] {
+			missing = append(missing, p)
+		}
+	}
+	if !granted.Cluster["monitor"] {
+		missing = append(missing, "cluster:monitor")
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		r.status, r.detail = checkFail, fmt.Sprintf("missing %s on %s", strings.Join(missing, ", "), d.index)
+		r.hint = "grant them to a role of the sync user"
+		return r
+	}
+	r.status, r.detail = checkOK, fmt.Sprintf("%s on %s", strings.Join(needed, ", "), d.index)
+	return r
+}
+
+func (d *doctor) health(ctx context.Context) checkResult {
+	r := checkResult{name: "cluster health", status: checkOK, detail: "green or yellow, write queues have room"}
+	if d.cluster.Serverless() {
+		r.status, r.detail = checkSkip, "managed by the serverless project"
+		return r
+	}
+	if err := sink.NewElasticsearch(d.es, d.index).Health(ctx); err != nil {
+		r.status, r.detail = checkFail, err.Error()
+		r.hint = "writes would be held back, see _cluster/health and _cat/thread_pool/write"
+	}
+	return r
+}
+
+func (d *doctor) diskSpace(ctx context.Context) checkResult {
+	r := checkResult{name: "disk space", status: checkOK, detail: "every data node is below the flood stage watermark"}
+	if d.cluster.Serverless() {
+		r.status, r.detail = checkSkip, "managed by the serverless project"
+		return r
+	}
+	err := sink.NewElasticsearch(d.es, d.index).CheckDiskSpace(ctx, 0)
+	switch {
+	case errors.Is(err, sink.ErrDiskWatermark):
+		r.status, r.detail = checkFail, err.Error()
+		r.hint = "free disk space or add nodes, the cluster blocks writes past the flood stage"
+	case err != nil:
+		r.status, r.detail = checkWarn, "couldn't check disk usage: "+err.Error()
+	}
+	return r
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// exportColumns are the columns of a csv export. Lists are joined with
+// "|" and objects written as json.
+var exportColumns = []string{
+	"id", "title", "description", "url", "canonical_url", "publication_date", "source_name",
+	"category", "tags", "relevance_score", "source_trust", "latitude", "longitude", "geohash", "location_name",
+	"country", "state", "city", "region", "llm_summary", "sentiment", "entities",
+	"author", "image_url", "is_paywalled", "word_count", "reading_time",
+	"ingested_at", "sync_run_id", "source_file", "expires_at", "story_id", "story_canonical", "story_sources", "tenant", "access",
+}
+
+// runExport dumps the index, or the documents matching --query, to a file
+// or stdout. ndjson and json output can be synced back with --source.
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	index := fs.String("index", indexName, "index to export")
+	output := fs.String("output", "-", `file to write to, "-" for stdout`)
+	format := fs.String("format", "ndjson", "output format: ndjson, json or csv")
+	query := fs.String("query", "", "only export matching documents: a query string, or query dsl json when it starts with {")
+	size := fs.Int("size", 1000, "documents fetched per page")
+	fs.Parse(args)
+
+	if *size < 1 || *size > 10000 {
+		exitWithConfigError(errors.New("--size must be between 1 and 10000"), "invalid configuration")
+	}
+	queryBody, err := exportQuery(*query)
+	if err != nil {
+		exitWithConfigError(err, "invalid --query")
+	}
+
+	es, err := newElasticsearchClient("")
+	if err != nil {
+		exitWithConfigError(err, "failed to create elasticsearch client")
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			exitWithConfigError(err, "failed to create output file")
+		}
+		defer f.Close()
+		w = f
+	}
+	buffered := bufio.NewWriter(w)
+	enc, err := newExportEncoder(*format, buffered)
+	if err != nil {
+		exitWithConfigError(err, "invalid configuration")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var count int
+	err = searchAll(ctx, es, *index, queryBody, *size, func(source json.RawMessage) error {
+		count++
+		return enc.write(source)
+	})
+	if err == nil {
+		err = enc.close()
+	}
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err != nil {
+		log.Error().Caller().Err(err).Msgf("export failed after %d documents", count)
+		return exitFailure
+	}
+	log.Info().Caller().Msgf("exported %d documents from %s", count, *index)
+	return exitSuccess
+}
+
+// exportQuery turns --query into a query clause, match_all when empty.
+func exportQuery(query string) (json.RawMessage, error) {
+	query = strings.TrimSpace(query)
+	switch {
+	case query == "":
+		return json.RawMessage(`{"match_all":{}}`), nil
+	case strings.HasPrefix(query, "{"):
+		if !json.Valid([]byte(query)) {
+			return nil, errors.New("query dsl is not valid json")
+		}
+		return json.RawMessage(query), nil
+	}
+	return json.Marshal(map[string]interface{}{
+		"query_string": map[string]string{"query": query},
+	})
+}
+
+// searchAll pages through every document matching query with a point in
+// time and search_after, so the export is consistent and not limited by
+// max_result_window. fn is called with the _source of each hit.
+func searchAll(ctx context.Context, es *elasticsearch.Client, index string, query json.RawMessage, size int, fn func(json.RawMessage) error) error {
+	res, err := es.OpenPointInTime([]string{index}, "2m", es.OpenPointInTime.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("failed to open point in time: %s", res.String())
+	}
+	var pit struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&pit); err != nil {
+		return err
+	}
+	defer func() {
+		body, _ := json.Marshal(map[string]string{"id": pit.ID})
+		res, err := es.ClosePointInTime(bytes.NewReader(body))
+		if err != nil {
+			log.Warn().Caller().Err(err).Msg("failed to close point in time")
+			return
+		}
+		res.Body.Close()
+	}()
+
+	var searchAfter []interface{}
+	for {
+		body := map[string]interface{}{
+			"size":  size,
+			"query": query,
+			"pit":   map[string]string{"id": pit.ID, "keep_alive": "2m"},
+			"sort":  []interface{}{map[string]string{"_shard_doc": "asc"}},
+		}
+		if searchAfter != nil {
+			body["search_after"] = searchAfter
+		}
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		res, err := es.Search(es.Search.WithContext(ctx), es.Search.WithBody(bytes.NewReader(data)))
+		if err != nil {
+			return err
+		}
+		var page struct {
+			PitID string `json:"pit_id"`
+			Hits  struct {
+				Hits []struct {
+					Source json.RawMessage `json:"_source"`
+					Sort   []interface{}   `json:"sort"`
+				} `json:"hits"`
+			} `json:"hits"`
+		}
+		if res.IsError() {
+			res.Body.Close()
+			return fmt.Errorf("search failed: %s", res.String())
+		}
+		err = json.NewDecoder(res.Body).Decode(&page)
+		res.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		hits := page.Hits.Hits
+		for _, hit := range hits {
+			if err := fn(hit.Source); err != nil {
+				return err
+			}
+		}
+		if len(hits) < size {
+			return nil
+		}
+		searchAfter = hits[len(hits)-1].Sort
+		// The id may change between requests, the latest one must be used
+		if page.PitID != "" {
+			pit.ID = page.PitID
+		}
+	}
+}
+
+// exportEncoder writes exported documents in one format.
+type exportEncoder interface {
+	write(source json.RawMessage) error
+	close() error
+}
+
+func newExportEncoder(format string, w io.Writer) (exportEncoder, error) {
+	switch format {
+	case "ndjson":
+		return &ndjsonExport{w: w}, nil
+	case "json":
+		return &jsonExport{w: w}, nil
+	case "csv":
+		return &csvExport{w: csv.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown --format %q, expected ndjson, json or csv", format)
+}
+
+type ndjsonExport struct{ w io.Writer }
+
+func (e *ndjsonExport) write(source json.RawMessage) error {
+	if _, err := e.w.Write(source); err != nil {
+		return err
+	}
+	_, err := e.w.Write([]byte{'\n'})
+	return err
+}
+
+func (e *ndjsonExport) close() error { return nil }
+
+// jsonExport writes a single array, the format of the input file.
+type jsonExport struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonExport) write(source json.RawMessage) error {
+	sep := ",\n"
+	if e.count == 0 {
+		sep = "[\n"
+	}
+	e.count++
+	if _, err := io.WriteString(e.w, sep); err != nil {
+		return err
+	}
+	_, err := e.w.Write(source)
+	return err
+}
+
+func (e *jsonExport) close() error {
+	end := "\n]\n"
+	if e.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+type csvExport struct {
+	w      *csv.Writer
+	header bool
+}
+
+func (e *csvExport) write(source json.RawMessage) error {
+	if !e.header {
+		e.header = true
+		if err := e.w.Write(exportColumns); err != nil {
+			return err
+		}
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(source, &doc); err != nil {
+		return err
+	}
+	record := make([]string, len(exportColumns))
+	for i, column := range exportColumns {
+		record[i] = csvValue(doc[column])
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvExport) close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func csvValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, item := range v {
+			values[i] = csvValue(item)
+		}
+		return strings.Join(values, "|")
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
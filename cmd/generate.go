@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/enrich"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// runGenerate syncs synthetic articles instead of a source, to load test
+// a cluster or run a demo without real data, e.g.
+//
+//	syncer generate --count 100000 --seed 7
+//	syncer generate --count 1000 --sink ndjson --output sample.json
+//
+// It accepts the options of a sync other than --source; the articles come
+// from the generate: source.
+func runGenerate(args []string) int {
+	count := flag.Int("count", 1000, "number of articles to generate")
+	seed := flag.Uint64("seed", 1, "random seed, the same seed and --until generate the same articles")
+	days := flag.Int("days", 365, "how many days back publication dates go, most of them recent")
+	until := flag.String("until", "", "latest publication date, e.g. 2025-01-01 (default now)")
+	cfg := parseFlags(args)
+
+	if *count < 1 {
+		exitWithConfigError(errors.New("--count must be positive"), "invalid configuration")
+	}
+	if *days < 1 {
+		exitWithConfigError(errors.New("--days must be positive"), "invalid configuration")
+	}
+	if *until != "" {
+		if _, err := time.Parse(time.DateOnly, *until); err != nil {
+			exitWithConfigError(err, "invalid --until")
+		}
+	}
+	if cfg.daemon() || cfg.jobs != "" {
+		exitWithConfigError(errors.New("generate runs once and can't be combined with --jobs, --schedule, --ingest or --grpc-addr"), "invalid configuration")
+	}
+	cfg.source = fmt.Sprintf("generate:?count=%d&seed=%d&days=%d", *count, *seed, *days)
+	if *until != "" {
+		cfg.source += "&until=" + *until
+	}
+	if err := cfg.validate(); err != nil {
+		exitWithConfigError(err, "invalid configuration")
+	}
+	cfg = cfg.scoped()
+	utils.AddDateLayouts(cfg.dateLayouts...)
+	applyMemoryLimit(cfg)
+
+	es, err := cfg.elasticsearchClient("")
+	if err != nil {
+		exitWithConfigError(err, "failed to create elasticsearch client")
+	}
+	enrichers, err := enrich.FromEnv()
+	if err != nil {
+		exitWithConfigError(err, "error while configuring enrichers")
+	}
+	out, err := newSink(cfg, es)
+	if err != nil {
+		exitWithConfigError(err, "error while configuring sink")
+	}
+	s := newSyncer(cfg, es, out, enrichers)
+	s.Name = "generate"
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	code := runJob(ctx, cfg, s)
+	if err := out.Close(); err != nil {
+		log.Error().Caller().Err(err).Msgf("error while closing %s sink", out.Name())
+		code = exitFailure
+	}
+	return code
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	newsv1 "inshorts.com/inshorts-news-data-syncer/api/news/v1"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/pkg/search"
+)
+
+// grpcIngestChunk is the number of streamed articles queued at once.
+const grpcIngestChunk = 100
+
+// grpcServer implements newsv1.NewsServiceServer on top of the ingest
+// buffer and the search API.
+type grpcServer struct {
+	newsv1.UnimplementedNewsServiceServer
+	search *searchServer
+	// ingest is nil unless the daemon runs with --ingest.
+	ingest *ingestBuffer
+}
+
+func newGRPCServer(search *searchServer, ingest *ingestBuffer) *grpc.Server {
+	server := grpc.NewServer()
+	newsv1.RegisterNewsServiceServer(server, &grpcServer{search: search, ingest: ingest})
+	return server
+}
+
+// IngestArticles validates and queues articles as they arrive. A full
+// buffer ends the stream with ResourceExhausted, after which the client
+// should retry the articles that weren't acknowledged.
+func (g *grpcServer) IngestArticles(stream grpc.ClientStreamingServer[newsv1.Article, newsv1.IngestArticlesResponse]) error {
+	if g.ingest == nil {
+		return status.Error(codes.Unimplemented, "ingestion is disabled, run the daemon with --ingest")
+	}
+
+	resp := &newsv1.IngestArticlesResponse{}
+	var chunk []model.Article
+	queue := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		if !g.ingest.add(chunk) {
+			return status.Errorf(codes.ResourceExhausted, "ingest buffer full after %d accepted articles, retry later", resp.Accepted)
+		}
+		resp.Accepted += int32(len(chunk))
+		chunk = nil
+		return nil
+	}
+
+	for index := int32(0); ; index++ {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		a := articleFromProto(msg)
+		if err := g.ingest.syncer.CheckPushed(a); err != nil {
+			resp.Rejected = append(resp.Rejected, &newsv1.Rejection{Index: index, Id: a.ID, Error: err.Error()})
+			continue
+		}
+		chunk = append(chunk, a)
+		if len(chunk) == grpcIngestChunk {
+			if err := queue(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := queue(); err != nil {
+		return err
+	}
+	return stream.SendAndClose(resp)
+}
+
+func (g *grpcServer) Search(ctx context.Context, req *newsv1.SearchRequest) (*newsv1.SearchResponse, error) {
+	q, err := queryFromProto(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	results, err := g.search.search(ctx, q, req.GetHybrid())
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "search failed: %v", err)
+	}
+
+	resp := &newsv1.SearchResponse{Total: int64(results.Total), Hits: make([]*newsv1.Hit, len(results.Hits))}
+	for i, h := range results.Hits {
+		resp.Hits[i] = &newsv1.Hit{Id: h.ID, Score: h.Score, Article: articleToProto(h.Article)}
+	}
+	return resp, nil
+}
+
+// queryFromProto applies the same limits as /v1/search.
+func queryFromProto(req *newsv1.SearchRequest) (search.Query, error) {
+	q := search.Query{
+		Text:       req.GetQuery(),
+		Categories: req.GetCategories(),
+		Sources:    req.GetSources(),
+		Size:       int(req.GetSize()),
+	}
+	if q.Size == 0 {
+		q.Size = search.DefaultSize
+	}
+	if q.Size < 0 || q.Size > maxSearchSize {
+		return q, fmt.Errorf("size must be between 1 and %d", maxSearchSize)
+	}
+	if req.GetSince() != nil {
+		q.Since = req.GetSince().AsTime()
+	}
+	if req.GetUntil() != nil {
+		q.Until = req.GetUntil().AsTime()
+	}
+	if near := req.GetNear(); near != nil {
+		var err error
+		if q.Near, err = search.ParseNear(fmt.Sprintf("%v,%v,%s", near.GetLat(), near.GetLon(), near.GetDistance())); err != nil {
+			return q, fmt.Errorf("invalid near: %w", err)
+		}
+	}
+	return q, nil
+}
+
+func articleFromProto(p *newsv1.Article) model.Article {
+	a := model.Article{
+		ID:              p.GetId(),
+		Title:           p.GetTitle(),
+		Description:     p.GetDescription(),
+		URL:             p.GetUrl(),
+		PublicationDate: p.GetPublicationDate(),
+		SourceName:      p.GetSourceName(),
+		Category:        p.GetCategory(),
+		RelevanceScore:  p.GetRelevanceScore(),
+		Latitude:        p.GetLatitude(),
+		Longitude:       p.GetLongitude(),
+		LocationName:    p.GetLocationName(),
+		LLMSummary:      p.GetLlmSummary(),
+		Country:         p.GetCountry(),
+		State:           p.GetState(),
+		City:            p.GetCity(),
+		Tags:            p.GetTags(),
+		CanonicalURL:    p.GetCanonicalUrl(),
+		Author:          p.GetAuthor(),
+		ImageURL:        p.GetImageUrl(),
+		IsPaywalled:     p.IsPaywalled,
+		SourceTrust:     p.SourceTrust,
+		WordCount:       int(p.GetWordCount()),
+		ReadingTime:     int(p.GetReadingTime()),
+	}
+	if e := p.GetEntities(); e != nil {
+		a.Entities = &model.Entities{Person: e.GetPerson(), Org: e.GetOrg(), Location: e.GetLocation()}
+	}
+	if s := p.GetSentiment(); s != nil {
+		a.Sentiment = &model.Sentiment{Label: s.GetLabel(), Score: s.GetScore()}
+	}
+	return a
+}
+
+func articleToProto(a model.Article) *newsv1.Article {
+	p := &newsv1.Article{
+		Id:              a.ID,
+		Title:           a.Title,
+		Description:     a.Description,
+		Url:             a.URL,
+		PublicationDate: a.PublicationDate,
+		SourceName:      a.SourceName,
+		Category:        a.Category,
+		RelevanceScore:  a.RelevanceScore,
+		Latitude:        a.Latitude,
+		Longitude:       a.Longitude,
+		LocationName:    a.LocationName,
+		LlmSummary:      a.LLMSummary,
+		Country:         a.Country,
+		State:           a.State,
+		City:            a.City,
+		Tags:            a.Tags,
+		CanonicalUrl:    a.CanonicalURL,
+		Author:          a.Author,
+		ImageUrl:        a.ImageURL,
+		IsPaywalled:     a.IsPaywalled,
+		SourceTrust:     a.SourceTrust,
+		WordCount:       int32(a.WordCount),
+		ReadingTime:     int32(a.ReadingTime),
+	}
+	if a.Entities != nil {
+		p.Entities = &newsv1.Entities{Person: a.Entities.Person, Org: a.Entities.Org, Location: a.Entities.Location}
+	}
+	if a.Sentiment != nil {
+		p.Sentiment = &newsv1.Sentiment{Label: a.Sentiment.Label, Score: a.Sentiment.Score}
+	}
+	return p
+}
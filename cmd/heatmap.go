@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/search"
+)
+
+// runHeatmap writes the located articles per map tile and category as
+// GeoJSON, so the map frontend draws heatmaps without counting articles
+// itself, e.g.
+//
+//	heatmap --since 2025-03-01 --until 2025-04-01 --precision 7 --output tiles.geojson
+func runHeatmap(args []string) int {
+	fs := flag.NewFlagSet("heatmap", flag.ExitOnError)
+	index := fs.String("index", indexName, "index of the articles")
+	since := fs.String("since", "", "only count articles published on or after this date, YYYY-MM-DD or RFC 3339")
+	until := fs.String("until", "", "only count articles published before this date, YYYY-MM-DD or RFC 3339")
+	categories := fs.String("category", "", "comma separated categories to count, all when empty")
+	precision := fs.Int("precision", 6, fmt.Sprintf("zoom level of the tiles, 0 to %d", search.MaxTilePrecision))
+	tiles := fs.Int("tiles", 10000, "maximum number of tiles, the most populated first")
+	top := fs.Int("top", 10, "number of categories counted per tile")
+	output := fs.String("output", "-", `GeoJSON file to write to, "-" for stdout`)
+	fs.Parse(args)
+
+	q := search.HeatmapQuery{Categories: splitList(*categories), Precision: *precision, Tiles: *tiles, Top: *top}
+	var err error
+	if q.Since, err = parseDateFlag(*since); err != nil {
+		exitWithConfigError(err, "invalid --since")
+	}
+	if q.Until, err = parseDateFlag(*until); err != nil {
+		exitWithConfigError(err, "invalid --until")
+	}
+	if *precision < 0 || *precision > search.MaxTilePrecision {
+		exitWithConfigError(fmt.Errorf("--precision must be between 0 and %d", search.MaxTilePrecision), "invalid configuration")
+	}
+	if *tiles < 1 || *top < 1 {
+		exitWithConfigError(errors.New("--tiles and --top must be positive"), "invalid configuration")
+	}
+
+	es, err := newElasticsearchClient("")
+	if err != nil {
+		exitWithConfigError(err, "failed to create elasticsearch client")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	heatmap, err := search.Heatmap(ctx, es, *index, q)
+	if err != nil {
+		log.Error().Caller().Err(err).Msg("heatmap failed")
+		return exitFailure
+	}
+	if err := writeHeatmap(*output, heatmap); err != nil {
+		log.Error().Caller().Err(err).Msg("heatmap failed")
+		return exitFailure
+	}
+	if *output != "-" {
+		log.Info().Caller().Msgf("wrote %d tiles to %s", len(heatmap.Features), *output)
+	}
+	return exitSuccess
+}
+
+func writeHeatmap(path string, heatmap *search.TileCollection) error {
+	if path == "-" {
+		return encodeHeatmap(os.Stdout, heatmap)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := encodeHeatmap(f, heatmap); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func encodeHeatmap(w io.Writer, heatmap *search.TileCollection) error {
+	return json.NewEncoder(w).Encode(heatmap)
+}
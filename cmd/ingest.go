@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	syncpkg "inshorts.com/inshorts-news-data-syncer/pkg/sync"
+)
+
+// maxIngestBody bounds the size of a single /ingest request.
+const maxIngestBody = 32 << 20
+
+// ingestSourceFile is stored as the source_file of pushed articles.
+const ingestSourceFile = "ingest"
+
+// finalFlushTimeout bounds the flush of buffered articles on shutdown.
+const finalFlushTimeout = 30 * time.Second
+
+// Failed flushes are retried with exponential backoff from the flush
+// interval, up to maxIngestBackoff, at most maxIngestAttempts times.
+const (
+	maxIngestAttempts = 5
+	maxIngestBackoff  = 5 * time.Minute
+)
+
+// ingestBuffer collects pushed articles and writes them through the
+// syncer once flushSize articles are pending or flushInterval has passed.
+type ingestBuffer struct {
+	syncer        *syncpkg.Syncer
+	flushSize     int
+	flushInterval time.Duration
+	// maxPending rejects new articles while earlier ones are still being
+	// flushed, so a slow cluster can't make the buffer grow without bound.
+	maxPending int
+
+	mu      sync.Mutex
+	pending []model.Article
+	flushCh chan struct{}
+
+	// attempts counts the failed flushes of the articles at the head of
+	// pending, which are retried from retryAt. Only run uses them.
+	attempts int
+	retryAt  time.Time
+}
+
+func newIngestBuffer(cfg config, s *syncpkg.Syncer) *ingestBuffer {
+	return &ingestBuffer{
+		syncer:        s,
+		flushSize:     cfg.ingestFlushSize,
+		flushInterval: cfg.ingestFlushInterval,
+		maxPending:    cfg.ingestFlushSize * 10,
+		flushCh:       make(chan struct{}, 1),
+	}
+}
+
+// run flushes the buffer until ctx is cancelled, then flushes what's left.
+func (b *ingestBuffer) run(ctx context.Context) {
+	b.syncer.Prepare(ctx)
+
+	ticker := time.NewTicker(b.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// Use a fresh context so the final flush isn't cancelled with
+			// ctx, bounded so a hung cluster can't block shutdown.
+			flushCtx, cancel := context.WithTimeout(context.Background(), finalFlushTimeout)
+			b.flush(flushCtx, true)
+			cancel()
+			return
+		case <-ticker.C:
+			b.flush(ctx, false)
+		case <-b.flushCh:
+			b.flush(ctx, false)
+		}
+	}
+}
+
+// add queues articles, returning false when the buffer is full.
+func (b *ingestBuffer) add(articles []model.Article) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.pending)+len(articles) > b.maxPending {
+		return false
+	}
+	b.pending = append(b.pending, articles...)
+	if len(b.pending) >= b.flushSize {
+		select {
+		case b.flushCh <- struct{}{}:
+		default:
+		}
+	}
+	return true
+}
+
+// flush writes the pending articles. As their clients were already told
+// they were accepted, a failed flush is requeued with backoff rather than
+// dropped, and dead lettered once it keeps failing or final, the flush on
+// shutdown, fails.
+func (b *ingestBuffer) flush(ctx context.Context, final bool) {
+	if !final && time.Now().Before(b.retryAt) {
+		return
+	}
+	b.mu.Lock()
+	batch := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+
+	// Ingest transforms the batch in place, retries start over from the
+	// articles as pushed
+	pushed := slices.Clone(batch)
+
+	// Pushed articles go through the same transform as synced ones, so
+	// both get the same IDs, dates and registry fields
+	report, err := b.syncer.Ingest(ctx, batch, ingestSourceFile)
+	if err == nil {
+		b.attempts = 0
+		log.Info().Caller().Msgf("indexed %d ingested articles, %d failed", report.Indexed, report.Failed)
+		return
+	}
+
+	b.attempts++
+	if final || b.attempts >= maxIngestAttempts {
+		b.attempts = 0
+		b.giveUp(pushed, report.RunID, err)
+		return
+	}
+	backoff := min(b.flushInterval<<(b.attempts-1), maxIngestBackoff)
+	b.retryAt = time.Now().Add(backoff)
+	b.mu.Lock()
+	b.pending = append(pushed, b.pending...)
+	b.mu.Unlock()
+	log.Warn().Caller().Err(err).Int("articles", len(pushed)).Msgf("error while indexing ingested articles, retrying in %v", backoff)
+}
+
+// giveUp dead letters articles that failed to be written with err, or
+// drops them when dead lettering is disabled.
+func (b *ingestBuffer) giveUp(articles []model.Article, runID string, err error) {
+	if b.syncer.DeadLetter == "" {
+		log.Error().Caller().Err(err).Int("articles", len(articles)).Msg("error while indexing ingested articles, dropping them as --dead-letter is not set")
+		return
+	}
+	added, dlqErr := b.syncer.DeadLetterPushed(articles, runID, err.Error())
+	if dlqErr != nil {
+		log.Error().Caller().Err(dlqErr).Int("articles", len(articles)-added).Msgf("error while dead lettering ingested articles to %s, dropping them", b.syncer.DeadLetter)
+		return
+	}
+	log.Error().Caller().Err(err).Msgf("error while indexing ingested articles, dead lettered %d of them to %s", added, b.syncer.DeadLetter)
+}
+
+// handleIngest accepts a single article object or an array of articles.
+// The whole request is rejected when any article is invalid.
+func (b *ingestBuffer) handleIngest(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIngestBody))
+	if err != nil {
+		writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": err.Error()})
+		return
+	}
+
+	articles, err := decodeIngestBody(data)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var problems []string
+	for i, a := range articles {
+		if err := b.syncer.CheckPushed(a); err != nil {
+			problems = append(problems, fmt.Sprintf("article %d: %v", i, err))
+		}
+	}
+	if len(problems) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"errors": problems})
+		return
+	}
+
+	if !b.add(articles) {
+		w.Header().Set("Retry-After", "5")
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "ingest buffer full, retry later"})
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]int{"accepted": len(articles)})
+}
+
+func decodeIngestBody(data []byte) ([]model.Article, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, errors.New("empty request body")
+	}
+
+	if data[0] == '[' {
+		var articles []model.Article
+		if err := json.Unmarshal(data, &articles); err != nil {
+			return nil, err
+		}
+		return articles, nil
+	}
+
+	var a model.Article
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, err
+	}
+	return []model.Article{a}, nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/enrich"
+	"inshorts.com/inshorts-news-data-syncer/pkg/sink"
+)
+
+// jobSpec is one dataset of a --jobs file. Empty fields keep the value
+// of the corresponding command line option.
+type jobSpec struct {
+	Name           string `json:"name"`
+	Source         string `json:"source"`
+	Index          string `json:"index"`
+	Sink           string `json:"sink"`
+	Output         string `json:"output"`
+	FieldMap       string `json:"field_map"`
+	SchemaFile     string `json:"schema_file"`
+	CategoryRoutes string `json:"category_routes"`
+	SourceRegistry string `json:"source_registry"`
+	IDStrategy     string `json:"id_strategy"`
+	SourceTimezone string `json:"source_timezone"`
+	Tenant         string `json:"tenant"`
+}
+
+// loadJobs reads a --jobs file, a JSON array of jobs such as
+//
+//	[
+//	  {"name": "india", "source": "s3-export/india.json", "index": "news-in"},
+//	  {"name": "world", "source": "https://feeds.example.com/world", "index": "news-world"}
+//	]
+func loadJobs(path string) ([]jobSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var jobs []jobSpec
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, errors.New("no jobs defined")
+	}
+	names := make(map[string]bool)
+	for _, job := range jobs {
+		if job.Name == "" {
+			return nil, errors.New("every job needs a name")
+		}
+		if names[job.Name] {
+			return nil, fmt.Errorf("job %q is defined twice", job.Name)
+		}
+		names[job.Name] = true
+	}
+	return jobs, nil
+}
+
+// forJob returns the configuration of job, the command line options
+// overridden by the fields it sets.
+func (c config) forJob(job jobSpec) config {
+	c.jobs = ""
+	overrides := []struct {
+		value  string
+		target *string
+	}{
+		{job.Source, &c.source},
+		{job.Index, &c.index},
+		{job.Sink, &c.sink},
+		{job.Output, &c.output},
+		{job.FieldMap, &c.fieldMap},
+		{job.SchemaFile, &c.schemaFile},
+		{job.CategoryRoutes, &c.categoryRoutes},
+		{job.SourceRegistry, &c.sourceRegistry},
+		{job.IDStrategy, &c.idStrategy},
+		{job.SourceTimezone, &c.sourceTimezone},
+		{job.Tenant, &c.tenant},
+	}
+	for _, o := range overrides {
+		if o.value != "" {
+			*o.target = o.value
+		}
+	}
+	if c.deadLetter != "" {
+		c.deadLetter += "." + job.Name
+	}
+	return c
+}
+
+// runJobs syncs every job of --jobs concurrently, each with its own
+// client, sink, enrichers and report, and returns the process exit code:
+// failure when any job failed.
+func runJobs(ctx context.Context, cfg config) int {
+	// validate already loaded the file
+	jobs, _ := loadJobs(cfg.jobs)
+
+	if cfg.livenessAddr != "" {
+		stopLiveness := serveLiveness(cfg.livenessAddr)
+		defer stopLiveness()
+	}
+
+	var wg sync.WaitGroup
+	failed := make([]bool, len(jobs))
+	for i, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Retries are reported per job, so jobs don't share a client
+			jobCfg := cfg.forJob(job).scoped()
+			jobCfg.retries = new(sink.RetryStats)
+			es, err := jobCfg.elasticsearchClient("")
+			if err != nil {
+				log.Error().Caller().Err(err).Msgf("failed to create elasticsearch client of job %s", job.Name)
+				failed[i] = true
+				return
+			}
+			failed[i] = !runNamedJob(ctx, jobCfg, job.Name, es, time.Time{}, time.Time{})
+		}()
+	}
+	wg.Wait()
+
+	code := exitSuccess
+	for i, job := range jobs {
+		if failed[i] {
+			log.Error().Caller().Msgf("job %s failed", job.Name)
+			code = exitFailure
+		}
+	}
+	return code
+}
+
+// runNamedJob performs the sync of one job, restricted to articles
+// published in [since, until) unless they are zero, and reports whether
+// it succeeded.
+func runNamedJob(ctx context.Context, cfg config, name string, es *elasticsearch.Client, since, until time.Time) bool {
+	// Enrichers keep state such as caches, so jobs don't share them
+	enrichers, err := enrich.FromEnv()
+	if err != nil {
+		log.Error().Caller().Err(err).Msgf("error while configuring enrichers of job %s", name)
+		return false
+	}
+	out, err := newSink(cfg, es)
+	if err != nil {
+		log.Error().Caller().Err(err).Msgf("error while configuring sink of job %s", name)
+		return false
+	}
+	s := newSyncer(cfg, es, out, enrichers)
+	s.Name = name
+	s.Since, s.Until = since, until
+
+	if cfg.maxRuntime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.maxRuntime)
+		defer cancel()
+	}
+	report := s.Run(ctx)
+	report.Log()
+	logTransportMetrics()
+	ok := report.OK()
+	if err := out.Close(); err != nil {
+		log.Error().Caller().Err(err).Msgf("error while closing %s sink of job %s", out.Name(), name)
+		ok = false
+	}
+	if !closeRouteSinks(s) {
+		ok = false
+	}
+	return ok
+}
+
+// validateJobs checks the --jobs file and the configuration of each job.
+func (c config) validateJobs() error {
+	if c.daemon() {
+		return errors.New("--jobs runs once and can't be combined with --schedule, --ingest or --grpc-addr")
+	}
+	jobs, err := loadJobs(c.jobs)
+	if err != nil {
+		return fmt.Errorf("invalid --jobs: %w", err)
+	}
+	for _, job := range jobs {
+		if err := c.forJob(job).validate(); err != nil {
+			return fmt.Errorf("job %s: %w", job.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// logFlags are the logging options. They apply to every subcommand, so
+// they are taken out of the arguments before the subcommand parses them.
+var logFlags = flag.NewFlagSet("logging", flag.ExitOnError)
+
+var (
+	logLevel      = logFlags.String("log-level", "info", "minimum level logged: trace, debug, info, warn or error")
+	logFormat     = logFlags.String("log-format", "json", "log format: json, or console for humans")
+	logFile       = logFlags.String("log-file", "", "file logs are appended to instead of stderr, rotated by size")
+	logMaxSize    = logFlags.Int("log-max-size", 100, "size in megabytes at which --log-file is rotated")
+	logMaxBackups = logFlags.Int("log-max-backups", 5, "rotated --log-file copies kept, as <file>.1 to <file>.<n>")
+	logModules    = logFlags.String("log-modules", "", "comma separated levels of single packages overriding --log-level, e.g. sink=debug,source=warn,enrich=debug")
+	logVerbose    = logFlags.Bool("verbose", false, "log at debug level, short for --log-level debug")
+	logQuiet      = logFlags.Bool("quiet", false, "log only errors and the summary of each run")
+)
+
+// setupLogging configures the global logger from the logging options in
+// args and returns the remaining arguments.
+func setupLogging(args []string) []string {
+	ours, rest := splitLogArgs(args)
+	logFlags.Parse(ours)
+	set := make(map[string]bool)
+	logFlags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if *logQuiet && (*logVerbose || set["log-level"] || set["log-modules"]) {
+		exitWithConfigError(errors.New("--quiet can't be combined with --verbose, --log-level or --log-modules"), "invalid configuration")
+	}
+	if *logVerbose {
+		if set["log-level"] {
+			exitWithConfigError(errors.New("--verbose can't be combined with --log-level"), "invalid configuration")
+		}
+		*logLevel = "debug"
+	}
+
+	level, err := zerolog.ParseLevel(*logLevel)
+	if err != nil || *logLevel == "" {
+		exitWithConfigError(fmt.Errorf("unknown level %q", *logLevel), "invalid --log-level")
+	}
+	modules, err := parseModuleLevels(*logModules)
+	if err != nil {
+		exitWithConfigError(err, "invalid --log-modules")
+	}
+
+	var w io.Writer = os.Stderr
+	if *logFile != "" {
+		if *logMaxSize < 1 || *logMaxBackups < 0 {
+			exitWithConfigError(errors.New("--log-max-size must be positive and --log-max-backups not negative"), "invalid configuration")
+		}
+		rf, err := openRotatingFile(*logFile, int64(*logMaxSize)<<20, *logMaxBackups)
+		if err != nil {
+			exitWithConfigError(err, "invalid --log-file")
+		}
+		w = rf
+	}
+	switch *logFormat {
+	case "json":
+	case "console":
+		w = zerolog.ConsoleWriter{Out: w, NoColor: *logFile != "", TimeFormat: zerolog.TimeFieldFormat}
+	default:
+		exitWithConfigError(fmt.Errorf("unknown format %q, expected json or console", *logFormat), "invalid --log-format")
+	}
+
+	// Events are dropped below the global level, so it's the lowest of
+	// all and the module filter applies the rest
+	global := level
+	for _, l := range modules {
+		global = min(global, l)
+	}
+	if len(modules) > 0 {
+		w = &moduleFilter{out: w, base: level, modules: modules}
+	}
+	if *logQuiet {
+		w = &quietFilter{out: w}
+	}
+	zerolog.SetGlobalLevel(global)
+	log.Logger = zerolog.New(w).With().Timestamp().Logger()
+	return rest
+}
+
+// splitLogArgs separates the logging options in args, as -name=value or
+// -name value, from the others.
+func splitLogArgs(args []string) (ours, rest []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return ours, append(rest, args[i:]...)
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		f := logFlags.Lookup(name)
+		if !strings.HasPrefix(arg, "-") || f == nil {
+			rest = append(rest, arg)
+			continue
+		}
+		ours = append(ours, arg)
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			i++
+			ours = append(ours, args[i])
+		}
+	}
+	return ours, rest
+}
+
+// parseModuleLevels parses --log-modules into levels by package name.
+func parseModuleLevels(spec string) (map[string]zerolog.Level, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	modules := make(map[string]zerolog.Level)
+	for _, entry := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%q is not package=level", entry)
+		}
+		level, err := zerolog.ParseLevel(value)
+		if err != nil || value == "" {
+			return nil, fmt.Errorf("unknown level %q of %s", value, name)
+		}
+		modules[name] = level
+	}
+	return modules, nil
+}
+
+// moduleFilter drops the events below the level of the package that
+// logged them, which it tells from the caller field.
+type moduleFilter struct {
+	out     io.Writer
+	base    zerolog.Level
+	modules map[string]zerolog.Level
+}
+
+func (f *moduleFilter) Write(p []byte) (int, error) { return f.out.Write(p) }
+
+func (f *moduleFilter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	threshold := f.base
+	if l, ok := f.modules[callerPackage(p)]; ok {
+		threshold = l
+	}
+	if level < threshold {
+		return len(p), nil
+	}
+	return f.out.Write(p)
+}
+
+// quietFilter keeps only errors and the run reports.
+type quietFilter struct {
+	out io.Writer
+}
+
+func (f *quietFilter) Write(p []byte) (int, error) { return f.out.Write(p) }
+
+func (f *quietFilter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level < zerolog.ErrorLevel && !strings.HasSuffix(callerFile(p), "/pkg/sync/report.go") {
+		return len(p), nil
+	}
+	return f.out.Write(p)
+}
+
+var callerField = []byte(`"` + zerolog.CallerFieldName + `":"`)
+
+// callerFile returns the source file of the caller of a JSON event, e.g.
+// ".../pkg/sink/bulk.go" for ".../pkg/sink/bulk.go:42".
+func callerFile(event []byte) string {
+	start := bytes.Index(event, callerField)
+	if start < 0 {
+		return ""
+	}
+	caller := event[start+len(callerField):]
+	if end := bytes.IndexByte(caller, '"'); end >= 0 {
+		caller = caller[:end]
+	}
+	file, _, _ := bytes.Cut(caller, []byte(":"))
+	return string(file)
+}
+
+// callerPackage returns the package name of the caller of a JSON event,
+// e.g. "sink" for ".../pkg/sink/bulk.go:42", or "main" for the command.
+func callerPackage(event []byte) string {
+	file := callerFile(event)
+	dir := file[:max(strings.LastIndexByte(file, '/'), 0)]
+	pkg := dir[strings.LastIndexByte(dir, '/')+1:]
+	if pkg == "cmd" {
+		return "main"
+	}
+	return pkg
+}
+
+// rotatingFile appends to a file, renaming it to <path>.1 once it
+// reaches maxSize and shifting older copies up to <path>.<backups>.
+type rotatingFile struct {
+	path    string
+	maxSize int64
+	backups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func openRotatingFile(path string, maxSize int64, backups int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, backups: backups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file, r.size = file, info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	if r.backups == 0 {
+		os.Remove(r.path)
+	} else {
+		for i := r.backups - 1; i > 0; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+		}
+		if err := os.Rename(r.path, r.path+".1"); err != nil {
+			return err
+		}
+	}
+	return r.open()
+}
@@ -1,42 +1,70 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"crypto/tls"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"runtime/debug"
 	"strings"
+	gosync "sync"
+	"syscall"
 	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/alert"
+	"inshorts.com/inshorts-news-data-syncer/pkg/enrich"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/pkg/notify"
+	"inshorts.com/inshorts-news-data-syncer/pkg/sink"
+	"inshorts.com/inshorts-news-data-syncer/pkg/source"
+	syncpkg "inshorts.com/inshorts-news-data-syncer/pkg/sync"
 	"inshorts.com/inshorts-news-data-syncer/utils"
 
 	"github.com/elastic/go-elasticsearch/v9"
-	"github.com/elastic/go-elasticsearch/v9/esapi"
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
 )
 
 const (
-	indexName = "inshorts-news"
-	bulkSize  = 500
-	path      = "resources/news_data.json"
+	indexName        = "inshorts-news"
+	defaultSource    = "resources/news_data.json"
+	defaultESAddress = "https://localhost:9200"
 )
 
-type Article struct {
-	ID              string   `json:"id"`
-	Title           string   `json:"title"`
-	Description     string   `json:"description"`
-	URL             string   `json:"url"`
-	PublicationDate string   `json:"publication_date"`
-	SourceName      string   `json:"source_name"`
-	Category        []string `json:"category"`
-	RelevanceScore  float64  `json:"relevance_score"`
-	Latitude        float64  `json:"latitude"`
-	Longitude       float64  `json:"longitude"`
-	LLMSummary      string   `json:"llm_summary,omitempty"`
+// Process exit codes for single run (job) mode.
+const (
+	exitSuccess = 0
+	// exitFailure covers partial as well as complete sync failures.
+	exitFailure = 1
+	// exitConfigError means the run never started due to invalid configuration.
+	exitConfigError = 2
+)
+
+// subcommands are run as "syncer <name> [flags]". Without one, the
+// binary syncs.
+var subcommands = map[string]func(args []string) int{
+	"export":    runExport,
+	"snapshot":  runSnapshot,
+	"restore":   runRestore,
+	"search":    runSearch,
+	"stats":     runStats,
+	"serve":     runServe,
+	"synonyms":  runSynonyms,
+	"alerts":    runAlerts,
+	"backfill":  runBackfill,
+	"generate":  runGenerate,
+	"bench":     runBench,
+	"retry-dlq": runRetryDLQ,
+	"doctor":    runDoctor,
+	"cleanup":   runCleanup,
+	"alias":     runAlias,
+	"ccr":       runCCR,
+	"trending":  runTrending,
+	"heatmap":   runHeatmap,
 }
 
 func main() {
@@ -46,281 +74,623 @@ func main() {
 	zerolog.TimeFieldFormat = "2006-01-02T15:04:05.000Z"
 	// Optional: force UTC to ensure 'Z' (Zulu time) is used instead of a numeric offset
 	zerolog.TimestampFieldName = "@timestamp" // example for compatibility with some log processors
+	args := setupLogging(os.Args[1:])
 
-	// I hardcoded locally, but production reads from env/secret manager.
-	username := os.Getenv("ES_USERNAME")
-	if username == "" {
-		username = "elastic"
+	if len(args) > 0 {
+		if run, ok := subcommands[args[0]]; ok {
+			code := run(args[1:])
+			closeAuditLog()
+			os.Exit(code)
+		}
 	}
-	password := os.Getenv("ES_PASSWORD")
-	if password == "" {
-		password = "UMEFncAL6JL_kBNauzej"
+
+	cfg := parseFlags(args)
+	if err := cfg.validate(); err != nil {
+		exitWithConfigError(err, "invalid configuration")
 	}
+	utils.AddDateLayouts(cfg.dateLayouts...)
+	applyMemoryLimit(cfg)
 
-	// Elasticsearch config
-	cfg := elasticsearch.Config{
-		Addresses: []string{
-			"https://localhost:9200",
-		},
-		Username: username,
-		Password: password,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
-		},
+	if cfg.jobs != "" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		code := runJobs(ctx, cfg)
+		stop()
+		closeAuditLog()
+		os.Exit(code)
 	}
 
 	// Elasticsearch client initialisation
-	es, err := elasticsearch.NewClient(cfg)
+	es, err := cfg.elasticsearchClient("")
 	if err != nil {
-		log.Fatal().Caller().Err(err).Msg("failed to create elasticsearch client")
+		exitWithConfigError(err, "failed to create elasticsearch client")
 	}
 
-	// Create index mapping before inserting data
-	err = createMappingsSettings(indexName, es)
+	cfg = cfg.scoped()
+
+	// Configure optional enrichment stages, some of which extend the mapping
+	enrichers, err := enrich.FromEnv()
 	if err != nil {
-		log.Error().Caller().Err(err).Msg("error while creating mappings in es")
+		exitWithConfigError(err, "error while configuring enrichers")
 	}
 
-	// Load articles from json file
-	startTime := time.Now()
-	articles, err := loadArticles(path)
+	out, err := newSink(cfg, es)
 	if err != nil {
-		log.Fatal().Caller().Err(err).Msg("error while loading articles from json file")
+		exitWithConfigError(err, "error while configuring sink")
+	}
+	s := newSyncer(cfg, es, out, enrichers)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Run as a daemon when a schedule or push ingestion is configured
+	if cfg.daemon() {
+		err := runDaemon(ctx, cfg, s, es)
+		out.Close()
+		closeRouteSinks(s)
+		closeAuditLog()
+		if err != nil {
+			log.Fatal().Caller().Err(err).Msg("daemon failed")
+		}
+		return
 	}
 
-	// Insert articles into elastic by using bulk api
-	if err := bulkIndex(es, articles); err != nil {
-		log.Fatal().Caller().Err(err).Msg("error while inserting articles in es using bulk api")
+	code := runJob(ctx, cfg, s)
+	if err := out.Close(); err != nil {
+		log.Error().Caller().Err(err).Msgf("error while closing %s sink", out.Name())
+		code = exitFailure
+	}
+	if !closeRouteSinks(s) {
+		code = exitFailure
 	}
-	log.Info().Caller().Msgf("indexed %d articles in %v milliseconds\n", len(articles), time.Since(startTime).Milliseconds())
+	closeAuditLog()
+	os.Exit(code)
 }
 
-func createMappingsSettings(index string, es *elasticsearch.Client) error {
-	// Check if index already exists
-	exists, _ := es.Indices.Exists([]string{index})
-	if exists.StatusCode == 200 {
-		return nil
+// runJob performs a single sync, as run by a Kubernetes CronJob, and
+// returns the process exit code.
+func runJob(ctx context.Context, cfg config, s *syncpkg.Syncer) int {
+	if cfg.maxRuntime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.maxRuntime)
+		defer cancel()
 	}
 
-	// 1. Define the mapping and settings as a JSON string
-	var settingsAndmappings = `
-{
-  "settings": {
-    "analysis": {
-      "analyzer": {
-        "news_text": {
-          "type": "custom",
-          "tokenizer": "standard",
-          "filter": [
-            "lowercase",
-            "stop",
-            "english_stemmer"
-          ]
-        }
-      },
-      "filter": {
-        "english_stemmer": {
-          "type": "stemmer",
-          "language": "english"
-        }
-      },
-      "normalizer": {
-        "keyword_lowercase": {
-          "type": "custom",
-          "filter": ["lowercase"]
-        }
-      }
-    }
-  },
-  "mappings": {
-    "dynamic": "strict",
-    "properties": {
-      "id": {
-        "type": "keyword"
-      },
-	  "url": {
-  		"type": "keyword",
-  		"ignore_above": 2048
-	  },
-      "title": {
-        "type": "text",
-        "analyzer": "news_text",
-        "fields": {
-          "keyword": {
-            "type": "keyword",
-            "ignore_above": 256
-          }
-        }
-      },
-      "description": {
-        "type": "text",
-        "analyzer": "news_text"
-      },
-      "llm_summary": {
-        "type": "text",
-        "analyzer": "news_text"
-      },
-      "source_name": {
-        "type": "text",
-        "analyzer": "news_text",
-        "fields": {
-          "keyword": {
-            "type": "keyword",
-            "normalizer": "keyword_lowercase"
-          }
-        }
-      },
-      "category": {
-        "type": "text",
-        "analyzer": "news_text",
-        "fields": {
-          "keyword": {
-            "type": "keyword",
-            "normalizer": "keyword_lowercase"
-          }
-        }
-      },
-      "publication_date": {
-        "type": "date"
-      },
-      "location": {
-        "type": "geo_point"
-      },
-      "relevance_score": {
-        "type": "float"
-      },
-	  "latitude": {
-  		"type": "float"
-	  },
-	  "longitude": {
-  		"type": "float"
-	  }
-    }
-  }
-}
-`
-	// 2. Create the index creation request
-	req := esapi.IndicesCreateRequest{
-		Index: index,
-		Body:  strings.NewReader(settingsAndmappings),
+	if cfg.livenessAddr != "" {
+		stopLiveness := serveLiveness(cfg.livenessAddr)
+		defer stopLiveness()
 	}
 
-	// 3. Execute the request
-	res, err := req.Do(context.Background(), es)
-	if err != nil {
-		return err
+	report := s.Run(ctx)
+	report.Log()
+	logTransportMetrics()
+	if !report.OK() {
+		return exitFailure
 	}
-	defer res.Body.Close()
+	return exitSuccess
+}
 
-	if res.IsError() {
-		log.Error().Caller().Err(err).Msgf("error response: %s\n", res.String())
-	} else {
-		log.Info().Caller().Err(err).Msgf("index: (%s) created successfully. Status: %s\n", index, res.Status())
+// serveLiveness serves /healthz and /metrics on addr in the background
+// and returns a function that stops the server.
+func serveLiveness(addr string) func() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("GET /metrics", handleMetrics)
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Caller().Err(err).Msg("liveness endpoint failed")
+		}
+	}()
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
 	}
-	return nil
 }
 
-func loadArticles(path string) ([]Article, error) {
-	if _, err := os.Stat(path); err != nil {
-		return nil, fmt.Errorf("file not found at path %s: %w", path, err)
+// applyMemoryLimit makes the garbage collector work harder as the heap
+// nears --max-memory, on top of the backpressure of the syncer.
+func applyMemoryLimit(cfg config) {
+	if cfg.maxMemory > 0 {
+		debug.SetMemoryLimit(cfg.maxMemory)
 	}
+}
+
+// exitWithConfigError logs err and exits with exitConfigError.
+func exitWithConfigError(err error, msg string) {
+	log.Error().Caller().Err(err).Msg(msg)
+	os.Exit(exitConfigError)
+}
 
-	data, err := os.ReadFile(path)
+// newElasticsearchClient returns a client for address, with credentials
+// and the per request timeout taken from the environment. An empty
+// address means ES_URL.
+func newElasticsearchClient(address string) (*elasticsearch.Client, error) {
+	return elasticsearch.NewClient(elasticsearchConfig(address))
+}
+
+// elasticsearchClient is newElasticsearchClient with the request
+// compression of --compress-requests, for clients that write. Their
+// transport metrics are reported, see logTransportMetrics, and their
+// retryable responses counted in c.retries.
+func (c config) elasticsearchClient(address string) (*elasticsearch.Client, error) {
+	esCfg := elasticsearchConfigWith(address, newTransport("ES", c.retries))
+	esCfg.CompressRequestBody = c.compress
+	esCfg.EnableMetrics = true
+	client, err := elasticsearch.NewClient(esCfg)
 	if err != nil {
 		return nil, err
 	}
+	measureClient(esCfg.Addresses[0], client)
+	return client, nil
+}
 
-	var articles []Article
-	if err := json.Unmarshal(data, &articles); err != nil {
-		return nil, err
+// elasticsearchConfig returns the client configuration of
+// newElasticsearchClient, for callers that tune it further.
+func elasticsearchConfig(address string) elasticsearch.Config {
+	return elasticsearchConfigWith(address, newTransport("ES", nil))
+}
+
+// elasticsearchConfigWith is elasticsearchConfig with transport.
+func elasticsearchConfigWith(address string, transport http.RoundTripper) elasticsearch.Config {
+	if address == "" {
+		address = utils.GetEnv("ES_URL", defaultESAddress)
+	}
+
+	// I hardcoded locally, but production reads from env/secret manager.
+	username := os.Getenv("ES_USERNAME")
+	if username == "" {
+		username = "elastic"
+	}
+	password := os.Getenv("ES_PASSWORD")
+	if password == "" {
+		password = "UMEFncAL6JL_kBNauzej"
+	}
+
+	// Elasticsearch config
+	retry := retryOptionsFromEnv("ES")
+	discovery := discoveryOptionsFromEnv("ES")
+	esCfg := elasticsearch.Config{
+		Addresses: []string{
+			address,
+		},
+		Username:              username,
+		Password:              password,
+		Transport:             transport,
+		DisableRetry:          retry.disabled,
+		MaxRetries:            retry.maxRetries,
+		RetryOnStatus:         retry.onStatus,
+		RetryBackoff:          retry.backoffFunc(),
+		DiscoverNodesOnStart:  discovery.onStart,
+		DiscoverNodesInterval: discovery.interval,
+	}
+	// Gateways in front of the cluster may want headers of their own
+	if headers := os.Getenv("ES_HEADERS"); headers != "" {
+		header, err := parseHeaders(headers)
+		if err != nil {
+			exitWithConfigError(err, "invalid ES_HEADERS")
+		}
+		esCfg.Header = header
+	}
+	// Serverless projects and scoped credentials use API keys instead
+	if apiKey := os.Getenv("ES_API_KEY"); apiKey != "" {
+		esCfg.Username, esCfg.Password, esCfg.APIKey = "", "", apiKey
+	}
+	return esCfg
+}
+
+// newSink returns the destinations selected by --sink, each throttled to
+// the configured rate. Several of them are combined into a fan out sink
+// that writes to all.
+func newSink(cfg config, es *elasticsearch.Client) (sink.Sink, error) {
+	specs := cfg.sinkSpecs()
+	sinks := make([]sink.Sink, 0, len(specs))
+	for _, spec := range specs {
+		s, err := newSinkFor(spec, cfg, es)
+		if err != nil {
+			for _, opened := range sinks {
+				opened.Close()
+			}
+			return nil, fmt.Errorf("sink %s: %w", spec.kind, err)
+		}
+		if cfg.maxDocsPerSec > 0 || cfg.maxBytesPerSec > 0 {
+			s = sink.NewThrottled(s, cfg.maxDocsPerSec, cfg.maxBytesPerSec)
+		}
+		sinks = append(sinks, s)
+	}
+	out := sinks[0]
+	if len(sinks) > 1 {
+		out = sink.NewFanOut(sinks...)
+	}
+	if specs := cfg.alertSpecs(); len(specs) > 0 {
+		out = alert.NewSink(out, alert.NewPercolator(es, cfg.index), newNotifier(specs, cfg.index))
 	}
-	return articles, nil
+	return out, nil
 }
 
-func bulkIndex(es *elasticsearch.Client, articles []Article) error {
-	var buf bytes.Buffer
-	ctx := context.Background()
+// newNotifier returns the notifiers of --alerts on index.
+func newNotifier(specs []sinkSpec, index string) alert.Notifier {
+	var notifiers alert.Notifiers
+	for _, spec := range specs {
+		switch spec.kind {
+		case "log":
+			notifiers = append(notifiers, alert.Log{})
+		case "webhook":
+			notifiers = append(notifiers, alert.NewWebhook(spec.target))
+		case "kafka":
+			topic := spec.target
+			if topic == "" {
+				topic = index + "-alerts"
+			}
+			brokers := strings.Split(utils.GetEnv("KAFKA_BROKERS", "localhost:9092"), ",")
+			notifiers = append(notifiers, alert.NewKafka(brokers, topic))
+		}
+	}
+	return notifiers
+}
 
-	for i, a := range articles {
-		formattedDate, err := utils.NormalizeToESDate(a.PublicationDate)
+func newSinkFor(spec sinkSpec, cfg config, es *elasticsearch.Client) (sink.Sink, error) {
+	switch spec.kind {
+	case "elasticsearch":
+		if spec.target != "" {
+			client, err := cfg.elasticsearchClient(spec.target)
+			if err != nil {
+				return nil, err
+			}
+			es = client
+		}
+		version, err := clusterVersion(es)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		s := sink.NewElasticsearch(es, cfg.index).
+			WithVersion(version).
+			WithRouting(cfg.routingField).
+			WithSettings(cfg.indexSettings()).
+			WithHistory(cfg.history).
+			WithTenant(cfg.tenant).
+			WithDumpDir(cfg.dumpBatches)
+		if cfg.rollover != "" {
+			// validate parsed them
+			conditions, _ := sink.ParseRolloverConditions(cfg.rollover)
+			s.WithRollover(conditions)
+		}
+		if cfg.healthInterval > 0 {
+			return sink.NewHealthGated(s, s, cfg.healthInterval), nil
 		}
-		meta := fmt.Sprintf(
-			`{ "index": { "_index": "%s", "_id": "%s" } }%s`,
-			indexName, a.ID, "\n",
-		)
-		buf.WriteString(meta)
-
-		doc := map[string]interface{}{
-			"id":               a.ID,
-			"title":            a.Title,
-			"description":      a.Description,
-			"url":              a.URL,
-			"publication_date": formattedDate,
-			"source_name":      a.SourceName,
-			"category":         a.Category,
-			"relevance_score":  a.RelevanceScore,
-			"latitude":         a.Latitude,
-			"longitude":        a.Longitude,
-			"location": map[string]float64{
-				"lat": a.Latitude,
-				"lon": a.Longitude,
-			},
-		}
-
-		body, err := json.Marshal(doc)
+		return s, nil
+	case "opensearch":
+		client, err := newOpenSearchClient(spec.target, cfg.compress, cfg.retries)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		return sink.NewOpenSearch(client, cfg.index).
+			WithRouting(cfg.routingField).
+			WithSettings(cfg.indexSettings()).
+			WithDumpDir(cfg.dumpBatches), nil
+	case "postgres":
+		dsn := spec.target
+		if dsn == "" {
+			dsn = os.Getenv("POSTGRES_DSN")
 		}
-		buf.Write(body)
-		buf.WriteByte('\n')
+		return sink.NewPostgres(dsn, utils.GetEnv("POSTGRES_TABLE", "articles"))
+	case "meilisearch":
+		address := spec.target
+		if address == "" {
+			address = utils.GetEnv("MEILISEARCH_URL", "http://localhost:7700")
+		}
+		return sink.NewMeilisearch(address, os.Getenv("MEILISEARCH_API_KEY"), cfg.index), nil
+	case "typesense":
+		address := spec.target
+		if address == "" {
+			address = utils.GetEnv("TYPESENSE_URL", "http://localhost:8108")
+		}
+		return sink.NewTypesense(address, os.Getenv("TYPESENSE_API_KEY"), cfg.index), nil
+	case "kafka":
+		brokers := spec.target
+		if brokers == "" {
+			brokers = utils.GetEnv("KAFKA_BROKERS", "localhost:9092")
+		}
+		// Commas already separate the sinks, so brokers in --sink use semicolons
+		list := strings.FieldsFunc(brokers, func(r rune) bool { return r == ',' || r == ';' })
+		return sink.NewKafka(list, utils.GetEnv("KAFKA_TOPIC", cfg.index)), nil
+	case "sqlite":
+		return sink.NewSQLite(spec.output(cfg), "articles")
+	case "ndjson":
+		return sink.NewNDJSON(spec.output(cfg))
+	}
+	return nil, fmt.Errorf("unknown sink %q", spec.kind)
+}
+
+// newOpenSearchClient configures the OpenSearch client from the
+// environment. A non-empty address overrides OPENSEARCH_URL. compress
+// gzips request bodies and retries counts the retryable responses.
+func newOpenSearchClient(address string, compress bool, retries *sink.RetryStats) (*opensearchapi.Client, error) {
+	if address == "" {
+		address = utils.GetEnv("OPENSEARCH_URL", defaultESAddress)
+	}
+	retry := retryOptionsFromEnv("OPENSEARCH")
+	discovery := discoveryOptionsFromEnv("OPENSEARCH")
+	return opensearchapi.NewClient(opensearchapi.Config{
+		Client: opensearch.Config{
+			Addresses:             []string{address},
+			Username:              utils.GetEnv("OPENSEARCH_USERNAME", "admin"),
+			Password:              os.Getenv("OPENSEARCH_PASSWORD"),
+			Transport:             newTransport("OPENSEARCH", retries),
+			CompressRequestBody:   compress,
+			DisableRetry:          retry.disabled,
+			MaxRetries:            retry.maxRetries,
+			RetryOnStatus:         retry.onStatus,
+			RetryBackoff:          retry.backoffFunc(),
+			DiscoverNodesOnStart:  &discovery.onStart,
+			DiscoverNodesInterval: discovery.interval,
+		},
+	})
+}
 
-		if (i+1)%bulkSize == 0 {
-			if err := flushBulk(ctx, es, &buf); err != nil {
-				return err
+// newSyncer wires the sink and, when enabled, the sync lock.
+func newSyncer(cfg config, es *elasticsearch.Client, out sink.Sink, enrichers []enrich.Enricher) *syncpkg.Syncer {
+	// The mapping, schema, zone, policy, id strategies, registry and sample rate
+	// were checked by validate
+	location, _ := cfg.location()
+	onBadDate, _ := syncpkg.ParseDatePolicy(cfg.onBadDate)
+	idStrategies, _ := syncpkg.ParseIDStrategies(cfg.idStrategy)
+	access, sources := cfg.access()
+	var sampleRate float64
+	if cfg.sample != "" {
+		sampleRate, _ = syncpkg.ParseSampleRate(cfg.sample)
+	}
+	var shard syncpkg.Shard
+	if cfg.shard != "" {
+		shard, _ = syncpkg.ParseShard(cfg.shard)
+	}
+	shape := source.Shape{KeepExtra: cfg.keepExtra}
+	if cfg.fieldMap != "" {
+		shape.Fields, _ = source.LoadFieldMapping(cfg.fieldMap)
+	}
+	switch {
+	case cfg.schemaFile != "":
+		shape.Schema, _ = source.LoadSchema(cfg.schemaFile)
+	case cfg.validateInput:
+		shape.Schema = source.ArticleSchema()
+	}
+	s := &syncpkg.Syncer{
+		Source:       cfg.source,
+		Shape:        shape,
+		Sink:         out,
+		Enrichers:    enrichers,
+		Timezone:     location,
+		OnBadDate:    onBadDate,
+		Sources:      sources,
+		IDStrategies: idStrategies,
+		SampleRate:   sampleRate,
+		Limit:        cfg.limit,
+		Shard:        shard,
+		MaxMemory:    cfg.maxMemory,
+		Pipeline:     cfg.pipeline,
+		Retries:      cfg.retries,
+		Access:       access,
+		Tenant:       cfg.tenant,
+		DeadLetter:   cfg.deadLetter,
+	}
+	if cfg.categoryRoutes != "" {
+		s.Routes = cfg.categoryRoutesOf()
+		if err := s.Routes.Check(enrichers); err != nil {
+			exitWithConfigError(err, "invalid --category-routes")
+		}
+		s.RouteSinks = make(map[string]sink.Sink)
+		for _, index := range s.Routes.Indices() {
+			routed := cfg
+			routed.index = index
+			out, err := newSink(routed, es)
+			if err != nil {
+				exitWithConfigError(err, "error while configuring sink of category route")
 			}
+			s.RouteSinks[index] = out
+		}
+	}
+	if cfg.lock {
+		s.Lock = syncpkg.NewESLock(es, cfg.index, cfg.lockTTL)
+	}
+	if cfg.prune {
+		s.Prune = pruner(cfg, es)
+	}
+	if cfg.related == sink.RelatedKNN && enrich.IndexOptionsFor(enrichers).EmbeddingDims == 0 {
+		exitWithConfigError(errors.New("--related knn needs the embedding enricher"), "invalid configuration")
+	}
+	s.Relate = relater(cfg, es)
+	s.Expire = expirer(cfg, es, enrich.IndexOptionsFor(enrichers))
+	s.Notifier = runNotifier(cfg)
+	s.Preflight = diskPreflight(cfg, es)
+	return s
+}
+
+// closeRouteSinks closes the sinks of the category routes of s and
+// reports whether all of them closed cleanly.
+func closeRouteSinks(s *syncpkg.Syncer) bool {
+	ok := true
+	for index, out := range s.RouteSinks {
+		if err := out.Close(); err != nil {
+			log.Error().Caller().Err(err).Msgf("error while closing %s sink of %s", out.Name(), index)
+			ok = false
 		}
 	}
+	return ok
+}
+
+// clusterVersions caches the versions found by clusterVersion.
+var clusterVersions gosync.Map
 
-	return flushBulk(ctx, es, &buf)
+// clusterVersion detects the version of the cluster of es, failing on
+// one the syncer can't write to. A cluster that can't be reached yet
+// only warns, as a daemon may start before it.
+func clusterVersion(es *elasticsearch.Client) (sink.Version, error) {
+	if v, ok := clusterVersions.Load(es); ok {
+		return v.(sink.Version), nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	version, err := sink.DetectVersion(ctx, es)
+	if err != nil {
+		log.Warn().Caller().Err(err).Msg("failed to detect the elasticsearch version, assuming a current one")
+		version = sink.Version{}
+	} else if err := version.Supported(); err != nil {
+		return sink.Version{}, err
+	}
+	log.Debug().Caller().Msgf("elasticsearch version %s", version)
+	clusterVersions.Store(es, version)
+	return version, nil
+}
+
+// runNotifier returns the notifiers of --notify, nil when there are none.
+func runNotifier(cfg config) syncpkg.Notifier {
+	var notifiers notify.Multi
+	for _, spec := range cfg.notifySpecs() {
+		switch spec.kind {
+		case "webhook":
+			url := spec.target
+			if url == "" {
+				url = os.Getenv("NOTIFY_WEBHOOK_URL")
+			}
+			notifiers = append(notifiers, notify.NewWebhook(url))
+		case "email":
+			notifiers = append(notifiers, notify.NewEmail(
+				os.Getenv("SMTP_ADDR"), os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"),
+				os.Getenv("SMTP_FROM"), emailRecipients(spec.target)))
+		}
+	}
+	if len(notifiers) == 0 {
+		return nil
+	}
+	if cfg.notifyOn == "failure" {
+		return notify.FailuresOnly(notifiers)
+	}
+	return notifiers
 }
 
-func flushBulk(ctx context.Context, es *elasticsearch.Client, buf *bytes.Buffer) error {
-	if buf.Len() == 0 {
+// pruner soft deletes the articles gone from the source on every
+// elasticsearch sink.
+func pruner(cfg config, es *elasticsearch.Client) func(context.Context, string) (int, error) {
+	var targets []*sink.Elasticsearch
+	for _, spec := range cfg.sinkSpecs() {
+		if spec.kind != "elasticsearch" {
+			continue
+		}
+		client := es
+		if spec.target != "" {
+			var err error
+			if client, err = cfg.elasticsearchClient(spec.target); err != nil {
+				exitWithConfigError(err, "failed to create elasticsearch client of sink to prune")
+			}
+		}
+		targets = append(targets, sink.NewElasticsearch(client, cfg.index).WithTenant(cfg.tenant))
+	}
+	if len(targets) == 0 {
 		return nil
 	}
 
-	res, err := es.Bulk(bytes.NewReader(buf.Bytes()), es.Bulk.WithContext(ctx))
-	if err != nil {
-		return err
+	return func(ctx context.Context, runID string) (int, error) {
+		total := 0
+		for _, target := range targets {
+			pruned, err := target.Prune(ctx, runID)
+			total += pruned
+			if err != nil {
+				return total, err
+			}
+		}
+		return total, nil
 	}
-	defer res.Body.Close()
+}
 
-	var bulkResp struct {
-		Errors bool `json:"errors"`
-		Items  []map[string]struct {
-			Status int                    `json:"status"`
-			Error  map[string]interface{} `json:"error,omitempty"`
-		} `json:"items"`
+// relater stores the related articles of the synced ones with --related,
+// on the index and the category route indices of every elasticsearch
+// sink.
+func relater(cfg config, es *elasticsearch.Client) func(context.Context, string) (int, error) {
+	if cfg.related == "" {
+		return nil
+	}
+	indices := []string{cfg.index}
+	if cfg.categoryRoutes != "" {
+		indices = append(indices, cfg.categoryRoutesOf().Indices()...)
+	}
+	var targets []*sink.Elasticsearch
+	for _, spec := range cfg.sinkSpecs() {
+		if spec.kind != "elasticsearch" {
+			continue
+		}
+		client := es
+		if spec.target != "" {
+			var err error
+			if client, err = cfg.elasticsearchClient(spec.target); err != nil {
+				exitWithConfigError(err, "failed to create elasticsearch client of sink to store related articles on")
+			}
+		}
+		for _, index := range indices {
+			targets = append(targets, sink.NewElasticsearch(client, index).WithTenant(cfg.tenant))
+		}
+	}
+	if len(targets) == 0 {
+		return nil
 	}
 
-	if err := json.NewDecoder(res.Body).Decode(&bulkResp); err != nil {
-		return err
+	return func(ctx context.Context, runID string) (int, error) {
+		total := 0
+		for _, target := range targets {
+			related, err := target.Relate(ctx, runID, cfg.related, cfg.relatedSize)
+			total += related
+			if err != nil {
+				return total, err
+			}
+		}
+		return total, nil
 	}
+}
 
-	if bulkResp.Errors {
-		for _, item := range bulkResp.Items {
-			for _, action := range item {
-				if action.Error != nil {
-					return fmt.Errorf("bulk item failed: %+v", action.Error)
-				}
+// diskPreflight checks that the articles fit on every elasticsearch sink
+// without crossing the flood stage watermark. A breach aborts the sync
+// unless --force is set; a failed check only warns.
+func diskPreflight(cfg config, es *elasticsearch.Client) func(context.Context, []model.Article) error {
+	var targets []*sink.Elasticsearch
+	for _, spec := range cfg.sinkSpecs() {
+		if spec.kind != "elasticsearch" {
+			continue
+		}
+		client := es
+		if spec.target != "" {
+			var err error
+			if client, err = newElasticsearchClient(spec.target); err != nil {
+				continue
 			}
 		}
+		version, err := clusterVersion(client)
+		if err != nil {
+			continue
+		}
+		targets = append(targets, sink.NewElasticsearch(client, cfg.index).WithVersion(version))
+	}
+	if len(targets) == 0 {
+		return nil
 	}
 
-	buf.Reset()
-	return nil
+	return func(ctx context.Context, articles []model.Article) error {
+		size, err := sink.EstimateSize(articles)
+		if err != nil {
+			return err
+		}
+		for _, target := range targets {
+			err := target.CheckDiskSpace(ctx, size)
+			switch {
+			case errors.Is(err, sink.ErrDiskWatermark) && cfg.force:
+				log.Warn().Caller().Err(err).Msg("syncing anyway because of --force")
+			case errors.Is(err, sink.ErrDiskWatermark):
+				return fmt.Errorf("%w, use --force to sync anyway", err)
+			case err != nil:
+				log.Warn().Caller().Err(err).Msg("could not check elasticsearch disk usage")
+			}
+		}
+		return nil
+	}
 }
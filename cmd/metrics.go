@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/elastic/elastic-transport-go/v8/elastictransport"
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// poolStats measures the connection pools of every cluster transport, so
+// requests waiting for a connection can be told apart from requests the
+// cluster is slow to answer.
+var poolStats connStats
+
+type connStats struct {
+	open     atomic.Int64
+	dialed   atomic.Int64
+	reused   atomic.Int64
+	inFlight atomic.Int64
+	// waits and waitNanos count the connections requests got and how long
+	// they waited for them, dialing included.
+	waits     atomic.Int64
+	waitNanos atomic.Int64
+}
+
+// dialContext counts the connections dial opens and closes.
+func (s *connStats) dialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		s.dialed.Add(1)
+		s.open.Add(1)
+		return &countedConn{Conn: conn, stats: s}, nil
+	}
+}
+
+type countedConn struct {
+	net.Conn
+	stats  *connStats
+	closed sync.Once
+}
+
+func (c *countedConn) Close() error {
+	c.closed.Do(func() { c.stats.open.Add(-1) })
+	return c.Conn.Close()
+}
+
+// wrap counts the requests in flight through rt and traces how they get
+// their connection.
+func (s *connStats) wrap(rt http.RoundTripper) http.RoundTripper {
+	return &tracedTransport{base: rt, stats: s}
+}
+
+type tracedTransport struct {
+	base  http.RoundTripper
+	stats *connStats
+}
+
+func (t *tracedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.stats.inFlight.Add(1)
+	defer t.stats.inFlight.Add(-1)
+	var asked time.Time
+	trace := &httptrace.ClientTrace{
+		GetConn: func(string) { asked = time.Now() },
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				t.stats.reused.Add(1)
+			}
+			t.stats.waits.Add(1)
+			t.stats.waitNanos.Add(int64(time.Since(asked)))
+		},
+	}
+	return t.base.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+}
+
+// measuredClients are the clients whose transport metrics are reported,
+// by address.
+var measuredClients = struct {
+	sync.Mutex
+	byAddress map[string]*elasticsearch.Client
+}{byAddress: map[string]*elasticsearch.Client{}}
+
+// measureClient reports the transport metrics of client, which must have
+// been created with EnableMetrics.
+func measureClient(address string, client *elasticsearch.Client) {
+	if u, err := url.Parse(address); err == nil {
+		address = u.Redacted()
+	}
+	measuredClients.Lock()
+	measuredClients.byAddress[address] = client
+	measuredClients.Unlock()
+}
+
+// clientMetrics is the transport metrics of a measured client.
+type clientMetrics struct {
+	address   string
+	requests  int
+	failures  int
+	responses map[int]int
+	nodes     int
+	deadNodes int
+}
+
+func measuredClientMetrics() []clientMetrics {
+	measuredClients.Lock()
+	defer measuredClients.Unlock()
+	var all []clientMetrics
+	for address, client := range measuredClients.byAddress {
+		m, err := client.Metrics()
+		if err != nil {
+			continue
+		}
+		metrics := clientMetrics{address: address, requests: m.Requests, failures: m.Failures, responses: m.Responses}
+		for _, c := range m.Connections {
+			metrics.nodes++
+			if node, ok := c.(elastictransport.ConnectionMetric); ok && node.IsDead {
+				metrics.deadNodes++
+			}
+		}
+		all = append(all, metrics)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].address < all[j].address })
+	return all
+}
+
+// logTransportMetrics writes the pool and client metrics as log lines,
+// after each run.
+func logTransportMetrics() {
+	waits := poolStats.waits.Load()
+	var meanWait time.Duration
+	if waits > 0 {
+		meanWait = time.Duration(poolStats.waitNanos.Load() / waits)
+	}
+	log.Info().Caller().
+		Int64("open_connections", poolStats.open.Load()).
+		Int64("dialed_connections", poolStats.dialed.Load()).
+		Int64("reused_connections", poolStats.reused.Load()).
+		Int64("requests_in_flight", poolStats.inFlight.Load()).
+		Float64("mean_connection_wait_ms", float64(meanWait.Microseconds())/1000).
+		Msg("transport connection pool")
+	for _, m := range measuredClientMetrics() {
+		log.Info().Caller().
+			Str("address", m.address).
+			Int("requests", m.requests).
+			Int("failures", m.failures).
+			Interface("responses", m.responses).
+			Int("nodes", m.nodes).
+			Int("dead_nodes", m.deadNodes).
+			Msg("elasticsearch client")
+	}
+}
+
+// handleMetrics serves the pool and client metrics in the Prometheus
+// text format.
+func handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	writeMetrics(w)
+}
+
+func writeMetrics(w io.Writer) {
+	metric := func(name, typ, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+	metric("syncer_transport_open_connections", "gauge", "Connections to the clusters currently open.")
+	fmt.Fprintf(w, "syncer_transport_open_connections %d\n", poolStats.open.Load())
+	metric("syncer_transport_dialed_connections_total", "counter", "Connections opened to the clusters.")
+	fmt.Fprintf(w, "syncer_transport_dialed_connections_total %d\n", poolStats.dialed.Load())
+	metric("syncer_transport_reused_connections_total", "counter", "Requests sent over an idle pooled connection.")
+	fmt.Fprintf(w, "syncer_transport_reused_connections_total %d\n", poolStats.reused.Load())
+	metric("syncer_transport_requests_in_flight", "gauge", "Requests to the clusters awaiting their response.")
+	fmt.Fprintf(w, "syncer_transport_requests_in_flight %d\n", poolStats.inFlight.Load())
+	metric("syncer_transport_connection_wait_seconds", "summary", "Time requests waited for a connection, dialing included.")
+	fmt.Fprintf(w, "syncer_transport_connection_wait_seconds_sum %g\n", time.Duration(poolStats.waitNanos.Load()).Seconds())
+	fmt.Fprintf(w, "syncer_transport_connection_wait_seconds_count %d\n", poolStats.waits.Load())
+
+	clients := measuredClientMetrics()
+	if len(clients) == 0 {
+		return
+	}
+	metric("syncer_elasticsearch_requests_total", "counter", "Requests sent by the Elasticsearch client, retries included.")
+	for _, m := range clients {
+		fmt.Fprintf(w, "syncer_elasticsearch_requests_total{address=%q} %d\n", m.address, m.requests)
+	}
+	metric("syncer_elasticsearch_request_failures_total", "counter", "Requests of the Elasticsearch client that got no response.")
+	for _, m := range clients {
+		fmt.Fprintf(w, "syncer_elasticsearch_request_failures_total{address=%q} %d\n", m.address, m.failures)
+	}
+	metric("syncer_elasticsearch_responses_total", "counter", "Responses to the Elasticsearch client by status code.")
+	for _, m := range clients {
+		statuses := make([]int, 0, len(m.responses))
+		for status := range m.responses {
+			statuses = append(statuses, status)
+		}
+		sort.Ints(statuses)
+		for _, status := range statuses {
+			fmt.Fprintf(w, "syncer_elasticsearch_responses_total{address=%q,status=\"%d\"} %d\n", m.address, status, m.responses[status])
+		}
+	}
+	metric("syncer_elasticsearch_nodes", "gauge", "Nodes in the connection pool of the Elasticsearch client.")
+	for _, m := range clients {
+		fmt.Fprintf(w, "syncer_elasticsearch_nodes{address=%q} %d\n", m.address, m.nodes)
+	}
+	metric("syncer_elasticsearch_dead_nodes", "gauge", "Nodes the Elasticsearch client marked dead after failed requests.")
+	for _, m := range clients {
+		fmt.Fprintf(w, "syncer_elasticsearch_dead_nodes{address=%q} %d\n", m.address, m.deadNodes)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/enrich"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// runRetryDLQ syncs the articles of a dead letter file written by a
+// previous run with --dead-letter, optionally fixed up first, e.g.
+//
+//	retry-dlq --file failed.ndjson --fix fix.json
+//
+// where fix.json is a JSON merge patch applied to every article, such as
+// {"latitude": null, "longitude": null} to drop bad coordinates. It
+// accepts the options of a sync other than --source. Articles rejected
+// again are appended to --dead-letter, <file>.retry by default.
+func runRetryDLQ(args []string) int {
+	file := flag.String("file", "", "dead letter file to retry")
+	fix := flag.String("fix", "", "json merge patch applied to every article before it is synced again")
+	cfg := parseFlags(args)
+
+	if *file == "" {
+		exitWithConfigError(errors.New("--file is required"), "invalid configuration")
+	}
+	if _, err := os.Stat(*file); err != nil {
+		exitWithConfigError(err, "invalid --file")
+	}
+	if cfg.daemon() || cfg.jobs != "" {
+		exitWithConfigError(errors.New("retry-dlq runs once and can't be combined with --jobs, --schedule, --ingest or --grpc-addr"), "invalid configuration")
+	}
+	if cfg.prune {
+		exitWithConfigError(errors.New("--prune would soft delete every article not retried"), "invalid configuration")
+	}
+	cfg.source = *file
+	if cfg.deadLetter == "" {
+		cfg.deadLetter = *file + ".retry"
+	}
+	if same, _ := samePath(cfg.deadLetter, *file); same {
+		exitWithConfigError(errors.New("--dead-letter must not be the retried file"), "invalid configuration")
+	}
+	var patch map[string]interface{}
+	if *fix != "" {
+		var err error
+		if patch, err = loadMergePatch(*fix); err != nil {
+			exitWithConfigError(err, "invalid --fix")
+		}
+	}
+	if err := cfg.validate(); err != nil {
+		exitWithConfigError(err, "invalid configuration")
+	}
+	cfg = cfg.scoped()
+	utils.AddDateLayouts(cfg.dateLayouts...)
+	applyMemoryLimit(cfg)
+
+	es, err := cfg.elasticsearchClient("")
+	if err != nil {
+		exitWithConfigError(err, "failed to create elasticsearch client")
+	}
+	enrichers, err := enrich.FromEnv()
+	if err != nil {
+		exitWithConfigError(err, "error while configuring enrichers")
+	}
+	out, err := newSink(cfg, es)
+	if err != nil {
+		exitWithConfigError(err, "error while configuring sink")
+	}
+	s := newSyncer(cfg, es, out, enrichers)
+	s.Name = "retry-dlq"
+	s.Fixup = func(a *model.Article) error {
+		// Kept under extra with --keep-extra-fields
+		delete(a.Extra, "dead_letter")
+		return applyMergePatch(a, patch)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	code := runJob(ctx, cfg, s)
+	if err := out.Close(); err != nil {
+		log.Error().Caller().Err(err).Msgf("error while closing %s sink", out.Name())
+		code = exitFailure
+	}
+	return code
+}
+
+// loadMergePatch reads a JSON merge patch (RFC 7386) and checks that it
+// yields a valid article.
+func loadMergePatch(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var patch map[string]interface{}
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return nil, err
+	}
+	if err := applyMergePatch(&model.Article{}, patch); err != nil {
+		return nil, err
+	}
+	return patch, nil
+}
+
+// applyMergePatch merges patch into a: its values replace those of a and
+// null removes a field.
+func applyMergePatch(a *model.Article, patch map[string]interface{}) error {
+	if len(patch) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	mergePatch(doc, patch)
+	if data, err = json.Marshal(doc); err != nil {
+		return err
+	}
+	var patched model.Article
+	if err := json.Unmarshal(data, &patched); err != nil {
+		return fmt.Errorf("patched article is invalid: %w", err)
+	}
+	*a = patched
+	return nil
+}
+
+func mergePatch(doc, patch map[string]interface{}) {
+	for key, value := range patch {
+		switch v := value.(type) {
+		case nil:
+			delete(doc, key)
+		case map[string]interface{}:
+			target, ok := doc[key].(map[string]interface{})
+			if !ok {
+				target = make(map[string]interface{})
+			}
+			mergePatch(target, v)
+			doc[key] = target
+		default:
+			doc[key] = v
+		}
+	}
+}
+
+// samePath reports whether a and b name the same file.
+func samePath(a, b string) (bool, error) {
+	absA, err := filepath.Abs(a)
+	if err != nil {
+		return false, err
+	}
+	absB, err := filepath.Abs(b)
+	if err != nil {
+		return false, err
+	}
+	return absA == absB, nil
+}
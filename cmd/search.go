@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/enrich"
+	"inshorts.com/inshorts-news-data-syncer/pkg/search"
+	"inshorts.com/inshorts-news-data-syncer/pkg/sink"
+)
+
+// runSearch queries the index and prints ranked results, e.g.
+//
+//	search "modi election" --category politics --since 2024-01-01 --near 28.6,77.2,50km
+func runSearch(args []string) int {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	index := fs.String("index", indexName, "index to search")
+	category := fs.String("category", "", "only return these comma separated categories")
+	source := fs.String("source", "", "only return articles from these comma separated sources")
+	since := fs.String("since", "", "only return articles published on or after this date, YYYY-MM-DD or RFC 3339")
+	until := fs.String("until", "", "only return articles published before this date, YYYY-MM-DD or RFC 3339")
+	near := fs.String("near", "", "only return articles within a distance of a point: lat,lon,distance e.g. 28.6,77.2,50km")
+	languages := fs.String("languages", "", "also match the text in these comma separated language subfields, as indexed with --languages, e.g. hindi")
+	size := fs.Int("size", search.DefaultSize, "number of results")
+	asJSON := fs.Bool("json", false, "print results as json instead of a table")
+	highlight := fs.Bool("highlight", false, "show the matching fragments of the title and description")
+	preTag := fs.String("pre-tag", "", `tag before highlighted terms (default "<em>")`)
+	postTag := fs.String("post-tag", "", `tag after highlighted terms (default "</em>")`)
+	fragmentSize := fs.Int("fragment-size", 0, "length of highlighted description fragments in characters (default 100)")
+	fragments := fs.Int("fragments", 0, "maximum number of highlighted description fragments (default 5)")
+	rankingFile := fs.String("ranking", "", "yaml ranking profile weighting text, recency, relevance and sources, see search.LoadRanking")
+	hybrid := fs.Bool("hybrid", false, "rank by text, semantic similarity, recency and relevance; semantic matching needs EMBEDDING_BASE_URL")
+	terms := parseInterspersed(fs, args)
+
+	q := search.Query{Text: strings.Join(terms, " "), Size: *size}
+	if *category != "" {
+		q.Categories = strings.Split(*category, ",")
+	}
+	if *source != "" {
+		q.Sources = strings.Split(*source, ",")
+	}
+	var err error
+	if q.Languages, err = sink.ParseLanguages(*languages); err != nil {
+		exitWithConfigError(err, "invalid --languages")
+	}
+	if q.Since, err = parseDateFlag(*since); err != nil {
+		exitWithConfigError(err, "invalid --since")
+	}
+	if q.Until, err = parseDateFlag(*until); err != nil {
+		exitWithConfigError(err, "invalid --until")
+	}
+	if *near != "" {
+		if q.Near, err = search.ParseNear(*near); err != nil {
+			exitWithConfigError(err, "invalid --near")
+		}
+	}
+	if *size < 1 {
+		exitWithConfigError(errors.New("--size must be positive"), "invalid configuration")
+	}
+	if *fragmentSize < 0 || *fragments < 0 {
+		exitWithConfigError(errors.New("--fragment-size and --fragments must not be negative"), "invalid configuration")
+	}
+	if *highlight {
+		q.Highlight = &search.Highlight{PreTag: *preTag, PostTag: *postTag, FragmentSize: *fragmentSize, Fragments: *fragments}
+	}
+
+	es, err := newElasticsearchClient("")
+	if err != nil {
+		exitWithConfigError(err, "failed to create elasticsearch client")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if *rankingFile != "" {
+		if q.Ranking, err = search.LoadRanking(*rankingFile); err != nil {
+			exitWithConfigError(err, "invalid --ranking")
+		}
+	}
+
+	if *hybrid {
+		if q.Ranking == nil {
+			ranking := search.DefaultRanking
+			q.Ranking = &ranking
+		}
+		embedder, err := enrich.NewQueryEmbedderFromEnv()
+		if err != nil {
+			exitWithConfigError(err, "error while configuring embeddings")
+		}
+		if embedder != nil && q.Text != "" {
+			if q.Vector, err = embedder.Embed(ctx, q.Text); err != nil {
+				log.Error().Caller().Err(err).Msg("failed to embed query")
+				return exitFailure
+			}
+		}
+	}
+
+	results, err := search.Run(ctx, es, *index, q)
+	if err != nil {
+		log.Error().Caller().Err(err).Msg("search failed")
+		return exitFailure
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return exitFailure
+		}
+		return exitSuccess
+	}
+	printResults(results, *highlight)
+	return exitSuccess
+}
+
+// parseInterspersed parses flags that may come before or after the
+// positional arguments, which the flag package alone stops at.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		if fs.NArg() == 0 {
+			return positional
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// parseDateFlag accepts a date or an RFC 3339 timestamp, empty meaning unset.
+func parseDateFlag(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// printResults prints the hits as a table. Highlighted searches show the
+// highlighted title and the first matching description fragment.
+func printResults(results *search.Results, highlighted bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := "#\tSCORE\tPUBLISHED\tCATEGORY\tSOURCE\tTITLE"
+	if highlighted {
+		header += "\tSNIPPET"
+	}
+	fmt.Fprintln(w, header)
+	for i, h := range results.Hits {
+		published := h.Article.PublicationDate
+		if t, err := time.Parse(time.RFC3339, published); err == nil {
+			published = t.Format("2006-01-02 15:04")
+		}
+		title := h.Article.Title
+		if fragments := h.Highlights["title"]; len(fragments) > 0 {
+			title = fragments[0]
+		}
+		fmt.Fprintf(w, "%d\t%.2f\t%s\t%s\t%s\t%s",
+			i+1, h.Score, published, strings.Join(h.Article.Category, ","), h.Article.SourceName, truncate(title, 80))
+		if highlighted {
+			var snippet string
+			if fragments := h.Highlights["description"]; len(fragments) > 0 {
+				snippet = strings.Join(strings.Fields(fragments[0]), " ")
+			}
+			fmt.Fprintf(w, "\t%s", snippet)
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+	fmt.Printf("%d of %d matching articles\n", len(results.Hits), results.Total)
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/enrich"
+	"inshorts.com/inshorts-news-data-syncer/pkg/search"
+	"inshorts.com/inshorts-news-data-syncer/pkg/sink"
+)
+
+// maxSearchSize caps the size parameter of /v1/search.
+const maxSearchSize = 100
+
+// searchTimeout bounds each request to Elasticsearch.
+const searchTimeout = 10 * time.Second
+
+// searchServer exposes the index over a read only REST API, so clients
+// don't need direct Elasticsearch access.
+type searchServer struct {
+	es       *elasticsearch.Client
+	index    string
+	embedder *enrich.QueryEmbedder
+	// ranking ranks every search when set, instead of only hybrid ones.
+	ranking *search.Ranking
+}
+
+// runServe serves the search API until interrupted, e.g.
+//
+//	serve --addr :8081
+//	curl 'localhost:8081/v1/search?q=election&category=politics&since=2024-01-01&near=28.6,77.2,50km'
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8081", "address of the search api")
+	index := fs.String("index", indexName, "index to search")
+	rankingFile := fs.String("ranking", "", "yaml ranking profile applied to every search, see search.LoadRanking")
+	fs.Parse(args)
+
+	es, err := newElasticsearchClient("")
+	if err != nil {
+		exitWithConfigError(err, "failed to create elasticsearch client")
+	}
+	s, err := newSearchServer(es, *index)
+	if err != nil {
+		exitWithConfigError(err, "error while configuring embeddings")
+	}
+	if *rankingFile != "" {
+		if s.ranking, err = search.LoadRanking(*rankingFile); err != nil {
+			exitWithConfigError(err, "invalid --ranking")
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: *addr, Handler: s.routes(), ReadHeaderTimeout: 5 * time.Second}
+	serverErr := make(chan error, 1)
+	go func() {
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+	log.Info().Caller().Msgf("search api serving index %s on %s", *index, *addr)
+
+	select {
+	case <-ctx.Done():
+	case err := <-serverErr:
+		log.Error().Caller().Err(err).Msg("search api failed")
+		return exitFailure
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Error().Caller().Err(err).Msg("error while stopping search api")
+		return exitFailure
+	}
+	return exitSuccess
+}
+
+// newSearchServer searches index, embedding hybrid queries with the
+// embedding stage's provider when one is configured.
+func newSearchServer(es *elasticsearch.Client, index string) (*searchServer, error) {
+	embedder, err := enrich.NewQueryEmbedderFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return &searchServer{es: es, index: index, embedder: embedder}, nil
+}
+
+func (s *searchServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/search", s.handleSearch)
+	mux.HandleFunc("GET /v1/suggest", s.handleSuggest)
+	mux.HandleFunc("GET /v1/articles/{id}", s.handleArticle)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	return mux
+}
+
+// handleSearch accepts q, category, source, languages, since, until,
+// near, size and hybrid, as well as highlight with pre_tag, post_tag,
+// fragment_size and fragments. Lists may be comma separated or repeated.
+func (s *searchServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	q, hybrid, err := searchQuery(r.URL.Query())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	results, err := s.search(r.Context(), q, hybrid)
+	if err != nil {
+		log.Error().Caller().Err(err).Msg("search failed")
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": "search failed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// search runs q, ranked by the server's profile if it has one and as a
+// hybrid query when asked to. The query text is only embedded when
+// embeddings are configured.
+func (s *searchServer) search(ctx context.Context, q search.Query, hybrid bool) (*search.Results, error) {
+	ctx, cancel := context.WithTimeout(ctx, searchTimeout)
+	defer cancel()
+
+	q.Ranking = s.ranking
+	if hybrid {
+		if q.Ranking == nil {
+			ranking := search.DefaultRanking
+			q.Ranking = &ranking
+		}
+		if s.embedder != nil && q.Text != "" {
+			vector, err := s.embedder.Embed(ctx, q.Text)
+			if err != nil {
+				return nil, fmt.Errorf("failed to embed query: %w", err)
+			}
+			q.Vector = vector
+		}
+	}
+	return search.Run(ctx, s.es, s.index, q)
+}
+
+// handleSuggest completes the title prefix q, optionally within the
+// categories given by category.
+func (s *searchServer) handleSuggest(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	prefix := params.Get("q")
+	if prefix == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "q is required"})
+		return
+	}
+	size := search.DefaultSize
+	if value := params.Get("size"); value != "" {
+		var err error
+		if size, err = strconv.Atoi(value); err != nil || size < 1 || size > maxSearchSize {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("size must be between 1 and %d", maxSearchSize)})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), searchTimeout)
+	defer cancel()
+	suggestions, err := search.Suggest(ctx, s.es, s.index, prefix, listParam(params, "category"), size)
+	if err != nil {
+		log.Error().Caller().Err(err).Msg("suggest failed")
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": "suggest failed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"suggestions": suggestions})
+}
+
+func (s *searchServer) handleArticle(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), searchTimeout)
+	defer cancel()
+
+	article, err := search.Get(ctx, s.es, s.index, r.PathValue("id"))
+	if errors.Is(err, search.ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Error().Caller().Err(err).Msg("failed to get article")
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": "failed to get article"})
+		return
+	}
+	writeJSON(w, http.StatusOK, article)
+}
+
+// searchQuery parses the query parameters of /v1/search.
+func searchQuery(params url.Values) (search.Query, bool, error) {
+	q := search.Query{
+		Text:       params.Get("q"),
+		Categories: listParam(params, "category"),
+		Sources:    listParam(params, "source"),
+		Size:       search.DefaultSize,
+	}
+
+	var err error
+	if q.Languages, err = sink.ParseLanguages(strings.Join(listParam(params, "languages"), ",")); err != nil {
+		return q, false, fmt.Errorf("invalid languages: %w", err)
+	}
+	if q.Since, err = parseDateFlag(params.Get("since")); err != nil {
+		return q, false, fmt.Errorf("invalid since: %w", err)
+	}
+	if q.Until, err = parseDateFlag(params.Get("until")); err != nil {
+		return q, false, fmt.Errorf("invalid until: %w", err)
+	}
+	if near := params.Get("near"); near != "" {
+		if q.Near, err = search.ParseNear(near); err != nil {
+			return q, false, fmt.Errorf("invalid near: %w", err)
+		}
+	}
+	if size := params.Get("size"); size != "" {
+		if q.Size, err = strconv.Atoi(size); err != nil || q.Size < 1 || q.Size > maxSearchSize {
+			return q, false, fmt.Errorf("size must be between 1 and %d", maxSearchSize)
+		}
+	}
+
+	if value := params.Get("highlight"); value != "" {
+		highlight, err := strconv.ParseBool(value)
+		if err != nil {
+			return q, false, fmt.Errorf("invalid highlight: %w", err)
+		}
+		if highlight {
+			q.Highlight = &search.Highlight{PreTag: params.Get("pre_tag"), PostTag: params.Get("post_tag")}
+			for name, value := range map[string]*int{"fragment_size": &q.Highlight.FragmentSize, "fragments": &q.Highlight.Fragments} {
+				if raw := params.Get(name); raw != "" {
+					if *value, err = strconv.Atoi(raw); err != nil || *value < 1 {
+						return q, false, fmt.Errorf("%s must be a positive number", name)
+					}
+				}
+			}
+		}
+	}
+
+	var hybrid bool
+	if value := params.Get("hybrid"); value != "" {
+		if hybrid, err = strconv.ParseBool(value); err != nil {
+			return q, false, fmt.Errorf("invalid hybrid: %w", err)
+		}
+	}
+	return q, hybrid, nil
+}
+
+// listParam returns the values of a repeated or comma separated parameter.
+func listParam(params url.Values, name string) []string {
+	var values []string
+	for _, value := range params[name] {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/elastic/go-elasticsearch/v9/esapi"
+	"github.com/rs/zerolog/log"
+)
+
+// snapshotFlags are shared by the snapshot and restore subcommands.
+type snapshotFlags struct {
+	repository   string
+	repoType     string
+	location     string
+	bucket       string
+	basePath     string
+	pollInterval time.Duration
+	timeout      time.Duration
+}
+
+func (f *snapshotFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.repository, "repository", "inshorts-news-backups", "snapshot repository name")
+	fs.StringVar(&f.repoType, "repository-type", "", "register the repository first: fs or s3 (empty uses an existing repository)")
+	fs.StringVar(&f.location, "location", "", "directory of an fs repository, must be listed in path.repo")
+	fs.StringVar(&f.bucket, "bucket", "", "bucket of an s3 repository")
+	fs.StringVar(&f.basePath, "base-path", "", "path inside the bucket of an s3 repository")
+	fs.DurationVar(&f.pollInterval, "poll-interval", 2*time.Second, "how often to check progress")
+	fs.DurationVar(&f.timeout, "timeout", time.Hour, "give up waiting after this long")
+}
+
+func (f *snapshotFlags) validate() error {
+	switch f.repoType {
+	case "":
+	case "fs":
+		if f.location == "" {
+			return errors.New("--repository-type=fs needs --location")
+		}
+	case "s3":
+		if f.bucket == "" {
+			return errors.New("--repository-type=s3 needs --bucket")
+		}
+	default:
+		return fmt.Errorf("unknown --repository-type %q, expected fs or s3", f.repoType)
+	}
+	if f.pollInterval <= 0 || f.timeout <= 0 {
+		return errors.New("--poll-interval and --timeout must be positive")
+	}
+	return nil
+}
+
+// runSnapshot takes a snapshot of the news index and waits for it.
+func runSnapshot(args []string) int {
+	var repo snapshotFlags
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	repo.register(fs)
+	index := fs.String("index", indexName, "index to snapshot")
+	name := fs.String("name", "", "snapshot name, defaults to <index>-<utc timestamp>")
+	fs.Parse(args)
+
+	if err := repo.validate(); err != nil {
+		exitWithConfigError(err, "invalid configuration")
+	}
+	if *name == "" {
+		*name = fmt.Sprintf("%s-%s", *index, time.Now().UTC().Format("20060102-150405"))
+	}
+
+	ctx, es, cancel := snapshotContext(repo)
+	defer cancel()
+
+	err := registerRepository(ctx, es, repo)
+	if err == nil {
+		err = createSnapshot(ctx, es, repo, *name, *index)
+	}
+	if err != nil {
+		log.Error().Caller().Err(err).Msgf("snapshot %s failed", *name)
+		return exitFailure
+	}
+	log.Info().Caller().Msgf("snapshot %s of %s completed in repository %s", *name, *index, repo.repository)
+	return exitSuccess
+}
+
+// runRestore restores the news index from a snapshot, optionally under a
+// different name so the live index is left alone.
+func runRestore(args []string) int {
+	var repo snapshotFlags
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	repo.register(fs)
+	index := fs.String("index", indexName, "index to restore from the snapshot")
+	name := fs.String("snapshot", "", "snapshot to restore (required)")
+	renameTo := fs.String("rename-to", "", "restore into this index instead of the original name")
+	fs.Parse(args)
+
+	if err := repo.validate(); err != nil {
+		exitWithConfigError(err, "invalid configuration")
+	}
+	if *name == "" {
+		exitWithConfigError(errors.New("--snapshot is required"), "invalid configuration")
+	}
+
+	ctx, es, cancel := snapshotContext(repo)
+	defer cancel()
+
+	target := *index
+	if *renameTo != "" {
+		target = *renameTo
+	}
+	err := registerRepository(ctx, es, repo)
+	if err == nil {
+		err = restoreSnapshot(ctx, es, repo, *name, *index, *renameTo)
+	}
+	if err != nil {
+		log.Error().Caller().Err(err).Msgf("restore of %s from %s failed", *index, *name)
+		return exitFailure
+	}
+	log.Info().Caller().Msgf("restored %s from snapshot %s into %s", *index, *name, target)
+	return exitSuccess
+}
+
+func snapshotContext(repo snapshotFlags) (context.Context, *elasticsearch.Client, context.CancelFunc) {
+	es, err := newElasticsearchClient("")
+	if err != nil {
+		exitWithConfigError(err, "failed to create elasticsearch client")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	ctx, cancel := context.WithTimeout(ctx, repo.timeout)
+	return ctx, es, func() {
+		cancel()
+		stop()
+	}
+}
+
+// registerRepository creates or updates the repository when a type is given.
+func registerRepository(ctx context.Context, es *elasticsearch.Client, repo snapshotFlags) error {
+	if repo.repoType == "" {
+		return nil
+	}
+	settings := map[string]string{}
+	switch repo.repoType {
+	case "fs":
+		settings["location"] = repo.location
+	case "s3":
+		settings["bucket"] = repo.bucket
+		if repo.basePath != "" {
+			settings["base_path"] = repo.basePath
+		}
+	}
+	body, err := json.Marshal(map[string]interface{}{"type": repo.repoType, "settings": settings})
+	if err != nil {
+		return err
+	}
+
+	res, err := es.Snapshot.CreateRepository(repo.repository, bytes.NewReader(body), es.Snapshot.CreateRepository.WithContext(ctx))
+	if err := esResult(res, err, nil); err != nil {
+		return fmt.Errorf("failed to register repository %s: %w", repo.repository, err)
+	}
+	log.Info().Caller().Msgf("registered %s snapshot repository %s", repo.repoType, repo.repository)
+	return nil
+}
+
+func createSnapshot(ctx context.Context, es *elasticsearch.Client, repo snapshotFlags, name, index string) error {
+	body, err := json.Marshal(map[string]interface{}{"indices": index, "include_global_state": false})
+	if err != nil {
+		return err
+	}
+	res, err := es.Snapshot.Create(repo.repository, name,
+		es.Snapshot.Create.WithBody(bytes.NewReader(body)),
+		es.Snapshot.Create.WithContext(ctx),
+	)
+	if err := esResult(res, err, nil); err != nil {
+		return err
+	}
+	log.Info().Caller().Msgf("snapshot %s started", name)
+
+	return poll(ctx, repo.pollInterval, func() (bool, error) {
+		var status struct {
+			Snapshots []struct {
+				State    string `json:"state"`
+				Failures []struct {
+					Reason string `json:"reason"`
+				} `json:"failures"`
+			} `json:"snapshots"`
+		}
+		res, err := es.Snapshot.Get(repo.repository, []string{name}, es.Snapshot.Get.WithContext(ctx))
+		if err := esResult(res, err, &status); err != nil {
+			return false, err
+		}
+		if len(status.Snapshots) == 0 {
+			return false, fmt.Errorf("snapshot %s not found", name)
+		}
+		switch s := status.Snapshots[0]; s.State {
+		case "SUCCESS":
+			return true, nil
+		case "FAILED", "PARTIAL", "INCOMPATIBLE":
+			reasons := make([]string, len(s.Failures))
+			for i, f := range s.Failures {
+				reasons[i] = f.Reason
+			}
+			return false, fmt.Errorf("snapshot %s ended in state %s: %s", name, s.State, strings.Join(reasons, "; "))
+		default:
+			log.Info().Caller().Msgf("snapshot %s is %s", name, strings.ToLower(s.State))
+			return false, nil
+		}
+	})
+}
+
+func restoreSnapshot(ctx context.Context, es *elasticsearch.Client, repo snapshotFlags, name, index, renameTo string) error {
+	request := map[string]interface{}{"indices": index, "include_global_state": false}
+	target := index
+	if renameTo != "" {
+		request["rename_pattern"] = "^" + regexp.QuoteMeta(index) + "$"
+		request["rename_replacement"] = renameTo
+		target = renameTo
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	res, err := es.Snapshot.Restore(repo.repository, name,
+		es.Snapshot.Restore.WithBody(bytes.NewReader(body)),
+		es.Snapshot.Restore.WithContext(ctx),
+	)
+	if err := esResult(res, err, nil); err != nil {
+		return err
+	}
+	log.Info().Caller().Msgf("restore of %s into %s started", name, target)
+
+	// The restore is done once every shard of the target index is recovered
+	return poll(ctx, repo.pollInterval, func() (bool, error) {
+		var health struct {
+			Status             string `json:"status"`
+			InitializingShards int    `json:"initializing_shards"`
+			UnassignedShards   int    `json:"unassigned_shards"`
+		}
+		res, err := es.Cluster.Health(es.Cluster.Health.WithIndex(target), es.Cluster.Health.WithContext(ctx))
+		if err := esResult(res, err, &health); err != nil {
+			return false, err
+		}
+		if health.Status != "red" && health.InitializingShards == 0 {
+			return true, nil
+		}
+		log.Info().Caller().Msgf("restore of %s is in progress, %d shards initializing", target, health.InitializingShards)
+		return false, nil
+	})
+}
+
+// poll calls check every interval until it reports done, fails or ctx ends.
+func poll(ctx context.Context, interval time.Duration, check func() (bool, error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		done, err := check()
+		if err != nil || done {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// esResult closes the response and decodes it into out when non-nil,
+// turning error statuses into errors.
+func esResult(res *esapi.Response, err error, out interface{}) error {
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return errors.New(res.String())
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/search"
+)
+
+// runStats prints articles per source, category and day and where the
+// located articles are, to sanity check a sync without writing
+// aggregation DSL.
+func runStats(args []string) int {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	index := fs.String("index", indexName, "index to summarise")
+	since := fs.String("since", "", "only count articles published on or after this date, YYYY-MM-DD or RFC 3339")
+	until := fs.String("until", "", "only count articles published before this date, YYYY-MM-DD or RFC 3339")
+	top := fs.Int("top", 10, "number of sources and categories listed")
+	asJSON := fs.Bool("json", false, "print the summary as json")
+	fs.Parse(args)
+
+	q := search.StatsQuery{Top: *top}
+	var err error
+	if q.Since, err = parseDateFlag(*since); err != nil {
+		exitWithConfigError(err, "invalid --since")
+	}
+	if q.Until, err = parseDateFlag(*until); err != nil {
+		exitWithConfigError(err, "invalid --until")
+	}
+	if *top < 1 {
+		exitWithConfigError(errors.New("--top must be positive"), "invalid configuration")
+	}
+
+	es, err := newElasticsearchClient("")
+	if err != nil {
+		exitWithConfigError(err, "failed to create elasticsearch client")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	summary, err := search.Stats(ctx, es, *index, q)
+	if err != nil {
+		log.Error().Caller().Err(err).Msg("stats failed")
+		return exitFailure
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(summary); err != nil {
+			return exitFailure
+		}
+		return exitSuccess
+	}
+	printSummary(summary)
+	return exitSuccess
+}
+
+func printSummary(s *search.Summary) {
+	fmt.Printf("articles: %d (%d with a location)\n", s.Total, s.Located)
+	if s.Oldest != "" {
+		fmt.Printf("published: %s to %s\n", s.Oldest, s.Newest)
+	}
+	if s.Bounds != nil {
+		fmt.Printf("geo bounds: top left %.4f,%.4f bottom right %.4f,%.4f\n",
+			s.Bounds.TopLeft.Lat, s.Bounds.TopLeft.Lon, s.Bounds.BottomRight.Lat, s.Bounds.BottomRight.Lon)
+	}
+
+	printBuckets("SOURCE", s.Sources)
+	printBuckets("CATEGORY", s.Categories)
+	printBuckets("DAY", s.PerDay)
+}
+
+func printBuckets(title string, buckets []search.Bucket) {
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\tARTICLES\t\n", title)
+	for _, b := range buckets {
+		fmt.Fprintf(w, "%s\t%d\t\n", b.Key, b.Count)
+	}
+	w.Flush()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/sink"
+)
+
+// runSynonyms replaces the synonym rules of an index created with
+// --synonyms and reloads its search analyzers, so searches pick up the
+// new rules without a reindex.
+func runSynonyms(args []string) int {
+	fs := flag.NewFlagSet("synonyms", flag.ExitOnError)
+	index := fs.String("index", indexName, "index whose synonyms are updated")
+	file := fs.String("file", "", "file of synonym rules, one per line such as \"US, USA, United States\"")
+	fs.Parse(args)
+
+	if *file == "" {
+		exitWithConfigError(errors.New("--file is required"), "invalid configuration")
+	}
+	rules, err := sink.LoadSynonyms(*file)
+	if err != nil {
+		exitWithConfigError(err, "invalid --file")
+	}
+
+	es, err := newElasticsearchClient("")
+	if err != nil {
+		exitWithConfigError(err, "failed to create elasticsearch client")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	version, err := sink.DetectVersion(ctx, es)
+	if err != nil {
+		log.Error().Caller().Err(err).Msg("failed to detect the elasticsearch version")
+		return exitFailure
+	}
+	target := sink.NewElasticsearch(es, *index).WithVersion(version)
+	if err := target.PutSynonyms(ctx, rules); err != nil {
+		log.Error().Caller().Err(err).Msg("failed to update synonyms")
+		return exitFailure
+	}
+	if err := target.ReloadSearchAnalyzers(ctx); err != nil {
+		log.Error().Caller().Err(err).Msg("failed to reload search analyzers")
+		return exitFailure
+	}
+	log.Info().Caller().Msgf("updated %d synonym rules of %s", len(rules), *index)
+	return exitSuccess
+}
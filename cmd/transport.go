@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/audit"
+	"inshorts.com/inshorts-news-data-syncer/pkg/sink"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// defaultRequestTimeout bounds a single request to the cluster unless
+// overridden through the environment.
+const defaultRequestTimeout = time.Minute
+
+// newTransport returns the transport of a cluster client, configured by
+// the environment variables starting with prefix, see
+// transportOptionsFromEnv. Its connections are measured in poolStats,
+// its retryable responses counted in retries, if not nil, and mutations
+// recorded in the audit log of AUDIT_LOG, if any.
+func newTransport(prefix string, retries *sink.RetryStats) http.RoundTripper {
+	rt := poolStats.wrap(baseTransport(transportOptionsFromEnv(prefix)))
+	if retries != nil {
+		rt = retries.Wrap(rt)
+	}
+	if rec := auditRecorder(); rec != nil {
+		rt = audit.Transport(rt, rec)
+	}
+	return rt
+}
+
+// transportOptions tune the connections to a cluster.
+type transportOptions struct {
+	// timeout bounds each request, including reading its response body,
+	// so a hung cluster can't wedge a sync.
+	timeout time.Duration
+	// proxy is the HTTP or SOCKS5 proxy requests go through, if any.
+	proxy *url.URL
+	// keepAlive is the TCP keep-alive period, negative to disable
+	// keep-alive probes, or zero for the default.
+	keepAlive time.Duration
+	// idleConnTimeout and maxIdleConnsPerHost bound the idle connections
+	// kept for reuse, zero for the defaults.
+	idleConnTimeout     time.Duration
+	maxIdleConnsPerHost int
+}
+
+// transportOptionsFromEnv reads the transport options of the cluster
+// whose environment variables start with prefix, e.g. ES:
+// <prefix>_REQUEST_TIMEOUT, <prefix>_PROXY (an http, https or socks5 URL),
+// <prefix>_KEEP_ALIVE, <prefix>_IDLE_CONN_TIMEOUT and
+// <prefix>_MAX_IDLE_CONNS_PER_HOST.
+func transportOptionsFromEnv(prefix string) transportOptions {
+	opts := transportOptions{
+		timeout:             utils.GetEnvDuration(prefix+"_REQUEST_TIMEOUT", defaultRequestTimeout),
+		keepAlive:           utils.GetEnvDuration(prefix+"_KEEP_ALIVE", 0),
+		idleConnTimeout:     utils.GetEnvDuration(prefix+"_IDLE_CONN_TIMEOUT", 0),
+		maxIdleConnsPerHost: utils.GetEnvInt(prefix+"_MAX_IDLE_CONNS_PER_HOST", 0),
+	}
+	if proxy := os.Getenv(prefix + "_PROXY"); proxy != "" {
+		u, err := parseProxyURL(proxy)
+		if err != nil {
+			exitWithConfigError(err, "invalid "+prefix+"_PROXY")
+		}
+		opts.proxy = u
+	}
+	return opts
+}
+
+func parseProxyURL(proxy string) (*url.URL, error) {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("proxy %s must be an http, https or socks5 URL", u.Redacted())
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy %s has no host", u.Redacted())
+	}
+	return u, nil
+}
+
+// retryOptions configure how a cluster client retries requests that
+// failed with a retryable status or a network error.
+type retryOptions struct {
+	disabled   bool
+	maxRetries int
+	onStatus   []int
+	// backoff is the wait before the first retry, doubled for every
+	// further one up to maxBackoff. Zero retries at once.
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+// defaultRetryStatuses are retried besides network errors: rejections of
+// an overloaded cluster and gateway errors of a restarting node.
+var defaultRetryStatuses = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// retryOptionsFromEnv reads the retry options of the cluster whose
+// environment variables start with prefix: <prefix>_DISABLE_RETRY,
+// <prefix>_MAX_RETRIES, <prefix>_RETRY_ON_STATUS (comma separated status
+// codes), <prefix>_RETRY_BACKOFF and <prefix>_RETRY_BACKOFF_MAX.
+func retryOptionsFromEnv(prefix string) retryOptions {
+	opts := retryOptions{
+		disabled:   os.Getenv(prefix+"_DISABLE_RETRY") == "true",
+		maxRetries: utils.GetEnvInt(prefix+"_MAX_RETRIES", 3),
+		onStatus:   defaultRetryStatuses,
+		backoff:    utils.GetEnvDuration(prefix+"_RETRY_BACKOFF", 100*time.Millisecond),
+		maxBackoff: utils.GetEnvDuration(prefix+"_RETRY_BACKOFF_MAX", 10*time.Second),
+	}
+	if statuses := os.Getenv(prefix + "_RETRY_ON_STATUS"); statuses != "" {
+		opts.onStatus = nil
+		for _, status := range splitList(statuses) {
+			code, err := strconv.Atoi(status)
+			if err != nil || code < 400 || code > 599 {
+				exitWithConfigError(fmt.Errorf("%q is not an error status code", status), "invalid "+prefix+"_RETRY_ON_STATUS")
+			}
+			opts.onStatus = append(opts.onStatus, code)
+		}
+	}
+	if opts.maxRetries < 0 || opts.backoff < 0 || opts.maxBackoff < opts.backoff {
+		exitWithConfigError(fmt.Errorf("%s_MAX_RETRIES and %s_RETRY_BACKOFF can't be negative, nor %s_RETRY_BACKOFF_MAX below the backoff", prefix, prefix, prefix), "invalid retry configuration")
+	}
+	// The clients take zero retries for their default
+	if opts.maxRetries == 0 {
+		opts.disabled = true
+	}
+	return opts
+}
+
+// backoffFunc returns the wait before the retry attempt, with up to half
+// of it random so clients rejected together don't retry together. It's
+// nil without a backoff.
+func (r retryOptions) backoffFunc() func(attempt int) time.Duration {
+	if r.backoff == 0 {
+		return nil
+	}
+	return func(attempt int) time.Duration {
+		wait := r.maxBackoff
+		if attempt < 32 {
+			wait = min(r.backoff<<(attempt-1), r.maxBackoff)
+		}
+		return wait/2 + rand.N(wait/2+1)
+	}
+}
+
+// discoveryOptions control whether a cluster client sniffs the nodes of
+// the cluster and spreads its requests over them. Sniffing is off by
+// default: behind a Kubernetes service or a gateway the nodes publish
+// internal addresses the syncer can't reach.
+type discoveryOptions struct {
+	onStart  bool
+	interval time.Duration
+}
+
+// discoveryOptionsFromEnv reads <prefix>_DISCOVER_NODES_ON_START and
+// <prefix>_DISCOVER_NODES_INTERVAL, e.g. 5m.
+func discoveryOptionsFromEnv(prefix string) discoveryOptions {
+	opts := discoveryOptions{
+		onStart:  os.Getenv(prefix+"_DISCOVER_NODES_ON_START") == "true",
+		interval: utils.GetEnvDuration(prefix+"_DISCOVER_NODES_INTERVAL", 0),
+	}
+	if opts.interval < 0 {
+		exitWithConfigError(fmt.Errorf("%s_DISCOVER_NODES_INTERVAL can't be negative", prefix), "invalid node discovery configuration")
+	}
+	if opts.onStart || opts.interval > 0 {
+		log.Info().Caller().Msgf("discovering the nodes of the %s cluster, on start: %t, every %v", prefix, opts.onStart, opts.interval)
+	}
+	return opts
+}
+
+// baseTransport is newTransport without the retry statistics and the
+// audit log.
+func baseTransport(opts transportOptions) http.RoundTripper {
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: true,
+		},
+		IdleConnTimeout:     opts.idleConnTimeout,
+		MaxIdleConnsPerHost: opts.maxIdleConnsPerHost,
+	}
+	if opts.proxy != nil {
+		transport.Proxy = http.ProxyURL(opts.proxy)
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: opts.keepAlive}
+	transport.DialContext = poolStats.dialContext(dialer.DialContext)
+	var base http.RoundTripper = transport
+	if opts.timeout > 0 {
+		base = &timeoutTransport{base: base, timeout: opts.timeout}
+	}
+	return base
+}
+
+var (
+	auditOnce sync.Once
+	auditRec  audit.Recorder
+)
+
+// auditRecorder returns the recorder configured by AUDIT_LOG, either the
+// path of a local file or "index", optionally "index=<name>", to write
+// to an index of ES_URL. It's nil when AUDIT_LOG is unset.
+func auditRecorder() audit.Recorder {
+	auditOnce.Do(func() {
+		spec := os.Getenv("AUDIT_LOG")
+		if spec == "" {
+			return
+		}
+		rec, err := newAuditRecorder(spec)
+		if err != nil {
+			exitWithConfigError(err, "invalid AUDIT_LOG")
+		}
+		auditRec = rec
+	})
+	return auditRec
+}
+
+func newAuditRecorder(spec string) (audit.Recorder, error) {
+	name, ok := strings.CutPrefix(spec, "index")
+	if !ok || (name != "" && !strings.HasPrefix(name, "=")) {
+		return audit.NewFile(spec)
+	}
+	name = strings.TrimPrefix(name, "=")
+	if name == "" {
+		name = indexName + "-audit"
+	}
+	// The audit index is written around the auditing transport, otherwise
+	// every entry would be audited in turn
+	client, err := elasticsearch.NewClient(elasticsearchConfigWith("", baseTransport(transportOptionsFromEnv("ES"))))
+	if err != nil {
+		return nil, fmt.Errorf("audit index client: %w", err)
+	}
+	log.Info().Caller().Msgf("recording cluster mutations in index %s", name)
+	return audit.NewIndex(client, name), nil
+}
+
+// closeAuditLog closes the audit log, if one was opened.
+func closeAuditLog() {
+	if auditRec == nil {
+		return
+	}
+	if err := auditRec.Close(); err != nil {
+		log.Error().Caller().Err(err).Msg("error while closing the audit log")
+	}
+}
+
+type timeoutTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	res, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// The deadline also covers the body, so cancel only once it's closed
+	res.Body = &cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// parseHeaders parses comma separated "Name=value" headers such as
+// "X-Org-Token=abc123".
+func parseHeaders(list string) (http.Header, error) {
+	header := http.Header{}
+	for _, item := range splitList(list) {
+		name, value, ok := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t:") {
+			return nil, fmt.Errorf("invalid header %q, expected Name=value", item)
+		}
+		header.Add(name, strings.TrimSpace(value))
+	}
+	return header, nil
+}
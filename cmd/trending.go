@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/search"
+)
+
+// runTrending computes the trending topics of the last hours and writes
+// them as json, or into an index the app's trending section reads, e.g.
+// every 15 minutes from a CronJob:
+//
+//	trending --window 6h --background 30d --trending-index news-trending
+func runTrending(args []string) int {
+	fs := flag.NewFlagSet("trending", flag.ExitOnError)
+	index := fs.String("index", indexName, "index of the articles")
+	window := fs.Duration("window", 6*time.Hour, "how far back recent articles go")
+	background := fs.Duration("background", 30*24*time.Hour, "how far back the articles recent ones are compared with go (0 compares with the whole index)")
+	fields := fs.String("fields", strings.Join(search.TrendingFields, ","), "comma separated keyword fields to find topics in")
+	top := fs.Int("top", 10, "number of topics per field")
+	minArticles := fs.Int("min-articles", 3, "number of recent articles a topic needs")
+	trendingIndex := fs.String("trending-index", "", "index the trends are written to, e.g. news-trending, instead of printing them")
+	output := fs.String("output", "-", `json file the trends are written to, "-" for stdout; with --trending-index only when given`)
+	fs.Parse(args)
+
+	if *window <= 0 {
+		exitWithConfigError(errors.New("--window must be positive"), "invalid configuration")
+	}
+	if *background < 0 || (*background > 0 && *background <= *window) {
+		exitWithConfigError(errors.New("--background must be longer than --window, or 0"), "invalid configuration")
+	}
+	if *top < 1 || *minArticles < 1 {
+		exitWithConfigError(errors.New("--top and --min-articles must be positive"), "invalid configuration")
+	}
+	if *trendingIndex == *index {
+		exitWithConfigError(errors.New("--trending-index must differ from --index"), "invalid configuration")
+	}
+	printJSON := *trendingIndex == ""
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "output" {
+			printJSON = true
+		}
+	})
+	q := search.TrendingQuery{
+		Window:      *window,
+		Background:  *background,
+		Fields:      splitList(*fields),
+		Top:         *top,
+		MinArticles: *minArticles,
+	}
+
+	es, err := newElasticsearchClient("")
+	if err != nil {
+		exitWithConfigError(err, "failed to create elasticsearch client")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	trends, err := search.Trending(ctx, es, *index, q)
+	if err != nil {
+		log.Error().Caller().Err(err).Msg("trending failed")
+		return exitFailure
+	}
+	if *trendingIndex != "" {
+		if err := search.WriteTrends(ctx, es, *trendingIndex, trends); err != nil {
+			log.Error().Caller().Err(err).Msg("trending failed")
+			return exitFailure
+		}
+		log.Info().Caller().Msgf("wrote %d trending topics of %d articles to %s", len(trends.Topics), trends.Articles, *trendingIndex)
+	}
+	if printJSON {
+		if err := writeTrends(*output, trends); err != nil {
+			log.Error().Caller().Err(err).Msg("trending failed")
+			return exitFailure
+		}
+	}
+	return exitSuccess
+}
+
+func writeTrends(path string, trends *search.Trends) error {
+	if path == "-" {
+		return encodeTrends(os.Stdout, trends)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := encodeTrends(f, trends); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func encodeTrends(w io.Writer, trends *search.Trends) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(trends)
+}
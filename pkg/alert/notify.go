@@ -0,0 +1,123 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+)
+
+// Notifier emits matches, e.g. to the service sending the alerts.
+type Notifier interface {
+	Notify(ctx context.Context, matches []Match) error
+	Close() error
+}
+
+// Log writes every match as a log line.
+type Log struct{}
+
+func (Log) Notify(_ context.Context, matches []Match) error {
+	for _, m := range matches {
+		log.Info().Caller().
+			Str("subscription_id", m.SubscriptionID).
+			Str("subscriber", m.Subscriber).
+			Str("article_id", m.Article.ID).
+			Str("title", m.Article.Title).
+			Msg("article matches subscription")
+	}
+	return nil
+}
+
+func (Log) Close() error { return nil }
+
+// Webhook posts the matches of a batch as {"matches": [...]} to a URL.
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook returns a notifier posting to url.
+func NewWebhook(url string) *Webhook {
+	return &Webhook{url: url, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (w *Webhook) Notify(ctx context.Context, matches []Match) error {
+	body, err := json.Marshal(map[string]interface{}{"matches": matches})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		data, _ := io.ReadAll(io.LimitReader(res.Body, 4<<10))
+		return fmt.Errorf("webhook returned %s: %s", res.Status, bytes.TrimSpace(data))
+	}
+	return nil
+}
+
+func (w *Webhook) Close() error { return nil }
+
+// Kafka publishes every match to a topic, keyed by subscriber so the
+// alerts of one subscriber stay in order.
+type Kafka struct {
+	writer *kafka.Writer
+}
+
+// NewKafka returns a notifier publishing to topic on brokers.
+func NewKafka(brokers []string, topic string) *Kafka {
+	return &Kafka{writer: &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchTimeout: 50 * time.Millisecond,
+	}}
+}
+
+func (k *Kafka) Notify(ctx context.Context, matches []Match) error {
+	messages := make([]kafka.Message, len(matches))
+	for i, m := range matches {
+		value, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		messages[i] = kafka.Message{Key: []byte(m.Subscriber), Value: value}
+	}
+	return k.writer.WriteMessages(ctx, messages...)
+}
+
+func (k *Kafka) Close() error { return k.writer.Close() }
+
+// Notifiers passes matches to all of its notifiers.
+type Notifiers []Notifier
+
+func (n Notifiers) Notify(ctx context.Context, matches []Match) error {
+	var errs []error
+	for _, notifier := range n {
+		errs = append(errs, notifier.Notify(ctx, matches))
+	}
+	return errors.Join(errs...)
+}
+
+func (n Notifiers) Close() error {
+	var errs []error
+	for _, notifier := range n {
+		errs = append(errs, notifier.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,260 @@
+// Package alert matches newly synced articles against saved queries kept
+// in a percolator index, so "alert me when X happens" features can be
+// built on top of the syncer.
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/elastic/go-elasticsearch/v9/esapi"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/pkg/sink"
+)
+
+// pageSize is the number of subscriptions fetched per request.
+const pageSize = 1000
+
+// ErrNotFound is returned by Delete for an unknown subscription.
+var ErrNotFound = errors.New("subscription not found")
+
+// Subscription is a saved query and who to alert when an article matches it.
+type Subscription struct {
+	ID         string `json:"id"`
+	Subscriber string `json:"subscriber"`
+	Name       string `json:"name,omitempty"`
+	// Query is an Elasticsearch query over the article fields, e.g. the
+	// query of a search.Query body.
+	Query map[string]interface{} `json:"query"`
+}
+
+// Match is an article matching a subscription.
+type Match struct {
+	SubscriptionID string        `json:"subscription_id"`
+	Subscriber     string        `json:"subscriber"`
+	Name           string        `json:"name,omitempty"`
+	Article        model.Article `json:"article"`
+}
+
+// Percolator keeps subscriptions in a percolator index next to the
+// article index and matches articles against them.
+type Percolator struct {
+	client *elasticsearch.Client
+	index  string
+}
+
+// NewPercolator returns the percolator of articleIndex, stored in
+// "<articleIndex>-alerts".
+func NewPercolator(client *elasticsearch.Client, articleIndex string) *Percolator {
+	return &Percolator{client: client, index: articleIndex + "-alerts"}
+}
+
+// Index returns the name of the percolator index.
+func (p *Percolator) Index() string { return p.index }
+
+// Prepare creates the percolator index unless it exists, in which case
+// article fields added since are mapped. Saved queries can only refer to
+// mapped fields, so the index shares the article mapping.
+func (p *Percolator) Prepare(ctx context.Context) error {
+	body, err := sink.BuildIndexBody(sink.IndexOptions{})
+	if err != nil {
+		return err
+	}
+	var definition map[string]interface{}
+	if err := json.Unmarshal(body, &definition); err != nil {
+		return err
+	}
+	mappings := definition["mappings"].(map[string]interface{})
+	properties := mappings["properties"].(map[string]interface{})
+	properties["query"] = map[string]string{"type": "percolator"}
+	properties["subscriber"] = map[string]string{"type": "keyword"}
+	properties["name"] = map[string]string{"type": "keyword"}
+
+	exists, err := p.client.Indices.Exists([]string{p.index}, p.client.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	exists.Body.Close()
+	var res *esapi.Response
+	if exists.StatusCode == http.StatusOK {
+		data, err := json.Marshal(mappings)
+		if err != nil {
+			return err
+		}
+		res, err = p.client.Indices.PutMapping([]string{p.index}, bytes.NewReader(data), p.client.Indices.PutMapping.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+	} else {
+		data, err := json.Marshal(definition)
+		if err != nil {
+			return err
+		}
+		res, err = p.client.Indices.Create(p.index, p.client.Indices.Create.WithBody(bytes.NewReader(data)), p.client.Indices.Create.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("failed to prepare percolator index %s: %s", p.index, res.String())
+	}
+	return nil
+}
+
+// Save creates or replaces sub. It is matched by the next Percolate.
+func (p *Percolator) Save(ctx context.Context, sub Subscription) error {
+	if sub.ID == "" || sub.Query == nil {
+		return errors.New("a subscription needs an id and a query")
+	}
+	body, err := json.Marshal(sub)
+	if err != nil {
+		return err
+	}
+	res, err := p.client.Index(p.index, bytes.NewReader(body),
+		p.client.Index.WithContext(ctx),
+		p.client.Index.WithDocumentID(sub.ID),
+		p.client.Index.WithRefresh("wait_for"),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("failed to save subscription %s: %s", sub.ID, res.String())
+	}
+	return nil
+}
+
+// Delete removes the subscription with id.
+func (p *Percolator) Delete(ctx context.Context, id string) error {
+	res, err := p.client.Delete(p.index, id,
+		p.client.Delete.WithContext(ctx),
+		p.client.Delete.WithRefresh("wait_for"),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if res.IsError() {
+		return fmt.Errorf("failed to delete subscription %s: %s", id, res.String())
+	}
+	return nil
+}
+
+// List returns all subscriptions ordered by id.
+func (p *Percolator) List(ctx context.Context) ([]Subscription, error) {
+	var subs []Subscription
+	err := p.page(ctx, map[string]interface{}{"match_all": map[string]interface{}{}}, func(h hit) {
+		subs = append(subs, h.Source)
+	})
+	return subs, err
+}
+
+// Percolate returns a match for every subscription each of articles
+// satisfies.
+func (p *Percolator) Percolate(ctx context.Context, articles []model.Article) ([]Match, error) {
+	if len(articles) == 0 {
+		return nil, nil
+	}
+	docs := make([]map[string]interface{}, len(articles))
+	for i, a := range articles {
+		doc, err := sink.Document(a)
+		if err != nil {
+			return nil, err
+		}
+		// The percolator index maps no vectors
+		delete(doc, "embedding")
+		docs[i] = doc
+	}
+
+	query := map[string]interface{}{
+		"percolate": map[string]interface{}{"field": "query", "documents": docs},
+	}
+	var matches []Match
+	err := p.page(ctx, query, func(h hit) {
+		for _, slot := range h.Fields.Slots {
+			if slot < 0 || slot >= len(articles) {
+				continue
+			}
+			article := articles[slot]
+			article.Embedding = nil
+			matches = append(matches, Match{
+				SubscriptionID: h.Source.ID,
+				Subscriber:     h.Source.Subscriber,
+				Name:           h.Source.Name,
+				Article:        article,
+			})
+		}
+	})
+	return matches, err
+}
+
+// hit is a subscription returned by a search of the percolator index.
+type hit struct {
+	Source Subscription `json:"_source"`
+	Fields struct {
+		// Slots are the positions of the percolated documents that matched.
+		Slots []int `json:"_percolator_document_slot"`
+	} `json:"fields"`
+	Sort []interface{} `json:"sort"`
+}
+
+// page runs query over the percolator index and passes every hit to fn,
+// paging through the results in id order.
+func (p *Percolator) page(ctx context.Context, query map[string]interface{}, fn func(hit)) error {
+	var after []interface{}
+	for {
+		request := map[string]interface{}{
+			"size":  pageSize,
+			"query": query,
+			"sort":  []interface{}{map[string]string{"id": "asc"}},
+		}
+		if after != nil {
+			request["search_after"] = after
+		}
+		body, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		res, err := p.client.Search(
+			p.client.Search.WithContext(ctx),
+			p.client.Search.WithIndex(p.index),
+			p.client.Search.WithBody(bytes.NewReader(body)),
+		)
+		if err != nil {
+			return err
+		}
+
+		var resp struct {
+			Hits struct {
+				Hits []hit `json:"hits"`
+			} `json:"hits"`
+		}
+		if res.IsError() {
+			err = errors.New(res.String())
+		} else {
+			err = json.NewDecoder(res.Body).Decode(&resp)
+		}
+		res.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, h := range resp.Hits.Hits {
+			fn(h)
+		}
+		if len(resp.Hits.Hits) < pageSize {
+			return nil
+		}
+		after = resp.Hits.Hits[len(resp.Hits.Hits)-1].Sort
+	}
+}
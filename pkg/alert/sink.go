@@ -0,0 +1,81 @@
+package alert
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/pkg/sink"
+)
+
+// Sink percolates every batch written to the wrapped sink and notifies
+// the matches. Alerting is best effort: its failures are logged and
+// never fail the write.
+type Sink struct {
+	sink.Sink
+	percolator *Percolator
+	notifier   Notifier
+}
+
+// NewSink wraps s to alert on the articles written to it. The result
+// still reports the stats of s when it is a sink.StatsReporter.
+func NewSink(s sink.Sink, percolator *Percolator, notifier Notifier) sink.Sink {
+	alerting := &Sink{Sink: s, percolator: percolator, notifier: notifier}
+	if _, ok := s.(sink.StatsReporter); ok {
+		return reportingSink{alerting}
+	}
+	return alerting
+}
+
+// reportingSink is a Sink wrapping a sink.StatsReporter.
+type reportingSink struct {
+	*Sink
+}
+
+func (r reportingSink) TakeStats() []sink.Stats {
+	return r.Sink.Sink.(sink.StatsReporter).TakeStats()
+}
+
+// Prepare prepares the wrapped sink and the percolator index.
+func (s *Sink) Prepare(ctx context.Context, opts sink.IndexOptions) error {
+	if err := s.percolator.Prepare(ctx); err != nil {
+		log.Error().Caller().Err(err).Msgf("error while preparing percolator index %s", s.percolator.Index())
+	}
+	return s.Sink.Prepare(ctx, opts)
+}
+
+// WriteBatch writes articles and alerts on them unless the batch failed.
+// Articles rejected from a partially written batch may still alert.
+func (s *Sink) WriteBatch(ctx context.Context, articles []model.Article) error {
+	err := s.Sink.WriteBatch(ctx, articles)
+	var partial *sink.PartialError
+	if err != nil && !errors.As(err, &partial) {
+		return err
+	}
+
+	matches, percolateErr := s.percolator.Percolate(ctx, articles)
+	if percolateErr != nil {
+		log.Warn().Caller().Err(percolateErr).Msg("failed to percolate articles")
+		return err
+	}
+	if len(matches) > 0 {
+		if notifyErr := s.notifier.Notify(ctx, matches); notifyErr != nil {
+			log.Warn().Caller().Err(notifyErr).Msgf("failed to notify %d alert matches", len(matches))
+		}
+	}
+	return err
+}
+
+// Close closes the wrapped sink and the notifier.
+func (s *Sink) Close() error {
+	return errors.Join(s.Sink.Close(), s.notifier.Close())
+}
+
+// Ping checks the wrapped sink when it can be pinged.
+func (s *Sink) Ping(ctx context.Context) error {
+	if pinger, ok := s.Sink.(sink.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Package audit records the changes the syncer makes to a cluster, such
+// as created indices, mapping updates and bulk writes, so incident
+// reviews can tell what it changed and when.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+)
+
+// Entry is one mutation of a cluster.
+type Entry struct {
+	Time time.Time `json:"@timestamp"`
+	// Action classifies the request, e.g. create_index, put_mapping,
+	// alias, delete_by_query or bulk.
+	Action string `json:"action"`
+	Method string `json:"method"`
+	Host   string `json:"host"`
+	Path   string `json:"path"`
+	Index  string `json:"index,omitempty"`
+	// Docs, FirstID and LastID describe the documents of a bulk request.
+	Docs    int    `json:"docs,omitempty"`
+	FirstID string `json:"first_id,omitempty"`
+	LastID  string `json:"last_id,omitempty"`
+	// Status is the response status, zero when the request failed.
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Actor is the host the syncer ran on.
+	Actor string `json:"actor"`
+}
+
+// Recorder stores entries. Implementations are safe for concurrent use.
+type Recorder interface {
+	Record(ctx context.Context, e Entry) error
+	Close() error
+}
+
+// File appends entries as newline delimited JSON to a local file.
+type File struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewFile opens path for appending, creating it if needed.
+func NewFile(path string) (*File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &File{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+func (f *File) Record(_ context.Context, e Entry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.enc.Encode(e)
+}
+
+func (f *File) Close() error { return f.file.Close() }
+
+// Index writes entries as documents of an index, which is never updated
+// in place. The client must not audit its own requests.
+type Index struct {
+	client *elasticsearch.Client
+	index  string
+}
+
+// NewIndex returns a recorder writing to index through client.
+func NewIndex(client *elasticsearch.Client, index string) *Index {
+	return &Index{client: client, index: index}
+}
+
+func (i *Index) Record(ctx context.Context, e Entry) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	// op_type create keeps the log append only
+	res, err := i.client.Index(i.index, bytes.NewReader(body),
+		i.client.Index.WithOpType("create"),
+		i.client.Index.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("recording audit entry in %s: %s", i.index, res.Status())
+	}
+	return nil
+}
+
+func (i *Index) Close() error { return nil }
@@ -0,0 +1,176 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// readEndpoints are the API endpoints taking a POST or DELETE without
+// changing any data.
+var readEndpoints = map[string]bool{
+	"_search": true, "_msearch": true, "_count": true, "_mget": true,
+	"_field_caps": true, "_explain": true, "_analyze": true, "_validate": true,
+	"_pit": true, "scroll": true, "_termvectors": true, "_mtermvectors": true,
+	"_rank_eval": true, "_sql": true, "_query": true, "_refresh": true,
+	"_reload_search_analyzers": true, "_flush": true,
+}
+
+// classify returns the action of a request and the index it targets, or
+// ok false when it changes nothing.
+func classify(method, path string) (action, index string, ok bool) {
+	if method == http.MethodGet || method == http.MethodHead {
+		return "", "", false
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, s := range segments {
+		if readEndpoints[s] {
+			return "", "", false
+		}
+	}
+	if !strings.HasPrefix(segments[0], "_") {
+		index = segments[0]
+	}
+
+	endpoint := ""
+	if len(segments) > 1 {
+		endpoint = segments[1]
+	}
+	switch {
+	case segments[0] == "_bulk" || endpoint == "_bulk":
+		return "bulk", index, true
+	case segments[0] == "_aliases" || endpoint == "_alias" || endpoint == "_aliases":
+		return "alias", index, true
+	case segments[0] == "_snapshot" && segments[len(segments)-1] == "_restore":
+		return "restore", "", true
+	case segments[0] == "_snapshot":
+		return "snapshot", "", true
+	case segments[0] == "_synonyms":
+		return "synonyms", "", true
+	case index != "" && len(segments) == 1 && method == http.MethodPut:
+		return "create_index", index, true
+	case index != "" && len(segments) == 1 && method == http.MethodDelete:
+		return "delete_index", index, true
+	case endpoint == "_mapping" || endpoint == "_mappings":
+		return "put_mapping", index, true
+	case endpoint == "_settings":
+		return "put_settings", index, true
+	case endpoint == "_doc" || endpoint == "_create" || endpoint == "_update":
+		if method == http.MethodDelete {
+			return "delete_doc", index, true
+		}
+		return "write_doc", index, true
+	case strings.HasPrefix(endpoint, "_"):
+		return strings.TrimPrefix(endpoint, "_"), index, true
+	case strings.HasPrefix(segments[0], "_"):
+		return strings.TrimPrefix(segments[0], "_"), "", true
+	}
+	return "other", index, true
+}
+
+// Transport records the mutating requests sent through base in rec.
+// Failing to record a request is logged and doesn't fail it.
+func Transport(base http.RoundTripper, rec Recorder) http.RoundTripper {
+	actor, _ := os.Hostname()
+	return &transport{base: base, rec: rec, actor: actor}
+}
+
+type transport struct {
+	base  http.RoundTripper
+	rec   Recorder
+	actor string
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	action, index, ok := classify(req.Method, req.URL.Path)
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+	entry := Entry{
+		Time:   time.Now().UTC(),
+		Action: action,
+		Method: req.Method,
+		Host:   req.URL.Host,
+		Path:   req.URL.Path,
+		Index:  index,
+		Actor:  t.actor,
+	}
+	if action == "bulk" {
+		entry.describeBulk(req)
+	}
+
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		entry.Status = res.StatusCode
+	}
+	// Recorded even when the request is cancelled
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(req.Context()), 10*time.Second)
+	defer cancel()
+	if recErr := t.rec.Record(ctx, entry); recErr != nil {
+		log.Error().Caller().Err(recErr).Msgf("failed to record %s of %s in the audit log", entry.Action, entry.Path)
+	}
+	return res, err
+}
+
+// describeBulk sets the document count and ID range of a bulk request,
+// reading a copy of its body.
+func (e *Entry) describeBulk(req *http.Request) {
+	if req.GetBody == nil {
+		return
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return
+	}
+	defer body.Close()
+	var r io.Reader = body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64<<20)
+	skipSource := false
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if skipSource || len(bytes.TrimSpace(line)) == 0 {
+			skipSource = false
+			continue
+		}
+		var action map[string]struct {
+			Index string `json:"_index"`
+			ID    string `json:"_id"`
+		}
+		if json.Unmarshal(line, &action) != nil {
+			continue
+		}
+		for op, meta := range action {
+			// Every operation but delete is followed by a source line
+			skipSource = op != "delete"
+			if e.FirstID == "" {
+				e.FirstID = meta.ID
+			}
+			e.LastID = meta.ID
+			if e.Index == "" {
+				e.Index = meta.Index
+			}
+			e.Docs++
+		}
+	}
+}
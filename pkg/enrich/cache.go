@@ -0,0 +1,72 @@
+package enrich
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// fileCache is a concurrency safe map persisted as a JSON object, used by
+// enrichers to avoid repeating expensive external calls across runs.
+type fileCache[V any] struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]V
+	dirty   bool
+}
+
+// loadFileCache reads the cache file at path. An empty path gives an
+// in-memory cache that only lives for the current run.
+func loadFileCache[V any](path string) (*fileCache[V], error) {
+	c := &fileCache[V]{path: path, entries: map[string]V{}}
+	if path == "" {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		return nil, fmt.Errorf("invalid cache file %s: %w", path, err)
+	}
+	return c, nil
+}
+
+func (c *fileCache[V]) get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.entries[key]
+	return v, ok
+}
+
+func (c *fileCache[V]) put(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = value
+	c.dirty = true
+}
+
+// save writes the cache back to disk when it changed.
+func (c *fileCache[V]) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.path == "" || !c.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.path, data, 0o644); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
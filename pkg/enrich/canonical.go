@@ -0,0 +1,54 @@
+package enrich
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// canonicalURLEnricher stores a normalised form of every article URL so
+// URL based deduplication and click analytics can rely on it.
+type canonicalURLEnricher struct {
+	extraParams []string
+}
+
+// newCanonicalURLEnricherFromEnv is enabled unless CANONICALIZE_URLS=false.
+// CANONICAL_URL_STRIP_PARAMS adds comma separated query parameters to strip.
+func newCanonicalURLEnricherFromEnv() *canonicalURLEnricher {
+	if os.Getenv("CANONICALIZE_URLS") == "false" {
+		return nil
+	}
+	c := &canonicalURLEnricher{}
+	if params := os.Getenv("CANONICAL_URL_STRIP_PARAMS"); params != "" {
+		c.extraParams = strings.Split(params, ",")
+	}
+	return c
+}
+
+func (c *canonicalURLEnricher) Name() string { return "canonical_url" }
+
+func (c *canonicalURLEnricher) Enrich(_ context.Context, articles []model.Article) error {
+	var invalid int
+	for i := range articles {
+		a := &articles[i]
+		if a.URL == "" {
+			continue
+		}
+		canonical, err := utils.CanonicalURL(a.URL, c.extraParams...)
+		if err != nil {
+			log.Warn().Caller().Err(err).Str("id", a.ID).Msg("failed to canonicalize url")
+			invalid++
+			continue
+		}
+		a.CanonicalURL = canonical
+	}
+
+	if invalid > 0 {
+		log.Warn().Caller().Msgf("%d articles have urls that could not be canonicalized", invalid)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+const classifyPrompt = "Classify the news article into exactly one of these categories: %s. " +
+	"Reply with the category name only."
+
+// categoryEnricher assigns a category to articles that arrive without one,
+// first through keyword rules and then, optionally, through the LLM.
+type categoryEnricher struct {
+	// rules maps a category to the keywords and phrases that indicate it.
+	rules      map[string][]string
+	categories []string
+	llm        *llmClient
+}
+
+// newCategoryEnricherFromEnv returns nil unless CATEGORY_RULES_FILE is set
+// or CATEGORY_LLM is enabled.
+func newCategoryEnricherFromEnv(llm *llmClient) (*categoryEnricher, error) {
+	rulesFile := os.Getenv("CATEGORY_RULES_FILE")
+	useLLM := os.Getenv("CATEGORY_LLM") == "true"
+	if rulesFile == "" && !useLLM {
+		return nil, nil
+	}
+
+	c := &categoryEnricher{rules: map[string][]string{}}
+	if rulesFile != "" {
+		data, err := os.ReadFile(rulesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read category rules %s: %w", rulesFile, err)
+		}
+		if err := json.Unmarshal(data, &c.rules); err != nil {
+			return nil, fmt.Errorf("invalid category rules %s: %w", rulesFile, err)
+		}
+		for category, keywords := range c.rules {
+			for i, k := range keywords {
+				keywords[i] = strings.ToLower(k)
+			}
+			c.categories = append(c.categories, category)
+		}
+	}
+
+	if useLLM {
+		if llm == nil {
+			return nil, fmt.Errorf("CATEGORY_LLM requires LLM_BASE_URL to be set")
+		}
+		c.llm = llm
+		if labels := utils.GetEnv("CATEGORY_LABELS", ""); labels != "" {
+			c.categories = strings.Split(labels, ",")
+		}
+		if len(c.categories) == 0 {
+			return nil, fmt.Errorf("CATEGORY_LLM requires CATEGORY_LABELS or CATEGORY_RULES_FILE to list the categories")
+		}
+	}
+	sort.Strings(c.categories)
+
+	return c, nil
+}
+
+func (c *categoryEnricher) Name() string { return "category" }
+
+// StartRun resets the LLM cost caps, which apply per run.
+func (c *categoryEnricher) StartRun() {
+	if c.llm != nil {
+		c.llm.resetBudget()
+	}
+}
+
+func (c *categoryEnricher) Enrich(ctx context.Context, articles []model.Article) error {
+	var byRules, byLLM, remaining int
+	for i := range articles {
+		a := &articles[i]
+		if len(a.Category) > 0 {
+			continue
+		}
+
+		if category := c.classifyByRules(a); category != "" {
+			a.Category = []string{category}
+			byRules++
+			continue
+		}
+
+		if c.llm != nil && !c.llm.budgetExhausted() {
+			category, err := c.classifyByLLM(ctx, a)
+			if err != nil {
+				log.Warn().Caller().Err(err).Str("id", a.ID).Msg("failed to classify article with llm")
+			} else if category != "" {
+				a.Category = []string{category}
+				byLLM++
+				continue
+			}
+		}
+		remaining++
+	}
+
+	log.Info().Caller().Msgf("auto-classified %d articles (%d by rules, %d by llm), %d left uncategorized",
+		byRules+byLLM, byRules, byLLM, remaining)
+	return nil
+}
+
+// classifyByRules returns the category with the most keyword hits,
+// or "" when no keyword matches. Ties go to the alphabetically first category.
+func (c *categoryEnricher) classifyByRules(a *model.Article) string {
+	if len(c.rules) == 0 {
+		return ""
+	}
+
+	text := " " + strings.Join(utils.Tokenize(a.Title+" "+a.Description), " ") + " "
+	var best string
+	var bestHits int
+	for _, category := range c.categories {
+		var hits int
+		for _, keyword := range c.rules[category] {
+			hits += strings.Count(text, " "+keyword+" ")
+		}
+		if hits > bestHits {
+			best, bestHits = category, hits
+		}
+	}
+	return best
+}
+
+// classifyByLLM asks the LLM for a category and only accepts known ones.
+func (c *categoryEnricher) classifyByLLM(ctx context.Context, a *model.Article) (string, error) {
+	prompt := fmt.Sprintf(classifyPrompt, strings.Join(c.categories, ", "))
+	reply, err := c.llm.complete(ctx, prompt, a.Title+"\n\n"+a.Description, 10)
+	if err != nil {
+		return "", err
+	}
+
+	reply = strings.Trim(strings.ToLower(reply), " .\"'")
+	for _, category := range c.categories {
+		if strings.ToLower(category) == reply {
+			return category, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,210 @@
+package enrich
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/pkg/sink"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// embeddingProvider turns texts into vectors of a fixed dimension. The
+// only provider calls an OpenAI-compatible /embeddings endpoint. Models
+// aren't run in process, as ONNX would need cgo and a native runtime in
+// every build: local models, ONNX ones included, are served behind such an
+// endpoint instead, e.g. by a sidecar inference server.
+type embeddingProvider interface {
+	embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// embeddingEnricher computes a dense vector over title and description
+// for articles that don't carry one yet.
+type embeddingEnricher struct {
+	provider  embeddingProvider
+	dims      int
+	batchSize int
+}
+
+// newEmbeddingEnricherFromEnv returns nil when EMBEDDING_BASE_URL is not set,
+// which keeps the stage disabled. It points at a hosted API or a local
+// inference server alike.
+func newEmbeddingEnricherFromEnv() (*embeddingEnricher, error) {
+	baseURL := os.Getenv("EMBEDDING_BASE_URL")
+	if baseURL == "" {
+		return nil, nil
+	}
+
+	dims := utils.GetEnvInt("EMBEDDING_DIMS", 1536)
+	if dims <= 0 || dims > 4096 {
+		return nil, fmt.Errorf("EMBEDDING_DIMS must be between 1 and 4096, got %d", dims)
+	}
+	batchSize := utils.GetEnvInt("EMBEDDING_BATCH_SIZE", 64)
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	return &embeddingEnricher{
+		provider: &httpEmbeddingProvider{
+			baseURL: strings.TrimRight(baseURL, "/"),
+			apiKey:  utils.GetEnv("EMBEDDING_API_KEY", os.Getenv("LLM_API_KEY")),
+			model:   utils.GetEnv("EMBEDDING_MODEL", "text-embedding-3-small"),
+			dims:    dims,
+			client:  &http.Client{Timeout: 60 * time.Second},
+		},
+		dims:      dims,
+		batchSize: batchSize,
+	}, nil
+}
+
+// QueryEmbedder embeds search queries with the provider of the embedding
+// stage, so queries and articles share one vector space.
+type QueryEmbedder struct {
+	provider embeddingProvider
+}
+
+// NewQueryEmbedderFromEnv returns nil when embeddings are not configured.
+func NewQueryEmbedderFromEnv() (*QueryEmbedder, error) {
+	e, err := newEmbeddingEnricherFromEnv()
+	if e == nil || err != nil {
+		return nil, err
+	}
+	return &QueryEmbedder{provider: e.provider}, nil
+}
+
+// Embed returns the vector of text.
+func (q *QueryEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := q.provider.embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embedding provider returned %d vectors for 1 text", len(vectors))
+	}
+	return vectors[0], nil
+}
+
+func (e *embeddingEnricher) Name() string { return "embedding" }
+
+func (e *embeddingEnricher) ConfigureIndex(opts *sink.IndexOptions) {
+	opts.EmbeddingDims = e.dims
+}
+
+func (e *embeddingEnricher) Enrich(ctx context.Context, articles []model.Article) error {
+	var (
+		pending []*model.Article
+		texts   []string
+		count   int
+	)
+
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		vectors, err := e.provider.embed(ctx, texts)
+		if err != nil {
+			return err
+		}
+		if len(vectors) != len(pending) {
+			return fmt.Errorf("embedding provider returned %d vectors for %d texts", len(vectors), len(pending))
+		}
+		for i, v := range vectors {
+			if len(v) != e.dims {
+				return fmt.Errorf("embedding for article %s has %d dims, expected %d", pending[i].ID, len(v), e.dims)
+			}
+			pending[i].Embedding = v
+		}
+		count += len(pending)
+		pending, texts = pending[:0], texts[:0]
+		return nil
+	}
+
+	for i := range articles {
+		a := &articles[i]
+		if len(a.Embedding) > 0 {
+			continue
+		}
+		pending = append(pending, a)
+		texts = append(texts, a.Title+"\n"+a.Description)
+		if len(pending) == e.batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	log.Info().Caller().Msgf("computed embeddings for %d articles", count)
+	return nil
+}
+
+// httpEmbeddingProvider calls an OpenAI-compatible /embeddings endpoint.
+type httpEmbeddingProvider struct {
+	baseURL string
+	apiKey  string
+	model   string
+	dims    int
+	client  *http.Client
+}
+
+type embeddingRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+func (p *httpEmbeddingProvider) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(embeddingRequest{Model: p.model, Input: texts, Dimensions: p.dims})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding endpoint returned status %s", res.Status)
+	}
+
+	var embResp embeddingResponse
+	if err := json.NewDecoder(res.Body).Decode(&embResp); err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range embResp.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding endpoint returned out of range index %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
@@ -0,0 +1,209 @@
+// Package enrich contains the optional stages that augment articles
+// before they are indexed: summaries, embeddings, entities, sentiment,
+// categories, tags, geocoding, geohashes, region shapes, URL
+// canonicalisation, relevance recalibration, story clustering and PII
+// redaction.
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/pkg/sink"
+)
+
+// Enricher is a stage that augments articles in place before they are indexed.
+type Enricher interface {
+	Name() string
+	Enrich(ctx context.Context, articles []model.Article) error
+}
+
+// IndexConfigurer is implemented by enrichers whose output needs
+// configuration dependent fields in the index mapping.
+type IndexConfigurer interface {
+	ConfigureIndex(opts *sink.IndexOptions)
+}
+
+// RunStarter is implemented by enrichers that keep per run state, e.g.
+// cost caps, which must be reset when a new run starts.
+type RunStarter interface {
+	StartRun()
+}
+
+// StartRun tells every enricher keeping per run state that a new run
+// starts. Long running processes call it before each run.
+func StartRun(enrichers []Enricher) {
+	for _, e := range enrichers {
+		if s, ok := e.(RunStarter); ok {
+			s.StartRun()
+		}
+	}
+}
+
+// RunWideEnricher is implemented by enrichers that relate the articles
+// they are given to each other, e.g. to normalise scores or cluster
+// stories, and so must see every article of a run at once.
+type RunWideEnricher interface {
+	RunWide() bool
+}
+
+// RunWide returns the names of the enrichers that must see every article
+// of a run at once, which rules out enriching batch by batch.
+func RunWide(enrichers []Enricher) []string {
+	var names []string
+	for _, e := range enrichers {
+		if w, ok := e.(RunWideEnricher); ok && w.RunWide() {
+			names = append(names, e.Name())
+		}
+	}
+	return names
+}
+
+// Run applies every enricher to articles, in order.
+func Run(ctx context.Context, enrichers []Enricher, articles []model.Article) error {
+	for _, e := range enrichers {
+		startTime := time.Now()
+		if err := e.Enrich(ctx, articles); err != nil {
+			return fmt.Errorf("enricher %s: %w", e.Name(), err)
+		}
+		log.Info().Caller().Msgf("enricher %s finished in %v milliseconds", e.Name(), time.Since(startTime).Milliseconds())
+	}
+	return nil
+}
+
+// FromEnv returns the enrichment stages enabled through the environment,
+// in the order they must run.
+func FromEnv() ([]Enricher, error) {
+	var enrichers []Enricher
+
+	// Redaction runs first so PII never reaches external providers
+	redact, err := newRedactEnricherFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if redact != nil {
+		enrichers = append(enrichers, redact)
+	}
+
+	if canonical := newCanonicalURLEnricherFromEnv(); canonical != nil {
+		enrichers = append(enrichers, canonical)
+	}
+
+	relevance, err := newRelevanceEnricherFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if relevance != nil {
+		enrichers = append(enrichers, relevance)
+	}
+
+	// LLM backed stages share a single client so the cost caps apply per run
+	llm := newLLMClientFromEnv()
+
+	category, err := newCategoryEnricherFromEnv(llm)
+	if err != nil {
+		return nil, err
+	}
+	if category != nil {
+		enrichers = append(enrichers, category)
+	}
+
+	summary, err := newSummaryEnricherFromEnv(llm)
+	if err != nil {
+		return nil, err
+	}
+	if summary != nil {
+		enrichers = append(enrichers, summary)
+	}
+
+	embedding, err := newEmbeddingEnricherFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if embedding != nil {
+		enrichers = append(enrichers, embedding)
+	}
+
+	entities, err := newEntityEnricherFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if entities != nil {
+		enrichers = append(enrichers, entities)
+	}
+
+	sentiment, err := newSentimentEnricherFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if sentiment != nil {
+		enrichers = append(enrichers, sentiment)
+	}
+
+	if tags := newTagsEnricherFromEnv(); tags != nil {
+		enrichers = append(enrichers, tags)
+	}
+
+	// Forward geocoding runs first so resolved points can be reverse geocoded
+	forwardGeocode, err := newForwardGeocodeEnricherFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if forwardGeocode != nil {
+		enrichers = append(enrichers, forwardGeocode)
+	}
+
+	reverseGeocode, err := newReverseGeocodeEnricherFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if reverseGeocode != nil {
+		enrichers = append(enrichers, reverseGeocode)
+	}
+
+	// Geohashes are computed from the final coordinates
+	geohash, err := newGeohashEnricherFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if geohash != nil {
+		enrichers = append(enrichers, geohash)
+	}
+
+	// Regions are derived from the geocoded state and country
+	regions, err := newRegionsEnricherFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if regions != nil {
+		enrichers = append(enrichers, regions)
+	}
+
+	// Stories compare embeddings, so they are grouped last
+	stories, err := newStoriesEnricherFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if stories != nil {
+		if stories.by == storiesByEmbedding && embedding == nil {
+			return nil, fmt.Errorf("STORY_CLUSTERING=%s needs the embedding enricher, set EMBEDDING_BASE_URL", storiesByEmbedding)
+		}
+		enrichers = append(enrichers, stories)
+	}
+
+	return enrichers, nil
+}
+
+// IndexOptionsFor collects the index options required by enrichers.
+func IndexOptionsFor(enrichers []Enricher) sink.IndexOptions {
+	var opts sink.IndexOptions
+	for _, e := range enrichers {
+		if c, ok := e.(IndexConfigurer); ok {
+			c.ConfigureIndex(&opts)
+		}
+	}
+	return opts
+}
@@ -0,0 +1,180 @@
+package enrich
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// entityExtractor is a named entity recognition backend.
+type entityExtractor interface {
+	extract(ctx context.Context, text string) (*model.Entities, error)
+}
+
+// entityEnricher populates the entities of every article without any.
+type entityEnricher struct {
+	extractor entityExtractor
+}
+
+// newEntityEnricherFromEnv selects the backend through NER_BACKEND
+// ("gazetteer" or "http"). It returns nil when no backend is configured.
+func newEntityEnricherFromEnv() (*entityEnricher, error) {
+	switch backend := os.Getenv("NER_BACKEND"); backend {
+	case "":
+		return nil, nil
+	case "gazetteer":
+		g, err := loadGazetteer(utils.GetEnv("NER_GAZETTEER_FILE", "resources/gazetteer.json"))
+		if err != nil {
+			return nil, err
+		}
+		return &entityEnricher{extractor: g}, nil
+	case "http":
+		url := os.Getenv("NER_URL")
+		if url == "" {
+			return nil, fmt.Errorf("NER_URL is required for the http ner backend")
+		}
+		return &entityEnricher{extractor: &httpEntityExtractor{
+			url:    url,
+			client: &http.Client{Timeout: 30 * time.Second},
+		}}, nil
+	default:
+		return nil, fmt.Errorf("unknown NER_BACKEND %q", backend)
+	}
+}
+
+func (e *entityEnricher) Name() string { return "entities" }
+
+func (e *entityEnricher) Enrich(ctx context.Context, articles []model.Article) error {
+	var count int
+	for i := range articles {
+		a := &articles[i]
+		if !a.Entities.Empty() {
+			continue
+		}
+
+		entities, err := e.extractor.extract(ctx, a.Title+"\n"+a.Description)
+		if err != nil {
+			log.Warn().Caller().Err(err).Str("id", a.ID).Msg("failed to extract entities")
+			continue
+		}
+		if !entities.Empty() {
+			a.Entities = entities
+			count++
+		}
+	}
+
+	log.Info().Caller().Msgf("extracted entities for %d articles", count)
+	return nil
+}
+
+// gazetteer is an offline extractor matching known names on word boundaries.
+type gazetteer struct {
+	person   []gazetteerEntry
+	org      []gazetteerEntry
+	location []gazetteerEntry
+}
+
+type gazetteerEntry struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// loadGazetteer reads a JSON file shaped like
+// {"person": ["Narendra Modi"], "org": ["ISRO"], "location": ["Hazaribagh"]}.
+func loadGazetteer(path string) (*gazetteer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gazetteer %s: %w", path, err)
+	}
+
+	var raw struct {
+		Person   []string `json:"person"`
+		Org      []string `json:"org"`
+		Location []string `json:"location"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid gazetteer %s: %w", path, err)
+	}
+
+	return &gazetteer{
+		person:   compileGazetteerEntries(raw.Person),
+		org:      compileGazetteerEntries(raw.Org),
+		location: compileGazetteerEntries(raw.Location),
+	}, nil
+}
+
+func compileGazetteerEntries(names []string) []gazetteerEntry {
+	entries := make([]gazetteerEntry, 0, len(names))
+	for _, n := range names {
+		entries = append(entries, gazetteerEntry{
+			name:    n,
+			pattern: regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(n) + `\b`),
+		})
+	}
+	return entries
+}
+
+func (g *gazetteer) extract(_ context.Context, text string) (*model.Entities, error) {
+	return &model.Entities{
+		Person:   matchGazetteerEntries(g.person, text),
+		Org:      matchGazetteerEntries(g.org, text),
+		Location: matchGazetteerEntries(g.location, text),
+	}, nil
+}
+
+func matchGazetteerEntries(entries []gazetteerEntry, text string) []string {
+	var found []string
+	for _, e := range entries {
+		if e.pattern.MatchString(text) {
+			found = append(found, e.name)
+		}
+	}
+	sort.Strings(found)
+	return found
+}
+
+// httpEntityExtractor posts {"text": "..."} to an external NER service
+// which must answer with an Entities JSON object.
+type httpEntityExtractor struct {
+	url    string
+	client *http.Client
+}
+
+func (h *httpEntityExtractor) extract(ctx context.Context, text string) (*model.Entities, error) {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ner service returned status %s", res.Status)
+	}
+
+	var entities model.Entities
+	if err := json.NewDecoder(res.Body).Decode(&entities); err != nil {
+		return nil, err
+	}
+	return &entities, nil
+}
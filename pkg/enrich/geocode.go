@@ -0,0 +1,358 @@
+package enrich
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// place is the administrative location of a point.
+type place struct {
+	Country string `json:"country,omitempty"`
+	State   string `json:"state,omitempty"`
+	City    string `json:"city,omitempty"`
+}
+
+// reverseGeocoder resolves coordinates to a place. It returns nil
+// without an error when the point can't be resolved.
+type reverseGeocoder interface {
+	reverse(ctx context.Context, lat, lon float64) (*place, error)
+}
+
+// reverseGeocodeEnricher fills country, state and city from coordinates.
+type reverseGeocodeEnricher struct {
+	geocoder reverseGeocoder
+}
+
+// newReverseGeocodeEnricherFromEnv selects the geocoder through
+// REVERSE_GEOCODER ("offline" or "nominatim"). It returns nil when none is configured.
+func newReverseGeocodeEnricherFromEnv() (*reverseGeocodeEnricher, error) {
+	switch geocoder := os.Getenv("REVERSE_GEOCODER"); geocoder {
+	case "":
+		return nil, nil
+	case "offline":
+		g, err := loadPlacesGeocoder(
+			utils.GetEnv("REVERSE_GEOCODER_PLACES_FILE", "resources/places.csv"),
+			float64(utils.GetEnvInt("REVERSE_GEOCODER_MAX_DISTANCE_KM", 150)),
+		)
+		if err != nil {
+			return nil, err
+		}
+		return &reverseGeocodeEnricher{geocoder: g}, nil
+	case "nominatim":
+		return &reverseGeocodeEnricher{geocoder: sharedNominatim()}, nil
+	default:
+		return nil, fmt.Errorf("unknown REVERSE_GEOCODER %q", geocoder)
+	}
+}
+
+func (r *reverseGeocodeEnricher) Name() string { return "reverse_geocode" }
+
+func (r *reverseGeocodeEnricher) Enrich(ctx context.Context, articles []model.Article) error {
+	var resolved, unresolved int
+	for i := range articles {
+		a := &articles[i]
+		if a.Country != "" || !utils.ValidCoordinates(a.Latitude, a.Longitude) {
+			continue
+		}
+
+		place, err := r.geocoder.reverse(ctx, a.Latitude, a.Longitude)
+		if err != nil {
+			log.Warn().Caller().Err(err).Str("id", a.ID).Msg("failed to reverse geocode article")
+			unresolved++
+			continue
+		}
+		if place == nil {
+			unresolved++
+			continue
+		}
+		a.Country, a.State, a.City = place.Country, place.State, place.City
+		resolved++
+	}
+
+	log.Info().Caller().Msgf("reverse geocoded %d articles, %d unresolved", resolved, unresolved)
+	return nil
+}
+
+// placesGeocoder resolves a point to the nearest known place within
+// maxDistanceKm, using a bundled CSV of city,state,country,latitude,longitude.
+type placesGeocoder struct {
+	places        []geoPlace
+	maxDistanceKm float64
+}
+
+type geoPlace struct {
+	place
+	lat, lon float64
+}
+
+func loadPlacesGeocoder(path string, maxDistanceKm float64) (*placesGeocoder, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open places file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid places file %s: %w", path, err)
+	}
+
+	g := &placesGeocoder{maxDistanceKm: maxDistanceKm}
+	for i, rec := range records {
+		if i == 0 || len(rec) < 5 {
+			continue // header or malformed row
+		}
+		lat, err := strconv.ParseFloat(rec[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid latitude on line %d of %s: %w", i+1, path, err)
+		}
+		lon, err := strconv.ParseFloat(rec[4], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid longitude on line %d of %s: %w", i+1, path, err)
+		}
+		g.places = append(g.places, geoPlace{
+			place: place{City: rec[0], State: rec[1], Country: rec[2]},
+			lat:   lat,
+			lon:   lon,
+		})
+	}
+	return g, nil
+}
+
+func (g *placesGeocoder) geocode(_ context.Context, name string) (*geoPoint, error) {
+	for _, p := range g.places {
+		if strings.EqualFold(p.City, name) {
+			return &geoPoint{Lat: p.lat, Lon: p.lon}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (g *placesGeocoder) reverse(_ context.Context, lat, lon float64) (*place, error) {
+	var nearest *geoPlace
+	best := g.maxDistanceKm
+	for i := range g.places {
+		p := &g.places[i]
+		if d := utils.HaversineKm(lat, lon, p.lat, p.lon); d <= best {
+			nearest, best = p, d
+		}
+	}
+	if nearest == nil {
+		return nil, nil
+	}
+	place := nearest.place
+	return &place, nil
+}
+
+// nominatimGeocoder uses the OpenStreetMap Nominatim search and reverse APIs.
+type nominatimGeocoder struct {
+	baseURL string
+	client  *http.Client
+	limiter *time.Ticker
+}
+
+// sharedNominatim returns the single Nominatim client used by forward and
+// reverse geocoding, so NOMINATIM_RPS bounds their combined request rate.
+// The public instance allows a single request per second.
+var sharedNominatim = sync.OnceValue(func() *nominatimGeocoder {
+	rps := utils.GetEnvInt("NOMINATIM_RPS", 1)
+	if rps < 1 {
+		rps = 1
+	}
+	return &nominatimGeocoder{
+		baseURL: strings.TrimRight(utils.GetEnv("NOMINATIM_URL", "https://nominatim.openstreetmap.org"), "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+		limiter: time.NewTicker(time.Second / time.Duration(rps)),
+	}
+})
+
+// wait blocks until the rate limiter allows the next request.
+func (n *nominatimGeocoder) wait(ctx context.Context) error {
+	select {
+	case <-n.limiter.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// get performs a rate limited GET against path and decodes the JSON response into v.
+func (n *nominatimGeocoder) get(ctx context.Context, path string, q url.Values, v interface{}) error {
+	if err := n.wait(ctx); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, n.baseURL+path+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "inshorts-news-data-syncer")
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("nominatim returned status %s", res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+func (n *nominatimGeocoder) reverse(ctx context.Context, lat, lon float64) (*place, error) {
+	q := url.Values{}
+	q.Set("format", "jsonv2")
+	q.Set("lat", strconv.FormatFloat(lat, 'f', 6, 64))
+	q.Set("lon", strconv.FormatFloat(lon, 'f', 6, 64))
+	q.Set("zoom", "10")
+
+	var body struct {
+		Address struct {
+			Country string `json:"country"`
+			State   string `json:"state"`
+			City    string `json:"city"`
+			Town    string `json:"town"`
+			Village string `json:"village"`
+		} `json:"address"`
+	}
+	if err := n.get(ctx, "/reverse", q, &body); err != nil {
+		return nil, err
+	}
+
+	addr := body.Address
+	if addr.Country == "" {
+		return nil, nil
+	}
+	city := addr.City
+	if city == "" {
+		city = addr.Town
+	}
+	if city == "" {
+		city = addr.Village
+	}
+	return &place{Country: addr.Country, State: addr.State, City: city}, nil
+}
+
+func (n *nominatimGeocoder) geocode(ctx context.Context, name string) (*geoPoint, error) {
+	q := url.Values{}
+	q.Set("format", "jsonv2")
+	q.Set("q", name)
+	q.Set("limit", "1")
+
+	var results []struct {
+		Lat string `json:"lat"`
+		Lon string `json:"lon"`
+	}
+	if err := n.get(ctx, "/search", q, &results); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	lat, err := strconv.ParseFloat(results[0].Lat, 64)
+	if err != nil {
+		return nil, err
+	}
+	lon, err := strconv.ParseFloat(results[0].Lon, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &geoPoint{Lat: lat, Lon: lon}, nil
+}
+
+// geoPoint is a resolved coordinate pair.
+type geoPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// forwardGeocoder resolves a place name to coordinates. It returns nil
+// without an error when the name is unknown.
+type forwardGeocoder interface {
+	geocode(ctx context.Context, name string) (*geoPoint, error)
+}
+
+// forwardGeocodeEnricher sets coordinates for articles that name a place
+// but arrive without valid coordinates.
+type forwardGeocodeEnricher struct {
+	geocoder forwardGeocoder
+	// cache holds resolved names, with nil recording names that could not
+	// be resolved so they are not looked up again.
+	cache *fileCache[*geoPoint]
+}
+
+// newForwardGeocodeEnricherFromEnv selects the geocoder through
+// FORWARD_GEOCODER ("offline" or "nominatim"). It returns nil when none is configured.
+func newForwardGeocodeEnricherFromEnv() (*forwardGeocodeEnricher, error) {
+	var geocoder forwardGeocoder
+	switch name := os.Getenv("FORWARD_GEOCODER"); name {
+	case "":
+		return nil, nil
+	case "offline":
+		g, err := loadPlacesGeocoder(utils.GetEnv("REVERSE_GEOCODER_PLACES_FILE", "resources/places.csv"), 0)
+		if err != nil {
+			return nil, err
+		}
+		geocoder = g
+	case "nominatim":
+		geocoder = sharedNominatim()
+	default:
+		return nil, fmt.Errorf("unknown FORWARD_GEOCODER %q", name)
+	}
+
+	cache, err := loadFileCache[*geoPoint](utils.GetEnv("FORWARD_GEOCODER_CACHE_FILE", "geocode_cache.json"))
+	if err != nil {
+		return nil, err
+	}
+	return &forwardGeocodeEnricher{geocoder: geocoder, cache: cache}, nil
+}
+
+func (f *forwardGeocodeEnricher) Name() string { return "forward_geocode" }
+
+func (f *forwardGeocodeEnricher) Enrich(ctx context.Context, articles []model.Article) error {
+	var resolved, unresolved int
+	for i := range articles {
+		a := &articles[i]
+		if a.LocationName == "" || utils.ValidCoordinates(a.Latitude, a.Longitude) {
+			continue
+		}
+
+		key := strings.ToLower(strings.TrimSpace(a.LocationName))
+		point, ok := f.cache.get(key)
+		if !ok {
+			var err error
+			point, err = f.geocoder.geocode(ctx, a.LocationName)
+			if err != nil {
+				log.Warn().Caller().Err(err).Str("id", a.ID).Msgf("failed to geocode location %q", a.LocationName)
+				unresolved++
+				continue
+			}
+			f.cache.put(key, point)
+		}
+
+		if point == nil {
+			unresolved++
+			continue
+		}
+		a.Latitude, a.Longitude = point.Lat, point.Lon
+		resolved++
+	}
+
+	log.Info().Caller().Msgf("geocoded %d articles from location names, %d unresolved", resolved, unresolved)
+	return f.cache.save()
+}
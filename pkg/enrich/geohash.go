@@ -0,0 +1,51 @@
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// geohashEnricher stores the geohash of every located article, so
+// location buckets are a cheap terms aggregation on a keyword rather
+// than a geohash_grid over the geo_point.
+type geohashEnricher struct {
+	precision int
+}
+
+// newGeohashEnricherFromEnv returns nil unless GEOHASH_PRECISION, the
+// length of the geohashes, is set.
+func newGeohashEnricherFromEnv() (*geohashEnricher, error) {
+	v := os.Getenv("GEOHASH_PRECISION")
+	if v == "" {
+		return nil, nil
+	}
+	precision, err := strconv.Atoi(v)
+	if err != nil || precision < 1 || precision > utils.MaxGeohashPrecision {
+		return nil, fmt.Errorf("GEOHASH_PRECISION must be between 1 and %d, got %q", utils.MaxGeohashPrecision, v)
+	}
+	return &geohashEnricher{precision: precision}, nil
+}
+
+func (g *geohashEnricher) Name() string { return "geohash" }
+
+func (g *geohashEnricher) Enrich(_ context.Context, articles []model.Article) error {
+	var hashed int
+	for i := range articles {
+		a := &articles[i]
+		if !utils.ValidCoordinates(a.Latitude, a.Longitude) {
+			a.Geohash = ""
+			continue
+		}
+		a.Geohash = utils.Geohash(a.Latitude, a.Longitude, g.precision)
+		hashed++
+	}
+
+	log.Info().Caller().Msgf("computed the geohash of %d articles", hashed)
+	return nil
+}
@@ -0,0 +1,131 @@
+package enrich
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// llmClient calls an OpenAI-compatible chat completions endpoint and
+// enforces the per run cost caps shared by every LLM backed stage.
+type llmClient struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+
+	// Cost caps for a single run. Zero means unlimited.
+	maxRequests int64
+	maxTokens   int64
+
+	requests atomic.Int64
+	tokens   atomic.Int64
+}
+
+// newLLMClientFromEnv returns nil when LLM_BASE_URL is not set.
+func newLLMClientFromEnv() *llmClient {
+	baseURL := os.Getenv("LLM_BASE_URL")
+	if baseURL == "" {
+		return nil
+	}
+
+	return &llmClient{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		apiKey:      os.Getenv("LLM_API_KEY"),
+		model:       utils.GetEnv("LLM_MODEL", "gpt-4o-mini"),
+		client:      &http.Client{Timeout: 60 * time.Second},
+		maxRequests: int64(utils.GetEnvInt("LLM_MAX_REQUESTS", 1000)),
+		maxTokens:   int64(utils.GetEnvInt("LLM_MAX_TOKENS", 0)),
+	}
+}
+
+// resetBudget starts a new run, clearing what the previous ones spent.
+func (c *llmClient) resetBudget() {
+	c.requests.Store(0)
+	c.tokens.Store(0)
+}
+
+// budgetExhausted reports whether either cost cap has been reached.
+func (c *llmClient) budgetExhausted() bool {
+	if c.maxRequests > 0 && c.requests.Load() >= c.maxRequests {
+		return true
+	}
+	return c.maxTokens > 0 && c.tokens.Load() >= c.maxTokens
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Temperature float64       `json:"temperature"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		TotalTokens int64 `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// complete sends a system and user prompt and returns the trimmed reply.
+func (c *llmClient) complete(ctx context.Context, system, user string, maxTokens int) (string, error) {
+	body, err := json.Marshal(chatRequest{
+		Model: c.model,
+		Messages: []chatMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: user},
+		},
+		MaxTokens:   maxTokens,
+		Temperature: 0.2,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	c.requests.Add(1)
+	res, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("llm endpoint returned status %s", res.Status)
+	}
+
+	var chatResp chatResponse
+	if err := json.NewDecoder(res.Body).Decode(&chatResp); err != nil {
+		return "", err
+	}
+	c.tokens.Add(chatResp.Usage.TotalTokens)
+
+	if len(chatResp.Choices) == 0 {
+		return "", errors.New("llm endpoint returned no choices")
+	}
+	return strings.TrimSpace(chatResp.Choices[0].Message.Content), nil
+}
@@ -0,0 +1,99 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+// defaultPIIPatterns are always applied when redaction is enabled.
+var defaultPIIPatterns = map[string]string{
+	"email": `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+	"phone": `(?:\+\d{1,3}[\s-]?)?\(?\d{3,5}\)?[\s-]?\d{3,4}[\s-]?\d{3,4}\b`,
+}
+
+// redactEnricher masks PII in the free text fields of every article,
+// replacing each match with the upper cased pattern name, e.g. "[EMAIL]".
+type redactEnricher struct {
+	names    []string
+	patterns map[string]*regexp.Regexp
+}
+
+// newRedactEnricherFromEnv returns nil unless REDACT_PII is enabled.
+// Extra patterns are read from REDACT_PATTERNS_FILE, a JSON object of
+// name to regular expression which may also override the defaults.
+func newRedactEnricherFromEnv() (*redactEnricher, error) {
+	if os.Getenv("REDACT_PII") != "true" {
+		return nil, nil
+	}
+
+	sources := map[string]string{}
+	for name, expr := range defaultPIIPatterns {
+		sources[name] = expr
+	}
+	if path := os.Getenv("REDACT_PATTERNS_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read redaction patterns %s: %w", path, err)
+		}
+		extra := map[string]string{}
+		if err := json.Unmarshal(data, &extra); err != nil {
+			return nil, fmt.Errorf("invalid redaction patterns %s: %w", path, err)
+		}
+		for name, expr := range extra {
+			sources[name] = expr
+		}
+	}
+
+	r := &redactEnricher{patterns: map[string]*regexp.Regexp{}}
+	for name, expr := range sources {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %s: %w", name, err)
+		}
+		r.names = append(r.names, name)
+		r.patterns[name] = re
+	}
+	// Apply patterns in a stable order so overlapping matches redact consistently.
+	sort.Strings(r.names)
+	return r, nil
+}
+
+func (r *redactEnricher) Name() string { return "redact" }
+
+func (r *redactEnricher) Enrich(_ context.Context, articles []model.Article) error {
+	counts := map[string]int{}
+	for i := range articles {
+		a := &articles[i]
+		for _, field := range []*string{&a.Title, &a.Description, &a.LLMSummary} {
+			*field = r.redact(*field, counts)
+		}
+	}
+
+	for _, name := range r.names {
+		if counts[name] > 0 {
+			log.Info().Caller().Msgf("redacted %d %s matches", counts[name], name)
+		}
+	}
+	return nil
+}
+
+func (r *redactEnricher) redact(text string, counts map[string]int) string {
+	for _, name := range r.names {
+		re := r.patterns[name]
+		matches := len(re.FindAllStringIndex(text, -1))
+		if matches == 0 {
+			continue
+		}
+		counts[name] += matches
+		text = re.ReplaceAllLiteralString(text, "["+strings.ToUpper(name)+"]")
+	}
+	return text
+}
@@ -0,0 +1,127 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// regionsEnricher attaches the boundary of the region an article is
+// about, so articles covering a whole state or country match geo_shape
+// queries over it. The region is the code the input gives, or else the
+// one of the state, then the country, the article was geocoded to.
+type regionsEnricher struct {
+	// shapes maps an upper cased region code to its GeoJSON geometry.
+	shapes map[string]json.RawMessage
+	// codes maps a lower cased "country/state" or country to its region
+	// code.
+	codes map[string]string
+}
+
+// newRegionsEnricherFromEnv returns nil unless REGIONS is enabled. The
+// boundaries are read from REGION_SHAPES_FILE.
+func newRegionsEnricherFromEnv() (*regionsEnricher, error) {
+	if os.Getenv("REGIONS") != "true" {
+		return nil, nil
+	}
+	return loadRegions(utils.GetEnv("REGION_SHAPES_FILE", "resources/regions.geojson"))
+}
+
+// loadRegions reads a GeoJSON FeatureCollection whose features have a
+// code property, and optionally the name and country the code stands for.
+func loadRegions(path string) (*regionsEnricher, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read region shapes file %s: %w", path, err)
+	}
+	var collection struct {
+		Features []struct {
+			Properties struct {
+				Code    string `json:"code"`
+				Name    string `json:"name"`
+				Country string `json:"country"`
+			} `json:"properties"`
+			Geometry json.RawMessage `json:"geometry"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, fmt.Errorf("invalid region shapes file %s: %w", path, err)
+	}
+
+	r := &regionsEnricher{shapes: map[string]json.RawMessage{}, codes: map[string]string{}}
+	for i, f := range collection.Features {
+		code := strings.ToUpper(f.Properties.Code)
+		if code == "" || len(f.Geometry) == 0 || string(f.Geometry) == "null" {
+			return nil, fmt.Errorf("invalid region shapes file %s: feature %d needs a code and a geometry", path, i)
+		}
+		if _, ok := r.shapes[code]; ok {
+			return nil, fmt.Errorf("invalid region shapes file %s: region %s is listed twice", path, code)
+		}
+		r.shapes[code] = f.Geometry
+		if f.Properties.Name != "" {
+			r.codes[regionKey(f.Properties.Country, f.Properties.Name)] = code
+		}
+	}
+	log.Info().Caller().Msgf("loaded %d region shapes from %s", len(r.shapes), path)
+	return r, nil
+}
+
+// regionKey is the key of a place in regionsEnricher.codes. Countries are
+// their own region, so their name is also their country.
+func regionKey(country, name string) string {
+	if country == "" || strings.EqualFold(country, name) {
+		return strings.ToLower(name)
+	}
+	return strings.ToLower(country + "/" + name)
+}
+
+func (r *regionsEnricher) Name() string { return "regions" }
+
+func (r *regionsEnricher) Enrich(_ context.Context, articles []model.Article) error {
+	var shaped, unknown int
+	for i := range articles {
+		a := &articles[i]
+		if len(a.RegionShape) > 0 {
+			continue
+		}
+		if a.Region == "" {
+			a.Region = r.regionOf(a)
+			if a.Region == "" {
+				continue
+			}
+		}
+		a.Region = strings.ToUpper(a.Region)
+		shape, ok := r.shapes[a.Region]
+		if !ok {
+			unknown++
+			continue
+		}
+		a.RegionShape = shape
+		shaped++
+	}
+
+	if unknown > 0 {
+		log.Warn().Caller().Msgf("%d articles are about regions without a shape", unknown)
+	}
+	log.Info().Caller().Msgf("attached region shapes to %d articles", shaped)
+	return nil
+}
+
+// regionOf returns the code of the state, or else the country, of a.
+func (r *regionsEnricher) regionOf(a *model.Article) string {
+	if a.State != "" {
+		if code, ok := r.codes[regionKey(a.Country, a.State)]; ok {
+			return code
+		}
+	}
+	if a.Country != "" {
+		return r.codes[regionKey(a.Country, a.Country)]
+	}
+	return ""
+}
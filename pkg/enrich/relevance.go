@@ -0,0 +1,140 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+const (
+	relevanceMinMax     = "minmax"
+	relevanceZScore     = "zscore"
+	relevanceMultiplier = "multiplier"
+)
+
+// relevanceEnricher rescales relevance_score per source_name, because
+// providers use incompatible scales and ranking across them is otherwise
+// meaningless.
+type relevanceEnricher struct {
+	mode string
+	// multipliers maps a lower cased source name to a static factor.
+	// Sources without an entry keep their score.
+	multipliers map[string]float64
+}
+
+// newRelevanceEnricherFromEnv selects the normalisation through
+// RELEVANCE_NORMALIZATION. It returns nil when none is configured.
+func newRelevanceEnricherFromEnv() (*relevanceEnricher, error) {
+	mode := os.Getenv("RELEVANCE_NORMALIZATION")
+	switch mode {
+	case "":
+		return nil, nil
+	case relevanceMinMax, relevanceZScore:
+		return &relevanceEnricher{mode: mode}, nil
+	case relevanceMultiplier:
+		path := os.Getenv("RELEVANCE_MULTIPLIERS_FILE")
+		if path == "" {
+			return nil, fmt.Errorf("RELEVANCE_MULTIPLIERS_FILE is required for multiplier normalization")
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read relevance multipliers %s: %w", path, err)
+		}
+		raw := map[string]float64{}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("invalid relevance multipliers %s: %w", path, err)
+		}
+		multipliers := make(map[string]float64, len(raw))
+		for source, m := range raw {
+			multipliers[strings.ToLower(source)] = m
+		}
+		return &relevanceEnricher{mode: mode, multipliers: multipliers}, nil
+	default:
+		return nil, fmt.Errorf("unknown RELEVANCE_NORMALIZATION %q", mode)
+	}
+}
+
+func (r *relevanceEnricher) Name() string { return "relevance" }
+
+// RunWide reports whether scores are normalised against those of the
+// other articles of the source, rather than by a static multiplier.
+func (r *relevanceEnricher) RunWide() bool { return r.mode != relevanceMultiplier }
+
+func (r *relevanceEnricher) Enrich(_ context.Context, articles []model.Article) error {
+	if r.mode == relevanceMultiplier {
+		for i := range articles {
+			if m, ok := r.multipliers[strings.ToLower(articles[i].SourceName)]; ok {
+				articles[i].RelevanceScore *= m
+			}
+		}
+		log.Info().Caller().Msgf("applied relevance multipliers for %d sources", len(r.multipliers))
+		return nil
+	}
+
+	bySource := map[string][]*model.Article{}
+	for i := range articles {
+		source := strings.ToLower(articles[i].SourceName)
+		bySource[source] = append(bySource[source], &articles[i])
+	}
+
+	for _, group := range bySource {
+		switch r.mode {
+		case relevanceMinMax:
+			normalizeMinMax(group)
+		case relevanceZScore:
+			normalizeZScore(group)
+		}
+	}
+
+	log.Info().Caller().Msgf("normalized relevance scores with %s across %d sources", r.mode, len(bySource))
+	return nil
+}
+
+// normalizeMinMax rescales scores into [0, 1]. A source whose scores are
+// all equal gets 0.5, the middle of the range.
+func normalizeMinMax(group []*model.Article) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, a := range group {
+		lo = math.Min(lo, a.RelevanceScore)
+		hi = math.Max(hi, a.RelevanceScore)
+	}
+	for _, a := range group {
+		if hi == lo {
+			a.RelevanceScore = 0.5
+			continue
+		}
+		a.RelevanceScore = (a.RelevanceScore - lo) / (hi - lo)
+	}
+}
+
+// normalizeZScore standardises scores and squashes them into (0, 1) with
+// the logistic function, so the field stays a positive score usable by
+// field_value_factor ranking.
+func normalizeZScore(group []*model.Article) {
+	var mean float64
+	for _, a := range group {
+		mean += a.RelevanceScore
+	}
+	mean /= float64(len(group))
+
+	var variance float64
+	for _, a := range group {
+		d := a.RelevanceScore - mean
+		variance += d * d
+	}
+	std := math.Sqrt(variance / float64(len(group)))
+
+	for _, a := range group {
+		var z float64
+		if std > 0 {
+			z = (a.RelevanceScore - mean) / std
+		}
+		a.RelevanceScore = 1 / (1 + math.Exp(-z))
+	}
+}
@@ -0,0 +1,198 @@
+package enrich
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// Scores within this distance of zero are labelled neutral.
+const neutralThreshold = 0.05
+
+// sentimentProvider scores the tone of a text.
+type sentimentProvider interface {
+	score(ctx context.Context, text string) (*model.Sentiment, error)
+}
+
+// sentimentEnricher sets the sentiment of every article without one.
+type sentimentEnricher struct {
+	provider sentimentProvider
+}
+
+// newSentimentEnricherFromEnv selects the provider through SENTIMENT_PROVIDER
+// ("lexicon" or "http"). It returns nil when no provider is configured.
+func newSentimentEnricherFromEnv() (*sentimentEnricher, error) {
+	switch provider := os.Getenv("SENTIMENT_PROVIDER"); provider {
+	case "":
+		return nil, nil
+	case "lexicon":
+		l, err := loadSentimentLexicon(os.Getenv("SENTIMENT_LEXICON_FILE"))
+		if err != nil {
+			return nil, err
+		}
+		return &sentimentEnricher{provider: l}, nil
+	case "http":
+		url := os.Getenv("SENTIMENT_URL")
+		if url == "" {
+			return nil, fmt.Errorf("SENTIMENT_URL is required for the http sentiment provider")
+		}
+		return &sentimentEnricher{provider: &httpSentimentProvider{
+			url:    url,
+			client: &http.Client{Timeout: 30 * time.Second},
+		}}, nil
+	default:
+		return nil, fmt.Errorf("unknown SENTIMENT_PROVIDER %q", provider)
+	}
+}
+
+func (s *sentimentEnricher) Name() string { return "sentiment" }
+
+func (s *sentimentEnricher) Enrich(ctx context.Context, articles []model.Article) error {
+	counts := map[string]int{}
+	for i := range articles {
+		a := &articles[i]
+		if a.Sentiment != nil {
+			continue
+		}
+
+		sentiment, err := s.provider.score(ctx, a.Title+"\n"+a.Description)
+		if err != nil {
+			log.Warn().Caller().Err(err).Str("id", a.ID).Msg("failed to score sentiment")
+			continue
+		}
+		a.Sentiment = sentiment
+		counts[sentiment.Label]++
+	}
+
+	log.Info().Caller().Msgf("sentiment: %d positive, %d negative, %d neutral",
+		counts[model.SentimentPositive], counts[model.SentimentNegative], counts[model.SentimentNeutral])
+	return nil
+}
+
+// labelForScore maps a score in [-1, 1] to a sentiment label.
+func labelForScore(score float64) string {
+	switch {
+	case score >= neutralThreshold:
+		return model.SentimentPositive
+	case score <= -neutralThreshold:
+		return model.SentimentNegative
+	default:
+		return model.SentimentNeutral
+	}
+}
+
+// defaultSentimentLexicon is a small news oriented word list used when
+// no lexicon file is configured. Weights range from -1 to 1.
+var defaultSentimentLexicon = map[string]float64{
+	"win": 0.8, "wins": 0.8, "won": 0.8, "victory": 0.8, "success": 0.7, "successful": 0.7,
+	"growth": 0.5, "gain": 0.5, "gains": 0.5, "rise": 0.3, "rises": 0.3, "record": 0.4,
+	"boost": 0.5, "celebrate": 0.7, "celebrates": 0.7, "award": 0.6, "praised": 0.6,
+	"launch": 0.3, "launches": 0.3, "agreement": 0.4, "peace": 0.7, "relief": 0.5,
+	"recovery": 0.5, "rescued": 0.6, "historic": 0.4, "improve": 0.5, "improves": 0.5,
+	"killed": -0.9, "dead": -0.8, "death": -0.8, "deaths": -0.8, "murder": -0.9,
+	"attack": -0.8, "attacks": -0.8, "clash": -0.6, "clashes": -0.6, "violence": -0.8,
+	"crash": -0.7, "fire": -0.4, "injured": -0.7, "arrested": -0.5, "fraud": -0.7,
+	"scam": -0.7, "protest": -0.4, "protests": -0.4, "loss": -0.5, "losses": -0.5,
+	"fall": -0.3, "falls": -0.3, "crisis": -0.7, "flood": -0.6, "floods": -0.6,
+	"war": -0.8, "terror": -0.9, "accused": -0.5, "ban": -0.4, "banned": -0.4,
+	"mislead": -0.5, "rumours": -0.3, "slams": -0.4, "criticised": -0.4, "fails": -0.5,
+}
+
+// sentimentLexicon is an offline provider averaging per-word weights.
+type sentimentLexicon struct {
+	weights map[string]float64
+}
+
+// loadSentimentLexicon reads a JSON object of word to weight from path,
+// falling back to defaultSentimentLexicon when path is empty.
+func loadSentimentLexicon(path string) (*sentimentLexicon, error) {
+	if path == "" {
+		return &sentimentLexicon{weights: defaultSentimentLexicon}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sentiment lexicon %s: %w", path, err)
+	}
+	weights := map[string]float64{}
+	if err := json.Unmarshal(data, &weights); err != nil {
+		return nil, fmt.Errorf("invalid sentiment lexicon %s: %w", path, err)
+	}
+	for w, v := range weights {
+		delete(weights, w)
+		weights[strings.ToLower(w)] = v
+	}
+	return &sentimentLexicon{weights: weights}, nil
+}
+
+func (l *sentimentLexicon) score(_ context.Context, text string) (*model.Sentiment, error) {
+	var sum float64
+	var hits int
+	for _, token := range utils.Tokenize(text) {
+		if w, ok := l.weights[token]; ok {
+			sum += w
+			hits++
+		}
+	}
+	if hits == 0 {
+		return &model.Sentiment{Label: model.SentimentNeutral}, nil
+	}
+
+	// Normalise into (-1, 1) the way VADER does, using the number of
+	// matched words as the smoothing term.
+	score := sum / math.Sqrt(sum*sum+float64(hits))
+	score = math.Round(score*1000) / 1000
+	return &model.Sentiment{Label: labelForScore(score), Score: score}, nil
+}
+
+// httpSentimentProvider posts {"text": "..."} to an external service which
+// must answer with {"score": <-1..1>} and optionally a label.
+type httpSentimentProvider struct {
+	url    string
+	client *http.Client
+}
+
+func (h *httpSentimentProvider) score(ctx context.Context, text string) (*model.Sentiment, error) {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sentiment service returned status %s", res.Status)
+	}
+
+	var sentiment model.Sentiment
+	if err := json.NewDecoder(res.Body).Decode(&sentiment); err != nil {
+		return nil, err
+	}
+	if sentiment.Score < -1 || sentiment.Score > 1 {
+		return nil, fmt.Errorf("sentiment score %v out of range", sentiment.Score)
+	}
+	if sentiment.Label == "" {
+		sentiment.Label = labelForScore(sentiment.Score)
+	}
+	return &sentiment, nil
+}
@@ -0,0 +1,260 @@
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// maxStoryCandidates bounds the articles one article is compared with:
+// those published after it within the window by embedding, and those
+// published last before it per shared shingle by title. It keeps
+// clustering a large run close to linear when many articles are
+// published within the window.
+const maxStoryCandidates = 200
+
+// Ways the story stage compares articles.
+const (
+	storiesByTitle     = "title"
+	storiesByEmbedding = "embedding"
+)
+
+// storiesEnricher groups the articles of the current run that cover the
+// same event into stories, by the overlap of their title shingles or the
+// cosine similarity of their embeddings. Every article of a story gets
+// the story_id of its canonical article, the one of the most trusted
+// source and, among those, the earliest. Articles of different runs are
+// not grouped together, nor are articles without a publication date.
+type storiesEnricher struct {
+	by         string
+	similarity float64
+	// window is how far apart the publication of two articles of one
+	// story may be.
+	window time.Duration
+}
+
+// newStoriesEnricherFromEnv returns nil unless STORY_CLUSTERING is title
+// or embedding. STORY_SIMILARITY is the similarity two articles need,
+// STORY_WINDOW how far apart they may be published.
+func newStoriesEnricherFromEnv() (*storiesEnricher, error) {
+	s := &storiesEnricher{by: os.Getenv("STORY_CLUSTERING")}
+	switch s.by {
+	case "":
+		return nil, nil
+	case storiesByTitle:
+		s.similarity = 0.4
+	case storiesByEmbedding:
+		s.similarity = 0.85
+	default:
+		return nil, fmt.Errorf("unknown STORY_CLUSTERING %q, expected %s or %s", s.by, storiesByTitle, storiesByEmbedding)
+	}
+	if v := os.Getenv("STORY_SIMILARITY"); v != "" {
+		similarity, err := strconv.ParseFloat(v, 64)
+		if err != nil || similarity <= 0 || similarity > 1 {
+			return nil, fmt.Errorf("STORY_SIMILARITY must be a number in (0, 1], got %q", v)
+		}
+		s.similarity = similarity
+	}
+	s.window = utils.GetEnvDuration("STORY_WINDOW", 48*time.Hour)
+	return s, nil
+}
+
+func (s *storiesEnricher) Name() string { return "stories" }
+
+// RunWide is true as stories group articles anywhere in the run.
+func (s *storiesEnricher) RunWide() bool { return true }
+
+func (s *storiesEnricher) Enrich(_ context.Context, articles []model.Article) error {
+	published := make([]time.Time, len(articles))
+	// Articles are compared in order of publication, each with those
+	// following it within the window. Articles without a date can't be
+	// placed in a window and stand alone.
+	var order []int
+	for i, a := range articles {
+		var err error
+		if published[i], err = utils.ParseDate(a.PublicationDate); err == nil {
+			order = append(order, i)
+		}
+	}
+	slices.SortStableFunc(order, func(i, j int) int { return published[i].Compare(published[j]) })
+
+	parent := make([]int, len(articles))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	join := func(i, j int) {
+		if ri, rj := find(i), find(j); ri != rj {
+			parent[ri] = rj
+		}
+	}
+
+	if s.by == storiesByEmbedding {
+		norms := make([]float64, len(articles))
+		for i, a := range articles {
+			norms[i] = norm(a.Embedding)
+		}
+		for x, i := range order {
+			if norms[i] == 0 {
+				continue
+			}
+			end := min(len(order), x+1+maxStoryCandidates)
+			for _, j := range order[x+1 : end] {
+				if published[j].Sub(published[i]) > s.window {
+					break
+				}
+				if norms[j] == 0 || len(articles[j].Embedding) != len(articles[i].Embedding) {
+					continue
+				}
+				if dot(articles[i].Embedding, articles[j].Embedding)/(norms[i]*norms[j]) >= s.similarity {
+					join(i, j)
+				}
+			}
+		}
+	} else {
+		// Only articles sharing a shingle can be similar, so every article
+		// is compared with the earlier ones within the window sharing one
+		shingles := make([]map[string]bool, len(articles))
+		withShingle := map[string][]int{}
+		compared := map[int]bool{}
+		for _, i := range order {
+			shingles[i] = titleShingles(articles[i].Title)
+			clear(compared)
+			for shingle := range shingles[i] {
+				group := withShingle[shingle]
+				// Groups are in order of publication, so the articles
+				// before the window are a prefix
+				for len(group) > 0 && published[i].Sub(published[group[0]]) > s.window {
+					group = group[1:]
+				}
+				for _, j := range group {
+					if compared[j] {
+						continue
+					}
+					compared[j] = true
+					if jaccard(shingles[i], shingles[j]) >= s.similarity {
+						join(i, j)
+					}
+				}
+				// A shingle common to many articles says little about
+				// them, only the latest few are kept
+				group = append(group, i)
+				if len(group) > maxStoryCandidates {
+					group = group[len(group)-maxStoryCandidates:]
+				}
+				withShingle[shingle] = group
+			}
+		}
+	}
+
+	stories := map[int][]int{}
+	for i := range articles {
+		root := find(i)
+		stories[root] = append(stories[root], i)
+	}
+	var grouped int
+	for _, members := range stories {
+		canonical := members[0]
+		sources := map[string]bool{}
+		for _, i := range members {
+			if moreCanonical(articles[i], published[i], articles[canonical], published[canonical]) {
+				canonical = i
+			}
+			sources[strings.ToLower(articles[i].SourceName)] = true
+		}
+		for _, i := range members {
+			a := &articles[i]
+			a.StoryID = articles[canonical].ID
+			a.StoryCanonical = i == canonical
+			a.StorySources = len(sources)
+		}
+		if len(members) > 1 {
+			grouped += len(members)
+		}
+	}
+
+	log.Info().Caller().Msgf("grouped %d articles into %d stories, %d articles stand alone", grouped, len(stories)-(len(articles)-grouped), len(articles)-grouped)
+	return nil
+}
+
+// moreCanonical reports whether a should rather be the canonical article
+// of its story than b: it has the more trusted source, was published
+// earlier, or, all else equal, has the smaller ID.
+func moreCanonical(a model.Article, aPublished time.Time, b model.Article, bPublished time.Time) bool {
+	aTrust, bTrust := math.Inf(-1), math.Inf(-1)
+	if a.SourceTrust != nil {
+		aTrust = *a.SourceTrust
+	}
+	if b.SourceTrust != nil {
+		bTrust = *b.SourceTrust
+	}
+	if aTrust != bTrust {
+		return aTrust > bTrust
+	}
+	if !aPublished.Equal(bPublished) {
+		if aPublished.IsZero() || bPublished.IsZero() {
+			return bPublished.IsZero()
+		}
+		return aPublished.Before(bPublished)
+	}
+	return a.ID < b.ID
+}
+
+// titleShingles returns the words of title that aren't stopwords, and
+// every pair of them that follow each other, so rewordings still overlap.
+func titleShingles(title string) map[string]bool {
+	shingles := map[string]bool{}
+	var prev string
+	for _, token := range utils.Tokenize(title) {
+		token = strings.Trim(token, "'")
+		if token == "" || utils.IsStopword(token) {
+			continue
+		}
+		shingles[token] = true
+		if prev != "" {
+			shingles[prev+" "+token] = true
+		}
+		prev = token
+	}
+	return shingles
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for shingle := range a {
+		if b[shingle] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+func norm(v []float32) float64 {
+	return math.Sqrt(dot(v, v))
+}
@@ -0,0 +1,112 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+const summaryPrompt = "Summarise the following news article in at most 60 words. " +
+	"Reply with the summary only."
+
+// summaryEnricher fills llm_summary for articles missing one.
+type summaryEnricher struct {
+	llm         *llmClient
+	concurrency int
+	// cache maps a content hash of title+description to a generated
+	// summary, so unchanged articles are not summarised again on the next run.
+	cache *fileCache[string]
+}
+
+// newSummaryEnricherFromEnv returns nil when LLM_SUMMARY is not enabled.
+func newSummaryEnricherFromEnv(llm *llmClient) (*summaryEnricher, error) {
+	if os.Getenv("LLM_SUMMARY") != "true" {
+		return nil, nil
+	}
+	if llm == nil {
+		return nil, errors.New("LLM_SUMMARY requires LLM_BASE_URL to be set")
+	}
+
+	cache, err := loadFileCache[string](os.Getenv("LLM_CACHE_FILE"))
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := utils.GetEnvInt("LLM_CONCURRENCY", 4)
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &summaryEnricher{
+		llm:         llm,
+		concurrency: concurrency,
+		cache:       cache,
+	}, nil
+}
+
+func (s *summaryEnricher) Name() string { return "llm_summary" }
+
+// StartRun resets the LLM cost caps, which apply per run.
+func (s *summaryEnricher) StartRun() { s.llm.resetBudget() }
+
+func (s *summaryEnricher) Enrich(ctx context.Context, articles []model.Article) error {
+	var (
+		wg      sync.WaitGroup
+		sem     = make(chan struct{}, s.concurrency)
+		cached  atomic.Int64
+		created atomic.Int64
+		failed  atomic.Int64
+	)
+
+	for i := range articles {
+		a := &articles[i]
+		if a.LLMSummary != "" {
+			continue
+		}
+
+		key := utils.ContentHash(a.Title, a.Description)
+		if summary, ok := s.cache.get(key); ok {
+			a.LLMSummary = summary
+			cached.Add(1)
+			continue
+		}
+
+		if s.llm.budgetExhausted() {
+			continue
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			summary, err := s.llm.complete(ctx, summaryPrompt, a.Title+"\n\n"+a.Description, 150)
+			if err != nil {
+				failed.Add(1)
+				log.Warn().Caller().Err(err).Str("id", a.ID).Msg("failed to generate llm summary")
+				return
+			}
+			a.LLMSummary = summary
+			s.cache.put(key, summary)
+			created.Add(1)
+		}()
+	}
+	wg.Wait()
+
+	if s.llm.budgetExhausted() {
+		log.Warn().Caller().Msgf("llm budget exhausted after %d requests and %d tokens, remaining articles left without summary",
+			s.llm.requests.Load(), s.llm.tokens.Load())
+	}
+	log.Info().Caller().Msgf("llm summaries: %d generated, %d from cache, %d failed", created.Load(), cached.Load(), failed.Load())
+
+	return s.cache.save()
+}
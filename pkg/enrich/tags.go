@@ -0,0 +1,135 @@
+package enrich
+
+import (
+	"context"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// tagsEnricher extracts the top keywords of each article by TF-IDF, using
+// the articles of the current run as the document corpus. Candidates are
+// single words and two word phrases that don't contain stopwords.
+type tagsEnricher struct {
+	topN int
+}
+
+// newTagsEnricherFromEnv returns nil unless TAGS is enabled.
+func newTagsEnricherFromEnv() *tagsEnricher {
+	if os.Getenv("TAGS") != "true" {
+		return nil
+	}
+	topN := utils.GetEnvInt("TAGS_TOP_N", 5)
+	if topN < 1 {
+		topN = 1
+	}
+	return &tagsEnricher{topN: topN}
+}
+
+func (t *tagsEnricher) Name() string { return "tags" }
+
+func (t *tagsEnricher) Enrich(_ context.Context, articles []model.Article) error {
+	candidates := make([]map[string]int, len(articles))
+	docFreq := map[string]int{}
+	for i := range articles {
+		candidates[i] = tagCandidates(articles[i].Title + "\n" + articles[i].Description)
+		for term := range candidates[i] {
+			docFreq[term]++
+		}
+	}
+
+	var tagged int
+	total := float64(len(articles))
+	for i := range articles {
+		a := &articles[i]
+		if len(a.Tags) > 0 {
+			continue
+		}
+
+		type scored struct {
+			term  string
+			score float64
+		}
+		var terms []scored
+		for term, tf := range candidates[i] {
+			// Terms found in every document have no discriminating power.
+			idf := math.Log(total / float64(docFreq[term]))
+			if idf <= 0 {
+				continue
+			}
+			score := float64(tf) * idf
+			if strings.Contains(term, " ") {
+				// Phrases are more descriptive than their individual words.
+				score *= 1.5
+			}
+			terms = append(terms, scored{term, score})
+		}
+		sort.Slice(terms, func(i, j int) bool {
+			if terms[i].score != terms[j].score {
+				return terms[i].score > terms[j].score
+			}
+			return terms[i].term < terms[j].term
+		})
+
+		for _, s := range terms {
+			if len(a.Tags) == t.topN {
+				break
+			}
+			if !coveredByTags(s.term, a.Tags) {
+				a.Tags = append(a.Tags, s.term)
+			}
+		}
+		if len(a.Tags) > 0 {
+			tagged++
+		}
+	}
+
+	log.Info().Caller().Msgf("extracted tags for %d articles", tagged)
+	return nil
+}
+
+// tagCandidates counts the keyword candidates in text.
+func tagCandidates(text string) map[string]int {
+	counts := map[string]int{}
+	var prev string
+	for _, token := range utils.Tokenize(text) {
+		token = strings.Trim(token, "'")
+		if !isTagWord(token) {
+			prev = ""
+			continue
+		}
+		counts[token]++
+		if prev != "" {
+			counts[prev+" "+token]++
+		}
+		prev = token
+	}
+	return counts
+}
+
+func isTagWord(token string) bool {
+	if len(token) < 3 || utils.IsStopword(token) {
+		return false
+	}
+	// Skip plain numbers, they make poor topics.
+	return strings.IndexFunc(token, unicode.IsLetter) >= 0
+}
+
+// coveredByTags reports whether term shares a word with an already chosen
+// tag, so "virat kohli" and "kohli" are not both selected.
+func coveredByTags(term string, tags []string) bool {
+	for _, tag := range tags {
+		for _, word := range strings.Fields(term) {
+			if strings.Contains(" "+tag+" ", " "+word+" ") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+// Package model defines the news article as it flows through the syncer,
+// from input files through enrichment to the index.
+package model
+
+import "encoding/json"
+
+// Article is a single news article. The JSON tags match both the input
+// format and the indexed document; the es tags define the index mapping
+// of each field, see sink.BuildIndexBody. Struct fields are mapped as
+// objects of their own fields.
+type Article struct {
+	ID              string   `json:"id" es:"type:keyword"`
+	Title           string   `json:"title" es:"type:text,analyzer:news_text,keyword.ignore_above:256,suggest"`
+	Description     string   `json:"description" es:"type:text,analyzer:news_text"`
+	URL             string   `json:"url" es:"type:keyword,ignore_above:2048"`
+	PublicationDate string   `json:"publication_date" es:"type:date"`
+	SourceName      string   `json:"source_name" es:"type:text,analyzer:news_text,keyword.normalizer:keyword_lowercase"`
+	Category        []string `json:"category" es:"type:text,analyzer:news_text,keyword.normalizer:keyword_lowercase"`
+	RelevanceScore  float64  `json:"relevance_score" es:"type:float"`
+	// SourceTrust is the trust score of SourceName from the source
+	// registry, nil for unregistered sources.
+	SourceTrust  *float64   `json:"source_trust,omitempty" es:"type:float"`
+	Latitude     float64    `json:"latitude,omitempty" es:"type:float"`
+	Longitude    float64    `json:"longitude,omitempty" es:"type:float"`
+	LocationName string     `json:"location_name,omitempty" es:"type:keyword"`
+	LLMSummary   string     `json:"llm_summary,omitempty" es:"type:text,analyzer:news_text"`
+	Embedding    []float32  `json:"embedding,omitempty" es:"-"`
+	Entities     *Entities  `json:"entities,omitempty"`
+	Sentiment    *Sentiment `json:"sentiment,omitempty"`
+	Country      string     `json:"country,omitempty" es:"type:keyword"`
+	State        string     `json:"state,omitempty" es:"type:keyword"`
+	City         string     `json:"city,omitempty" es:"type:keyword"`
+	Tags         []string   `json:"tags,omitempty" es:"type:keyword,normalizer:keyword_lowercase"`
+	CanonicalURL string     `json:"canonical_url,omitempty" es:"type:keyword,ignore_above:2048"`
+	Author       string     `json:"author,omitempty" es:"type:text,analyzer:news_text,keyword.ignore_above:256"`
+	ImageURL     string     `json:"image_url,omitempty" es:"type:keyword,index:false"`
+	IsPaywalled  *bool      `json:"is_paywalled,omitempty" es:"type:boolean"` // nil when the source doesn't say
+	WordCount    int        `json:"word_count,omitempty" es:"type:integer"`
+	ReadingTime  int        `json:"reading_time,omitempty" es:"type:integer"` // in minutes
+	// Geohash is the geohash of the coordinates, at the precision the
+	// geohash enricher is configured with, to bucket articles by.
+	Geohash string `json:"geohash,omitempty" es:"type:keyword"`
+	// Region is the code of the state or country an article is about as a
+	// whole, e.g. "IN-MH", and RegionShape its boundary as a GeoJSON
+	// geometry, filled in by the regions enricher.
+	Region      string          `json:"region,omitempty" es:"type:keyword"`
+	RegionShape json.RawMessage `json:"region_shape,omitempty" es:"type:geo_shape"`
+	// Extra holds input fields the model has no field for, when the
+	// source is read with extra fields kept.
+	Extra map[string]interface{} `json:"extra,omitempty" es:"type:object,dynamic:true"`
+	// IngestedAt, SyncRunID and SourceFile trace the article back to the
+	// sync that wrote it. They are set by the syncer, not read from input.
+	IngestedAt string `json:"ingested_at,omitempty" es:"type:date"`
+	SyncRunID  string `json:"sync_run_id,omitempty" es:"type:keyword"`
+	SourceFile string `json:"source_file,omitempty" es:"type:keyword,ignore_above:2048"`
+	// Access holds the document level security fields of the article,
+	// e.g. visibility, region or license, which search roles filter on.
+	// It is set by the syncer and mapped from its configuration.
+	Access map[string]string `json:"access,omitempty" es:"-"`
+	// RelatedIDs are the IDs of the most related articles, stored after
+	// the sync with --related. The syncer doesn't write them.
+	RelatedIDs []string `json:"related_ids,omitempty" es:"type:keyword"`
+	// ExpiresAt is when the license of the article runs out, after which
+	// it is purged. The syncer sets it from the license window of the
+	// source unless the input expires it earlier.
+	ExpiresAt string `json:"expires_at,omitempty" es:"type:date"`
+	// StoryID groups the articles covering the same event, and is the ID
+	// of the canonical article of the story, which has StoryCanonical
+	// set. StorySources counts the sources covering it. They are set by
+	// the stories enricher.
+	StoryID        string `json:"story_id,omitempty" es:"type:keyword"`
+	StoryCanonical bool   `json:"story_canonical,omitempty" es:"type:boolean"`
+	StorySources   int    `json:"story_sources,omitempty" es:"type:integer"`
+	// Tenant is the brand the article was synced for, when several share
+	// one deployment. It is set by the syncer too.
+	Tenant string `json:"tenant,omitempty" es:"type:keyword"`
+}
+
+// Entities are the named entities mentioned in an article.
+type Entities struct {
+	Person   []string `json:"person,omitempty" es:"type:keyword"`
+	Org      []string `json:"org,omitempty" es:"type:keyword"`
+	Location []string `json:"location,omitempty" es:"type:keyword"`
+}
+
+// Empty reports whether no entity of any type is set. It is safe to call on nil.
+func (e *Entities) Empty() bool {
+	return e == nil || len(e.Person)+len(e.Org)+len(e.Location) == 0
+}
+
+// Sentiment labels.
+const (
+	SentimentPositive = "positive"
+	SentimentNegative = "negative"
+	SentimentNeutral  = "neutral"
+)
+
+// Sentiment is the tone of an article. Score ranges from -1 (negative) to 1 (positive).
+type Sentiment struct {
+	Label string  `json:"label" es:"type:keyword"`
+	Score float64 `json:"score" es:"type:float"`
+}
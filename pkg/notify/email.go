@@ -0,0 +1,167 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	syncpkg "inshorts.com/inshorts-news-data-syncer/pkg/sync"
+)
+
+// Email mails run summaries through an SMTP server, with the report
+// attached as report.json and report.csv.
+type Email struct {
+	// addr is the host:port of the server, which is asked for STARTTLS
+	// when it offers it.
+	addr     string
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+// NewEmail returns a notifier mailing to through the server at addr,
+// authenticating when username is set.
+func NewEmail(addr, username, password, from string, to []string) *Email {
+	return &Email{addr: addr, username: username, password: password, from: from, to: to}
+}
+
+func (e *Email) NotifyRun(ctx context.Context, r *syncpkg.Report) error {
+	msg, err := e.message(r)
+	if err != nil {
+		return err
+	}
+	return e.send(ctx, msg)
+}
+
+// message builds the multipart mail of r.
+func (e *Email) message(r *syncpkg.Report) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s\n\n", title(r))
+	for _, f := range fields(r) {
+		fmt.Fprintf(&text, "%s: %s\n", f.label, f.value)
+	}
+	part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+	if err != nil {
+		return nil, err
+	}
+	part.Write([]byte(text.String()))
+
+	report, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := attach(mw, "report.json", "application/json", report); err != nil {
+		return nil, err
+	}
+	if err := attach(mw, "report.csv", "text/csv", reportCSV(r)); err != nil {
+		return nil, err
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", title(r)))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+func attach(mw *multipart.Writer, name, contentType string, data []byte) error {
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":        {contentType},
+		"Content-Disposition": {mime.FormatMediaType("attachment", map[string]string{"filename": name})},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(data)
+	return err
+}
+
+// reportCSV is a header row and the row of r, for spreadsheets that
+// collect the reports of many runs.
+func reportCSV(r *syncpkg.Report) []byte {
+	rows := [][]string{
+		{"run_id", "job", "started_at", "duration_ms", "loaded", "indexed", "failed", "dead_lettered", "bad_dates", "missing_ids", "pruned", "expired", "ok", "error"},
+		{
+			r.RunID, r.Job, r.StartedAt.Format(time.RFC3339), strconv.FormatInt(r.DurationMs, 10),
+			strconv.Itoa(r.Loaded), strconv.Itoa(r.Indexed), strconv.Itoa(r.Failed), strconv.Itoa(r.DeadLettered),
+			strconv.Itoa(r.BadDates), strconv.Itoa(r.MissingIDs), strconv.Itoa(r.Pruned), strconv.Itoa(r.Expired), strconv.FormatBool(r.OK()), r.Error,
+		},
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.WriteAll(rows)
+	return buf.Bytes()
+}
+
+// send delivers msg, giving up when ctx is done.
+func (e *Email) send(ctx context.Context, msg []byte) error {
+	host, _, err := net.SplitHostPort(e.addr)
+	if err != nil {
+		return err
+	}
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", e.addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if e.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.username, e.password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(e.from); err != nil {
+		return err
+	}
+	for _, to := range e.to {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("recipient %s: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
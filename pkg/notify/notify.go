@@ -0,0 +1,97 @@
+// Package notify tells people how sync runs went, e.g. by posting a
+// summary to a chat webhook, so failed nightly syncs are noticed without
+// reading logs.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	syncpkg "inshorts.com/inshorts-news-data-syncer/pkg/sync"
+)
+
+// Multi tells every notifier, returning their errors joined.
+type Multi []syncpkg.Notifier
+
+func (m Multi) NotifyRun(ctx context.Context, r *syncpkg.Report) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.NotifyRun(ctx, r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FailuresOnly passes only the runs that didn't succeed to n, for
+// frequently scheduled syncs.
+func FailuresOnly(n syncpkg.Notifier) syncpkg.Notifier {
+	return failuresOnly{n}
+}
+
+type failuresOnly struct{ syncpkg.Notifier }
+
+func (f failuresOnly) NotifyRun(ctx context.Context, r *syncpkg.Report) error {
+	if r.OK() {
+		return nil
+	}
+	return f.Notifier.NotifyRun(ctx, r)
+}
+
+// title is the one line summary of r, e.g. "sync nightly failed on
+// host-1".
+func title(r *syncpkg.Report) string {
+	name := "sync"
+	if r.Job != "" {
+		name += " " + r.Job
+	}
+	outcome := "succeeded"
+	if !r.OK() {
+		outcome = "failed"
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s %s on %s", name, outcome, host)
+}
+
+// field is a labelled figure of a summary.
+type field struct {
+	label, value string
+}
+
+// fields are the figures of r worth a glance, leaving out the counts
+// that are zero.
+func fields(r *syncpkg.Report) []field {
+	fs := []field{
+		{"Run", r.RunID},
+		{"Duration", (time.Duration(r.DurationMs) * time.Millisecond).String()},
+		{"Loaded", fmt.Sprint(r.Loaded)},
+		{"Indexed", fmt.Sprint(r.Indexed)},
+	}
+	counts := []struct {
+		label string
+		n     int
+	}{
+		{"Failed", r.Failed},
+		{"Dead lettered", r.DeadLettered},
+		{"Bad dates", r.BadDates},
+		{"Missing IDs", r.MissingIDs},
+		{"Pruned", r.Pruned},
+		{"Expired", r.Expired},
+		{"Related", r.Related},
+	}
+	for _, c := range counts {
+		if c.n > 0 {
+			fs = append(fs, field{c.label, fmt.Sprint(c.n)})
+		}
+	}
+	if r.Consistent != nil && !*r.Consistent {
+		fs = append(fs, field{"Sinks", "inconsistent"})
+	}
+	if r.Error != "" {
+		fs = append(fs, field{"Error", r.Error})
+	}
+	return fs
+}
@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	syncpkg "inshorts.com/inshorts-news-data-syncer/pkg/sync"
+)
+
+// Webhook posts run summaries as a Slack incoming webhook message: a
+// text line, which Teams and most chat webhooks also accept, and an
+// attachment with the figures.
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook returns a notifier posting to url.
+func NewWebhook(url string) *Webhook {
+	return &Webhook{url: url, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Fields []slackField `json:"fields"`
+	Ts     int64        `json:"ts"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+func (w *Webhook) NotifyRun(ctx context.Context, r *syncpkg.Report) error {
+	msg := slackMessage{Text: ":white_check_mark: " + title(r)}
+	attachment := slackAttachment{Color: "good", Ts: r.FinishedAt.Unix()}
+	if !r.OK() {
+		msg.Text = ":x: " + title(r)
+		attachment.Color = "danger"
+	}
+	for _, f := range fields(r) {
+		attachment.Fields = append(attachment.Fields, slackField{Title: f.label, Value: f.value, Short: f.label != "Error"})
+	}
+	msg.Attachments = []slackAttachment{attachment}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		data, _ := io.ReadAll(io.LimitReader(res.Body, 4<<10))
+		return fmt.Errorf("webhook returned %s: %s", res.Status, bytes.TrimSpace(data))
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+)
+
+// MaxTilePrecision is the highest zoom level of geotile_grid.
+const MaxTilePrecision = 29
+
+// HeatmapQuery selects the articles Heatmap counts and how finely.
+type HeatmapQuery struct {
+	Since      time.Time
+	Until      time.Time
+	Categories []string
+	// Precision is the zoom level of the tiles, 0 to MaxTilePrecision.
+	Precision int
+	// Tiles is the maximum number of tiles, the most populated first,
+	// 10000 when zero.
+	Tiles int
+	// Top is the number of categories counted per tile.
+	Top int
+}
+
+// TileCollection is a GeoJSON FeatureCollection with a polygon feature
+// per map tile, which a map frontend can draw as is.
+type TileCollection struct {
+	Type     string        `json:"type"`
+	Features []TileFeature `json:"features"`
+}
+
+// TileFeature is the GeoJSON feature of one tile.
+type TileFeature struct {
+	Type       string         `json:"type"`
+	Geometry   TileGeometry   `json:"geometry"`
+	Properties TileProperties `json:"properties"`
+}
+
+// TileGeometry is the polygon of a tile, in GeoJSON longitude, latitude
+// order.
+type TileGeometry struct {
+	Type        string         `json:"type"`
+	Coordinates [][][2]float64 `json:"coordinates"`
+}
+
+// TileProperties are what a tile holds.
+type TileProperties struct {
+	// Tile is the "zoom/x/y" key of the tile.
+	Tile     string `json:"tile"`
+	Articles int    `json:"articles"`
+	// Categories counts the articles of the tile per category.
+	Categories map[string]int `json:"categories"`
+}
+
+// Heatmap counts the located articles matching q per map tile and
+// category, with a geotile_grid aggregation.
+func Heatmap(ctx context.Context, es *elasticsearch.Client, index string, q HeatmapQuery) (*TileCollection, error) {
+	if q.Precision < 0 || q.Precision > MaxTilePrecision {
+		return nil, fmt.Errorf("tile precision must be between 0 and %d", MaxTilePrecision)
+	}
+	tiles := q.Tiles
+	if tiles == 0 {
+		tiles = 10000
+	}
+	top := q.Top
+	if top == 0 {
+		top = DefaultSize
+	}
+
+	filter := Query{Since: q.Since, Until: q.Until, Categories: q.Categories}.filter()
+	filter = append(filter, map[string]interface{}{"exists": map[string]interface{}{"field": "location"}})
+	body, err := json.Marshal(map[string]interface{}{
+		"size":  0,
+		"query": map[string]interface{}{"bool": map[string]interface{}{"filter": filter}},
+		"aggs": map[string]interface{}{
+			"tiles": map[string]interface{}{
+				"geotile_grid": map[string]interface{}{"field": "location", "precision": q.Precision, "size": tiles},
+				"aggs": map[string]interface{}{
+					"categories": map[string]interface{}{"terms": map[string]interface{}{"field": "category.keyword", "size": top}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := es.Search(
+		es.Search.WithContext(ctx),
+		es.Search.WithIndex(index),
+		es.Search.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, errors.New(res.String())
+	}
+
+	var resp struct {
+		Aggregations struct {
+			Tiles struct {
+				Buckets []struct {
+					Key        string `json:"key"`
+					DocCount   int    `json:"doc_count"`
+					Categories struct {
+						Buckets []struct {
+							Key      string `json:"key"`
+							DocCount int    `json:"doc_count"`
+						} `json:"buckets"`
+					} `json:"categories"`
+				} `json:"buckets"`
+			} `json:"tiles"`
+		} `json:"aggregations"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, err
+	}
+
+	heatmap := &TileCollection{Type: "FeatureCollection", Features: []TileFeature{}}
+	for _, b := range resp.Aggregations.Tiles.Buckets {
+		polygon, err := tilePolygon(b.Key)
+		if err != nil {
+			return nil, err
+		}
+		categories := make(map[string]int, len(b.Categories.Buckets))
+		for _, c := range b.Categories.Buckets {
+			categories[c.Key] = c.DocCount
+		}
+		heatmap.Features = append(heatmap.Features, TileFeature{
+			Type:       "Feature",
+			Geometry:   TileGeometry{Type: "Polygon", Coordinates: [][][2]float64{polygon}},
+			Properties: TileProperties{Tile: b.Key, Articles: b.DocCount, Categories: categories},
+		})
+	}
+	return heatmap, nil
+}
+
+// tilePolygon returns the closed ring of the corners of the web mercator
+// tile "zoom/x/y".
+func tilePolygon(key string) ([][2]float64, error) {
+	parts := strings.Split(key, "/")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid tile %q", key)
+	}
+	var zxy [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tile %q", key)
+		}
+		zxy[i] = n
+	}
+	n := math.Exp2(float64(zxy[0]))
+	lon := func(x int) float64 { return float64(x)/n*360 - 180 }
+	lat := func(y int) float64 { return math.Atan(math.Sinh(math.Pi*(1-2*float64(y)/n))) * 180 / math.Pi }
+	west, east := lon(zxy[1]), lon(zxy[1]+1)
+	north, south := lat(zxy[2]), lat(zxy[2]+1)
+	return [][2]float64{{west, south}, {east, south}, {east, north}, {west, north}, {west, south}}, nil
+}
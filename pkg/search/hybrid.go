@@ -0,0 +1,235 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Ranking weights the signals of a hybrid query. The final score is
+//
+//	(Text*bm25 + Recency*decay(publication_date) + Relevance*log1p(relevance_score) + Trust*source_trust) * SourceWeights[source_name] + Vector*knn
+//
+// so consumers share one ranking formula instead of reimplementing it.
+// A Script, if any, rescores the matches, with _score bound to bm25.
+type Ranking struct {
+	Text   float64 `yaml:"text"`
+	Vector float64 `yaml:"vector"`
+	// Recency weights a gauss decay on publication_date that halves every
+	// RecencyScale, e.g. "7d".
+	Recency      float64 `yaml:"recency"`
+	RecencyScale string  `yaml:"recency_scale"`
+	Relevance    float64 `yaml:"relevance"`
+	// Trust weights the source_trust of the source registry. Sources
+	// without one count as 0.5.
+	Trust float64 `yaml:"trust"`
+	// SourceWeights multiply the score of articles by their source, e.g.
+	// to trust wire agencies over aggregators. Unlisted sources keep 1.
+	SourceWeights map[string]float64 `yaml:"source_weights"`
+	// Script optionally replaces the text score with a painless
+	// script_score, e.g. "_score * Math.log(2 + doc['word_count'].value)".
+	Script *Script `yaml:"script"`
+	// RuntimeFields are computed per search and can be used by Script.
+	RuntimeFields map[string]RuntimeField `yaml:"runtime_fields"`
+}
+
+// Script is a painless script and its parameters.
+type Script struct {
+	Source string                 `yaml:"source" json:"source"`
+	Params map[string]interface{} `yaml:"params" json:"params,omitempty"`
+}
+
+// RuntimeField is a field computed at search time by a painless script
+// that calls emit, e.g. a "hours_old" double.
+type RuntimeField struct {
+	Type   string `yaml:"type" json:"type"`
+	Script string `yaml:"script" json:"-"`
+}
+
+// LoadRanking reads a ranking profile from a YAML file such as
+//
+//	text: 1
+//	recency: 0.8
+//	recency_scale: 2d
+//	source_weights:
+//	  pti: 1.3
+//	  aggregator-x: 0.6
+//
+// Weights the file leaves out keep their DefaultRanking value.
+func LoadRanking(path string) (*Ranking, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ranking := DefaultRanking
+	if err := yaml.Unmarshal(data, &ranking); err != nil {
+		return nil, err
+	}
+	if ranking.Text < 0 || ranking.Vector < 0 || ranking.Recency < 0 || ranking.Relevance < 0 || ranking.Trust < 0 {
+		return nil, errors.New("weights must not be negative")
+	}
+	weights := make(map[string]float64, len(ranking.SourceWeights))
+	for source, weight := range ranking.SourceWeights {
+		if weight < 0 {
+			return nil, fmt.Errorf("weight of source %q must not be negative", source)
+		}
+		// source_name.keyword is lowercased
+		weights[strings.ToLower(source)] = weight
+	}
+	ranking.SourceWeights = weights
+	if ranking.Script != nil && ranking.Script.Source == "" {
+		return nil, errors.New("script needs a source")
+	}
+	for name, field := range ranking.RuntimeFields {
+		if field.Type == "" || field.Script == "" {
+			return nil, fmt.Errorf("runtime field %q needs a type and a script", name)
+		}
+	}
+	return &ranking, nil
+}
+
+// runtimeMappings returns the runtime_mappings section of a search
+// request, nil without runtime fields.
+func (r Ranking) runtimeMappings() map[string]interface{} {
+	if len(r.RuntimeFields) == 0 {
+		return nil
+	}
+	mappings := make(map[string]interface{}, len(r.RuntimeFields))
+	for name, field := range r.RuntimeFields {
+		mappings[name] = map[string]interface{}{
+			"type":   field.Type,
+			"script": map[string]string{"source": field.Script},
+		}
+	}
+	return mappings
+}
+
+// DefaultRanking favours text and semantic matches, nudged by freshness,
+// the editorial relevance score and source trust.
+var DefaultRanking = Ranking{
+	Text:         1,
+	Vector:       1,
+	Recency:      0.5,
+	RecencyScale: "7d",
+	Relevance:    0.2,
+	Trust:        0.2,
+}
+
+// numCandidatesFactor is how many candidates per shard kNN considers for
+// each requested result.
+const numCandidatesFactor = 10
+
+// rank wraps the bool query in a function_score adding the recency and
+// relevance boosts to the text score, then weights it by source.
+func (r Ranking) rank(query map[string]interface{}) map[string]interface{} {
+	if r.Script != nil {
+		query = map[string]interface{}{
+			"script_score": map[string]interface{}{
+				"query":  query,
+				"script": r.Script,
+			},
+		}
+	}
+	return r.weighBySource(r.boost(query))
+}
+
+// weighBySource multiplies the score of query by the source weights.
+func (r Ranking) weighBySource(query map[string]interface{}) map[string]interface{} {
+	if len(r.SourceWeights) == 0 {
+		return query
+	}
+	functions := make([]interface{}, 0, len(r.SourceWeights))
+	for source, weight := range r.SourceWeights {
+		functions = append(functions, map[string]interface{}{
+			"filter": map[string]interface{}{"term": map[string]string{"source_name.keyword": source}},
+			"weight": weight,
+		})
+	}
+	return map[string]interface{}{
+		"function_score": map[string]interface{}{
+			"query":      query,
+			"functions":  functions,
+			"score_mode": "first",
+			"boost_mode": "multiply",
+		},
+	}
+}
+
+// boost adds the recency and relevance boosts to the score of query.
+func (r Ranking) boost(query map[string]interface{}) map[string]interface{} {
+	var functions []interface{}
+	if r.Recency > 0 {
+		scale := r.RecencyScale
+		if scale == "" {
+			scale = DefaultRanking.RecencyScale
+		}
+		functions = append(functions, map[string]interface{}{
+			"gauss": map[string]interface{}{
+				"publication_date": map[string]interface{}{
+					"origin": "now",
+					"scale":  scale,
+					"decay":  0.5,
+				},
+			},
+			"weight": r.Recency,
+		})
+	}
+	if r.Relevance > 0 {
+		functions = append(functions, map[string]interface{}{
+			"field_value_factor": map[string]interface{}{
+				"field":    "relevance_score",
+				"modifier": "log1p",
+				"missing":  0,
+			},
+			"weight": r.Relevance,
+		})
+	}
+	if r.Trust > 0 {
+		functions = append(functions, map[string]interface{}{
+			"field_value_factor": map[string]interface{}{
+				"field":   "source_trust",
+				"missing": 0.5,
+			},
+			"weight": r.Trust,
+		})
+	}
+
+	if r.Text != 1 {
+		query = map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must":  query,
+				"boost": r.Text,
+			},
+		}
+	}
+	if len(functions) == 0 {
+		return query
+	}
+	return map[string]interface{}{
+		"function_score": map[string]interface{}{
+			"query":      query,
+			"functions":  functions,
+			"score_mode": "sum",
+			"boost_mode": "sum",
+		},
+	}
+}
+
+// knn returns the approximate nearest neighbour clause over the article
+// embeddings, restricted by the same filters as the text query.
+func (r Ranking) knn(vector []float32, size int, filter []interface{}) map[string]interface{} {
+	knn := map[string]interface{}{
+		"field":          "embedding",
+		"query_vector":   vector,
+		"k":              size,
+		"num_candidates": size * numCandidatesFactor,
+		"boost":          r.Vector,
+	}
+	if len(filter) > 0 {
+		knn["filter"] = filter
+	}
+	return knn
+}
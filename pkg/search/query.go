@@ -0,0 +1,338 @@
+// Package search builds and runs queries against the news index.
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/elastic/go-elasticsearch/v9/esapi"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// DefaultSize is the number of results returned when Query.Size is zero.
+const DefaultSize = 10
+
+// textFields are matched by free text, with the title weighted highest.
+var textFields = []string{"title^3", "llm_summary^2", "description"}
+
+// Query describes a search over the news index. Zero values are ignored.
+type Query struct {
+	Text       string
+	Categories []string
+	Sources    []string
+	Since      time.Time
+	Until      time.Time
+	Near       *GeoDistance
+	Size       int
+	// Languages also match the text in the language subfields of an index
+	// created with them, e.g. "title.hindi".
+	Languages []string
+	// Vector, the embedded query text, adds a kNN match over the article
+	// embeddings.
+	Vector []float32
+	// Routing limits the search to the shards of these routing values,
+	// for indices written with a routing field.
+	Routing []string
+	// Highlight returns the matching fragments of the title and
+	// description with every hit.
+	Highlight *Highlight
+	// Ranking turns the query into a hybrid one. Nil ranks by text alone,
+	// or by DefaultRanking when Vector is set.
+	Ranking *Ranking
+}
+
+// Highlight configures the fragments returned by a highlighted search.
+// Zero values use the defaults of Elasticsearch.
+type Highlight struct {
+	// PreTag and PostTag surround the matched terms, "<em>" and "</em>"
+	// by default.
+	PreTag  string
+	PostTag string
+	// FragmentSize is the length of description fragments in characters.
+	FragmentSize int
+	// Fragments is the maximum number of description fragments.
+	Fragments int
+}
+
+// body returns the highlight section of a search request. Titles are
+// short, so they are highlighted whole.
+func (h Highlight) body() map[string]interface{} {
+	description := map[string]interface{}{}
+	if h.FragmentSize > 0 {
+		description["fragment_size"] = h.FragmentSize
+	}
+	if h.Fragments > 0 {
+		description["number_of_fragments"] = h.Fragments
+	}
+	body := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"title":       map[string]interface{}{"number_of_fragments": 0},
+			"description": description,
+		},
+	}
+	if h.PreTag != "" {
+		body["pre_tags"] = []string{h.PreTag}
+	}
+	if h.PostTag != "" {
+		body["post_tags"] = []string{h.PostTag}
+	}
+	return body
+}
+
+// GeoDistance restricts results to articles within Distance of a point.
+type GeoDistance struct {
+	Lat, Lon float64
+	// Distance uses Elasticsearch units, e.g. "50km".
+	Distance string
+}
+
+var distancePattern = regexp.MustCompile(`^\d+(\.\d+)?(km|m|mi|yd|ft|nmi)$`)
+
+// ParseNear parses "lat,lon,distance", e.g. "28.6,77.2,50km".
+func ParseNear(s string) (*GeoDistance, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("expected lat,lon,distance, got %q", s)
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid latitude: %w", err)
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid longitude: %w", err)
+	}
+	if !utils.ValidCoordinates(lat, lon) {
+		return nil, fmt.Errorf("invalid coordinates %v,%v", lat, lon)
+	}
+	distance := strings.TrimSpace(parts[2])
+	if !distancePattern.MatchString(distance) {
+		return nil, fmt.Errorf("invalid distance %q, expected e.g. 50km", distance)
+	}
+	return &GeoDistance{Lat: lat, Lon: lon, Distance: distance}, nil
+}
+
+// Body returns the search request body.
+func (q Query) Body() map[string]interface{} {
+	size := q.Size
+	if size == 0 {
+		size = DefaultSize
+	}
+	body := map[string]interface{}{
+		"size":  size,
+		"query": q.query(),
+		"sort":  []interface{}{"_score", map[string]string{"publication_date": "desc"}},
+		// Vectors are large and of no use to readers of the results
+		"_source": map[string]interface{}{"excludes": []string{"embedding"}},
+	}
+	if q.Highlight != nil {
+		body["highlight"] = q.Highlight.body()
+	}
+	ranking := q.Ranking
+	if ranking == nil && len(q.Vector) > 0 {
+		ranking = &DefaultRanking
+	}
+	if ranking != nil {
+		if mappings := ranking.runtimeMappings(); mappings != nil {
+			body["runtime_mappings"] = mappings
+		}
+		body["query"] = ranking.rank(q.query())
+		if len(q.Vector) > 0 {
+			body["knn"] = ranking.knn(q.Vector, size, q.filter())
+		}
+	}
+	return body
+}
+
+// query returns the bool query. Free text scores the results; every other
+// criterion is a non scoring filter.
+func (q Query) query() map[string]interface{} {
+	var must []interface{}
+	if q.Text != "" {
+		must = append(must, map[string]interface{}{
+			"multi_match": map[string]interface{}{
+				"query":  q.Text,
+				"fields": q.textFields(),
+			},
+		})
+	} else {
+		must = append(must, map[string]interface{}{"match_all": map[string]interface{}{}})
+	}
+
+	return map[string]interface{}{
+		"bool": map[string]interface{}{"must": must, "filter": q.filter()},
+	}
+}
+
+// textFields returns the fields matched by free text, including the
+// subfields of q.Languages weighted like their parent.
+func (q Query) textFields() []string {
+	fields := append([]string(nil), textFields...)
+	for _, language := range q.Languages {
+		for _, field := range textFields {
+			name, boost, _ := strings.Cut(field, "^")
+			if boost != "" {
+				boost = "^" + boost
+			}
+			fields = append(fields, name+"."+language+boost)
+		}
+	}
+	return fields
+}
+
+// filter returns the non scoring criteria of q.
+func (q Query) filter() []interface{} {
+	var filter []interface{}
+	if len(q.Categories) > 0 {
+		filter = append(filter, map[string]interface{}{
+			"terms": map[string]interface{}{"category.keyword": q.Categories},
+		})
+	}
+	if len(q.Sources) > 0 {
+		filter = append(filter, map[string]interface{}{
+			"terms": map[string]interface{}{"source_name.keyword": q.Sources},
+		})
+	}
+	if !q.Since.IsZero() || !q.Until.IsZero() {
+		dateRange := map[string]interface{}{}
+		if !q.Since.IsZero() {
+			dateRange["gte"] = q.Since.UTC().Format(time.RFC3339)
+		}
+		if !q.Until.IsZero() {
+			dateRange["lt"] = q.Until.UTC().Format(time.RFC3339)
+		}
+		filter = append(filter, map[string]interface{}{
+			"range": map[string]interface{}{"publication_date": dateRange},
+		})
+	}
+	if q.Near != nil {
+		filter = append(filter, map[string]interface{}{
+			"geo_distance": map[string]interface{}{
+				"distance": q.Near.Distance,
+				"location": map[string]float64{"lat": q.Near.Lat, "lon": q.Near.Lon},
+			},
+		})
+	}
+
+	return filter
+}
+
+// ErrNotFound is returned by Get for an unknown article.
+var ErrNotFound = errors.New("article not found")
+
+// Get returns the article with id. It searches by id rather than using
+// the get API, which needs the routing of documents written with one.
+func Get(ctx context.Context, es *elasticsearch.Client, index, id string) (*model.Article, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"size":    1,
+		"query":   map[string]interface{}{"ids": map[string]interface{}{"values": []string{id}}},
+		"_source": map[string]interface{}{"excludes": []string{"embedding"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := es.Search(
+		es.Search.WithContext(ctx),
+		es.Search.WithIndex(index),
+		es.Search.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if res.IsError() {
+		return nil, errors.New(res.String())
+	}
+
+	var resp struct {
+		Hits struct {
+			Hits []struct {
+				Source model.Article `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Hits.Hits) == 0 {
+		return nil, ErrNotFound
+	}
+	return &resp.Hits.Hits[0].Source, nil
+}
+
+// Hit is a single ranked result.
+type Hit struct {
+	ID      string        `json:"id"`
+	Score   float64       `json:"score"`
+	Article model.Article `json:"article"`
+	// Highlights holds the matching fragments per field of a highlighted
+	// search.
+	Highlights map[string][]string `json:"highlights,omitempty"`
+}
+
+// Results holds the ranked hits and the total number of matches.
+type Results struct {
+	Total int   `json:"total"`
+	Hits  []Hit `json:"hits"`
+}
+
+// Run executes q against index.
+func Run(ctx context.Context, es *elasticsearch.Client, index string, q Query) (*Results, error) {
+	body, err := json.Marshal(q.Body())
+	if err != nil {
+		return nil, err
+	}
+	opts := []func(*esapi.SearchRequest){
+		es.Search.WithContext(ctx),
+		es.Search.WithIndex(index),
+		es.Search.WithBody(bytes.NewReader(body)),
+		es.Search.WithTrackTotalHits(true),
+	}
+	if len(q.Routing) > 0 {
+		opts = append(opts, es.Search.WithRouting(q.Routing...))
+	}
+	res, err := es.Search(opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, errors.New(res.String())
+	}
+
+	var resp struct {
+		Hits struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				ID        string              `json:"_id"`
+				Score     float64             `json:"_score"`
+				Source    model.Article       `json:"_source"`
+				Highlight map[string][]string `json:"highlight"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, err
+	}
+
+	results := &Results{Total: resp.Hits.Total.Value, Hits: make([]Hit, len(resp.Hits.Hits))}
+	for i, h := range resp.Hits.Hits {
+		results.Hits[i] = Hit{ID: h.ID, Score: h.Score, Article: h.Source, Highlights: h.Highlight}
+	}
+	return results, nil
+}
@@ -0,0 +1,149 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+)
+
+// StatsQuery selects the articles summarised by Stats.
+type StatsQuery struct {
+	Since time.Time
+	Until time.Time
+	// Top is the number of sources and categories listed.
+	Top int
+}
+
+// Bucket is a value and the number of articles that have it.
+type Bucket struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// Bounds is the bounding box of the located articles.
+type Bounds struct {
+	TopLeft     Point `json:"top_left"`
+	BottomRight Point `json:"bottom_right"`
+}
+
+// Point is a geo coordinate as returned by Elasticsearch.
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// Summary describes the indexed articles, to sanity check a sync.
+type Summary struct {
+	Total      int      `json:"total"`
+	Located    int      `json:"located"`
+	Oldest     string   `json:"oldest,omitempty"`
+	Newest     string   `json:"newest,omitempty"`
+	Sources    []Bucket `json:"sources"`
+	Categories []Bucket `json:"categories"`
+	PerDay     []Bucket `json:"per_day"`
+	Bounds     *Bounds  `json:"bounds,omitempty"`
+}
+
+// Stats runs the aggregations behind a Summary in a single request.
+func Stats(ctx context.Context, es *elasticsearch.Client, index string, q StatsQuery) (*Summary, error) {
+	query := Query{Since: q.Since, Until: q.Until}.query()
+	top := q.Top
+	if top == 0 {
+		top = DefaultSize
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"size":             0,
+		"track_total_hits": true,
+		"query":            query,
+		"aggs": map[string]interface{}{
+			"sources":    map[string]interface{}{"terms": map[string]interface{}{"field": "source_name.keyword", "size": top}},
+			"categories": map[string]interface{}{"terms": map[string]interface{}{"field": "category.keyword", "size": top}},
+			"per_day": map[string]interface{}{"date_histogram": map[string]interface{}{
+				"field": "publication_date", "calendar_interval": "day", "format": "yyyy-MM-dd", "min_doc_count": 1,
+			}},
+			"oldest":  map[string]interface{}{"min": map[string]interface{}{"field": "publication_date"}},
+			"newest":  map[string]interface{}{"max": map[string]interface{}{"field": "publication_date"}},
+			"located": map[string]interface{}{"value_count": map[string]interface{}{"field": "location"}},
+			"bounds":  map[string]interface{}{"geo_bounds": map[string]interface{}{"field": "location"}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := es.Search(
+		es.Search.WithContext(ctx),
+		es.Search.WithIndex(index),
+		es.Search.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, errors.New(res.String())
+	}
+
+	type terms struct {
+		Buckets []struct {
+			Key         json.RawMessage `json:"key"`
+			KeyAsString string          `json:"key_as_string"`
+			DocCount    int             `json:"doc_count"`
+		} `json:"buckets"`
+	}
+	var resp struct {
+		Hits struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
+		} `json:"hits"`
+		Aggregations struct {
+			Sources    terms `json:"sources"`
+			Categories terms `json:"categories"`
+			PerDay     terms `json:"per_day"`
+			Oldest     struct {
+				ValueAsString string `json:"value_as_string"`
+			} `json:"oldest"`
+			Newest struct {
+				ValueAsString string `json:"value_as_string"`
+			} `json:"newest"`
+			Located struct {
+				Value int `json:"value"`
+			} `json:"located"`
+			Bounds struct {
+				Bounds *Bounds `json:"bounds"`
+			} `json:"bounds"`
+		} `json:"aggregations"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, err
+	}
+
+	buckets := func(t terms) []Bucket {
+		out := make([]Bucket, len(t.Buckets))
+		for i, b := range t.Buckets {
+			key := b.KeyAsString
+			if key == "" {
+				json.Unmarshal(b.Key, &key)
+			}
+			out[i] = Bucket{Key: key, Count: b.DocCount}
+		}
+		return out
+	}
+	aggs := resp.Aggregations
+	return &Summary{
+		Total:      resp.Hits.Total.Value,
+		Located:    aggs.Located.Value,
+		Oldest:     aggs.Oldest.ValueAsString,
+		Newest:     aggs.Newest.ValueAsString,
+		Sources:    buckets(aggs.Sources),
+		Categories: buckets(aggs.Categories),
+		PerDay:     buckets(aggs.PerDay),
+		Bounds:     aggs.Bounds.Bounds,
+	}, nil
+}
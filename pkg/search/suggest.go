@@ -0,0 +1,77 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/elastic/go-elasticsearch/v9"
+)
+
+// Suggestion is a title completing the typed prefix.
+type Suggestion struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// Suggest returns up to size titles starting with prefix from the
+// title.suggest completion field, for search as you type. Categories,
+// matched exactly as indexed, restrict the suggestions to articles in any
+// of them.
+func Suggest(ctx context.Context, es *elasticsearch.Client, index, prefix string, categories []string, size int) ([]Suggestion, error) {
+	if size == 0 {
+		size = DefaultSize
+	}
+	completion := map[string]interface{}{
+		"field":           "title.suggest",
+		"size":            size,
+		"skip_duplicates": true,
+	}
+	if len(categories) > 0 {
+		completion["contexts"] = map[string]interface{}{"category": categories}
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"_source": false,
+		"suggest": map[string]interface{}{
+			"title": map[string]interface{}{"prefix": prefix, "completion": completion},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := es.Search(
+		es.Search.WithContext(ctx),
+		es.Search.WithIndex(index),
+		es.Search.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, errors.New(res.String())
+	}
+
+	var resp struct {
+		Suggest struct {
+			Title []struct {
+				Options []struct {
+					ID   string `json:"_id"`
+					Text string `json:"text"`
+				} `json:"options"`
+			} `json:"title"`
+		} `json:"suggest"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, err
+	}
+	suggestions := []Suggestion{}
+	for _, entry := range resp.Suggest.Title {
+		for _, option := range entry.Options {
+			suggestions = append(suggestions, Suggestion{ID: option.ID, Title: option.Text})
+		}
+	}
+	return suggestions, nil
+}
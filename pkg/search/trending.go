@@ -0,0 +1,206 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+)
+
+// TrendingFields are the fields Trending looks for topics in by default.
+var TrendingFields = []string{"tags", "entities.person", "entities.org", "entities.location"}
+
+// TrendingQuery selects the articles Trending compares.
+type TrendingQuery struct {
+	// Window is how far back the recent articles go, e.g. 6h.
+	Window time.Duration
+	// Background is how far back the articles they are compared with go,
+	// the whole index when zero.
+	Background time.Duration
+	// Fields are keyword fields to find topics in, TrendingFields when
+	// empty.
+	Fields []string
+	// Top is the number of topics per field.
+	Top int
+	// MinArticles is the number of recent articles a topic needs, 3 when
+	// zero, so one-off tags don't trend.
+	MinArticles int
+}
+
+// Topic is a term that is unusually frequent in recent articles.
+type Topic struct {
+	Field string `json:"field"`
+	Term  string `json:"term"`
+	// Articles is how many recent articles have the term, Background
+	// how many of the background articles do.
+	Articles   int     `json:"articles"`
+	Background int     `json:"background"`
+	Score      float64 `json:"score"`
+}
+
+// Trends are the topics of one Trending computation, by descending score.
+type Trends struct {
+	ComputedAt string  `json:"computed_at"`
+	Window     string  `json:"window"`
+	Articles   int     `json:"articles"`
+	Topics     []Topic `json:"topics"`
+}
+
+// Trending finds the terms of q.Fields significantly more frequent in the
+// articles of the last q.Window than in the background, with a
+// significant_terms aggregation per field in one request.
+func Trending(ctx context.Context, es *elasticsearch.Client, index string, q TrendingQuery) (*Trends, error) {
+	if q.Window <= 0 {
+		return nil, errors.New("trending window must be positive")
+	}
+	fields := q.Fields
+	if len(fields) == 0 {
+		fields = TrendingFields
+	}
+	top := q.Top
+	if top == 0 {
+		top = DefaultSize
+	}
+	minArticles := q.MinArticles
+	if minArticles == 0 {
+		minArticles = 3
+	}
+
+	now := time.Now().UTC()
+	aggs := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		significant := map[string]interface{}{"field": field, "size": top, "min_doc_count": minArticles}
+		if q.Background > 0 {
+			significant["background_filter"] = publishedSince(now.Add(-q.Background))
+		}
+		aggs[field] = map[string]interface{}{"significant_terms": significant}
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"size":             0,
+		"track_total_hits": true,
+		"query":            map[string]interface{}{"bool": map[string]interface{}{"filter": publishedSince(now.Add(-q.Window))}},
+		"aggs":             aggs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := es.Search(
+		es.Search.WithContext(ctx),
+		es.Search.WithIndex(index),
+		es.Search.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, errors.New(res.String())
+	}
+
+	var resp struct {
+		Hits struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
+		} `json:"hits"`
+		Aggregations map[string]struct {
+			Buckets []struct {
+				Key      string  `json:"key"`
+				DocCount int     `json:"doc_count"`
+				BgCount  int     `json:"bg_count"`
+				Score    float64 `json:"score"`
+			} `json:"buckets"`
+		} `json:"aggregations"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, err
+	}
+
+	trends := &Trends{
+		ComputedAt: now.Format(time.RFC3339),
+		Window:     fmt.Sprint(q.Window),
+		Articles:   resp.Hits.Total.Value,
+		Topics:     []Topic{},
+	}
+	for _, field := range fields {
+		for _, b := range resp.Aggregations[field].Buckets {
+			trends.Topics = append(trends.Topics, Topic{Field: field, Term: b.Key, Articles: b.DocCount, Background: b.BgCount, Score: b.Score})
+		}
+	}
+	sort.SliceStable(trends.Topics, func(i, j int) bool { return trends.Topics[i].Score > trends.Topics[j].Score })
+	return trends, nil
+}
+
+// TrendsMapping is the index definition of the documents holding Trends.
+const TrendsMapping = `{
+  "mappings": {
+    "dynamic": "strict",
+    "properties": {
+      "computed_at": {"type": "date"},
+      "window": {"type": "keyword"},
+      "articles": {"type": "integer"},
+      "topics": {
+        "properties": {
+          "field": {"type": "keyword"},
+          "term": {"type": "keyword"},
+          "articles": {"type": "integer"},
+          "background": {"type": "integer"},
+          "score": {"type": "float"}
+        }
+      }
+    }
+  }
+}`
+
+// WriteTrends indexes t into index, creating it when missing, as a
+// document named after its computation time. Readers take the latest by
+// sorting on computed_at.
+func WriteTrends(ctx context.Context, es *elasticsearch.Client, index string, t *Trends) error {
+	exists, err := es.Indices.Exists([]string{index}, es.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	exists.Body.Close()
+	if exists.StatusCode == 404 {
+		res, err := es.Indices.Create(index, es.Indices.Create.WithBody(bytes.NewReader([]byte(TrendsMapping))), es.Indices.Create.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		// Another run may have just created it
+		if res.IsError() {
+			if body := res.String(); !strings.Contains(body, "resource_already_exists_exception") {
+				return fmt.Errorf("failed to create %s: %s", index, body)
+			}
+		}
+	}
+
+	doc, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	res, err := es.Index(index, bytes.NewReader(doc),
+		es.Index.WithDocumentID(t.ComputedAt),
+		es.Index.WithRefresh("true"),
+		es.Index.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("failed to write trends to %s: %s", index, res.String())
+	}
+	return nil
+}
+
+// publishedSince filters articles published at or after t.
+func publishedSince(t time.Time) map[string]interface{} {
+	return map[string]interface{}{"range": map[string]interface{}{"publication_date": map[string]interface{}{"gte": t.Format(time.RFC3339)}}}
+}
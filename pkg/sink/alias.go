@@ -0,0 +1,170 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/elastic/go-elasticsearch/v9/esapi"
+	"github.com/rs/zerolog/log"
+)
+
+// Alias is an alias of an index.
+type Alias struct {
+	Name  string `json:"alias"`
+	Index string `json:"index"`
+	// Write is set on the index an alias of several indices writes to.
+	Write    bool `json:"is_write_index,omitempty"`
+	Filtered bool `json:"filtered,omitempty"`
+}
+
+// ListAliases returns the aliases of the indices matching pattern, every
+// index when it's empty, sorted by alias and index.
+func ListAliases(ctx context.Context, client *elasticsearch.Client, pattern string) ([]Alias, error) {
+	req := client.Indices.GetAlias
+	opts := []func(*esapi.IndicesGetAliasRequest){req.WithContext(ctx)}
+	if pattern != "" {
+		opts = append(opts, req.WithIndex(pattern))
+	}
+	res, err := req(opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == 404 {
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("failed to list aliases: %s", res.String())
+	}
+	var indices map[string]struct {
+		Aliases map[string]struct {
+			Filter       json.RawMessage `json:"filter"`
+			IsWriteIndex bool            `json:"is_write_index"`
+		} `json:"aliases"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&indices); err != nil {
+		return nil, err
+	}
+	var aliases []Alias
+	for index, i := range indices {
+		for name, a := range i.Aliases {
+			aliases = append(aliases, Alias{Name: name, Index: index, Write: a.IsWriteIndex, Filtered: len(a.Filter) > 0})
+		}
+	}
+	sort.Slice(aliases, func(i, j int) bool {
+		if aliases[i].Name != aliases[j].Name {
+			return aliases[i].Name < aliases[j].Name
+		}
+		return aliases[i].Index < aliases[j].Index
+	})
+	return aliases, nil
+}
+
+// AliasUpdate points aliases at an index in one atomic request.
+type AliasUpdate struct {
+	Index string
+	// Read and Write name the read and write alias, either may be empty.
+	// The index becomes the write index of Write.
+	Read, Write string
+	// Move removes the aliases from the indices they point at so far,
+	// as when promoting a reindexed index.
+	Move bool
+}
+
+// UpdateAliases applies u, returning the indices the aliases were moved
+// away from.
+func UpdateAliases(ctx context.Context, client *elasticsearch.Client, u AliasUpdate) ([]string, error) {
+	if u.Read == "" && u.Write == "" {
+		return nil, errors.New("no alias given")
+	}
+	var actions []map[string]interface{}
+	var moved, changes []string
+	for _, name := range []string{u.Read, u.Write} {
+		if name == "" {
+			continue
+		}
+		if u.Move {
+			current, err := aliasIndices(ctx, client, name)
+			if err != nil {
+				return nil, err
+			}
+			for _, index := range current {
+				if index == u.Index {
+					continue
+				}
+				actions = append(actions, map[string]interface{}{"remove": map[string]interface{}{"index": index, "alias": name}})
+				if !slices.Contains(moved, index) {
+					moved = append(moved, index)
+				}
+				changes = append(changes, fmt.Sprintf("removed %s from %s", name, index))
+			}
+		}
+		add := map[string]interface{}{"index": u.Index, "alias": name}
+		if name == u.Write {
+			add["is_write_index"] = true
+		}
+		actions = append(actions, map[string]interface{}{"add": add})
+		changes = append(changes, fmt.Sprintf("added %s to %s", name, u.Index))
+	}
+	if err := updateAliases(ctx, client, actions); err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		log.Info().Caller().Msgf("alias %s", change)
+	}
+	return moved, nil
+}
+
+// RemoveAlias removes the alias name from index.
+func RemoveAlias(ctx context.Context, client *elasticsearch.Client, index, name string) error {
+	return updateAliases(ctx, client, []map[string]interface{}{
+		{"remove": map[string]interface{}{"index": index, "alias": name}},
+	})
+}
+
+func updateAliases(ctx context.Context, client *elasticsearch.Client, actions []map[string]interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"actions": actions})
+	if err != nil {
+		return err
+	}
+	res, err := client.Indices.UpdateAliases(bytes.NewReader(body), client.Indices.UpdateAliases.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("failed to update aliases: %s", res.String())
+	}
+	return nil
+}
+
+// aliasIndices returns the indices alias points at.
+func aliasIndices(ctx context.Context, client *elasticsearch.Client, alias string) ([]string, error) {
+	res, err := client.Indices.GetAlias(client.Indices.GetAlias.WithName(alias), client.Indices.GetAlias.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == 404 {
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("failed to look up alias %s: %s", alias, res.String())
+	}
+	var indices map[string]json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&indices); err != nil {
+		return nil, err
+	}
+	var names []string
+	for index := range indices {
+		names = append(names, index)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,276 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+// bulkBuffers recycles bulk request bodies between batches, so large
+// loads don't regrow a buffer of several megabytes for every request.
+var bulkBuffers = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// maxPooledBuffer keeps the odd huge batch from pinning its buffer.
+const maxPooledBuffer = 64 << 20
+
+// releaseBulkBody returns a body of bulkBody to the pool once the request
+// using it completed.
+func releaseBulkBody(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	bulkBuffers.Put(buf)
+}
+
+// bulkBody builds the bulk request body indexing articles into index,
+// routed by the value of routingField when set. The format is shared by
+// Elasticsearch and OpenSearch. Documents are encoded straight into a
+// pooled buffer, which the caller hands back with releaseBulkBody.
+func bulkBody(index string, articles []model.Article, routingField string) (*bytes.Buffer, error) {
+	buf := bulkBuffers.Get().(*bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	var withoutLocation int
+
+	// Every action line starts the same way, only the id and routing vary
+	prefix := appendJSONString([]byte(`{"index":{"_index":`), index)
+	prefix = append(prefix, `,"_id":`...)
+	meta := make([]byte, 0, 256)
+
+	for _, a := range articles {
+		formattedDate, err := publicationDate(a)
+		if err != nil {
+			releaseBulkBody(buf)
+			return nil, err
+		}
+		doc, missingLocation := document(a, formattedDate)
+		if missingLocation {
+			withoutLocation++
+		}
+
+		meta = appendJSONString(append(meta[:0], prefix...), a.ID)
+		if routing := routingValue(doc, routingField); routing != "" {
+			meta = appendJSONString(append(meta, `,"_routing":`...), routing)
+		}
+		meta = append(meta, "}}\n"...)
+		buf.Write(meta)
+
+		// Encode terminates the document with the newline bulk expects
+		if err := enc.Encode(doc); err != nil {
+			releaseBulkBody(buf)
+			return nil, err
+		}
+	}
+
+	if withoutLocation > 0 {
+		log.Warn().Caller().Msgf("%d articles indexed without location due to missing or invalid coordinates", withoutLocation)
+	}
+	return buf, nil
+}
+
+// dumpBatch writes the body of the batch-th bulk request of a sink to
+// dir, named like "elasticsearch-inshorts-news-000042.ndjson" so the
+// item failures logged with the batch number lead to it. A failed dump
+// only warns.
+func dumpBatch(dir, sinkName, index string, batch int64, body []byte) {
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s-%06d.ndjson", sinkName, index, batch))
+	if err := os.WriteFile(path, body, 0o644); err != nil {
+		log.Warn().Caller().Err(err).Msgf("failed to dump batch %d", batch)
+	}
+}
+
+// appendJSONString appends s to dst as a JSON string.
+func appendJSONString(dst []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	dst = append(dst, '"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			dst = append(dst, '\\', c)
+		case c < 0x20:
+			dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return append(dst, '"')
+}
+
+// routingValue returns the value of field in doc used as its routing
+// key: the field itself when it's a string, or its first value when it's
+// a list such as category. Routing keys are case insensitive.
+func routingValue(doc map[string]interface{}, field string) string {
+	if field == "" {
+		return ""
+	}
+	switch v := doc[field].(type) {
+	case string:
+		return strings.ToLower(v)
+	case []string:
+		if len(v) > 0 {
+			return strings.ToLower(v[0])
+		}
+	}
+	return ""
+}
+
+// bulkFilterPath slims bulk responses down to what bulkFailures reads, as
+// the full results of thousands of items dominate the response otherwise.
+var bulkFilterPath = []string{"errors", "items.*._id", "items.*.status", "items.*.error"}
+
+// bulkItem is the result of one action of a bulk request.
+type bulkItem struct {
+	ID     string `json:"_id"`
+	Status int    `json:"status"`
+	Error  *struct {
+		Type     string `json:"type"`
+		Reason   string `json:"reason"`
+		CausedBy struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"caused_by"`
+	} `json:"error,omitempty"`
+}
+
+// bulkFailures decodes the bulk response to the batch-th request. Rejected
+// items are logged and reported as a *PartialError rather than aborting
+// the run. Items are decoded one at a time, and not at all once the
+// response says none failed.
+func bulkFailures(body io.Reader, batch int64) error {
+	dec := json.NewDecoder(body)
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	// A batch may hold several articles with the same ID, so rejections
+	// are counted as they come rather than by rejected ID
+	failed := 0
+	rejected := make(map[string]string)
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "errors":
+			var anyFailed bool
+			if err := dec.Decode(&anyFailed); err != nil {
+				return err
+			}
+			if !anyFailed {
+				// Drain the rest so the connection can be reused
+				_, err := io.Copy(io.Discard, body)
+				return err
+			}
+		case "items":
+			if err := expectDelim(dec, '['); err != nil {
+				return err
+			}
+			for dec.More() {
+				var item map[string]bulkItem
+				if err := dec.Decode(&item); err != nil {
+					return err
+				}
+				for _, action := range item {
+					if e := action.Error; e != nil {
+						failed++
+						rejected[action.ID] = e.Type + ": " + e.Reason
+						logItemFailure(itemFailure{
+							id:          action.ID,
+							status:      action.Status,
+							batch:       batch,
+							errType:     e.Type,
+							reason:      e.Reason,
+							causeType:   e.CausedBy.Type,
+							causeReason: e.CausedBy.Reason,
+						})
+					}
+				}
+			}
+			if err := expectDelim(dec, ']'); err != nil {
+				return err
+			}
+		default:
+			var skipped json.RawMessage
+			if err := dec.Decode(&skipped); err != nil {
+				return err
+			}
+		}
+	}
+	if failed > 0 {
+		return &PartialError{Failed: failed, Rejected: rejected}
+	}
+	return nil
+}
+
+// expectDelim reads the next token of dec, which must be delim.
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("unexpected %v in bulk response, expected %v", token, delim)
+	}
+	return nil
+}
+
+// itemFailure is a document rejected by a bulk request.
+type itemFailure struct {
+	id                     string
+	status                 int
+	batch                  int64
+	errType, reason        string
+	causeType, causeReason string
+}
+
+// failedFieldPatterns find the offending field in the reasons of mapping
+// errors, e.g. "failed to parse field [latitude] of type [float]" or
+// "mapping set to strict, dynamic introduction of [foo] within [_doc] is
+// not allowed".
+var failedFieldPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`failed to parse field \[([^\]]+)\]`),
+	regexp.MustCompile(`dynamic introduction of \[([^\]]+)\]`),
+	regexp.MustCompile(`field \[([^\]]+)\]`),
+}
+
+// field returns the field the failure is about, empty when the reasons
+// don't name one.
+func (f itemFailure) field() string {
+	for _, pattern := range failedFieldPatterns {
+		for _, reason := range []string{f.reason, f.causeReason} {
+			if m := pattern.FindStringSubmatch(reason); m != nil {
+				return m[1]
+			}
+		}
+	}
+	return ""
+}
+
+// logItemFailure logs f as one structured entry, so rejected documents
+// can be found and grouped by error type.
+func logItemFailure(f itemFailure) {
+	event := log.Error().Caller().
+		Str("id", f.id).
+		Int("status", f.status).
+		Int64("batch", f.batch).
+		Str("error_type", f.errType).
+		Str("reason", f.reason)
+	if f.causeType != "" {
+		event = event.Str("caused_by_type", f.causeType).Str("caused_by_reason", f.causeReason)
+	}
+	if field := f.field(); field != "" {
+		event = event.Str("field", field)
+	}
+	event.Msg("bulk item failed")
+}
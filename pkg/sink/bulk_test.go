@@ -0,0 +1,287 @@
+package sink
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+func TestBulkBody(t *testing.T) {
+	tests := []struct {
+		name         string
+		articles     []model.Article
+		routingField string
+		// actions are the expected action lines, each followed by the
+		// document of the article
+		actions []string
+	}{
+		{
+			name:     "empty",
+			articles: nil,
+		},
+		{
+			name: "index actions",
+			articles: []model.Article{
+				{ID: "1", Title: "First", PublicationDate: "2024-03-01T10:00:00Z"},
+				{ID: "2", Title: "Second"},
+			},
+			actions: []string{
+				`{"index":{"_index":"news","_id":"1"}}`,
+				`{"index":{"_index":"news","_id":"2"}}`,
+			},
+		},
+		{
+			name:     "escaped id",
+			articles: []model.Article{{ID: "a\"b\\c\n"}},
+			actions:  []string{`{"index":{"_index":"news","_id":"a\"b\\c\u000a"}}`},
+		},
+		{
+			name:         "routed by source",
+			articles:     []model.Article{{ID: "1", SourceName: "PTI"}, {ID: "2"}},
+			routingField: "source_name",
+			actions: []string{
+				`{"index":{"_index":"news","_id":"1","_routing":"pti"}}`,
+				`{"index":{"_index":"news","_id":"2"}}`,
+			},
+		},
+		{
+			name:         "routed by first category",
+			articles:     []model.Article{{ID: "1", Category: []string{"Sports", "national"}}},
+			routingField: "category",
+			actions:      []string{`{"index":{"_index":"news","_id":"1","_routing":"sports"}}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := bulkBody("news", tt.articles, tt.routingField)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer releaseBulkBody(buf)
+
+			body := buf.String()
+			if len(tt.actions) == 0 {
+				if body != "" {
+					t.Fatalf("expected an empty body, got %q", body)
+				}
+				return
+			}
+			if !strings.HasSuffix(body, "\n") {
+				t.Error("body must end with a newline")
+			}
+			lines := strings.Split(strings.TrimSuffix(body, "\n"), "\n")
+			if len(lines) != 2*len(tt.actions) {
+				t.Fatalf("got %d lines, want %d:\n%s", len(lines), 2*len(tt.actions), body)
+			}
+			for i, want := range tt.actions {
+				if !json.Valid([]byte(lines[2*i])) {
+					t.Errorf("action line %d is not valid JSON: %s", i, lines[2*i])
+				}
+				if lines[2*i] != want {
+					t.Errorf("action line %d is %s, want %s", i, lines[2*i], want)
+				}
+				var doc map[string]interface{}
+				if err := json.Unmarshal([]byte(lines[2*i+1]), &doc); err != nil {
+					t.Fatalf("document line %d: %v", i, err)
+				}
+				if doc["id"] != tt.articles[i].ID {
+					t.Errorf("document line %d has id %v, want %q", i, doc["id"], tt.articles[i].ID)
+				}
+			}
+		})
+	}
+}
+
+func TestBulkBodyNormalizesDates(t *testing.T) {
+	buf, err := bulkBody("news", []model.Article{{ID: "1", PublicationDate: "2024-03-01T15:30:00+05:30"}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer releaseBulkBody(buf)
+
+	lines := strings.Split(buf.String(), "\n")
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := doc["publication_date"], "2024-03-01T10:00:00.000Z"; got != want {
+		t.Errorf("publication_date is %v, want %s", got, want)
+	}
+}
+
+func TestBulkBodyRejectsBadDate(t *testing.T) {
+	if _, err := bulkBody("news", []model.Article{{ID: "1", PublicationDate: "not a date"}}, ""); err == nil {
+		t.Error("expected an error for an unparseable publication date")
+	}
+}
+
+func TestAppendJSONString(t *testing.T) {
+	for _, s := range []string{"", "plain", `quo"te`, `back\slash`, "tab\tnew\nline\x00", "ünïcode ✓", "<html>&"} {
+		var decoded string
+		got := appendJSONString(nil, s)
+		if err := json.Unmarshal(got, &decoded); err != nil {
+			t.Errorf("%q encodes to invalid JSON %s: %v", s, got, err)
+			continue
+		}
+		if decoded != s {
+			t.Errorf("%q round trips to %q", s, decoded)
+		}
+	}
+}
+
+func TestBulkFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		rejected map[string]string
+		// failed is the expected Failed count, if not one per rejected ID
+		failed int
+	}{
+		{
+			name: "all indexed",
+			response: `{"took":30,"errors":false,"items":[
+				{"index":{"_index":"news","_id":"1","_version":1,"result":"created","status":201}},
+				{"index":{"_index":"news","_id":"2","_version":3,"result":"updated","status":200}}]}`,
+		},
+		{
+			name: "mixed success and failure",
+			response: `{"took":30,"errors":true,"items":[
+				{"index":{"_index":"news","_id":"1","_version":1,"result":"created","status":201}},
+				{"index":{"_index":"news","_id":"2","status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse field [latitude] of type [float]","caused_by":{"type":"number_format_exception","reason":"For input string: \"north\""}}}},
+				{"index":{"_index":"news","_id":"3","status":429,"error":{"type":"es_rejected_execution_exception","reason":"rejected execution"}}}]}`,
+			rejected: map[string]string{
+				"2": "mapper_parsing_exception: failed to parse field [latitude] of type [float]",
+				"3": "es_rejected_execution_exception: rejected execution",
+			},
+		},
+		{
+			name: "failure before errors flag",
+			response: `{"items":[
+				{"index":{"_id":"1","status":400,"error":{"type":"strict_dynamic_mapping_exception","reason":"mapping set to strict, dynamic introduction of [foo] within [_doc] is not allowed"}}}],"errors":true}`,
+			rejected: map[string]string{
+				"1": "strict_dynamic_mapping_exception: mapping set to strict, dynamic introduction of [foo] within [_doc] is not allowed",
+			},
+		},
+		{
+			name: "duplicate ids",
+			response: `{"errors":true,"items":[
+				{"index":{"_id":"1","status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse field [latitude] of type [float]"}}},
+				{"index":{"_id":"1","status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse field [longitude] of type [float]"}}},
+				{"index":{"_id":"2","status":201}}]}`,
+			rejected: map[string]string{
+				"1": "mapper_parsing_exception: failed to parse field [longitude] of type [float]",
+			},
+			failed: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := bulkFailures(strings.NewReader(tt.response), 1)
+			if tt.rejected == nil {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			var partial *PartialError
+			if !errors.As(err, &partial) {
+				t.Fatalf("expected a *PartialError, got %v", err)
+			}
+			failed := tt.failed
+			if failed == 0 {
+				failed = len(tt.rejected)
+			}
+			if partial.Failed != failed {
+				t.Errorf("Failed is %d, want %d", partial.Failed, failed)
+			}
+			for id, reason := range tt.rejected {
+				if partial.Rejected[id] != reason {
+					t.Errorf("document %s rejected for %q, want %q", id, partial.Rejected[id], reason)
+				}
+			}
+			if len(partial.Rejected) != len(tt.rejected) {
+				t.Errorf("rejected %v, want %v", partial.Rejected, tt.rejected)
+			}
+		})
+	}
+}
+
+func TestBulkFailuresMalformed(t *testing.T) {
+	for _, response := range []string{``, `[]`, `{"errors":true,"items":{}}`, `{"errors":true,"items":[{"index":`} {
+		var partial *PartialError
+		if err := bulkFailures(strings.NewReader(response), 1); err == nil || errors.As(err, &partial) {
+			t.Errorf("expected a decoding error for %q, got %v", response, err)
+		}
+	}
+}
+
+func TestItemFailureField(t *testing.T) {
+	tests := []struct {
+		failure itemFailure
+		want    string
+	}{
+		{itemFailure{reason: "failed to parse field [latitude] of type [float] in document with id '1'"}, "latitude"},
+		{itemFailure{reason: "mapping set to strict, dynamic introduction of [foo] within [_doc] is not allowed"}, "foo"},
+		{itemFailure{reason: "document parsing failed", causeReason: "field [title] too long"}, "title"},
+		{itemFailure{reason: "rejected execution"}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.failure.field(); got != tt.want {
+			t.Errorf("field of %q is %q, want %q", tt.failure.reason, got, tt.want)
+		}
+	}
+}
+
+// TestBulkFailuresFiltered decodes responses as slimmed by
+// bulkFilterPath, which leaves out items without the filtered fields and
+// the items altogether when none are left.
+func TestBulkFailuresFiltered(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		failed   int
+	}{
+		{name: "no items", response: `{"errors":false}`},
+		{
+			name:     "status only",
+			response: `{"errors":false,"items":[{"index":{"_id":"1","status":201}},{"index":{"_id":"2","status":200}}]}`,
+		},
+		{
+			name: "with errors",
+			response: `{"errors":true,"items":[{"index":{"_id":"1","status":201}},` +
+				`{"index":{"_id":"2","status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse field [url]"}}}]}`,
+			failed: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := bulkFailures(strings.NewReader(tt.response), 1)
+			var partial *PartialError
+			switch {
+			case tt.failed == 0 && err != nil:
+				t.Fatalf("expected no error, got %v", err)
+			case tt.failed > 0 && !errors.As(err, &partial):
+				t.Fatalf("expected a *PartialError, got %v", err)
+			case tt.failed > 0 && partial.Failed != tt.failed:
+				t.Errorf("Failed is %d, want %d", partial.Failed, tt.failed)
+			}
+		})
+	}
+}
+
+// TestBulkFailuresDrainsSuccess checks items aren't decoded once the
+// response says none failed, but the body is still read to its end so the
+// connection can be reused.
+func TestBulkFailuresDrainsSuccess(t *testing.T) {
+	// Items that don't decode would fail the call if they were decoded
+	body := strings.NewReader(`{"errors":false,"items":[{"index":"not an item"}]}`)
+	if err := bulkFailures(body, 1); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if body.Len() != 0 {
+		t.Errorf("%d bytes of the response were left unread", body.Len())
+	}
+}
@@ -0,0 +1,162 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// RemoteCluster is a cluster the follower cluster replicates from.
+type RemoteCluster struct {
+	Name string
+	// Seeds are the transport addresses of the remote, e.g. host:9300,
+	// used unless Proxy is set.
+	Seeds []string
+	// Proxy is the address of a proxy in front of the remote.
+	Proxy string
+}
+
+// RegisterRemoteCluster persistently registers r on the cluster of client.
+func RegisterRemoteCluster(ctx context.Context, client *elasticsearch.Client, r RemoteCluster) error {
+	var remote map[string]interface{}
+	switch {
+	case r.Proxy != "":
+		remote = map[string]interface{}{"mode": "proxy", "proxy_address": r.Proxy, "seeds": nil}
+	case len(r.Seeds) > 0:
+		remote = map[string]interface{}{"mode": "sniff", "seeds": r.Seeds, "proxy_address": nil}
+	default:
+		return errors.New("remote cluster has neither seeds nor a proxy")
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"persistent": map[string]interface{}{
+			"cluster": map[string]interface{}{"remote": map[string]interface{}{r.Name: remote}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	res, err := client.Cluster.PutSettings(bytes.NewReader(body), client.Cluster.PutSettings.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("failed to register remote cluster %s: %s", r.Name, res.String())
+	}
+	log.Info().Caller().Msgf("remote cluster %s registered", r.Name)
+	return nil
+}
+
+// Follow creates the follower index index of the index of the same name
+// on remote. An index that already follows is left alone.
+func Follow(ctx context.Context, client *elasticsearch.Client, remote, index string) error {
+	body, err := json.Marshal(map[string]interface{}{"remote_cluster": remote, "leader_index": index})
+	if err != nil {
+		return err
+	}
+	res, err := client.CCR.Follow(index, bytes.NewReader(body), client.CCR.Follow.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == 400 && strings.Contains(res.String(), "resource_already_exists_exception") {
+		log.Info().Caller().Msgf("%s already exists, not following %s:%s", index, remote, index)
+		return nil
+	}
+	if res.IsError() {
+		return fmt.Errorf("failed to follow %s:%s: %s", remote, index, res.String())
+	}
+	log.Info().Caller().Msgf("%s follows %s:%s", index, remote, index)
+	return nil
+}
+
+// AutoFollow makes the cluster of client follow the indices of remote
+// matching patterns as they are created, such as the indices a rollover
+// alias rolls over to. Followers are named after their leader.
+func AutoFollow(ctx context.Context, client *elasticsearch.Client, name, remote string, patterns []string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"remote_cluster":        remote,
+		"leader_index_patterns": patterns,
+		"follow_index_pattern":  "{{leader_index}}",
+	})
+	if err != nil {
+		return err
+	}
+	res, err := client.CCR.PutAutoFollowPattern(name, bytes.NewReader(body), client.CCR.PutAutoFollowPattern.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("failed to create auto-follow pattern %s: %s", name, res.String())
+	}
+	log.Info().Caller().Msgf("auto-follow pattern %s follows new indices of %s matching %v", name, remote, patterns)
+	return nil
+}
+
+// PauseFollow stops the follower index from replicating until
+// ResumeFollow.
+func PauseFollow(ctx context.Context, client *elasticsearch.Client, index string) error {
+	res, err := client.CCR.PauseFollow(index, client.CCR.PauseFollow.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("failed to pause following %s: %s", index, res.String())
+	}
+	log.Info().Caller().Msgf("paused following %s", index)
+	return nil
+}
+
+// ResumeFollow resumes a follower index paused with PauseFollow.
+func ResumeFollow(ctx context.Context, client *elasticsearch.Client, index string) error {
+	res, err := client.CCR.ResumeFollow(index, client.CCR.ResumeFollow.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("failed to resume following %s: %s", index, res.String())
+	}
+	log.Info().Caller().Msgf("resumed following %s", index)
+	return nil
+}
+
+// Follower is a follower index and what it follows.
+type Follower struct {
+	Index  string `json:"follower_index"`
+	Remote string `json:"remote_cluster"`
+	Leader string `json:"leader_index"`
+	// Status is active or paused.
+	Status string `json:"status"`
+}
+
+// Followers returns the follower indices among indices, sorted by index.
+func Followers(ctx context.Context, client *elasticsearch.Client, indices []string) ([]Follower, error) {
+	res, err := client.CCR.FollowInfo(indices, client.CCR.FollowInfo.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, fmt.Errorf("failed to get the followers of %v: %s", indices, res.String())
+	}
+	var info struct {
+		FollowerIndices []Follower `json:"follower_indices"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+	sort.Slice(info.FollowerIndices, func(i, j int) bool {
+		return info.FollowerIndices[i].Index < info.FollowerIndices[j].Index
+	})
+	return info.FollowerIndices, nil
+}
@@ -0,0 +1,203 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// indexSizeFactor approximates the on disk size of an indexed document
+// relative to its JSON source, accounting for the inverted index and doc
+// values.
+const indexSizeFactor = 1.5
+
+// defaultReplicas is assumed for an index that doesn't exist yet.
+const defaultReplicas = 1
+
+// EstimateSize returns the approximate on disk size of articles once
+// indexed, excluding replicas.
+func EstimateSize(articles []model.Article) (int64, error) {
+	var size int64
+	for _, a := range articles {
+		data, err := json.Marshal(a)
+		if err != nil {
+			return 0, err
+		}
+		size += int64(len(data))
+	}
+	return int64(float64(size) * indexSizeFactor), nil
+}
+
+// ErrDiskWatermark is returned when a write would push a node past the
+// flood stage watermark, at which Elasticsearch blocks writes to its indices.
+var ErrDiskWatermark = errors.New("write would exceed the flood stage disk watermark")
+
+// CheckDiskSpace returns an error wrapping ErrDiskWatermark when incoming
+// bytes, spread evenly over the data nodes together with their replicas,
+// would push a node past the flood stage watermark. Serverless projects
+// have no nodes to check.
+func (e *Elasticsearch) CheckDiskSpace(ctx context.Context, incoming int64) error {
+	if e.version.Serverless() {
+		return nil
+	}
+	floodStage, err := e.floodStageWatermark(ctx)
+	if err != nil {
+		return err
+	}
+	replicas, err := e.replicas(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := e.client.Cat.Allocation(
+		e.client.Cat.Allocation.WithContext(ctx),
+		e.client.Cat.Allocation.WithBytes("b"),
+		e.client.Cat.Allocation.WithH("node", "disk.used", "disk.total"),
+		e.client.Cat.Allocation.WithFormat("json"),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return errors.New(res.String())
+	}
+	var nodes []struct {
+		Node  string `json:"node"`
+		Used  string `json:"disk.used"`
+		Total string `json:"disk.total"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&nodes); err != nil {
+		return err
+	}
+
+	// Unassigned shards are listed without a disk
+	var data []int
+	for i, n := range nodes {
+		if n.Total != "" {
+			data = append(data, i)
+		}
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	perNode := incoming * int64(1+replicas) / int64(len(data))
+	for _, i := range data {
+		used, _ := strconv.ParseInt(nodes[i].Used, 10, 64)
+		total, _ := strconv.ParseInt(nodes[i].Total, 10, 64)
+		if projected := used + perNode; floodStage.exceeded(projected, total) {
+			return fmt.Errorf("%w: node %s would use %d of %d bytes, flood stage is %s",
+				ErrDiskWatermark, nodes[i].Node, projected, total, floodStage.raw)
+		}
+	}
+	return nil
+}
+
+// watermark is either a maximum used ratio or a minimum free size.
+type watermark struct {
+	raw      string
+	maxRatio float64
+	minFree  int64
+}
+
+func (w watermark) exceeded(used, total int64) bool {
+	if total <= 0 {
+		return false
+	}
+	if w.maxRatio > 0 {
+		return float64(used)/float64(total) > w.maxRatio
+	}
+	return total-used < w.minFree
+}
+
+// parseWatermark parses the percentage, ratio or byte value forms of a
+// disk watermark setting.
+func parseWatermark(raw string) (watermark, error) {
+	value := strings.ToLower(strings.TrimSpace(raw))
+	if percent, ok := strings.CutSuffix(value, "%"); ok {
+		ratio, err := strconv.ParseFloat(percent, 64)
+		if err != nil {
+			return watermark{}, fmt.Errorf("invalid watermark %q", raw)
+		}
+		return watermark{raw: raw, maxRatio: ratio / 100}, nil
+	}
+	if ratio, err := strconv.ParseFloat(value, 64); err == nil && ratio <= 1 {
+		return watermark{raw: raw, maxRatio: ratio}, nil
+	}
+	// Elasticsearch byte units are binary, "1gb" meaning 1GiB
+	free, err := utils.ParseByteSize(strings.TrimSuffix(value, "b"))
+	if err != nil {
+		return watermark{}, fmt.Errorf("invalid watermark %q", raw)
+	}
+	return watermark{raw: raw, minFree: free}, nil
+}
+
+func (e *Elasticsearch) floodStageWatermark(ctx context.Context) (watermark, error) {
+	res, err := e.client.Cluster.GetSettings(
+		e.client.Cluster.GetSettings.WithContext(ctx),
+		e.client.Cluster.GetSettings.WithIncludeDefaults(true),
+		e.client.Cluster.GetSettings.WithFlatSettings(true),
+	)
+	if err != nil {
+		return watermark{}, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return watermark{}, errors.New(res.String())
+	}
+	var settings map[string]map[string]interface{}
+	if err := json.NewDecoder(res.Body).Decode(&settings); err != nil {
+		return watermark{}, err
+	}
+
+	const key = "cluster.routing.allocation.disk.watermark.flood_stage"
+	// Transient settings override persistent ones, which override defaults
+	for _, scope := range []string{"transient", "persistent", "defaults"} {
+		if value, ok := settings[scope][key].(string); ok {
+			return parseWatermark(value)
+		}
+	}
+	return parseWatermark("95%")
+}
+
+// replicas returns the number of replicas of the index, or
+// defaultReplicas when it doesn't exist yet.
+func (e *Elasticsearch) replicas(ctx context.Context) (int, error) {
+	res, err := e.client.Indices.GetSettings(
+		e.client.Indices.GetSettings.WithContext(ctx),
+		e.client.Indices.GetSettings.WithIndex(e.index),
+		e.client.Indices.GetSettings.WithName("index.number_of_replicas"),
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == 404 {
+		return defaultReplicas, nil
+	}
+	if res.IsError() {
+		return 0, errors.New(res.String())
+	}
+	var indices map[string]struct {
+		Settings struct {
+			Index struct {
+				Replicas string `json:"number_of_replicas"`
+			} `json:"index"`
+		} `json:"settings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&indices); err != nil {
+		return 0, err
+	}
+	for _, index := range indices {
+		if replicas, err := strconv.Atoi(index.Settings.Index.Replicas); err == nil {
+			return replicas, nil
+		}
+	}
+	return defaultReplicas, nil
+}
@@ -0,0 +1,340 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/elastic/go-elasticsearch/v9/esapi"
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// Elasticsearch indexes articles into a single index.
+type Elasticsearch struct {
+	client *elasticsearch.Client
+	index  string
+	// routing names the document field whose value routes it to a shard.
+	routing string
+	// settings are applied when the index is created.
+	settings IndexSettings
+	// batches numbers the bulk requests in logs.
+	batches atomic.Int64
+	// dumpDir receives a copy of every bulk request body, empty for none.
+	dumpDir string
+	// version is the cluster's, the zero Version when unknown.
+	version Version
+	// history is the index overwritten versions are archived to, empty
+	// for none.
+	history string
+	// rollover makes index a write alias rolled over on these
+	// conditions, unless they are zero.
+	rollover RolloverConditions
+	// tenant scopes Prune and Expire to its articles, empty for all.
+	tenant string
+}
+
+// NewElasticsearch returns a sink writing to index through client.
+func NewElasticsearch(client *elasticsearch.Client, index string) *Elasticsearch {
+	return &Elasticsearch{client: client, index: index}
+}
+
+// WithRouting routes each document by the value of field, e.g.
+// "source_name", so documents sharing it are collocated on one shard.
+func (e *Elasticsearch) WithRouting(field string) *Elasticsearch {
+	e.routing = field
+	return e
+}
+
+// WithTenant scopes Prune and Expire to the articles of tenant, so
+// brands sharing an index don't delete each other's articles.
+func (e *Elasticsearch) WithTenant(tenant string) *Elasticsearch {
+	e.tenant = tenant
+	return e
+}
+
+// tenantFilter returns the query clauses restricting a query to the
+// tenant of e.
+func (e *Elasticsearch) tenantFilter() []interface{} {
+	if e.tenant == "" {
+		return []interface{}{}
+	}
+	return []interface{}{map[string]interface{}{"term": map[string]interface{}{"tenant": e.tenant}}}
+}
+
+// WithDumpDir writes the body of every bulk request to a file in dir,
+// for diagnosing rejected documents.
+func (e *Elasticsearch) WithDumpDir(dir string) *Elasticsearch {
+	e.dumpDir = dir
+	return e
+}
+
+// WithVersion adapts the index definition and the APIs used to the
+// cluster version v, see DetectVersion.
+func (e *Elasticsearch) WithVersion(v Version) *Elasticsearch {
+	e.version = v
+	return e
+}
+
+// WithSettings sets the settings Prepare applies when it creates the
+// index.
+func (e *Elasticsearch) WithSettings(settings IndexSettings) *Elasticsearch {
+	e.settings = settings
+	return e
+}
+
+func (e *Elasticsearch) Name() string { return "elasticsearch" }
+
+func (e *Elasticsearch) Close() error { return nil }
+
+// Ping implements Pinger.
+func (e *Elasticsearch) Ping(ctx context.Context) error {
+	res, err := e.client.Ping(e.client.Ping.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return errors.New(res.Status())
+	}
+	return nil
+}
+
+// Prepare implements Sink by creating the index with the mapping for opts
+// unless it already exists, in which case fields added since are mapped.
+func (e *Elasticsearch) Prepare(ctx context.Context, opts IndexOptions) error {
+	es, index := e.client, e.index
+
+	if e.history != "" {
+		if err := e.prepareHistory(ctx); err != nil {
+			return err
+		}
+	}
+
+	// 1. Build the mapping and settings for the configured options
+	opts.Settings = e.settings
+	opts.Version = e.version
+	// Older clusters get the rules inline, fixed at index creation
+	if len(opts.Settings.Synonyms) > 0 && e.version.synonymSets() {
+		// The set must exist before an index referencing it is created
+		if err := e.PutSynonyms(ctx, opts.Settings.Synonyms); err != nil {
+			return err
+		}
+		opts.Settings.SynonymsSet = synonymsSet(index)
+	}
+	body, err := BuildIndexBody(opts)
+	if err != nil {
+		return err
+	}
+	if !e.rollover.IsZero() {
+		return e.prepareRollover(ctx, body)
+	}
+
+	// Check if index already exists
+	exists, err := es.Indices.Exists([]string{index}, es.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	exists.Body.Close()
+	if exists.StatusCode == 200 {
+		return e.putMapping(ctx, body)
+	}
+
+	// 2. Create the index creation request
+	req := esapi.IndicesCreateRequest{
+		Index: index,
+		Body:  bytes.NewReader(body),
+	}
+
+	// 3. Execute the request
+	res, err := req.Do(ctx, es)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.Error().Caller().Err(err).Msgf("error response: %s\n", res.String())
+	} else {
+		log.Info().Caller().Err(err).Msgf("index: (%s) created successfully. Status: %s\n", index, res.Status())
+	}
+	return nil
+}
+
+// putMapping adds the fields of the index definition body that an
+// existing index lacks. New fields are accepted by the strict mapping
+// only once they are mapped.
+func (e *Elasticsearch) putMapping(ctx context.Context, body []byte) error {
+	mappings, err := mappingsOf(body)
+	if err != nil {
+		return err
+	}
+	res, err := e.client.Indices.PutMapping([]string{e.index}, bytes.NewReader(mappings), e.client.Indices.PutMapping.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("failed to update mapping of %s: %s", e.index, res.String())
+	}
+	return nil
+}
+
+// WriteBatch implements Sink by sending articles in a single bulk request.
+func (e *Elasticsearch) WriteBatch(ctx context.Context, articles []model.Article) error {
+	batch := e.batches.Add(1)
+	if e.history != "" {
+		if err := e.archive(ctx, articles); err != nil {
+			return err
+		}
+	}
+	body, err := bulkBody(e.index, articles, e.routing)
+	if err != nil {
+		return err
+	}
+	defer releaseBulkBody(body)
+	if e.dumpDir != "" {
+		dumpBatch(e.dumpDir, e.Name(), e.index, batch, body.Bytes())
+	}
+
+	res, err := e.client.Bulk(body, e.client.Bulk.WithFilterPath(bulkFilterPath...), e.client.Bulk.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("bulk request failed: %s", res.String())
+	}
+	return bulkFailures(res.Body, batch)
+}
+
+// publicationDate returns the normalized publication date of a, empty
+// when the article has none.
+func publicationDate(a model.Article) (string, error) {
+	if a.PublicationDate == "" {
+		return "", nil
+	}
+	return utils.NormalizeToESDate(a.PublicationDate)
+}
+
+// Document returns a as the Elasticsearch and OpenSearch sinks index it.
+func Document(a model.Article) (map[string]interface{}, error) {
+	date, err := publicationDate(a)
+	if err != nil {
+		return nil, err
+	}
+	doc, _ := document(a, date)
+	return doc, nil
+}
+
+// document builds the indexed body of a. Optional fields are left out
+// when empty, as the mapping is strict. It also reports whether the
+// article has no usable location.
+func document(a model.Article, publicationDate string) (map[string]interface{}, bool) {
+	doc := map[string]interface{}{
+		"id":               a.ID,
+		"title":            a.Title,
+		"description":      a.Description,
+		"url":              a.URL,
+		"publication_date": publicationDate,
+		"source_name":      a.SourceName,
+		"category":         a.Category,
+		"relevance_score":  a.RelevanceScore,
+	}
+	if publicationDate == "" {
+		delete(doc, "publication_date")
+	}
+	// Missing or out of range coordinates would land at Null Island or be
+	// rejected by geo_point, so the geo fields are only set when valid.
+	if utils.ValidCoordinates(a.Latitude, a.Longitude) {
+		doc["latitude"] = a.Latitude
+		doc["longitude"] = a.Longitude
+		doc["location"] = map[string]float64{
+			"lat": a.Latitude,
+			"lon": a.Longitude,
+		}
+	}
+
+	if a.LLMSummary != "" {
+		doc["llm_summary"] = a.LLMSummary
+	}
+	if a.Author != "" {
+		doc["author"] = a.Author
+	}
+	if a.ImageURL != "" {
+		doc["image_url"] = a.ImageURL
+	}
+	if a.SourceTrust != nil {
+		doc["source_trust"] = *a.SourceTrust
+	}
+	if a.IsPaywalled != nil {
+		doc["is_paywalled"] = *a.IsPaywalled
+	}
+	if a.WordCount > 0 {
+		doc["word_count"] = a.WordCount
+	}
+	if a.ReadingTime > 0 {
+		doc["reading_time"] = a.ReadingTime
+	}
+	if len(a.Extra) > 0 {
+		doc["extra"] = a.Extra
+	}
+	if a.IngestedAt != "" {
+		doc["ingested_at"] = a.IngestedAt
+	}
+	if a.SyncRunID != "" {
+		doc["sync_run_id"] = a.SyncRunID
+	}
+	if a.SourceFile != "" {
+		doc["source_file"] = a.SourceFile
+	}
+	if a.ExpiresAt != "" {
+		doc["expires_at"] = a.ExpiresAt
+	}
+	if a.StoryID != "" {
+		doc["story_id"] = a.StoryID
+		doc["story_canonical"] = a.StoryCanonical
+		doc["story_sources"] = a.StorySources
+	}
+	if a.Tenant != "" {
+		doc["tenant"] = a.Tenant
+	}
+	if len(a.Access) > 0 {
+		doc["access"] = a.Access
+	}
+	if len(a.Embedding) > 0 {
+		doc["embedding"] = a.Embedding
+	}
+	if !a.Entities.Empty() {
+		doc["entities"] = a.Entities
+	}
+	if a.Sentiment != nil {
+		doc["sentiment"] = a.Sentiment
+	}
+	if len(a.Tags) > 0 {
+		doc["tags"] = a.Tags
+	}
+	if len(a.RegionShape) > 0 {
+		doc["region_shape"] = a.RegionShape
+	}
+	optional := map[string]string{
+		"canonical_url": a.CanonicalURL,
+		"location_name": a.LocationName,
+		"country":       a.Country,
+		"state":         a.State,
+		"city":          a.City,
+		"region":        a.Region,
+		"geohash":       a.Geohash,
+	}
+	for field, value := range optional {
+		if value != "" {
+			doc[field] = value
+		}
+	}
+	return doc, !utils.ValidCoordinates(a.Latitude, a.Longitude)
+}
@@ -0,0 +1,174 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+// Stats is the outcome of writing to one sink of a FanOut.
+type Stats struct {
+	Sink    string `json:"sink"`
+	Indexed int    `json:"indexed"`
+	Failed  int    `json:"failed"`
+	// Error is the last batch level error, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// StatsReporter is implemented by sinks that track the outcome per destination.
+type StatsReporter interface {
+	// TakeStats returns the stats collected since the previous call.
+	TakeStats() []Stats
+}
+
+// FanOut writes every batch to all of its sinks concurrently, e.g. to
+// keep two clusters in sync during a migration. A failing sink doesn't
+// stop the others; each one's outcome is tracked separately.
+type FanOut struct {
+	sinks []Sink
+
+	mu    sync.Mutex
+	stats []Stats
+}
+
+// NewFanOut returns a sink writing to all of sinks.
+func NewFanOut(sinks ...Sink) *FanOut {
+	f := &FanOut{sinks: sinks}
+	f.resetStats()
+	return f
+}
+
+func (f *FanOut) Name() string {
+	names := make([]string, len(f.sinks))
+	for i, s := range f.sinks {
+		names[i] = s.Name()
+	}
+	return "fanout(" + strings.Join(names, ",") + ")"
+}
+
+// Prepare implements Sink, preparing every sink.
+func (f *FanOut) Prepare(ctx context.Context, opts IndexOptions) error {
+	var errs []error
+	for _, s := range f.sinks {
+		if err := s.Prepare(ctx, opts); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WriteBatch implements Sink. It fails outright only when every sink
+// failed; otherwise the largest number of articles missing from any one
+// sink is reported as a *PartialError.
+func (f *FanOut) WriteBatch(ctx context.Context, articles []model.Article) error {
+	results := make([]Result, len(f.sinks))
+	errs := make([]error, len(f.sinks))
+
+	var wg sync.WaitGroup
+	for i, s := range f.sinks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = writeBatch(ctx, s, articles)
+			if errs[i] != nil {
+				results[i] = Result{Failed: len(articles)}
+			}
+		}()
+	}
+	wg.Wait()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var failed, failedSinks int
+	// A document missing from any sink is rejected
+	rejected := make(map[string]string)
+	for i, s := range f.sinks {
+		f.stats[i].add(results[i])
+		if errs[i] != nil {
+			f.stats[i].Error = errs[i].Error()
+			failedSinks++
+			errs[i] = fmt.Errorf("%s: %w", s.Name(), errs[i])
+			for _, a := range articles {
+				rejected[a.ID] = errs[i].Error()
+			}
+		}
+		for id, reason := range results[i].Rejected {
+			rejected[id] = s.Name() + ": " + reason
+		}
+		failed = max(failed, results[i].Failed)
+	}
+
+	if failedSinks == len(f.sinks) {
+		return errors.Join(errs...)
+	}
+	if failed > 0 {
+		return &PartialError{Failed: failed, Rejected: rejected}
+	}
+	return nil
+}
+
+// TakeStats implements StatsReporter.
+func (f *FanOut) TakeStats() []Stats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stats := f.stats
+	f.resetStats()
+	return stats
+}
+
+// resetStats clears the stats, numbering sinks of the same kind so they
+// can be told apart.
+func (f *FanOut) resetStats() {
+	f.stats = make([]Stats, len(f.sinks))
+	seen := make(map[string]int)
+	for i, s := range f.sinks {
+		name := s.Name()
+		seen[name]++
+		if seen[name] > 1 {
+			name = fmt.Sprintf("%s#%d", name, seen[name])
+		}
+		f.stats[i].Sink = name
+	}
+}
+
+// Ping implements Pinger, checking every sink that supports it.
+func (f *FanOut) Ping(ctx context.Context) error {
+	for _, s := range f.sinks {
+		if pinger, ok := s.(Pinger); ok {
+			if err := pinger.Ping(ctx); err != nil {
+				return fmt.Errorf("%s: %w", s.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+func (f *FanOut) Close() error {
+	var errs []error
+	for _, s := range f.sinks {
+		if err := s.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Stats) add(r Result) {
+	s.Indexed += r.Indexed
+	s.Failed += r.Failed
+}
+
+// Consistent reports whether every sink received the same documents
+// without failures.
+func Consistent(stats []Stats) bool {
+	for _, s := range stats {
+		if s.Failed > 0 || s.Error != "" || s.Indexed != stats[0].Indexed {
+			return false
+		}
+	}
+	return true
+}
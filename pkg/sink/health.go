@@ -0,0 +1,150 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+// HealthChecker is implemented by sinks that can tell whether their
+// destination is fit to take writes.
+type HealthChecker interface {
+	// Health returns an error describing why writes should be held back.
+	Health(ctx context.Context) error
+}
+
+// writeQueueSaturation is the fill ratio of a node's write queue above
+// which the cluster is considered saturated.
+const writeQueueSaturation = 0.9
+
+// Health implements HealthChecker. The cluster is unhealthy when its
+// status is red or the write thread pool queue of any node is nearly full.
+// Serverless projects don't expose either and are assumed healthy.
+func (e *Elasticsearch) Health(ctx context.Context) error {
+	if e.version.Serverless() {
+		return nil
+	}
+	res, err := e.client.Cluster.Health(e.client.Cluster.Health.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return errors.New(res.String())
+	}
+	var health struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&health); err != nil {
+		return err
+	}
+	if health.Status == "red" {
+		return errors.New("cluster status is red")
+	}
+
+	res, err = e.client.Cat.ThreadPool(
+		e.client.Cat.ThreadPool.WithContext(ctx),
+		e.client.Cat.ThreadPool.WithThreadPoolPatterns("write"),
+		e.client.Cat.ThreadPool.WithH("node_name", "queue", "queue_size"),
+		e.client.Cat.ThreadPool.WithFormat("json"),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return errors.New(res.String())
+	}
+	// The cat API returns numbers as strings
+	var pools []struct {
+		Node      string `json:"node_name"`
+		Queue     string `json:"queue"`
+		QueueSize string `json:"queue_size"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&pools); err != nil {
+		return err
+	}
+	for _, p := range pools {
+		queue, _ := strconv.Atoi(p.Queue)
+		size, _ := strconv.Atoi(p.QueueSize)
+		if size > 0 && float64(queue) >= writeQueueSaturation*float64(size) {
+			return fmt.Errorf("write queue of node %s is saturated (%d/%d)", p.Node, queue, size)
+		}
+	}
+	return nil
+}
+
+// HealthGated pauses writes to a sink while its destination is unhealthy
+// and resumes them once it recovers.
+type HealthGated struct {
+	Sink
+	checker  HealthChecker
+	interval time.Duration
+
+	mu        sync.Mutex
+	checkedAt time.Time
+}
+
+// NewHealthGated checks the health of s before it is prepared and then
+// at most every interval before a batch is written.
+func NewHealthGated(s Sink, checker HealthChecker, interval time.Duration) *HealthGated {
+	return &HealthGated{Sink: s, checker: checker, interval: interval}
+}
+
+func (g *HealthGated) Prepare(ctx context.Context, opts IndexOptions) error {
+	if err := g.waitHealthy(ctx); err != nil {
+		return err
+	}
+	return g.Sink.Prepare(ctx, opts)
+}
+
+func (g *HealthGated) WriteBatch(ctx context.Context, articles []model.Article) error {
+	if err := g.waitHealthy(ctx); err != nil {
+		return err
+	}
+	return g.Sink.WriteBatch(ctx, articles)
+}
+
+// Ping checks the wrapped sink when it can be pinged.
+func (g *HealthGated) Ping(ctx context.Context) error {
+	if pinger, ok := g.Sink.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// waitHealthy blocks, checking every interval, until the destination is
+// healthy or ctx is done. Checks are skipped within interval of the last
+// healthy one.
+func (g *HealthGated) waitHealthy(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if time.Since(g.checkedAt) < g.interval {
+		return nil
+	}
+
+	for paused := false; ; paused = true {
+		err := g.checker.Health(ctx)
+		if err == nil {
+			if paused {
+				log.Info().Caller().Msgf("%s is healthy again, resuming writes", g.Name())
+			}
+			g.checkedAt = time.Now()
+			return nil
+		}
+		log.Warn().Caller().Err(err).Msgf("%s is unhealthy, pausing writes for %s", g.Name(), g.interval)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for %s to become healthy: %w", g.Name(), ctx.Err())
+		case <-time.After(g.interval):
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9/esapi"
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+// historyMapping indexes the metadata of archived versions. The archived
+// document itself is only stored, so old versions never conflict with a
+// mapping that changed since.
+const historyMapping = `{
+  "mappings": {
+    "dynamic": "strict",
+    "properties": {
+      "article_id":      { "type": "keyword" },
+      "version":         { "type": "long" },
+      "archived_at":     { "type": "date" },
+      "replaced_by_run": { "type": "keyword" },
+      "document":        { "type": "object", "enabled": false }
+    }
+  }
+}`
+
+// historyEntry is a version of an article as it was before a sync
+// overwrote it.
+type historyEntry struct {
+	ArticleID     string          `json:"article_id"`
+	Version       int64           `json:"version"`
+	ArchivedAt    time.Time       `json:"archived_at"`
+	ReplacedByRun string          `json:"replaced_by_run,omitempty"`
+	Document      json.RawMessage `json:"document"`
+}
+
+// WithHistory copies the current version of every document a batch
+// overwrites into index first, keeping a trail of how articles changed
+// across syncs.
+func (e *Elasticsearch) WithHistory(index string) *Elasticsearch {
+	e.history = index
+	return e
+}
+
+// prepareHistory creates the history index unless it exists.
+func (e *Elasticsearch) prepareHistory(ctx context.Context) error {
+	es := e.client
+	exists, err := es.Indices.Exists([]string{e.history}, es.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	exists.Body.Close()
+	if exists.StatusCode == 200 {
+		return nil
+	}
+
+	res, err := es.Indices.Create(e.history, es.Indices.Create.WithBody(bytes.NewReader([]byte(historyMapping))), es.Indices.Create.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("failed to create history index %s: %s", e.history, res.String())
+	}
+	log.Info().Caller().Msgf("history index: (%s) created", e.history)
+	return nil
+}
+
+// archive copies the stored versions of articles that already exist into
+// the history index. Versions are archived under <id>:<version>, so a
+// retried batch doesn't archive one twice.
+func (e *Elasticsearch) archive(ctx context.Context, articles []model.Article) error {
+	current, err := e.currentVersions(ctx, articles)
+	if err != nil || len(current) == 0 {
+		return err
+	}
+
+	now := time.Now().UTC()
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, a := range articles {
+		doc, ok := current[a.ID]
+		if !ok {
+			continue
+		}
+		meta := appendJSONString([]byte(`{"index":{"_index":`), e.history)
+		meta = append(meta, `,"_id":`...)
+		meta = appendJSONString(meta, a.ID+":"+strconv.FormatInt(doc.Version, 10))
+		body.Write(append(meta, "}}\n"...))
+		entry := historyEntry{ArticleID: a.ID, Version: doc.Version, ArchivedAt: now, ReplacedByRun: a.SyncRunID, Document: doc.Source}
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+
+	res, err := e.client.Bulk(&body, e.client.Bulk.WithFilterPath(bulkFilterPath...), e.client.Bulk.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("archiving to %s failed: %s", e.history, res.String())
+	}
+	// Overwriting a document whose version wasn't archived would lose it
+	if err := bulkFailures(res.Body, 0); err != nil {
+		return fmt.Errorf("archiving to %s failed: %w", e.history, err)
+	}
+	return nil
+}
+
+type storedVersion struct {
+	Version int64
+	Source  json.RawMessage
+}
+
+// currentVersions fetches the stored documents of articles by id, with
+// the routing they were indexed with.
+func (e *Elasticsearch) currentVersions(ctx context.Context, articles []model.Article) (map[string]storedVersion, error) {
+	type docRef struct {
+		ID      string `json:"_id"`
+		Routing string `json:"routing,omitempty"`
+	}
+	refs := make([]docRef, 0, len(articles))
+	for _, a := range articles {
+		ref := docRef{ID: a.ID}
+		if e.routing != "" {
+			date, err := publicationDate(a)
+			if err != nil {
+				return nil, err
+			}
+			doc, _ := document(a, date)
+			ref.Routing = routingValue(doc, e.routing)
+		}
+		refs = append(refs, ref)
+	}
+	query, err := json.Marshal(map[string]interface{}{"docs": refs})
+	if err != nil {
+		return nil, err
+	}
+
+	req := esapi.MgetRequest{Index: e.index, Body: bytes.NewReader(query)}
+	res, err := req.Do(ctx, e.client)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, fmt.Errorf("failed to fetch current versions from %s: %s", e.index, res.String())
+	}
+
+	var found struct {
+		Docs []struct {
+			ID      string          `json:"_id"`
+			Found   bool            `json:"found"`
+			Version int64           `json:"_version"`
+			Source  json.RawMessage `json:"_source"`
+		} `json:"docs"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&found); err != nil {
+		return nil, err
+	}
+	current := make(map[string]storedVersion)
+	for _, d := range found.Docs {
+		if d.Found {
+			current[d.ID] = storedVersion{Version: d.Version, Source: d.Source}
+		}
+	}
+	return current, nil
+}
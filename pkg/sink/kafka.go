@@ -0,0 +1,89 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+// Kafka publishes articles to a topic, keyed by article ID so updates to
+// an article land on the same partition in order. Values are the
+// documents as they would be indexed. The topic is expected to exist.
+type Kafka struct {
+	brokers []string
+	writer  *kafka.Writer
+}
+
+// NewKafka returns a sink publishing to topic on brokers.
+func NewKafka(brokers []string, topic string) *Kafka {
+	return &Kafka{
+		brokers: brokers,
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			BatchSize:    DefaultBulkSize,
+			BatchTimeout: 50 * time.Millisecond,
+		},
+	}
+}
+
+func (k *Kafka) Name() string { return "kafka" }
+
+// Prepare implements Sink. Topics are managed outside the syncer.
+func (k *Kafka) Prepare(context.Context, IndexOptions) error { return nil }
+
+// Ping implements Pinger by connecting to the first reachable broker.
+func (k *Kafka) Ping(ctx context.Context) error {
+	var errs []error
+	for _, broker := range k.brokers {
+		conn, err := kafka.DialContext(ctx, "tcp", broker)
+		if err == nil {
+			return conn.Close()
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// WriteBatch implements Sink. Messages the brokers didn't acknowledge are
+// reported as a *PartialError.
+func (k *Kafka) WriteBatch(ctx context.Context, articles []model.Article) error {
+	messages := make([]kafka.Message, 0, len(articles))
+	for _, a := range articles {
+		formattedDate, err := publicationDate(a)
+		if err != nil {
+			return err
+		}
+		doc, _ := document(a, formattedDate)
+		value, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, kafka.Message{Key: []byte(a.ID), Value: value})
+	}
+
+	err := k.writer.WriteMessages(ctx, messages...)
+	var writeErrs kafka.WriteErrors
+	if !errors.As(err, &writeErrs) {
+		return err
+	}
+	failed := writeErrs.Count()
+	if failed == len(messages) {
+		return err
+	}
+	for i, msgErr := range writeErrs {
+		if msgErr != nil {
+			log.Error().Caller().Err(msgErr).Msgf("failed to publish article %s", articles[i].ID)
+		}
+	}
+	return &PartialError{Failed: failed}
+}
+
+func (k *Kafka) Close() error { return k.writer.Close() }
@@ -0,0 +1,54 @@
+package sink
+
+import (
+	"fmt"
+	"strings"
+)
+
+// languageAnalyzers are the built in language analyzers of Elasticsearch
+// and OpenSearch.
+var languageAnalyzers = map[string]bool{
+	"arabic": true, "armenian": true, "basque": true, "bengali": true, "brazilian": true,
+	"bulgarian": true, "catalan": true, "cjk": true, "czech": true, "danish": true,
+	"dutch": true, "english": true, "estonian": true, "finnish": true, "french": true,
+	"galician": true, "german": true, "greek": true, "hindi": true, "hungarian": true,
+	"indonesian": true, "irish": true, "italian": true, "latvian": true, "lithuanian": true,
+	"norwegian": true, "persian": true, "portuguese": true, "romanian": true, "russian": true,
+	"sorani": true, "spanish": true, "swedish": true, "thai": true, "turkish": true,
+}
+
+// languageFields are the text fields given a subfield per language.
+var languageFields = []string{"title", "description", "llm_summary"}
+
+// ParseLanguages returns the comma separated analyzer names in s, e.g.
+// "hindi,spanish".
+func ParseLanguages(s string) ([]string, error) {
+	var languages []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !languageAnalyzers[name] {
+			return nil, fmt.Errorf("unsupported language %q", name)
+		}
+		languages = append(languages, name)
+	}
+	return languages, nil
+}
+
+// addLanguages maps the language fields as multi-fields analysed by each
+// language, e.g. "title.hindi".
+func addLanguages(properties map[string]interface{}, languages []string) {
+	for _, field := range languageFields {
+		mapping := properties[field].(map[string]interface{})
+		subfields, _ := mapping["fields"].(map[string]interface{})
+		if subfields == nil {
+			subfields = make(map[string]interface{})
+			mapping["fields"] = subfields
+		}
+		for _, language := range languages {
+			subfields[language] = map[string]interface{}{"type": "text", "analyzer": language}
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+// analysisSettings are the static index settings. The mappings are
+// generated from the es tags of model.Article, see articleMappings.
+const analysisSettings = `
+{
+  "analysis": {
+    "analyzer": {
+      "news_text": {
+        "type": "custom",
+        "tokenizer": "standard",
+        "filter": [
+          "lowercase",
+          "stop",
+          "english_stemmer"
+        ]
+      }
+    },
+    "filter": {
+      "english_stemmer": {
+        "type": "stemmer",
+        "language": "english"
+      }
+    },
+    "normalizer": {
+      "keyword_lowercase": {
+        "type": "custom",
+        "filter": ["lowercase"]
+      }
+    }
+  }
+}
+`
+
+// documentOnlyProperties map the fields of the indexed document that
+// model.Article doesn't have.
+var documentOnlyProperties = map[string]interface{}{
+	// location is built from latitude and longitude by document
+	"location": map[string]interface{}{"type": "geo_point"},
+	// deleted and deleted_at are set in place by Prune
+	"deleted":    map[string]interface{}{"type": "boolean"},
+	"deleted_at": map[string]interface{}{"type": "date"},
+}
+
+// articleMappings returns the strict mappings of the indexed document,
+// generated from the es tags of model.Article. Configuration dependent
+// fields such as the embedding are added by buildIndexBody.
+func articleMappings() (map[string]interface{}, error) {
+	properties, err := tagProperties(reflect.TypeOf(model.Article{}))
+	if err != nil {
+		return nil, err
+	}
+	for name, mapping := range documentOnlyProperties {
+		properties[name] = mapping
+	}
+	return map[string]interface{}{"dynamic": "strict", "properties": properties}, nil
+}
+
+// tagProperties maps the fields of struct t by their es tags. Struct
+// fields without one are mapped as objects of their own fields, "-"
+// leaves a field out and any other untagged field is an error, so a
+// field added to the model can't be forgotten in the mapping.
+func tagProperties(t reflect.Type) (map[string]interface{}, error) {
+	properties := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		tag, tagged := field.Tag.Lookup("es")
+		typ := field.Type
+		if typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		switch {
+		case tag == "-":
+		case !tagged && typ.Kind() == reflect.Struct:
+			nested, err := tagProperties(typ)
+			if err != nil {
+				return nil, err
+			}
+			properties[name] = map[string]interface{}{"properties": nested}
+		case !tagged:
+			return nil, fmt.Errorf("field %s.%s has no es tag", t.Name(), field.Name)
+		default:
+			mapping, err := parseMappingTag(tag)
+			if err != nil {
+				return nil, fmt.Errorf("field %s.%s: %w", t.Name(), field.Name, err)
+			}
+			properties[name] = mapping
+		}
+	}
+	return properties, nil
+}
+
+// parseMappingTag parses an es tag such as
+// "type:text,analyzer:news_text,keyword.ignore_above:256" into a field
+// mapping. Parameters before a dot belong to that sub field, which is a
+// keyword unless given another type. "suggest" adds a completion sub
+// field suggesting by category.
+func parseMappingTag(tag string) (map[string]interface{}, error) {
+	mapping := make(map[string]interface{})
+	for _, item := range strings.Split(tag, ",") {
+		if item == "suggest" {
+			subField(mapping, "suggest")["type"] = "completion"
+			subField(mapping, "suggest")["contexts"] = []interface{}{
+				map[string]interface{}{"name": "category", "type": "category", "path": "category"},
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(item, ":")
+		if !ok || key == "" || value == "" {
+			return nil, fmt.Errorf("invalid es tag item %q, expected parameter:value", item)
+		}
+		target := mapping
+		if sub, param, nested := strings.Cut(key, "."); nested {
+			target, key = subField(mapping, sub), param
+		}
+		target[key] = tagValue(value)
+	}
+	if _, ok := mapping["type"]; !ok {
+		return nil, fmt.Errorf("es tag %q has no type", tag)
+	}
+	return mapping, nil
+}
+
+// subField returns the mapping of the sub field name, adding it.
+func subField(mapping map[string]interface{}, name string) map[string]interface{} {
+	fields, ok := mapping["fields"].(map[string]interface{})
+	if !ok {
+		fields = make(map[string]interface{})
+		mapping["fields"] = fields
+	}
+	sub, ok := fields[name].(map[string]interface{})
+	if !ok {
+		sub = map[string]interface{}{"type": "keyword"}
+		fields[name] = sub
+	}
+	return sub
+}
+
+// tagValue returns the JSON value of a tag parameter.
+func tagValue(value string) interface{} {
+	if b, err := strconv.ParseBool(value); err == nil {
+		return b
+	}
+	if n, err := strconv.Atoi(value); err == nil {
+		return n
+	}
+	return value
+}
+
+// IndexOptions holds the parts of the index definition that depend on configuration.
+type IndexOptions struct {
+	// EmbeddingDims adds a vector "embedding" field when non-zero.
+	EmbeddingDims int
+	// Settings size the index. Sinks fill them in from their own
+	// configuration, see Elasticsearch.WithSettings.
+	Settings IndexSettings
+	// Version is the Elasticsearch version the definition is for, filled
+	// in by the sink. The zero Version means the current one.
+	Version Version
+}
+
+// IndexSettings are index settings applied when the index is created.
+// Zero values leave the cluster defaults.
+type IndexSettings struct {
+	Shards int
+	// Replicas is nil for the default, as zero replicas is a valid choice.
+	Replicas *int
+	// RefreshInterval such as "30s", or "-1" to disable refreshes.
+	RefreshInterval string
+	// Codec is the stored fields compression, e.g. "best_compression".
+	Codec string
+	// Synonyms are rules expanded at search time, see LoadSynonyms.
+	Synonyms []string
+	// Languages add a subfield per language analyzer to the text fields,
+	// see ParseLanguages. Unlike the settings they are also added to an
+	// existing index, for documents written from then on.
+	Languages []string
+	// SynonymsSet names the Elasticsearch synonyms set holding Synonyms,
+	// which lets them be updated without closing the index.
+	SynonymsSet string
+	// AccessFields are the keyword properties of the access object of
+	// documents, see model.Article.Access. Like the languages they are
+	// also added to an existing index.
+	AccessFields []string
+}
+
+// apply adds the settings that are set to the index settings.
+func (s IndexSettings) apply(settings map[string]interface{}) {
+	if s.Shards > 0 {
+		settings["number_of_shards"] = s.Shards
+	}
+	if s.Replicas != nil {
+		settings["number_of_replicas"] = *s.Replicas
+	}
+	if s.RefreshInterval != "" {
+		settings["refresh_interval"] = s.RefreshInterval
+	}
+	if s.Codec != "" {
+		settings["codec"] = s.Codec
+	}
+	switch {
+	case s.SynonymsSet != "":
+		addSynonyms(settings, map[string]interface{}{"synonyms_set": s.SynonymsSet, "updateable": true})
+	case len(s.Synonyms) > 0:
+		addSynonyms(settings, map[string]interface{}{"synonyms": s.Synonyms})
+	}
+}
+
+// serverless returns s without the settings serverless projects reject.
+func (s IndexSettings) serverless() IndexSettings {
+	if s.Shards > 0 || s.Replicas != nil || s.RefreshInterval != "" || s.Codec != "" {
+		log.Warn().Caller().Msg("ignoring shards, replicas, refresh interval and codec, which serverless projects manage themselves")
+	}
+	s.Shards, s.Replicas, s.RefreshInterval, s.Codec = 0, nil, "", ""
+	return s
+}
+
+// BuildIndexBody returns the Elasticsearch index creation body for opts.
+func BuildIndexBody(opts IndexOptions) ([]byte, error) {
+	return buildIndexBody(opts, func(properties, _ map[string]interface{}) {
+		vector := map[string]interface{}{
+			"type": "dense_vector",
+			"dims": opts.EmbeddingDims,
+		}
+		if opts.Version.indexedVectors() {
+			vector["index"] = true
+			vector["similarity"] = "cosine"
+		}
+		properties["embedding"] = vector
+	})
+}
+
+// BuildOpenSearchIndexBody returns the OpenSearch index creation body for
+// opts. OpenSearch has no dense_vector, so embeddings use the k-NN plugin.
+func BuildOpenSearchIndexBody(opts IndexOptions) ([]byte, error) {
+	return buildIndexBody(opts, func(properties, settings map[string]interface{}) {
+		settings["index.knn"] = true
+		properties["embedding"] = map[string]interface{}{
+			"type":      "knn_vector",
+			"dimension": opts.EmbeddingDims,
+			"method": map[string]interface{}{
+				"name":       "hnsw",
+				"engine":     "lucene",
+				"space_type": "cosinesimil",
+			},
+		}
+	})
+}
+
+// mappingsOf extracts the mappings from an index definition body, as
+// taken by the put mapping API.
+func mappingsOf(body []byte) ([]byte, error) {
+	var definition struct {
+		Mappings json.RawMessage `json:"mappings"`
+	}
+	if err := json.Unmarshal(body, &definition); err != nil {
+		return nil, err
+	}
+	return definition.Mappings, nil
+}
+
+// buildIndexBody adds the configuration dependent fields to the static
+// definition. addVector is only called when embeddings are enabled.
+func buildIndexBody(opts IndexOptions, addVector func(properties, settings map[string]interface{})) ([]byte, error) {
+	var settings map[string]interface{}
+	if err := json.Unmarshal([]byte(analysisSettings), &settings); err != nil {
+		return nil, err
+	}
+	mappings, err := articleMappings()
+	if err != nil {
+		return nil, err
+	}
+	body := map[string]interface{}{"settings": settings, "mappings": mappings}
+	properties := mappings["properties"].(map[string]interface{})
+
+	if opts.Version.Serverless() {
+		opts.Settings = opts.Settings.serverless()
+	}
+	opts.Settings.apply(settings)
+	if opts.Settings.SynonymsSet != "" || len(opts.Settings.Synonyms) > 0 {
+		searchWithSynonyms(properties)
+	}
+	if len(opts.Settings.Languages) > 0 {
+		addLanguages(properties, opts.Settings.Languages)
+	}
+	if len(opts.Settings.AccessFields) > 0 {
+		access := make(map[string]interface{}, len(opts.Settings.AccessFields))
+		for _, name := range opts.Settings.AccessFields {
+			access[name] = map[string]interface{}{"type": "keyword"}
+		}
+		properties["access"] = map[string]interface{}{"properties": access}
+	}
+	if opts.EmbeddingDims > 0 {
+		addVector(properties, settings)
+	}
+
+	return json.Marshal(body)
+}
@@ -0,0 +1,121 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestArticleMappingsGolden catches changes to the generated mapping: a
+// field added to model.Article or a changed es tag shows up as a diff of
+// testdata/mappings.json, which is updated with go test -update.
+func TestArticleMappingsGolden(t *testing.T) {
+	mappings, err := articleMappings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.MarshalIndent(mappings, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+
+	golden := filepath.Join("testdata", "mappings.json")
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("mapping generated from model.Article differs from %s, run go test -update if the change is intended:\n%s", golden, got)
+	}
+}
+
+// TestArticleMappingsCoverModel checks every field of the indexed
+// document is mapped, as the mapping is strict.
+func TestArticleMappingsCoverModel(t *testing.T) {
+	mappings, err := articleMappings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	properties := mappings["properties"].(map[string]interface{})
+
+	typ := reflect.TypeOf(model.Article{})
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Tag.Get("es") == "-" {
+			if _, ok := properties[name]; ok {
+				t.Errorf("field %s is excluded with es:\"-\" but mapped as %s", field.Name, name)
+			}
+			continue
+		}
+		if _, ok := properties[name]; !ok {
+			t.Errorf("field %s is not mapped as %s", field.Name, name)
+		}
+	}
+	for name := range documentOnlyProperties {
+		if _, ok := properties[name]; !ok {
+			t.Errorf("document only field %s is not mapped", name)
+		}
+	}
+}
+
+func TestTagPropertiesRejectsUntaggedField(t *testing.T) {
+	type untagged struct {
+		Title string `json:"title"`
+	}
+	if _, err := tagProperties(reflect.TypeOf(untagged{})); err == nil {
+		t.Error("expected an error for a field without es tag")
+	}
+}
+
+func TestParseMappingTag(t *testing.T) {
+	tests := []struct {
+		tag     string
+		want    string
+		wantErr bool
+	}{
+		{tag: "type:keyword", want: `{"type":"keyword"}`},
+		{tag: "type:keyword,index:false", want: `{"index":false,"type":"keyword"}`},
+		{tag: "type:keyword,ignore_above:2048", want: `{"ignore_above":2048,"type":"keyword"}`},
+		{
+			tag:  "type:text,analyzer:news_text,keyword.normalizer:keyword_lowercase",
+			want: `{"analyzer":"news_text","fields":{"keyword":{"normalizer":"keyword_lowercase","type":"keyword"}},"type":"text"}`,
+		},
+		{tag: "analyzer:news_text", wantErr: true},
+		{tag: "type:keyword,index", wantErr: true},
+		{tag: "type:", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			mapping, err := parseMappingTag(tt.tag)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %v", mapping)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := json.Marshal(mapping)
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// Meilisearch writes articles to a Meilisearch index. Its settings are
+// derived from the Elasticsearch mapping: text fields are searchable,
+// keyword, numeric and date fields filterable and numbers and dates
+// sortable. Dates are stored as unix seconds and the location as _geo.
+// Embeddings are not sent, as Meilisearch manages its own embedders.
+type Meilisearch struct {
+	baseURL string
+	apiKey  string
+	index   string
+	client  *http.Client
+}
+
+// NewMeilisearch returns a sink writing to index of the instance at baseURL.
+func NewMeilisearch(baseURL, apiKey, index string) *Meilisearch {
+	return &Meilisearch{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		index:   index,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (m *Meilisearch) Name() string { return "meilisearch" }
+
+func (m *Meilisearch) Close() error { return nil }
+
+// Ping implements Pinger.
+func (m *Meilisearch) Ping(ctx context.Context) error {
+	return m.do(ctx, http.MethodGet, "/health", nil, nil)
+}
+
+// meiliTask is the summary returned for asynchronous operations.
+type meiliTask struct {
+	TaskUID int64  `json:"taskUid"`
+	UID     int64  `json:"uid"`
+	Status  string `json:"status"`
+	Error   *struct {
+		Message string `json:"message"`
+		Code    string `json:"code"`
+	} `json:"error"`
+}
+
+// Prepare implements Sink by creating the index and applying the settings
+// derived from the mapping. Both are idempotent.
+func (m *Meilisearch) Prepare(ctx context.Context, _ IndexOptions) error {
+	var created meiliTask
+	err := m.do(ctx, http.MethodPost, "/indexes", map[string]string{"uid": m.index, "primaryKey": "id"}, &created)
+	if err != nil {
+		return err
+	}
+	if err := m.wait(ctx, created.TaskUID); err != nil && !strings.Contains(err.Error(), "index_already_exists") {
+		return err
+	}
+
+	fields, err := schemaFields()
+	if err != nil {
+		return err
+	}
+	searchable, filterable, sortable := []string{}, []string{}, []string{}
+	for _, f := range fields {
+		name := f.name
+		if f.esType == "geo_point" {
+			name = "_geo"
+		}
+		if f.searchable() {
+			searchable = append(searchable, name)
+		}
+		if f.filterable() {
+			filterable = append(filterable, name)
+		}
+		if f.sortable() || f.esType == "geo_point" {
+			sortable = append(sortable, name)
+		}
+	}
+
+	var updated meiliTask
+	settings := map[string][]string{
+		"searchableAttributes": searchable,
+		"filterableAttributes": filterable,
+		"sortableAttributes":   sortable,
+	}
+	if err := m.do(ctx, http.MethodPatch, "/indexes/"+url.PathEscape(m.index)+"/settings", settings, &updated); err != nil {
+		return err
+	}
+	return m.wait(ctx, updated.TaskUID)
+}
+
+// WriteBatch implements Sink. Meilisearch applies a batch atomically, so
+// a rejected document fails the whole batch.
+func (m *Meilisearch) WriteBatch(ctx context.Context, articles []model.Article) error {
+	docs := make([]map[string]interface{}, 0, len(articles))
+	for _, a := range articles {
+		doc, err := flatDocument(a)
+		if err != nil {
+			return err
+		}
+		delete(doc, "embedding")
+		if utils.ValidCoordinates(a.Latitude, a.Longitude) {
+			doc["_geo"] = map[string]float64{"lat": a.Latitude, "lng": a.Longitude}
+		}
+		docs = append(docs, doc)
+	}
+
+	var task meiliTask
+	if err := m.do(ctx, http.MethodPost, "/indexes/"+url.PathEscape(m.index)+"/documents", docs, &task); err != nil {
+		return err
+	}
+	return m.wait(ctx, task.TaskUID)
+}
+
+// wait polls the task until Meilisearch has processed it.
+func (m *Meilisearch) wait(ctx context.Context, uid int64) error {
+	delay := 50 * time.Millisecond
+	for {
+		var task meiliTask
+		if err := m.do(ctx, http.MethodGet, fmt.Sprintf("/tasks/%d", uid), nil, &task); err != nil {
+			return err
+		}
+		switch task.Status {
+		case "succeeded":
+			return nil
+		case "failed", "canceled":
+			if task.Error != nil {
+				return fmt.Errorf("meilisearch task %d %s: %s (%s)", uid, task.Status, task.Error.Message, task.Error.Code)
+			}
+			return fmt.Errorf("meilisearch task %d %s", uid, task.Status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, time.Second)
+	}
+}
+
+func (m *Meilisearch) do(ctx context.Context, method, path string, body, out interface{}) error {
+	header := http.Header{}
+	if m.apiKey != "" {
+		header.Set("Authorization", "Bearer "+m.apiKey)
+	}
+	return doJSON(ctx, m.client, method, m.baseURL+path, header, body, out)
+}
@@ -0,0 +1,57 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+// NDJSON writes one document per line, exactly as it would be indexed.
+// It is handy for inspecting the output of the pipeline without a cluster.
+type NDJSON struct {
+	w   io.Writer
+	enc *json.Encoder
+}
+
+// NewNDJSON writes to the file at path, or to stdout when path is "-".
+func NewNDJSON(path string) (*NDJSON, error) {
+	var w io.Writer = os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		w = f
+	}
+	return &NDJSON{w: w, enc: json.NewEncoder(w)}, nil
+}
+
+func (n *NDJSON) Name() string { return "ndjson" }
+
+// Prepare implements Sink. There is no schema to create.
+func (n *NDJSON) Prepare(context.Context, IndexOptions) error { return nil }
+
+// WriteBatch implements Sink.
+func (n *NDJSON) WriteBatch(_ context.Context, articles []model.Article) error {
+	for _, a := range articles {
+		formattedDate, err := publicationDate(a)
+		if err != nil {
+			return err
+		}
+		doc, _ := document(a, formattedDate)
+		if err := n.enc.Encode(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *NDJSON) Close() error {
+	if f, ok := n.w.(*os.File); ok && f != os.Stdout {
+		return f.Close()
+	}
+	return nil
+}
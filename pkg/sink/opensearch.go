@@ -0,0 +1,155 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+// OpenSearch indexes articles into a single OpenSearch index, using the
+// same mapping and bulk semantics as the Elasticsearch sink.
+type OpenSearch struct {
+	client *opensearchapi.Client
+	index  string
+	// routing names the document field whose value routes it to a shard.
+	routing string
+	// settings are applied when the index is created.
+	settings IndexSettings
+	// batches numbers the bulk requests in logs.
+	batches atomic.Int64
+	// dumpDir receives a copy of every bulk request body, empty for none.
+	dumpDir string
+}
+
+// NewOpenSearch returns a sink writing to index through client.
+func NewOpenSearch(client *opensearchapi.Client, index string) *OpenSearch {
+	return &OpenSearch{client: client, index: index}
+}
+
+// WithRouting routes each document by the value of field.
+func (o *OpenSearch) WithRouting(field string) *OpenSearch {
+	o.routing = field
+	return o
+}
+
+// WithDumpDir writes the body of every bulk request to a file in dir,
+// for diagnosing rejected documents.
+func (o *OpenSearch) WithDumpDir(dir string) *OpenSearch {
+	o.dumpDir = dir
+	return o
+}
+
+// WithSettings sets the settings Prepare applies when it creates the
+// index.
+func (o *OpenSearch) WithSettings(settings IndexSettings) *OpenSearch {
+	o.settings = settings
+	return o
+}
+
+func (o *OpenSearch) Name() string { return "opensearch" }
+
+func (o *OpenSearch) Close() error { return nil }
+
+// Ping implements Pinger.
+func (o *OpenSearch) Ping(ctx context.Context) error {
+	res, err := o.client.Ping(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("ping failed: %s", res.Status())
+	}
+	return nil
+}
+
+// Prepare implements Sink by creating the index with the mapping for opts
+// unless it already exists, in which case fields added since are mapped.
+func (o *OpenSearch) Prepare(ctx context.Context, opts IndexOptions) error {
+	opts.Settings = o.settings
+	body, err := BuildOpenSearchIndexBody(opts)
+	if err != nil {
+		return err
+	}
+
+	res, err := o.client.Indices.Exists(ctx, opensearchapi.IndicesExistsReq{Indices: []string{o.index}})
+	if res != nil {
+		res.Body.Close()
+		if res.StatusCode == http.StatusOK {
+			return o.putMapping(ctx, body)
+		}
+	}
+	if err != nil && (res == nil || res.StatusCode != http.StatusNotFound) {
+		return err
+	}
+
+	if _, err := o.client.Indices.Create(ctx, opensearchapi.IndicesCreateReq{
+		Index: o.index,
+		Body:  bytes.NewReader(body),
+	}); err != nil {
+		return fmt.Errorf("failed to create index %s: %w", o.index, err)
+	}
+	log.Info().Caller().Msgf("index: (%s) created successfully", o.index)
+	return nil
+}
+
+// putMapping adds the fields an existing index lacks.
+func (o *OpenSearch) putMapping(ctx context.Context, body []byte) error {
+	mappings, err := mappingsOf(body)
+	if err != nil {
+		return err
+	}
+	if _, err := o.client.Indices.Mapping.Put(ctx, opensearchapi.MappingPutReq{
+		Indices: []string{o.index},
+		Body:    bytes.NewReader(mappings),
+	}); err != nil {
+		return fmt.Errorf("failed to update mapping of %s: %w", o.index, err)
+	}
+	return nil
+}
+
+// WriteBatch implements Sink by sending articles in a single bulk request.
+func (o *OpenSearch) WriteBatch(ctx context.Context, articles []model.Article) error {
+	batch := o.batches.Add(1)
+	body, err := bulkBody(o.index, articles, o.routing)
+	if err != nil {
+		return err
+	}
+	defer releaseBulkBody(body)
+	if o.dumpDir != "" {
+		dumpBatch(o.dumpDir, o.Name(), o.index, batch, body.Bytes())
+	}
+
+	res, err := o.client.Bulk(ctx, opensearchapi.BulkReq{Body: body, Params: opensearchapi.BulkParams{FilterPath: bulkFilterPath}})
+	if err != nil {
+		return fmt.Errorf("bulk request failed: %w", err)
+	}
+
+	rejected := make(map[string]string)
+	for _, item := range res.Items {
+		for _, action := range item {
+			if e := action.Error; e != nil {
+				rejected[action.ID] = e.Type + ": " + e.Reason
+				logItemFailure(itemFailure{
+					id:          action.ID,
+					status:      action.Status,
+					batch:       batch,
+					errType:     e.Type,
+					reason:      e.Reason,
+					causeType:   e.Cause.Type,
+					causeReason: e.Cause.Reason,
+				})
+			}
+		}
+	}
+	if len(rejected) > 0 {
+		return &PartialError{Failed: len(rejected), Rejected: rejected}
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Ways Relate finds related articles.
+const (
+	// RelatedMoreLikeThis matches the terms of the title and description.
+	RelatedMoreLikeThis = "mlt"
+	// RelatedKNN finds the nearest embeddings, which needs the embedding
+	// enricher.
+	RelatedKNN = "knn"
+)
+
+// relateBatch is how many articles share a multi search request.
+const relateBatch = 100
+
+// Relate stores the IDs of the size articles most related to each article
+// the run runID wrote in its related_ids, so readers get them with the
+// article. by is RelatedMoreLikeThis or RelatedKNN. It returns how many
+// articles it updated.
+func (e *Elasticsearch) Relate(ctx context.Context, runID, by string, size int) (int, error) {
+	if by != RelatedMoreLikeThis && by != RelatedKNN {
+		return 0, fmt.Errorf("unknown way %q to relate articles, expected %s or %s", by, RelatedMoreLikeThis, RelatedKNN)
+	}
+	es := e.client
+	// The run's writes must be searchable, both to be related and to be
+	// found related
+	refresh, err := es.Indices.Refresh(es.Indices.Refresh.WithIndex(e.index), es.Indices.Refresh.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	refresh.Body.Close()
+	if refresh.IsError() {
+		return 0, fmt.Errorf("failed to refresh %s: %s", e.index, refresh.Status())
+	}
+
+	updated := 0
+	var after []interface{}
+	for {
+		batch, err := e.runArticles(ctx, runID, by == RelatedKNN, after)
+		if err != nil {
+			return updated, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		related, err := e.related(ctx, batch, by, size)
+		if err != nil {
+			return updated, err
+		}
+		if err := e.putRelated(ctx, batch, related); err != nil {
+			return updated, err
+		}
+		updated += len(batch)
+		after = batch[len(batch)-1].sort
+	}
+	if updated > 0 {
+		log.Info().Caller().Msgf("stored the related articles of %d articles of %s", updated, e.index)
+	}
+	return updated, nil
+}
+
+// runArticle is an article Relate looks up related ones for.
+type runArticle struct {
+	// index is the concrete index of the article, as the index of a
+	// rollover alias may point at several.
+	index     string
+	id        string
+	routing   string
+	embedding []float32
+	sort      []interface{}
+}
+
+// runArticles returns the next batch of articles written by the run
+// runID after the sort values after, with their embedding when asked.
+func (e *Elasticsearch) runArticles(ctx context.Context, runID string, embedding bool, after []interface{}) ([]runArticle, error) {
+	request := map[string]interface{}{
+		"size":    relateBatch,
+		"query":   map[string]interface{}{"term": map[string]interface{}{"sync_run_id": runID}},
+		"sort":    []interface{}{map[string]interface{}{"id": "asc"}},
+		"_source": false,
+	}
+	if embedding {
+		request["_source"] = []string{"embedding"}
+	}
+	if after != nil {
+		request["search_after"] = after
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	es := e.client
+	res, err := es.Search(es.Search.WithIndex(e.index), es.Search.WithBody(bytes.NewReader(body)), es.Search.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, fmt.Errorf("failed to list the articles of run %s in %s: %s", runID, e.index, res.String())
+	}
+	var found struct {
+		Hits struct {
+			Hits []struct {
+				Index   string `json:"_index"`
+				ID      string `json:"_id"`
+				Routing string `json:"_routing"`
+				Source  struct {
+					Embedding []float32 `json:"embedding"`
+				} `json:"_source"`
+				Sort []interface{} `json:"sort"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&found); err != nil {
+		return nil, err
+	}
+	batch := make([]runArticle, len(found.Hits.Hits))
+	for i, hit := range found.Hits.Hits {
+		batch[i] = runArticle{index: hit.Index, id: hit.ID, routing: hit.Routing, embedding: hit.Source.Embedding, sort: hit.Sort}
+	}
+	return batch, nil
+}
+
+// related returns the IDs of the articles related to each of batch, in
+// one multi search request.
+func (e *Elasticsearch) related(ctx context.Context, batch []runArticle, by string, size int) ([][]string, error) {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, a := range batch {
+		deleted := map[string]interface{}{"term": map[string]interface{}{"deleted": true}}
+		var search map[string]interface{}
+		if by == RelatedKNN {
+			if len(a.embedding) == 0 {
+				// Still one search per article, so responses line up
+				search = map[string]interface{}{"size": 0, "query": map[string]interface{}{"match_none": map[string]interface{}{}}}
+			} else {
+				search = map[string]interface{}{
+					"size": size,
+					"knn": map[string]interface{}{
+						"field":          "embedding",
+						"query_vector":   a.embedding,
+						"k":              size + 1,
+						"num_candidates": 10 * (size + 1),
+						"filter": map[string]interface{}{"bool": map[string]interface{}{
+							"filter":   e.tenantFilter(),
+							"must_not": []interface{}{deleted, map[string]interface{}{"ids": map[string]interface{}{"values": []string{a.id}}}},
+						}},
+					},
+				}
+			}
+		} else {
+			like := map[string]interface{}{"_index": a.index, "_id": a.id}
+			if a.routing != "" {
+				like["routing"] = a.routing
+			}
+			search = map[string]interface{}{
+				"size": size,
+				"query": map[string]interface{}{"bool": map[string]interface{}{
+					"must": map[string]interface{}{"more_like_this": map[string]interface{}{
+						"fields":          []string{"title", "description"},
+						"like":            []interface{}{like},
+						"min_term_freq":   1,
+						"max_query_terms": 25,
+					}},
+					"filter":   e.tenantFilter(),
+					"must_not": deleted,
+				}},
+			}
+		}
+		search["_source"] = false
+		if err := enc.Encode(map[string]interface{}{}); err != nil {
+			return nil, err
+		}
+		if err := enc.Encode(search); err != nil {
+			return nil, err
+		}
+	}
+
+	es := e.client
+	res, err := es.Msearch(&body, es.Msearch.WithIndex(e.index), es.Msearch.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, fmt.Errorf("failed to find related articles in %s: %s", e.index, res.String())
+	}
+	var found struct {
+		Responses []struct {
+			Hits struct {
+				Hits []struct {
+					ID string `json:"_id"`
+				} `json:"hits"`
+			} `json:"hits"`
+			Error json.RawMessage `json:"error"`
+		} `json:"responses"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&found); err != nil {
+		return nil, err
+	}
+	if len(found.Responses) != len(batch) {
+		return nil, fmt.Errorf("got %d related article searches back for %d articles", len(found.Responses), len(batch))
+	}
+	related := make([][]string, len(batch))
+	for i, r := range found.Responses {
+		if len(r.Error) > 0 {
+			return nil, fmt.Errorf("failed to find the articles related to %s: %s", batch[i].id, r.Error)
+		}
+		related[i] = []string{}
+		for _, hit := range r.Hits.Hits {
+			if hit.ID != batch[i].id {
+				related[i] = append(related[i], hit.ID)
+			}
+		}
+	}
+	return related, nil
+}
+
+// putRelated sets the related_ids of batch in one bulk request.
+func (e *Elasticsearch) putRelated(ctx context.Context, batch []runArticle, related [][]string) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for i, a := range batch {
+		meta := map[string]interface{}{"_index": a.index, "_id": a.id}
+		if a.routing != "" {
+			meta["routing"] = a.routing
+		}
+		if err := enc.Encode(map[string]interface{}{"update": meta}); err != nil {
+			return err
+		}
+		if err := enc.Encode(map[string]interface{}{"doc": map[string]interface{}{"related_ids": related[i]}}); err != nil {
+			return err
+		}
+	}
+	es := e.client
+	res, err := es.Bulk(&body, es.Bulk.WithFilterPath(bulkFilterPath...), es.Bulk.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("failed to store related articles in %s: %s", e.index, res.String())
+	}
+	if err := bulkFailures(res.Body, 0); err != nil {
+		var partial *PartialError
+		if errors.As(err, &partial) {
+			return fmt.Errorf("related articles of %d articles of %s couldn't be stored", partial.Failed, e.index)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Retention is how many days articles are kept by lowercase category,
+// with "*" for the categories not listed. Articles of no rule are kept.
+type Retention map[string]int
+
+// ParseRetention parses a comma separated list of category=days, e.g.
+// "sports=30d,politics=365d,*=180d".
+func ParseRetention(spec string) (Retention, error) {
+	retention := make(Retention)
+	for _, entry := range strings.Split(spec, ",") {
+		category, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		category = strings.ToLower(strings.TrimSpace(category))
+		if !ok || category == "" {
+			return nil, fmt.Errorf("%q is not category=days", entry)
+		}
+		days, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(value), "d"))
+		if err != nil || days < 1 {
+			return nil, fmt.Errorf("invalid retention %q of %s, expected a number of days such as 30d", value, category)
+		}
+		if _, ok := retention[category]; ok {
+			return nil, fmt.Errorf("category %s is listed twice", category)
+		}
+		retention[category] = days
+	}
+	return retention, nil
+}
+
+// Expire deletes the articles published longer ago than the retention of
+// their category, first copying them to archive unless it's empty. An
+// article of several listed categories is kept for the longest of their
+// retentions. With dryRun it only counts them. It returns how many
+// articles expired.
+func (e *Elasticsearch) Expire(ctx context.Context, retention Retention, archive string, dryRun bool) (int, error) {
+	categories := make([]string, 0, len(retention))
+	for category := range retention {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	total := 0
+	for _, category := range categories {
+		days := retention[category]
+		filter := append([]interface{}{
+			map[string]interface{}{"range": map[string]interface{}{"publication_date": map[string]interface{}{"lt": fmt.Sprintf("now-%dd/d", days)}}},
+		}, e.tenantFilter()...)
+		mustNot := []interface{}{}
+		var listed, longer []string
+		for other, otherDays := range retention {
+			if other == "*" {
+				continue
+			}
+			listed = append(listed, other)
+			if otherDays > days {
+				longer = append(longer, other)
+			}
+		}
+		if category == "*" {
+			if len(listed) > 0 {
+				mustNot = append(mustNot, map[string]interface{}{"terms": map[string]interface{}{"category.keyword": listed}})
+			}
+		} else {
+			filter = append(filter, map[string]interface{}{"term": map[string]interface{}{"category.keyword": category}})
+			if len(longer) > 0 {
+				mustNot = append(mustNot, map[string]interface{}{"terms": map[string]interface{}{"category.keyword": longer}})
+			}
+		}
+		query := map[string]interface{}{"bool": map[string]interface{}{"filter": filter, "must_not": mustNot}}
+
+		label := category
+		if category == "*" {
+			label = "other"
+		}
+		count, err := e.count(ctx, query)
+		if err != nil {
+			return total, err
+		}
+		if count == 0 {
+			continue
+		}
+		if dryRun {
+			log.Info().Caller().Msgf("would expire %d %s articles of %s older than %d days", count, label, e.index, days)
+			total += count
+			continue
+		}
+		if archive != "" {
+			if err := e.reindex(ctx, query, archive); err != nil {
+				return total, err
+			}
+		}
+		deleted, err := e.deleteByQuery(ctx, query)
+		total += deleted
+		if err != nil {
+			return total, err
+		}
+		log.Info().Caller().Msgf("expired %d %s articles of %s older than %d days", deleted, label, e.index, days)
+	}
+	return total, nil
+}
+
+// PurgeExpired deletes the articles whose expires_at has passed, i.e.
+// whose license ran out. They aren't archived, as their content may no
+// longer be kept. With dryRun it only counts them. It returns how many
+// articles were purged.
+func (e *Elasticsearch) PurgeExpired(ctx context.Context, dryRun bool) (int, error) {
+	query := map[string]interface{}{"bool": map[string]interface{}{
+		"filter": append([]interface{}{
+			map[string]interface{}{"range": map[string]interface{}{"expires_at": map[string]interface{}{"lte": "now"}}},
+		}, e.tenantFilter()...),
+	}}
+	count, err := e.count(ctx, query)
+	if err != nil || count == 0 {
+		return 0, err
+	}
+	if dryRun {
+		log.Info().Caller().Msgf("would purge %d articles of %s past their license", count, e.index)
+		return count, nil
+	}
+	purged, err := e.deleteByQuery(ctx, query)
+	if err != nil {
+		return purged, err
+	}
+	log.Info().Caller().Msgf("purged %d articles of %s past their license", purged, e.index)
+	return purged, nil
+}
+
+func (e *Elasticsearch) count(ctx context.Context, query map[string]interface{}) (int, error) {
+	body, err := json.Marshal(map[string]interface{}{"query": query})
+	if err != nil {
+		return 0, err
+	}
+	es := e.client
+	res, err := es.Count(es.Count.WithIndex(e.index), es.Count.WithBody(bytes.NewReader(body)), es.Count.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return 0, fmt.Errorf("failed to count articles of %s: %s", e.index, res.String())
+	}
+	var counted struct {
+		Count int `json:"count"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&counted); err != nil {
+		return 0, err
+	}
+	return counted.Count, nil
+}
+
+// reindex copies the articles matching query to dest.
+func (e *Elasticsearch) reindex(ctx context.Context, query map[string]interface{}, dest string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"source": map[string]interface{}{"index": e.index, "query": query},
+		"dest":   map[string]interface{}{"index": dest},
+	})
+	if err != nil {
+		return err
+	}
+	es := e.client
+	res, err := es.Reindex(bytes.NewReader(body), es.Reindex.WithWaitForCompletion(true), es.Reindex.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("failed to archive articles of %s to %s: %s", e.index, dest, res.String())
+	}
+	var reindexed struct {
+		Failures []json.RawMessage `json:"failures"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&reindexed); err != nil {
+		return err
+	}
+	if len(reindexed.Failures) > 0 {
+		// Nothing is deleted that wasn't archived
+		return fmt.Errorf("%d articles of %s couldn't be archived to %s, e.g. %s", len(reindexed.Failures), e.index, dest, reindexed.Failures[0])
+	}
+	return nil
+}
+
+func (e *Elasticsearch) deleteByQuery(ctx context.Context, query map[string]interface{}) (int, error) {
+	body, err := json.Marshal(map[string]interface{}{"query": query})
+	if err != nil {
+		return 0, err
+	}
+	es := e.client
+	res, err := es.DeleteByQuery([]string{e.index}, bytes.NewReader(body),
+		es.DeleteByQuery.WithConflicts("proceed"),
+		es.DeleteByQuery.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return 0, fmt.Errorf("failed to delete expired articles of %s: %s", e.index, res.String())
+	}
+	var deleted struct {
+		Deleted  int               `json:"deleted"`
+		Failures []json.RawMessage `json:"failures"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&deleted); err != nil {
+		return 0, err
+	}
+	if len(deleted.Failures) > 0 {
+		return deleted.Deleted, fmt.Errorf("%d expired articles of %s couldn't be deleted, e.g. %s", len(deleted.Failures), e.index, deleted.Failures[0])
+	}
+	return deleted.Deleted, nil
+}
@@ -0,0 +1,55 @@
+package sink
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// RetryStats counts the responses the cluster clients get with a
+// retryable status, 429 or 502 to 504, by wrapping their transport.
+// The clients retry these attempts, or back off after a 429.
+type RetryStats struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// Wrap returns rt counting into r.
+func (r *RetryStats) Wrap(rt http.RoundTripper) http.RoundTripper {
+	return &retryCounting{base: rt, stats: r}
+}
+
+// Take returns the counts per status code since the previous call, nil
+// when there were none.
+func (r *RetryStats) Take() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := r.counts
+	r.counts = nil
+	return counts
+}
+
+func (r *RetryStats) add(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.counts == nil {
+		r.counts = make(map[string]int)
+	}
+	r.counts[strconv.Itoa(status)]++
+}
+
+type retryCounting struct {
+	base  http.RoundTripper
+	stats *RetryStats
+}
+
+func (t *retryCounting) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.base.RoundTrip(req)
+	if err == nil {
+		switch res.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			t.stats.add(res.StatusCode)
+		}
+	}
+	return res, err
+}
@@ -0,0 +1,180 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v9/esapi"
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// RolloverConditions are when a write alias rolls over to a new index.
+// Zero fields don't apply.
+type RolloverConditions struct {
+	MaxDocs int64
+	// MaxSize is the total size of the primary shards, in bytes.
+	MaxSize int64
+	// MaxAge is an Elasticsearch time value such as "30d".
+	MaxAge string
+}
+
+var timeValue = regexp.MustCompile(`^[0-9]+(d|h|m|s|ms)$`)
+
+// ParseRolloverConditions parses a comma separated list of conditions,
+// e.g. "max_docs=10000000,max_size=50gb,max_age=30d".
+func ParseRolloverConditions(spec string) (RolloverConditions, error) {
+	var c RolloverConditions
+	for _, entry := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || value == "" {
+			return c, fmt.Errorf("%q is not condition=value", entry)
+		}
+		var err error
+		switch name {
+		case "max_docs":
+			c.MaxDocs, err = strconv.ParseInt(value, 10, 64)
+			if err == nil && c.MaxDocs < 1 {
+				err = fmt.Errorf("max_docs must be positive")
+			}
+		case "max_size":
+			c.MaxSize, err = utils.ParseByteSize(value)
+			if err == nil && c.MaxSize < 1 {
+				err = fmt.Errorf("max_size must be positive")
+			}
+		case "max_age":
+			if c.MaxAge = value; !timeValue.MatchString(value) {
+				err = fmt.Errorf("invalid max_age %q, expected e.g. 30d or 12h", value)
+			}
+		default:
+			err = fmt.Errorf("unknown condition %q, expected max_docs, max_size or max_age", name)
+		}
+		if err != nil {
+			return RolloverConditions{}, err
+		}
+	}
+	return c, nil
+}
+
+// IsZero reports whether no condition is set.
+func (c RolloverConditions) IsZero() bool {
+	return c == RolloverConditions{}
+}
+
+func (c RolloverConditions) body() map[string]interface{} {
+	conditions := make(map[string]interface{})
+	if c.MaxDocs > 0 {
+		conditions["max_docs"] = c.MaxDocs
+	}
+	if c.MaxSize > 0 {
+		conditions["max_size"] = fmt.Sprintf("%db", c.MaxSize)
+	}
+	if c.MaxAge != "" {
+		conditions["max_age"] = c.MaxAge
+	}
+	return conditions
+}
+
+// WithRollover makes the index of e a write alias that Prepare rolls
+// over to a new index when one of c is met, bootstrapping it with the
+// index <alias>-000001. Articles synced again after a rollover are
+// written to the new index while their old version stays in the
+// previous one.
+func (e *Elasticsearch) WithRollover(c RolloverConditions) *Elasticsearch {
+	e.rollover = c
+	return e
+}
+
+// FirstRolloverIndex is the index bootstrapped behind the write alias.
+func FirstRolloverIndex(alias string) string { return alias + "-000001" }
+
+// prepareRollover bootstraps the write alias unless it exists, and
+// otherwise updates the mapping of its indices and rolls it over when a
+// condition is met. body is the index definition of new indices.
+func (e *Elasticsearch) prepareRollover(ctx context.Context, body []byte) error {
+	es, alias := e.client, e.index
+	indices, err := aliasIndices(ctx, es, alias)
+	if err != nil {
+		return err
+	}
+	if len(indices) == 0 {
+		exists, err := es.Indices.Exists([]string{alias}, es.Indices.Exists.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		exists.Body.Close()
+		if exists.StatusCode == 200 {
+			return fmt.Errorf("%s is an index, rollover needs a write alias of that name: reindex it into %s and alias that", alias, FirstRolloverIndex(alias))
+		}
+		return e.bootstrapRollover(ctx, body)
+	}
+
+	if err := e.putMapping(ctx, body); err != nil {
+		return err
+	}
+	var definition map[string]interface{}
+	if err := json.Unmarshal(body, &definition); err != nil {
+		return err
+	}
+	definition["conditions"] = e.rollover.body()
+	request, err := json.Marshal(definition)
+	if err != nil {
+		return err
+	}
+	res, err := es.Indices.Rollover(alias, es.Indices.Rollover.WithBody(bytes.NewReader(request)), es.Indices.Rollover.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("failed to roll over %s: %s", alias, res.String())
+	}
+	var rolled struct {
+		RolledOver bool            `json:"rolled_over"`
+		OldIndex   string          `json:"old_index"`
+		NewIndex   string          `json:"new_index"`
+		Conditions map[string]bool `json:"conditions"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&rolled); err != nil {
+		return err
+	}
+	if rolled.RolledOver {
+		var met []string
+		for condition, ok := range rolled.Conditions {
+			if ok {
+				met = append(met, condition)
+			}
+		}
+		log.Info().Caller().Msgf("rolled %s over from %s to %s on %s", alias, rolled.OldIndex, rolled.NewIndex, strings.Join(met, ", "))
+	}
+	return nil
+}
+
+// bootstrapRollover creates the first index behind the write alias.
+func (e *Elasticsearch) bootstrapRollover(ctx context.Context, body []byte) error {
+	var definition map[string]interface{}
+	if err := json.Unmarshal(body, &definition); err != nil {
+		return err
+	}
+	definition["aliases"] = map[string]interface{}{e.index: map[string]interface{}{"is_write_index": true}}
+	request, err := json.Marshal(definition)
+	if err != nil {
+		return err
+	}
+	first := FirstRolloverIndex(e.index)
+	res, err := esapi.IndicesCreateRequest{Index: first, Body: bytes.NewReader(request)}.Do(ctx, e.client)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("failed to create %s behind write alias %s: %s", first, e.index, res.String())
+	}
+	log.Info().Caller().Msgf("index %s created behind write alias %s", first, e.index)
+	return nil
+}
@@ -0,0 +1,141 @@
+package sink
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+// schemaField is a field of the Elasticsearch mapping, flattened so the
+// schema of lighter search engines can be derived from it.
+type schemaField struct {
+	// name is dotted for the properties of objects, e.g. "sentiment.label".
+	name   string
+	esType string
+	// keyword is set for text fields with an exact match sub field.
+	keyword bool
+	// array is set when the article holds a list of values.
+	array bool
+}
+
+func (f schemaField) searchable() bool { return f.esType == "text" }
+
+func (f schemaField) filterable() bool {
+	switch f.esType {
+	case "keyword", "float", "integer", "boolean", "date", "geo_point":
+		return true
+	}
+	return f.keyword
+}
+
+func (f schemaField) sortable() bool {
+	return f.esType == "float" || f.esType == "integer" || f.esType == "date"
+}
+
+// schemaFields flattens the generated mapping. Configuration dependent
+// fields such as the embedding aren't included.
+func schemaFields() ([]schemaField, error) {
+	mappings, err := articleMappings()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(mappings)
+	if err != nil {
+		return nil, err
+	}
+	var body struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, err
+	}
+	arrays := arrayFields(reflect.TypeOf(model.Article{}), "")
+
+	var fields []schemaField
+	var walk func(prefix string, properties map[string]json.RawMessage) error
+	walk = func(prefix string, properties map[string]json.RawMessage) error {
+		for name, raw := range properties {
+			var prop struct {
+				Type       string                     `json:"type"`
+				Fields     map[string]json.RawMessage `json:"fields"`
+				Properties map[string]json.RawMessage `json:"properties"`
+			}
+			if err := json.Unmarshal(raw, &prop); err != nil {
+				return err
+			}
+			if prop.Properties != nil {
+				if err := walk(prefix+name+".", prop.Properties); err != nil {
+					return err
+				}
+				continue
+			}
+			_, keyword := prop.Fields["keyword"]
+			fields = append(fields, schemaField{
+				name:    prefix + name,
+				esType:  prop.Type,
+				keyword: keyword,
+				array:   arrays[prefix+name],
+			})
+		}
+		return nil
+	}
+	if err := walk("", body.Properties); err != nil {
+		return nil, err
+	}
+	// Keep derived schemas stable across runs
+	sort.Slice(fields, func(i, j int) bool { return fields[i].name < fields[j].name })
+	return fields, nil
+}
+
+// arrayFields returns the dotted json names of the slice fields of t,
+// except the embedding which is a vector rather than a list of values.
+func arrayFields(t reflect.Type, prefix string) map[string]bool {
+	arrays := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		typ := field.Type
+		if typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		switch {
+		case typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.String:
+			arrays[prefix+name] = true
+		case typ.Kind() == reflect.Struct:
+			for nested := range arrayFields(typ, prefix+name+".") {
+				arrays[nested] = true
+			}
+		}
+	}
+	return arrays
+}
+
+// flatDocument returns the document for search engines without a date or
+// geo_point type: dates become unix seconds, the location is left to the
+// caller, which receives it in its own format, and the region shape is
+// left out.
+func flatDocument(a model.Article) (map[string]interface{}, error) {
+	formattedDate, err := publicationDate(a)
+	if err != nil {
+		return nil, err
+	}
+	doc, _ := document(a, formattedDate)
+	delete(doc, "location")
+	delete(doc, "region_shape")
+	for _, field := range []string{"publication_date", "ingested_at", "expires_at"} {
+		value, ok := doc[field].(string)
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, err
+		}
+		doc[field] = t.Unix()
+	}
+	return doc, nil
+}
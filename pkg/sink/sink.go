@@ -0,0 +1,90 @@
+// Package sink writes articles to their destinations. It owns the index
+// mapping and the document shape shared by all of them.
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+// DefaultBulkSize is the number of documents passed to a single WriteBatch.
+const DefaultBulkSize = 500
+
+// Sink is a destination articles are written to.
+type Sink interface {
+	// Name identifies the sink in logs and reports.
+	Name() string
+	// Prepare creates the destination schema for opts unless it exists.
+	Prepare(ctx context.Context, opts IndexOptions) error
+	// WriteBatch writes articles. When only some of them are rejected it
+	// returns a *PartialError; any other error means the batch failed.
+	WriteBatch(ctx context.Context, articles []model.Article) error
+	Close() error
+}
+
+// Pinger is implemented by sinks that can check their destination is reachable.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// PartialError reports documents rejected by the destination while the
+// rest of the batch was written.
+type PartialError struct {
+	Failed int
+	// Rejected holds the reason of each rejected document by ID, when the
+	// destination names them.
+	Rejected map[string]string
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("%d documents were rejected", e.Failed)
+}
+
+// Result counts the outcome of writing articles.
+type Result struct {
+	Indexed int
+	Failed  int
+	// Rejected is PartialError.Rejected of the batches written.
+	Rejected map[string]string
+}
+
+func (r *Result) add(other Result) {
+	r.Indexed += other.Indexed
+	r.Failed += other.Failed
+	if len(other.Rejected) > 0 && r.Rejected == nil {
+		r.Rejected = make(map[string]string, len(other.Rejected))
+	}
+	maps.Copy(r.Rejected, other.Rejected)
+}
+
+// Write passes articles to s in batches of batchSize. Rejected documents
+// are counted in the result; the first batch that fails outright stops
+// the write and its error is returned.
+func Write(ctx context.Context, s Sink, articles []model.Article, batchSize int) (Result, error) {
+	var result Result
+	for start := 0; start < len(articles); start += batchSize {
+		end := min(start+batchSize, len(articles))
+		batch, err := writeBatch(ctx, s, articles[start:end])
+		result.add(batch)
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func writeBatch(ctx context.Context, s Sink, articles []model.Article) (Result, error) {
+	err := s.WriteBatch(ctx, articles)
+	var partial *PartialError
+	if errors.As(err, &partial) {
+		return Result{Indexed: len(articles) - partial.Failed, Failed: partial.Failed, Rejected: partial.Rejected}, nil
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Indexed: len(articles)}, nil
+}
@@ -0,0 +1,105 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9/esapi"
+	"github.com/rs/zerolog/log"
+)
+
+// LiveAlias returns the filtered alias of index that leaves out soft
+// deleted articles, for readers that shouldn't see them.
+func LiveAlias(index string) string { return index + "-live" }
+
+// softDeleteScript marks a document deleted. Syncing the article again
+// replaces the whole document, which undeletes it.
+const softDeleteScript = `ctx._source.deleted = true; ctx._source.deleted_at = params.deleted_at`
+
+// Prune soft deletes the articles of the index that the run runID didn't
+// write, i.e. those gone from the source: they are marked deleted with
+// the time, rather than removed, and left out of LiveAlias. It returns
+// how many it marked.
+func (e *Elasticsearch) Prune(ctx context.Context, runID string) (int, error) {
+	es := e.client
+	// The run's writes must be searchable, or the versions they replaced
+	// would match
+	refresh, err := es.Indices.Refresh(es.Indices.Refresh.WithIndex(e.index), es.Indices.Refresh.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	refresh.Body.Close()
+	if refresh.IsError() {
+		return 0, fmt.Errorf("failed to refresh %s: %s", e.index, refresh.Status())
+	}
+
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": e.tenantFilter(),
+				"must_not": []interface{}{
+					map[string]interface{}{"term": map[string]interface{}{"sync_run_id": runID}},
+					map[string]interface{}{"term": map[string]interface{}{"deleted": true}},
+				},
+			},
+		},
+		"script": map[string]interface{}{
+			"source": softDeleteScript,
+			"lang":   "painless",
+			"params": map[string]interface{}{"deleted_at": time.Now().UTC().Format(time.RFC3339)},
+		},
+	}
+	body, err := json.Marshal(query)
+	if err != nil {
+		return 0, err
+	}
+	res, err := es.UpdateByQuery([]string{e.index},
+		es.UpdateByQuery.WithBody(bytes.NewReader(body)),
+		es.UpdateByQuery.WithConflicts("proceed"),
+		es.UpdateByQuery.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return 0, fmt.Errorf("failed to soft delete pruned articles of %s: %s", e.index, res.String())
+	}
+	var updated struct {
+		Updated  int               `json:"updated"`
+		Failures []json.RawMessage `json:"failures"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&updated); err != nil {
+		return 0, err
+	}
+	if len(updated.Failures) > 0 {
+		return updated.Updated, fmt.Errorf("%d articles of %s couldn't be soft deleted, e.g. %s", len(updated.Failures), e.index, updated.Failures[0])
+	}
+
+	if err := e.putLiveAlias(ctx); err != nil {
+		return updated.Updated, err
+	}
+	return updated.Updated, nil
+}
+
+// putLiveAlias creates or updates LiveAlias of the index.
+func (e *Elasticsearch) putLiveAlias(ctx context.Context) error {
+	filter := `{"filter":{"bool":{"must_not":{"term":{"deleted":true}}}}}`
+	req := esapi.IndicesPutAliasRequest{
+		Index: []string{e.index},
+		Name:  LiveAlias(e.index),
+		Body:  bytes.NewReader([]byte(filter)),
+	}
+	res, err := req.Do(ctx, e.client)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("failed to update alias %s: %s", LiveAlias(e.index), res.String())
+	}
+	log.Info().Caller().Msgf("alias %s excludes soft deleted articles of %s", LiveAlias(e.index), e.index)
+	return nil
+}
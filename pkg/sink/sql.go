@@ -0,0 +1,270 @@
+package sink
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// dialect covers the differences between the supported SQL databases.
+type dialect struct {
+	name string
+	// placeholder returns the bind parameter for the i-th (1 based) value.
+	placeholder func(i int) string
+	// types maps the portable column types used below to database types.
+	types map[string]string
+}
+
+// sqlColumn is a column of the articles table and how to fill it.
+type sqlColumn struct {
+	name  string
+	kind  string
+	value func(a model.Article, publicationDate string) interface{}
+}
+
+// sqlColumns mirror the indexed document. The embedding is left out as
+// it is only useful for vector search.
+var sqlColumns = []sqlColumn{
+	{"id", "text", func(a model.Article, _ string) interface{} { return a.ID }},
+	{"title", "text", func(a model.Article, _ string) interface{} { return a.Title }},
+	{"description", "text", func(a model.Article, _ string) interface{} { return a.Description }},
+	{"url", "text", func(a model.Article, _ string) interface{} { return a.URL }},
+	{"publication_date", "timestamp", func(_ model.Article, d string) interface{} { return nullString(d) }},
+	{"source_name", "text", func(a model.Article, _ string) interface{} { return a.SourceName }},
+	{"category", "json", func(a model.Article, _ string) interface{} { return jsonValue(a.Category) }},
+	{"relevance_score", "float", func(a model.Article, _ string) interface{} { return a.RelevanceScore }},
+	{"source_trust", "float", func(a model.Article, _ string) interface{} {
+		if a.SourceTrust == nil {
+			return nil
+		}
+		return *a.SourceTrust
+	}},
+	{"latitude", "float", func(a model.Article, _ string) interface{} { return coordinate(a, a.Latitude) }},
+	{"longitude", "float", func(a model.Article, _ string) interface{} { return coordinate(a, a.Longitude) }},
+	{"location_name", "text", func(a model.Article, _ string) interface{} { return nullString(a.LocationName) }},
+	{"country", "text", func(a model.Article, _ string) interface{} { return nullString(a.Country) }},
+	{"state", "text", func(a model.Article, _ string) interface{} { return nullString(a.State) }},
+	{"city", "text", func(a model.Article, _ string) interface{} { return nullString(a.City) }},
+	{"geohash", "text", func(a model.Article, _ string) interface{} { return nullString(a.Geohash) }},
+	{"region", "text", func(a model.Article, _ string) interface{} { return nullString(a.Region) }},
+	{"region_shape", "json", func(a model.Article, _ string) interface{} {
+		if len(a.RegionShape) == 0 {
+			return nil
+		}
+		return jsonValue(a.RegionShape)
+	}},
+	{"canonical_url", "text", func(a model.Article, _ string) interface{} { return nullString(a.CanonicalURL) }},
+	{"llm_summary", "text", func(a model.Article, _ string) interface{} { return nullString(a.LLMSummary) }},
+	{"tags", "json", func(a model.Article, _ string) interface{} { return jsonValue(a.Tags) }},
+	{"author", "text", func(a model.Article, _ string) interface{} { return nullString(a.Author) }},
+	{"image_url", "text", func(a model.Article, _ string) interface{} { return nullString(a.ImageURL) }},
+	{"is_paywalled", "boolean", func(a model.Article, _ string) interface{} {
+		if a.IsPaywalled == nil {
+			return nil
+		}
+		return *a.IsPaywalled
+	}},
+	{"word_count", "integer", func(a model.Article, _ string) interface{} { return nullInt(a.WordCount) }},
+	{"reading_time", "integer", func(a model.Article, _ string) interface{} { return nullInt(a.ReadingTime) }},
+	{"extra", "json", func(a model.Article, _ string) interface{} {
+		if len(a.Extra) == 0 {
+			return nil
+		}
+		return jsonValue(a.Extra)
+	}},
+	{"ingested_at", "timestamp", func(a model.Article, _ string) interface{} { return nullString(a.IngestedAt) }},
+	{"sync_run_id", "text", func(a model.Article, _ string) interface{} { return nullString(a.SyncRunID) }},
+	{"source_file", "text", func(a model.Article, _ string) interface{} { return nullString(a.SourceFile) }},
+	{"expires_at", "timestamp", func(a model.Article, _ string) interface{} { return nullString(a.ExpiresAt) }},
+	{"story_id", "text", func(a model.Article, _ string) interface{} { return nullString(a.StoryID) }},
+	{"story_canonical", "boolean", func(a model.Article, _ string) interface{} {
+		if a.StoryID == "" {
+			return nil
+		}
+		return a.StoryCanonical
+	}},
+	{"story_sources", "integer", func(a model.Article, _ string) interface{} { return nullInt(a.StorySources) }},
+	{"tenant", "text", func(a model.Article, _ string) interface{} { return nullString(a.Tenant) }},
+	{"access", "json", func(a model.Article, _ string) interface{} {
+		if len(a.Access) == 0 {
+			return nil
+		}
+		return jsonValue(a.Access)
+	}},
+	{"entities", "json", func(a model.Article, _ string) interface{} {
+		if a.Entities.Empty() {
+			return nil
+		}
+		return jsonValue(a.Entities)
+	}},
+	{"sentiment", "json", func(a model.Article, _ string) interface{} {
+		if a.Sentiment == nil {
+			return nil
+		}
+		return jsonValue(a.Sentiment)
+	}},
+}
+
+// SQL upserts articles into a relational table, which is created by
+// Prepare when missing. Re-syncing an article replaces its row.
+type SQL struct {
+	db      *sql.DB
+	dialect dialect
+	table   string
+}
+
+// tableName guards the table name, which can't be a bind parameter.
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+func openSQL(driver, dsn, table string, d dialect) (*SQL, error) {
+	if !tableName.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &SQL{db: db, dialect: d, table: table}, nil
+}
+
+func (s *SQL) Name() string { return s.dialect.name }
+
+func (s *SQL) Close() error { return s.db.Close() }
+
+// Ping implements Pinger.
+func (s *SQL) Ping(ctx context.Context) error { return s.db.PingContext(ctx) }
+
+// Prepare implements Sink by creating the table unless it exists, and
+// adding the columns an existing table lacks. The index options don't
+// apply to SQL sinks.
+func (s *SQL) Prepare(ctx context.Context, _ IndexOptions) error {
+	defs := make([]string, len(sqlColumns))
+	for i, c := range sqlColumns {
+		defs[i] = c.name + " " + s.dialect.types[c.kind]
+	}
+	defs[0] += " PRIMARY KEY"
+
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\t%s\n)", s.table, strings.Join(defs, ",\n\t"))
+	if _, err := s.db.ExecContext(ctx, query); err != nil {
+		return err
+	}
+	return s.addMissingColumns(ctx)
+}
+
+// addMissingColumns brings tables created by older versions up to date.
+func (s *SQL) addMissingColumns(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s LIMIT 0", s.table))
+	if err != nil {
+		return err
+	}
+	existing, err := rows.Columns()
+	rows.Close()
+	if err != nil {
+		return err
+	}
+	have := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		have[strings.ToLower(name)] = true
+	}
+
+	for _, c := range sqlColumns {
+		if have[c.name] {
+			continue
+		}
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", s.table, c.name, s.dialect.types[c.kind])
+		if _, err := s.db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("adding column %s: %w", c.name, err)
+		}
+	}
+	return nil
+}
+
+// WriteBatch implements Sink, upserting articles in a single transaction.
+func (s *SQL) WriteBatch(ctx context.Context, articles []model.Article) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, s.upsertQuery())
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	values := make([]interface{}, len(sqlColumns))
+	for _, a := range articles {
+		formattedDate, err := publicationDate(a)
+		if err != nil {
+			return err
+		}
+		for i, c := range sqlColumns {
+			values[i] = c.value(a, formattedDate)
+		}
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			return fmt.Errorf("upserting article %s: %w", a.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// upsertQuery inserts a row or replaces the one with the same id. The
+// ON CONFLICT syntax is shared by PostgreSQL and SQLite.
+func (s *SQL) upsertQuery() string {
+	names := make([]string, len(sqlColumns))
+	params := make([]string, len(sqlColumns))
+	var updates []string
+	for i, c := range sqlColumns {
+		names[i] = c.name
+		params[i] = s.dialect.placeholder(i + 1)
+		if i > 0 {
+			updates = append(updates, fmt.Sprintf("%s = excluded.%s", c.name, c.name))
+		}
+	}
+	return fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (id) DO UPDATE SET %s",
+		s.table, strings.Join(names, ", "), strings.Join(params, ", "), strings.Join(updates, ", "),
+	)
+}
+
+// jsonValue encodes v for a json column, storing empty slices as NULL.
+func jsonValue(v interface{}) interface{} {
+	if s, ok := v.([]string); ok && len(s) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return string(data)
+}
+
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func nullInt(n int) interface{} {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
+
+// coordinate returns NULL for articles without a valid location, matching
+// the indexed document.
+func coordinate(a model.Article, value float64) interface{} {
+	if !utils.ValidCoordinates(a.Latitude, a.Longitude) {
+		return nil
+	}
+	return value
+}
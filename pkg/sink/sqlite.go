@@ -0,0 +1,32 @@
+package sink
+
+import (
+	// Registers the pure Go "sqlite" database/sql driver.
+	_ "modernc.org/sqlite"
+)
+
+var sqliteDialect = dialect{
+	name:        "sqlite",
+	placeholder: func(int) string { return "?" },
+	types: map[string]string{
+		"text":      "TEXT",
+		"timestamp": "TEXT",
+		"float":     "REAL",
+		"integer":   "INTEGER",
+		"boolean":   "INTEGER",
+		"json":      "TEXT",
+	},
+}
+
+// NewSQLite returns a sink upserting articles into table of the SQLite
+// database file at path, which is created when missing. JSON columns can
+// be queried with the json1 functions, e.g. json_each(category).
+func NewSQLite(path, table string) (*SQL, error) {
+	s, err := openSQL("sqlite", path, table, sqliteDialect)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer, more connections only cause busy errors.
+	s.db.SetMaxOpenConns(1)
+	return s, nil
+}
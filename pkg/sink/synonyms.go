@@ -0,0 +1,115 @@
+package sink
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LoadSynonyms reads synonym rules in Solr format, one per line such as
+// "PM, Prime Minister" or "USA => United States". Blank lines and lines
+// starting with # are skipped.
+func LoadSynonyms(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rules = append(rules, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no synonym rules in %s", path)
+	}
+	return rules, nil
+}
+
+// synonymsSet names the synonyms set of index.
+func synonymsSet(index string) string {
+	return index + "-synonyms"
+}
+
+// synonymsAnalyzer is the search analyzer of the news text fields when
+// synonyms are configured.
+const synonymsAnalyzer = "news_search"
+
+// addSynonyms defines the synonymsAnalyzer, which expands synonyms with
+// filter, a synonym_graph token filter. It is only used at search time,
+// see searchWithSynonyms, so documents are indexed without synonyms and
+// the rules can change without a reindex.
+func addSynonyms(settings map[string]interface{}, filter map[string]interface{}) {
+	analysis := settings["analysis"].(map[string]interface{})
+	filter["type"] = "synonym_graph"
+	analysis["filter"].(map[string]interface{})["news_synonyms"] = filter
+	analysis["analyzer"].(map[string]interface{})[synonymsAnalyzer] = map[string]interface{}{
+		"type":      "custom",
+		"tokenizer": "standard",
+		"filter":    []string{"lowercase", "news_synonyms", "stop", "english_stemmer"},
+	}
+}
+
+// searchWithSynonyms sets the synonymsAnalyzer as search analyzer of the
+// fields analysed by news_text. It isn't the index's default_search, as
+// their subfields, e.g. those of the languages, must keep searching with
+// their own analyzer rather than English stemming.
+func searchWithSynonyms(properties map[string]interface{}) {
+	for _, mapping := range properties {
+		if field, ok := mapping.(map[string]interface{}); ok && field["analyzer"] == "news_text" {
+			field["search_analyzer"] = synonymsAnalyzer
+		}
+	}
+}
+
+// PutSynonyms creates or replaces the synonyms set of the index with
+// rules. Elasticsearch reloads the search analyzers using the set.
+func (e *Elasticsearch) PutSynonyms(ctx context.Context, rules []string) error {
+	if !e.version.synonymSets() {
+		return fmt.Errorf("synonyms sets need elasticsearch 8.10, the cluster is %s: recreate the index with --synonyms to change its rules", e.version)
+	}
+	set := make([]map[string]string, len(rules))
+	for i, rule := range rules {
+		set[i] = map[string]string{"synonyms": rule}
+	}
+	body, err := json.Marshal(map[string]interface{}{"synonyms_set": set})
+	if err != nil {
+		return err
+	}
+
+	res, err := e.client.SynonymsPutSynonym(synonymsSet(e.index), bytes.NewReader(body), e.client.SynonymsPutSynonym.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("failed to update synonyms of %s: %s", e.index, res.String())
+	}
+	return nil
+}
+
+// ReloadSearchAnalyzers reloads the synonyms of the index's search
+// analyzers, for sets that were changed outside PutSynonyms.
+func (e *Elasticsearch) ReloadSearchAnalyzers(ctx context.Context) error {
+	res, err := e.client.Indices.ReloadSearchAnalyzers([]string{e.index}, e.client.Indices.ReloadSearchAnalyzers.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("failed to reload search analyzers of %s: %s", e.index, res.String())
+	}
+	return nil
+}
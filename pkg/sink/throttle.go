@@ -0,0 +1,99 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+// Throttled limits the rate at which batches reach a sink, so a backfill
+// doesn't starve other traffic on a shared cluster.
+type Throttled struct {
+	Sink
+	docs  *tokenBucket
+	bytes *tokenBucket
+}
+
+// NewThrottled limits s to docsPerSec documents and bytesPerSec bytes of
+// JSON per second. A zero limit is not enforced.
+func NewThrottled(s Sink, docsPerSec float64, bytesPerSec int64) *Throttled {
+	t := &Throttled{Sink: s}
+	if docsPerSec > 0 {
+		t.docs = &tokenBucket{rate: docsPerSec}
+	}
+	if bytesPerSec > 0 {
+		t.bytes = &tokenBucket{rate: float64(bytesPerSec)}
+	}
+	return t
+}
+
+// WriteBatch waits until both limits allow articles before writing them.
+func (t *Throttled) WriteBatch(ctx context.Context, articles []model.Article) error {
+	if err := t.docs.wait(ctx, float64(len(articles))); err != nil {
+		return err
+	}
+	if t.bytes != nil {
+		// The encoded articles approximate the size of the request body
+		size := 0
+		for _, a := range articles {
+			data, err := json.Marshal(a)
+			if err != nil {
+				return err
+			}
+			size += len(data)
+		}
+		if err := t.bytes.wait(ctx, float64(size)); err != nil {
+			return err
+		}
+	}
+	return t.Sink.WriteBatch(ctx, articles)
+}
+
+// Ping checks the wrapped sink when it can be pinged.
+func (t *Throttled) Ping(ctx context.Context) error {
+	if pinger, ok := t.Sink.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// tokenBucket spaces out takes so that on average no more than rate
+// tokens are taken per second. A take larger than a second's worth is
+// let through and paid back by the takes that follow.
+type tokenBucket struct {
+	rate float64
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// wait blocks until n tokens are available. It is a no-op on nil.
+func (b *tokenBucket) wait(ctx context.Context, n float64) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	now := time.Now()
+	start := b.next
+	if start.Before(now) {
+		start = now
+	}
+	b.next = start.Add(time.Duration(n / b.rate * float64(time.Second)))
+	b.mu.Unlock()
+
+	delay := time.Until(start)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,194 @@
+package sink
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// Typesense writes articles to a Typesense collection whose schema is
+// derived from the Elasticsearch mapping. Keyword fields become facets,
+// dates are stored as unix seconds and the location as a geopoint.
+type Typesense struct {
+	baseURL    string
+	apiKey     string
+	collection string
+	client     *http.Client
+}
+
+// NewTypesense returns a sink writing to collection of the node at baseURL.
+func NewTypesense(baseURL, apiKey, collection string) *Typesense {
+	return &Typesense{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		collection: collection,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (t *Typesense) Name() string { return "typesense" }
+
+func (t *Typesense) Close() error { return nil }
+
+// Ping implements Pinger.
+func (t *Typesense) Ping(ctx context.Context) error {
+	return t.do(ctx, http.MethodGet, "/health", nil, nil)
+}
+
+// Prepare implements Sink by creating the collection unless it exists.
+func (t *Typesense) Prepare(ctx context.Context, opts IndexOptions) error {
+	err := t.do(ctx, http.MethodGet, "/collections/"+url.PathEscape(t.collection), nil, nil)
+	var statusErr *httpStatusError
+	if !errors.As(err, &statusErr) || statusErr.status != http.StatusNotFound {
+		return err
+	}
+
+	schema, err := t.schema(opts)
+	if err != nil {
+		return err
+	}
+	if err := t.do(ctx, http.MethodPost, "/collections", schema, nil); err != nil {
+		return err
+	}
+	log.Info().Caller().Msgf("typesense collection: (%s) created successfully", t.collection)
+	return nil
+}
+
+func (t *Typesense) schema(opts IndexOptions) (map[string]interface{}, error) {
+	fields, err := schemaFields()
+	if err != nil {
+		return nil, err
+	}
+
+	var defs []map[string]interface{}
+	for _, f := range fields {
+		// The id is implicit in Typesense
+		if f.name == "id" {
+			continue
+		}
+		var typ string
+		switch f.esType {
+		case "text", "keyword":
+			typ = "string"
+		case "float":
+			typ = "float"
+		case "integer":
+			typ = "int32"
+		case "boolean":
+			typ = "bool"
+		case "date":
+			typ = "int64"
+		case "geo_point":
+			typ = "geopoint"
+		default:
+			continue
+		}
+		if f.array {
+			typ += "[]"
+		}
+		// Every field is optional, as articles synced with
+		// --on-bad-date=omit have no publication_date
+		def := map[string]interface{}{"name": f.name, "type": typ, "optional": true}
+		if typ == "string" || typ == "string[]" {
+			def["facet"] = f.filterable()
+		}
+		if f.sortable() {
+			def["sort"] = true
+		}
+		defs = append(defs, def)
+	}
+	if opts.EmbeddingDims > 0 {
+		defs = append(defs, map[string]interface{}{
+			"name": "embedding", "type": "float[]", "num_dim": opts.EmbeddingDims, "optional": true,
+		})
+	}
+
+	return map[string]interface{}{
+		"name":                 t.collection,
+		"fields":               defs,
+		"enable_nested_fields": true,
+	}, nil
+}
+
+// WriteBatch implements Sink by upserting articles with the import API,
+// which reports the outcome of every document.
+func (t *Typesense) WriteBatch(ctx context.Context, articles []model.Article) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, a := range articles {
+		doc, err := flatDocument(a)
+		if err != nil {
+			return err
+		}
+		if utils.ValidCoordinates(a.Latitude, a.Longitude) {
+			doc["location"] = []float64{a.Latitude, a.Longitude}
+		}
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+	}
+
+	header := t.header()
+	header.Set("Content-Type", "text/plain")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		t.baseURL+"/collections/"+url.PathEscape(t.collection)+"/documents/import?action=upsert", &buf)
+	if err != nil {
+		return err
+	}
+	req.Header = header
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("typesense import returned status %s", res.Status)
+	}
+
+	// The response holds one result per line, in the order of the documents
+	var failed int
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(make([]byte, 0, 64<<10), 4<<20)
+	for scanner.Scan() {
+		var line struct {
+			Success bool   `json:"success"`
+			Error   string `json:"error"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return err
+		}
+		if !line.Success {
+			failed++
+			log.Error().Caller().Msgf("typesense import item failed: %s", line.Error)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return &PartialError{Failed: failed}
+	}
+	return nil
+}
+
+func (t *Typesense) header() http.Header {
+	header := http.Header{}
+	header.Set("X-TYPESENSE-API-KEY", t.apiKey)
+	return header
+}
+
+func (t *Typesense) do(ctx context.Context, method, path string, body, out interface{}) error {
+	return doJSON(ctx, t.client, method, t.baseURL+path, t.header(), body, out)
+}
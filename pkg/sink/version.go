@@ -0,0 +1,120 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v9"
+)
+
+// ErrUnsupportedVersion is returned by Version.Supported for clusters the
+// syncer can't write to.
+var ErrUnsupportedVersion = errors.New("unsupported elasticsearch version")
+
+// Version identifies an Elasticsearch cluster, so the index definition and
+// the APIs used can follow what it supports. The zero Version stands for
+// a cluster that couldn't be asked, assumed to be current.
+type Version struct {
+	Number       string
+	Major, Minor int
+	// BuildFlavor is e.g. "default" or "serverless".
+	BuildFlavor string
+	// Distribution is "opensearch" for OpenSearch clusters, which answer
+	// like Elasticsearch 7.10.
+	Distribution string
+}
+
+// Oldest version the client talks to: earlier ones don't send the
+// product header it checks.
+const minMajor, minMinor = 7, 14
+
+// maxMajor is the newest major version the mapping was tested with.
+const maxMajor = 9
+
+// DetectVersion asks the cluster of client for its version.
+func DetectVersion(ctx context.Context, client *elasticsearch.Client) (Version, error) {
+	res, err := client.Info(client.Info.WithContext(ctx))
+	if err != nil {
+		return Version{}, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return Version{}, errors.New(res.String())
+	}
+	var info struct {
+		Version struct {
+			Number       string `json:"number"`
+			BuildFlavor  string `json:"build_flavor"`
+			Distribution string `json:"distribution"`
+		} `json:"version"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		return Version{}, err
+	}
+	return ParseVersion(info.Version.Number, info.Version.BuildFlavor, info.Version.Distribution)
+}
+
+// ParseVersion parses a version number such as "8.15.2".
+func ParseVersion(number, buildFlavor, distribution string) (Version, error) {
+	v := Version{Number: number, BuildFlavor: buildFlavor, Distribution: distribution}
+	major, rest, _ := strings.Cut(number, ".")
+	minor, _, _ := strings.Cut(rest, ".")
+	var err error
+	if v.Major, err = strconv.Atoi(major); err != nil {
+		return Version{}, fmt.Errorf("invalid version %q", number)
+	}
+	if v.Minor, err = strconv.Atoi(minor); err != nil {
+		return Version{}, fmt.Errorf("invalid version %q", number)
+	}
+	return v, nil
+}
+
+func (v Version) String() string {
+	if v.Major == 0 {
+		return "unknown"
+	}
+	if v.BuildFlavor != "" && v.BuildFlavor != "default" {
+		return v.Number + " (" + v.BuildFlavor + ")"
+	}
+	return v.Number
+}
+
+// AtLeast reports whether v is major.minor or later. It is true for the
+// zero Version.
+func (v Version) AtLeast(major, minor int) bool {
+	if v.Major == 0 {
+		return true
+	}
+	return v.Major > major || v.Major == major && v.Minor >= minor
+}
+
+// Supported returns an error wrapping ErrUnsupportedVersion, naming what
+// to do instead, when the syncer can't write to v.
+func (v Version) Supported() error {
+	switch {
+	case v.Distribution == "opensearch":
+		return fmt.Errorf("%w: the cluster is opensearch %s, sync to it with --sink opensearch", ErrUnsupportedVersion, v.Number)
+	case !v.AtLeast(minMajor, minMinor):
+		return fmt.Errorf("%w %s: at least %d.%d is needed", ErrUnsupportedVersion, v.Number, minMajor, minMinor)
+	case v.Major > maxMajor:
+		return fmt.Errorf("%w %s: versions after %d.x aren't supported yet", ErrUnsupportedVersion, v.Number, maxMajor)
+	}
+	return nil
+}
+
+// Serverless reports whether v is an Elastic serverless project, which
+// manages shards, replicas, refreshes and disk space itself and rejects
+// the settings and APIs for them.
+func (v Version) Serverless() bool { return v.BuildFlavor == "serverless" }
+
+// synonymSets reports whether synonyms can be kept in a synonyms set,
+// which is updated without closing the index.
+func (v Version) synonymSets() bool { return v.AtLeast(8, 10) }
+
+// indexedVectors reports whether dense_vector fields can be indexed for
+// kNN search, with a similarity. Before 8.0 they are only stored.
+func (v Version) indexedVectors() bool { return v.AtLeast(8, 0) }
@@ -0,0 +1,28 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+)
+
+func init() {
+	Register("file", openFile)
+}
+
+// openFile opens a JSON array or NDJSON file of articles, either from a
+// plain path or a file:// URI. Relative paths are also accepted as
+// file://dir/name.json.
+func openFile(_ context.Context, uri *url.URL) (Source, error) {
+	path := uri.Host + uri.Path
+	if uri.Opaque != "" {
+		path = uri.Opaque
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("file not found at path %s: %w", path, err)
+	}
+	return newJSONStream(f)
+}
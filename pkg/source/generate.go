@@ -0,0 +1,252 @@
+package source
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+func init() {
+	Register("generate", openGenerator)
+}
+
+// openGenerator yields synthetic articles for load tests and demos,
+// addressed as
+//
+//	generate:?count=100000&seed=42&days=365&until=2025-01-01
+//
+// The same seed and until always yield the same articles. count defaults
+// to 1000, seed to 1, days (how far back publication dates go) to 365
+// and until to now.
+func openGenerator(_ context.Context, uri *url.URL) (Source, error) {
+	q := uri.Query()
+	g := &generator{count: 1000, days: 365, until: time.Now().UTC()}
+	seed := uint64(1)
+	var err error
+	if v := q.Get("count"); v != "" {
+		if g.count, err = strconv.Atoi(v); err != nil || g.count < 0 {
+			return nil, fmt.Errorf("invalid generate count %q", v)
+		}
+	}
+	if v := q.Get("seed"); v != "" {
+		if seed, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid generate seed %q", v)
+		}
+	}
+	if v := q.Get("days"); v != "" {
+		if g.days, err = strconv.Atoi(v); err != nil || g.days < 1 {
+			return nil, fmt.Errorf("invalid generate days %q", v)
+		}
+	}
+	if v := q.Get("until"); v != "" {
+		if g.until, err = time.Parse(time.DateOnly, v); err != nil {
+			return nil, fmt.Errorf("invalid generate until %q", v)
+		}
+	}
+
+	var key [32]byte
+	binary.LittleEndian.PutUint64(key[:], seed)
+	g.entropy = rand.NewChaCha8(key)
+	g.rng = rand.New(g.entropy)
+	// A few outlets publish most articles, like in the real feed
+	g.sources = rand.NewZipf(g.rng, 1.3, 1, uint64(len(fakeSources)-1))
+	return g, nil
+}
+
+// generator is the synthetic article source.
+type generator struct {
+	count, days int
+	until       time.Time
+	produced    int
+	entropy     *rand.ChaCha8
+	rng         *rand.Rand
+	sources     *rand.Zipf
+}
+
+func (g *generator) Next(ctx context.Context) (model.Article, error) {
+	if g.produced >= g.count {
+		return model.Article{}, io.EOF
+	}
+	if err := ctx.Err(); err != nil {
+		return model.Article{}, err
+	}
+	g.produced++
+
+	id, err := uuid.NewRandomFromReader(g.entropy)
+	if err != nil {
+		return model.Article{}, err
+	}
+	category := g.weighted(fakeCategories)
+	city := fakeCities[g.rng.IntN(len(fakeCities))]
+	src := fakeSources[g.sources.Uint64()]
+	subject := g.pick(fakePeople)
+	if g.rng.IntN(2) == 0 {
+		subject = g.pick(fakeOrgs)
+	}
+	topic := g.pick(fakeTopics[category])
+	published := g.date()
+	title := capitalize(fmt.Sprintf(g.pick(fakeHeadlines), subject, topic, city.name))
+	description := capitalize(fmt.Sprintf("%s %s %s, officials in %s said on %s. %s",
+		subject, g.pick(fakeVerbs), topic, city.name, published.Weekday(), g.pick(fakeFollowUps)))
+
+	a := model.Article{
+		ID:              id.String(),
+		Title:           title,
+		Description:     description,
+		URL:             fmt.Sprintf("https://%s/%s/%s-%d", src.domain, category, slug(title), g.rng.IntN(9000000)+1000000),
+		PublicationDate: published.Format("2006-01-02T15:04:05"),
+		SourceName:      src.name,
+		Category:        []string{category},
+		RelevanceScore:  math.Round(g.rng.Float64()*g.rng.Float64()*100) / 100,
+		Latitude:        math.Round((city.lat+g.rng.NormFloat64()*0.03)*1e6) / 1e6,
+		Longitude:       math.Round((city.lon+g.rng.NormFloat64()*0.03)*1e6) / 1e6,
+		LocationName:    city.name,
+		Country:         "India",
+		State:           city.state,
+		City:            city.name,
+		Author:          g.pick(fakeAuthors),
+	}
+	return a, nil
+}
+
+func (g *generator) Close() error { return nil }
+
+// date returns a publication date skewed towards until: most news is
+// recent, with a long tail back to days before it.
+func (g *generator) date() time.Time {
+	window := time.Duration(g.days) * 24 * time.Hour
+	age := time.Duration(g.rng.ExpFloat64() * float64(window) / 4)
+	if age >= window {
+		age = time.Duration(g.rng.Int64N(int64(window)))
+	}
+	return g.until.Add(-age).Truncate(time.Second)
+}
+
+func (g *generator) pick(values []string) string {
+	return values[g.rng.IntN(len(values))]
+}
+
+// weighted picks a category with the share it has in the real feed.
+func (g *generator) weighted(weights []weightedValue) string {
+	total := 0
+	for _, w := range weights {
+		total += w.weight
+	}
+	n := g.rng.IntN(total)
+	for _, w := range weights {
+		if n < w.weight {
+			return w.value
+		}
+		n -= w.weight
+	}
+	return weights[len(weights)-1].value
+}
+
+func capitalize(s string) string {
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// slug turns a title into a URL path segment.
+func slug(title string) string {
+	fields := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < '0' || r > '9')
+	})
+	return strings.Join(fields, "-")
+}
+
+type weightedValue struct {
+	value  string
+	weight int
+}
+
+var fakeCategories = []weightedValue{
+	{"national", 30}, {"sports", 14}, {"world", 11}, {"business", 10},
+	{"entertainment", 10}, {"politics", 10}, {"technology", 5},
+	{"health", 4}, {"startup", 3}, {"science", 3},
+}
+
+var fakeTopics = map[string][]string{
+	"national":      {"new metro line", "flood relief package", "school reform", "highway project", "census drive"},
+	"sports":        {"test series", "league final", "transfer deal", "world cup squad", "doping probe"},
+	"world":         {"trade talks", "ceasefire proposal", "climate summit", "election results", "border dispute"},
+	"business":      {"quarterly results", "rate cut", "IPO plans", "merger talks", "export targets"},
+	"entertainment": {"film release", "box office record", "award nomination", "streaming deal", "music tour"},
+	"politics":      {"coalition talks", "assembly polls", "budget session", "cabinet reshuffle", "new bill"},
+	"technology":    {"AI model", "chip plant", "5G rollout", "data protection rules", "smartphone launch"},
+	"health":        {"vaccination drive", "hospital upgrade", "dengue outbreak", "fitness campaign", "drug pricing"},
+	"startup":       {"funding round", "unicorn valuation", "layoffs", "acquisition", "new product"},
+	"science":       {"satellite launch", "lunar mission", "fossil discovery", "research grant", "telescope images"},
+}
+
+var fakeHeadlines = []string{
+	"%s announces %s in %s",
+	"%s under fire over %s, protests in %s",
+	"What %s's %s means for %s",
+	"%s backs %s as %s waits",
+	"%s confirms %s after meeting in %s",
+}
+
+var fakeVerbs = []string{"announced", "defended", "criticised", "unveiled", "postponed", "welcomed"}
+
+var fakeFollowUps = []string{
+	"More details are expected later this week.",
+	"The opposition called the move hasty.",
+	"Analysts expect the decision to have wide impact.",
+	"Residents have been demanding it for years.",
+	"The announcement was made at a press conference.",
+}
+
+var fakePeople = []string{"Aarav Mehta", "Priya Nair", "Rohan Gupta", "Ananya Iyer", "Vikram Singh", "Meera Das", "Kabir Khan", "Sara Thomas"}
+
+var fakeOrgs = []string{"the state government", "the Reserve Bank", "the Election Commission", "BCCI", "ISRO", "the health ministry", "a Bengaluru startup", "the city council"}
+
+var fakeAuthors = []string{"Staff Reporter", "News Desk", "Neha Kapoor", "Arjun Rao", "Fatima Sheikh", "Rahul Verma"}
+
+type fakeCity struct {
+	name, state string
+	lat, lon    float64
+}
+
+var fakeCities = []fakeCity{
+	{"Mumbai", "Maharashtra", 19.076, 72.8777},
+	{"Delhi", "Delhi", 28.6139, 77.209},
+	{"Bengaluru", "Karnataka", 12.9716, 77.5946},
+	{"Chennai", "Tamil Nadu", 13.0827, 80.2707},
+	{"Kolkata", "West Bengal", 22.5726, 88.3639},
+	{"Hyderabad", "Telangana", 17.385, 78.4867},
+	{"Pune", "Maharashtra", 18.5204, 73.8567},
+	{"Ahmedabad", "Gujarat", 23.0225, 72.5714},
+	{"Jaipur", "Rajasthan", 26.9124, 75.7873},
+	{"Lucknow", "Uttar Pradesh", 26.8467, 80.9462},
+	{"Kochi", "Kerala", 9.9312, 76.2673},
+	{"Guwahati", "Assam", 26.1445, 91.7362},
+}
+
+type fakeSource struct {
+	name, domain string
+}
+
+// fakeSources are ordered from the most to the least prolific.
+var fakeSources = []fakeSource{
+	{"Hindustan Times", "www.hindustantimes.com"},
+	{"News Karnataka", "www.newskarnataka.com"},
+	{"Free Press Journal", "www.freepressjournal.in"},
+	{"News18", "www.news18.com"},
+	{"ET Now", "www.etnownews.com"},
+	{"The Indian Express", "indianexpress.com"},
+	{"Moneycontrol", "www.moneycontrol.com"},
+	{"Reuters", "www.reuters.com"},
+	{"Times Now", "www.timesnownews.com"},
+	{"PTI", "www.ptinews.com"},
+	{"ANI", "www.aninews.in"},
+}
@@ -0,0 +1,33 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+func init() {
+	Register("http", openHTTP)
+	Register("https", openHTTP)
+}
+
+// openHTTP fetches a JSON array or NDJSON of articles with a GET request.
+// The body is streamed, so ctx must stay alive until the source is drained.
+func openHTTP(ctx context.Context, uri *url.URL) (Source, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("fetching %s: unexpected status %s", uri.Redacted(), res.Status)
+	}
+	return newJSONStream(res.Body)
+}
@@ -0,0 +1,137 @@
+package source
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"unicode"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+// jsonStream streams the articles of a JSON array, or of newline
+// delimited JSON as written by export, without reading the whole input
+// into memory.
+type jsonStream struct {
+	r   io.ReadCloser
+	dec *json.Decoder
+	// array is set when the articles are wrapped in a JSON array.
+	array bool
+	lines *lineCounter
+	// line is where the last raw object started.
+	line int
+}
+
+func newJSONStream(r io.ReadCloser) (*jsonStream, error) {
+	buffered := bufio.NewReader(r)
+	first, skipped, err := firstNonSpace(buffered)
+	if err != nil && !errors.Is(err, io.EOF) {
+		r.Close()
+		return nil, err
+	}
+
+	lines := &lineCounter{r: buffered, passed: skipped}
+	s := &jsonStream{r: r, dec: json.NewDecoder(lines), array: first == '[', lines: lines}
+	if s.array {
+		if _, err := s.dec.Token(); err != nil {
+			r.Close()
+			return nil, err
+		}
+	} else if first != '{' && first != 0 {
+		r.Close()
+		return nil, fmt.Errorf("expected a json array or ndjson of articles, got %q", first)
+	}
+	return s, nil
+}
+
+// firstNonSpace peeks at the first significant byte without consuming
+// it, and returns the number of lines skipped before it.
+func firstNonSpace(r *bufio.Reader) (byte, int, error) {
+	lines := 0
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return 0, lines, err
+		}
+		if !unicode.IsSpace(rune(b[0])) {
+			return b[0], lines, nil
+		}
+		if b[0] == '\n' {
+			lines++
+		}
+		r.ReadByte()
+	}
+}
+
+// lineCounter tells the line of an offset into what it read. It keeps
+// the offsets of the newlines not yet passed, so offsets asked about
+// must not decrease.
+type lineCounter struct {
+	r        io.Reader
+	offset   int64
+	newlines []int64
+	// passed counts the newlines before the last offset asked about.
+	passed int
+}
+
+func (c *lineCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	for i, b := range p[:n] {
+		if b == '\n' {
+			c.newlines = append(c.newlines, c.offset+int64(i))
+		}
+	}
+	c.offset += int64(n)
+	return n, err
+}
+
+// lineAt returns the line, from 1, of offset.
+func (c *lineCounter) lineAt(offset int64) int {
+	i := 0
+	for i < len(c.newlines) && c.newlines[i] < offset {
+		i++
+	}
+	c.passed += i
+	c.newlines = c.newlines[i:]
+	return c.passed + 1
+}
+
+func (s *jsonStream) Next(ctx context.Context) (model.Article, error) {
+	var a model.Article
+	if err := ctx.Err(); err != nil {
+		return a, err
+	}
+	if s.array && !s.dec.More() {
+		return a, io.EOF
+	}
+	err := s.dec.Decode(&a)
+	return a, err
+}
+
+// NextRaw implements RawSource.
+func (s *jsonStream) NextRaw(ctx context.Context) (json.RawMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if s.array && !s.dec.More() {
+		return nil, io.EOF
+	}
+	var raw json.RawMessage
+	if err := s.dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	s.line = s.lines.lineAt(s.dec.InputOffset() - int64(len(raw)))
+	return raw, nil
+}
+
+// Line returns the line the last object read by NextRaw started on.
+func (s *jsonStream) Line() int {
+	return s.line
+}
+
+func (s *jsonStream) Close() error {
+	return s.r.Close()
+}
@@ -0,0 +1,273 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+// RawSource is implemented by sources that can yield each input object
+// before it is decoded into an article.
+type RawSource interface {
+	Source
+	NextRaw(ctx context.Context) (json.RawMessage, error)
+}
+
+// FieldMapping maps article fields, such as "title" or "sentiment.label",
+// to JSONPath style paths into the input objects, such as "$.headline",
+// "$.meta.pub_date" or "$.sections[*].name". Fields that aren't mapped
+// are read from the input key of the same name.
+type FieldMapping map[string]string
+
+// LoadFieldMapping reads a FieldMapping from a JSON file and checks its paths.
+func LoadFieldMapping(path string) (FieldMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m FieldMapping
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid field mapping %s: %w", path, err)
+	}
+	for field, p := range m {
+		if _, err := parsePath(p); err != nil {
+			return nil, fmt.Errorf("invalid path for %s: %w", field, err)
+		}
+	}
+	return m, nil
+}
+
+// Shape describes how input objects become articles.
+type Shape struct {
+	Fields FieldMapping
+	// KeepExtra preserves input keys without an article field, and not
+	// consumed by Fields, in Article.Extra.
+	KeepExtra bool
+	// Schema optionally validates the input objects, before they are
+	// mapped. An object not matching it fails with a *SchemaError.
+	Schema *Schema
+}
+
+// IsZero reports whether the shape decodes input objects as they are.
+func (s Shape) IsZero() bool {
+	return len(s.Fields) == 0 && !s.KeepExtra && s.Schema == nil
+}
+
+// Reshape returns a source turning the input objects of src into
+// articles according to shape.
+func Reshape(src Source, shape Shape) (Source, error) {
+	raw, ok := src.(RawSource)
+	if !ok {
+		return nil, fmt.Errorf("source %T doesn't support reshaping input", src)
+	}
+	m := &mappedSource{
+		RawSource: raw,
+		paths:     make(map[string][]pathStep, len(shape.Fields)),
+		keepExtra: shape.KeepExtra,
+		schema:    shape.Schema,
+		consumed:  map[string]bool{},
+	}
+	for field, p := range shape.Fields {
+		steps, err := parsePath(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path for %s: %w", field, err)
+		}
+		m.paths[field] = steps
+		m.consumed[steps[0].key] = true
+	}
+	return m, nil
+}
+
+type mappedSource struct {
+	RawSource
+	paths     map[string][]pathStep
+	keepExtra bool
+	// consumed holds the top level input keys read by paths, which
+	// aren't kept as extra fields.
+	consumed map[string]bool
+	schema   *Schema
+	// read counts the input objects, to locate schema violations.
+	read int
+}
+
+func (s *mappedSource) Next(ctx context.Context) (model.Article, error) {
+	var a model.Article
+	raw, err := s.NextRaw(ctx)
+	if err != nil {
+		return a, err
+	}
+	s.read++
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return a, err
+	}
+	if s.schema != nil {
+		if violations := s.schema.Validate(value); len(violations) > 0 {
+			err := &SchemaError{Document: s.read, Violations: violations}
+			if positioned, ok := s.RawSource.(interface{ Line() int }); ok {
+				err.Line = positioned.Line()
+			}
+			return a, err
+		}
+	}
+	input, ok := value.(map[string]interface{})
+	if !ok {
+		return a, fmt.Errorf("input %d is a json %s, not an object", s.read, typeOf(value))
+	}
+
+	var extra map[string]interface{}
+	if s.keepExtra {
+		extra = map[string]interface{}{}
+		for key, value := range input {
+			if !articleFields[key] && !s.consumed[key] {
+				extra[key] = value
+			}
+		}
+	}
+
+	for field, steps := range s.paths {
+		if value, ok := lookup(input, steps); ok {
+			setField(input, strings.Split(field, "."), value)
+		}
+	}
+
+	data, err := json.Marshal(input)
+	if err != nil {
+		return a, err
+	}
+	if err := json.Unmarshal(data, &a); err != nil {
+		return a, fmt.Errorf("mapped article doesn't fit the model: %w", err)
+	}
+	if len(extra) > 0 {
+		a.Extra = extra
+	}
+	return a, nil
+}
+
+// articleFields are the JSON keys of model.Article.
+var articleFields = func() map[string]bool {
+	fields := map[string]bool{}
+	typ := reflect.TypeOf(model.Article{})
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}()
+
+// setField sets the possibly nested field named by keys.
+func setField(obj map[string]interface{}, keys []string, value interface{}) {
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := obj[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			obj[key] = next
+		}
+		obj = next
+	}
+	obj[keys[len(keys)-1]] = value
+}
+
+// pathStep is a key, an index or, when both are unset, a [*] wildcard.
+type pathStep struct {
+	key   string
+	index *int
+}
+
+// parsePath parses the supported JSONPath subset: $, .key, ['key'], [n] and [*].
+func parsePath(p string) ([]pathStep, error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(p), "$")
+	var steps []pathStep
+	for rest != "" {
+		switch {
+		case rest[0] == '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty key in %q", p)
+			}
+			steps = append(steps, pathStep{key: rest[:end]})
+			rest = rest[end:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in %q", p)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			switch {
+			case inner == "*":
+				steps = append(steps, pathStep{})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, pathStep{key: inner[1 : len(inner)-1]})
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %q in %q", inner, p)
+				}
+				steps = append(steps, pathStep{index: &n})
+			}
+		default:
+			// A leading key without a dot, as in "meta.pub_date"
+			if len(steps) > 0 {
+				return nil, fmt.Errorf("unexpected %q in %q", rest, p)
+			}
+			rest = "." + rest
+		}
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("empty path %q", p)
+	}
+	return steps, nil
+}
+
+// lookup returns the value at steps. A wildcard collects the values of
+// every element into a list.
+func lookup(value interface{}, steps []pathStep) (interface{}, bool) {
+	for i, step := range steps {
+		switch {
+		case step.key != "":
+			obj, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if value, ok = obj[step.key]; !ok {
+				return nil, false
+			}
+		case step.index != nil:
+			list, ok := value.([]interface{})
+			n := *step.index
+			if n < 0 {
+				n += len(list)
+			}
+			if !ok || n < 0 || n >= len(list) {
+				return nil, false
+			}
+			value = list[n]
+		default:
+			list, ok := value.([]interface{})
+			if !ok {
+				return nil, false
+			}
+			collected := make([]interface{}, 0, len(list))
+			for _, item := range list {
+				if v, ok := lookup(item, steps[i+1:]); ok {
+					collected = append(collected, v)
+				}
+			}
+			return collected, true
+		}
+	}
+	return value, true
+}
@@ -0,0 +1,265 @@
+package source
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+//go:embed article.schema.json
+var articleSchema []byte
+
+// Schema is a JSON Schema input objects are validated against before
+// they become articles. The subset supported is type, properties,
+// required, additionalProperties, items, enum, minLength, maxLength,
+// pattern, format (date-time and uri), minimum, maximum, minItems and
+// maxItems; other keywords are ignored.
+type Schema struct {
+	Type                 schemaTypes        `json:"type"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *Schema            `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Enum                 []interface{}      `json:"enum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Pattern              string             `json:"pattern"`
+	Format               string             `json:"format"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+
+	pattern *regexp.Regexp
+	// never is set for the false schema, which nothing matches.
+	never bool
+}
+
+// schemaTypes is the type keyword, a single type or a list of them.
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = schemaTypes{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings")
+	}
+	*t = list
+	return nil
+}
+
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	// true and false are the schemas matching everything and nothing
+	var b bool
+	if err := json.Unmarshal(data, &b); err == nil {
+		*s = Schema{never: !b}
+		return nil
+	}
+	type plain Schema
+	if err := json.Unmarshal(data, (*plain)(s)); err != nil {
+		return err
+	}
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = pattern
+	}
+	return nil
+}
+
+// ArticleSchema returns the schema of the article input format, which
+// is shipped with the syncer.
+func ArticleSchema() *Schema {
+	var s Schema
+	if err := json.Unmarshal(articleSchema, &s); err != nil {
+		panic("source: invalid article schema: " + err.Error())
+	}
+	return &s
+}
+
+// LoadSchema reads a JSON Schema file.
+func LoadSchema(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// Violation is a value of an input object not matching the schema.
+type Violation struct {
+	// Path locates the value, e.g. "$.entities.person[2]".
+	Path    string
+	Message string
+}
+
+func (v Violation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// SchemaError is returned for an input object that doesn't match the
+// schema, with every violation found in it.
+type SchemaError struct {
+	// Document is the position of the object in the input, from 1.
+	Document int
+	// Line is the line the object starts on, 0 when the source can't tell.
+	Line       int
+	Violations []Violation
+}
+
+func (e *SchemaError) Error() string {
+	where := fmt.Sprintf("document %d", e.Document)
+	if e.Line > 0 {
+		where += fmt.Sprintf(" (line %d)", e.Line)
+	}
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.String()
+	}
+	return fmt.Sprintf("%s doesn't match the input schema: %s", where, strings.Join(messages, "; "))
+}
+
+// Validate returns the violations of value, a decoded JSON value, in
+// the order of their paths.
+func (s *Schema) Validate(value interface{}) []Violation {
+	var violations []Violation
+	s.validate("$", value, &violations)
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].Path < violations[j].Path })
+	return violations
+}
+
+func (s *Schema) validate(path string, value interface{}, violations *[]Violation) {
+	fail := func(format string, args ...interface{}) {
+		*violations = append(*violations, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	if s.never {
+		fail("not allowed")
+		return
+	}
+	if len(s.Type) > 0 && !slices.ContainsFunc(s.Type, func(t string) bool { return hasType(value, t) }) {
+		fail("expected %s, got %s", strings.Join(s.Type, " or "), typeOf(value))
+		return
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e interface{}) bool { return jsonEqual(e, value) }) {
+		allowed, _ := json.Marshal(s.Enum)
+		fail("must be one of %s", allowed)
+	}
+
+	switch v := value.(type) {
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			fail("shorter than %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail("longer than %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("doesn't match %s", s.Pattern)
+		}
+		if err := checkFormat(s.Format, v); err != nil {
+			fail("%v", err)
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			fail("less than %g", *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			fail("greater than %g", *s.Maximum)
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			fail("fewer than %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			fail("more than %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, violations)
+			}
+		}
+	case map[string]interface{}:
+		for _, key := range s.Required {
+			if _, ok := v[key]; !ok {
+				*violations = append(*violations, Violation{Path: path + "." + key, Message: "required"})
+			}
+		}
+		for key, item := range v {
+			if prop, ok := s.Properties[key]; ok {
+				prop.validate(path+"."+key, item, violations)
+			} else if s.AdditionalProperties != nil {
+				s.AdditionalProperties.validate(path+"."+key, item, violations)
+			}
+		}
+	}
+}
+
+// hasType reports whether value is of the JSON Schema type t.
+func hasType(value interface{}, t string) bool {
+	switch t {
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	}
+	return typeOf(value) == t
+}
+
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func jsonEqual(a, b interface{}) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return string(x) == string(y)
+}
+
+func checkFormat(format, value string) error {
+	switch format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return fmt.Errorf("not an RFC 3339 date-time")
+		}
+	case "uri":
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" {
+			return fmt.Errorf("not an absolute uri")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Package source loads articles from the supported inputs. Inputs are
+// addressed by URI and opened through a registry keyed by scheme, so new
+// kinds of input can be added without touching the sync loop.
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"sync"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+// Source yields articles one at a time. Next returns io.EOF once the
+// input is exhausted.
+type Source interface {
+	Next(ctx context.Context) (model.Article, error)
+	Close() error
+}
+
+// Factory opens a source for a parsed URI.
+type Factory func(ctx context.Context, uri *url.URL) (Source, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+// Register makes a source available under scheme. It panics when the
+// scheme is already registered, like database/sql drivers.
+func Register(scheme string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[scheme]; ok {
+		panic("source: Register called twice for scheme " + scheme)
+	}
+	registry[scheme] = factory
+}
+
+// Schemes returns the registered schemes in sorted order.
+func Schemes() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	schemes := make([]string, 0, len(registry))
+	for scheme := range registry {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// Open opens the source addressed by raw. A plain path without a scheme
+// is treated as a file.
+func Open(ctx context.Context, raw string) (Source, error) {
+	uri, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source %q: %w", raw, err)
+	}
+	scheme := uri.Scheme
+	if scheme == "" {
+		scheme = "file"
+	}
+
+	registryMu.RLock()
+	factory, ok := registry[scheme]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown source scheme %q, registered: %v", scheme, Schemes())
+	}
+	return factory(ctx, uri)
+}
+
+// ReadAll drains src into a slice.
+func ReadAll(ctx context.Context, src Source) ([]model.Article, error) {
+	var articles []model.Article
+	for {
+		a, err := src.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			return articles, nil
+		}
+		if err != nil {
+			return articles, err
+		}
+		articles = append(articles, a)
+	}
+}
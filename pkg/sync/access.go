@@ -0,0 +1,78 @@
+package sync
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+// accessFieldName guards the names of access fields, which become
+// properties of the index mapping.
+var accessFieldName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// ParseAccessFields parses a comma separated list of field=value, e.g.
+// "visibility=public,region=in", into the access fields of every article.
+func ParseAccessFields(spec string) (map[string]string, error) {
+	fields := make(map[string]string)
+	for _, entry := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("%q is not field=value", entry)
+		}
+		if err := checkAccessFields(map[string]string{name: value}); err != nil {
+			return nil, err
+		}
+		if _, ok := fields[name]; ok {
+			return nil, fmt.Errorf("access field %s is listed twice", name)
+		}
+		fields[name] = value
+	}
+	return fields, nil
+}
+
+func checkAccessFields(fields map[string]string) error {
+	for name := range fields {
+		if !accessFieldName.MatchString(name) {
+			return fmt.Errorf("invalid access field %q, expected lowercase letters, digits and _", name)
+		}
+	}
+	return nil
+}
+
+// AccessFieldNames returns the names of the access fields of constant
+// and of every source of sources, sorted, for the index mapping.
+func AccessFieldNames(constant map[string]string, sources SourceRegistry) []string {
+	names := slices.Collect(maps.Keys(constant))
+	for _, source := range sources {
+		for name := range source.Access {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// InjectAccess sets the access fields of the articles: Access, overridden
+// by those of the article's source in Sources. Access fields read from
+// the input are replaced, so a source can't grant itself wider access.
+func (s *Syncer) InjectAccess(articles []model.Article) {
+	if len(s.Access) == 0 && !s.Sources.hasAccess() {
+		return
+	}
+	for i := range articles {
+		access := maps.Clone(s.Access)
+		if source, ok := s.Sources[strings.ToLower(strings.TrimSpace(articles[i].SourceName))]; ok && len(source.Access) > 0 {
+			if access == nil {
+				access = make(map[string]string, len(source.Access))
+			}
+			maps.Copy(access, source.Access)
+		}
+		articles[i].Access = access
+	}
+}
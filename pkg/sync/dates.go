@@ -0,0 +1,75 @@
+package sync
+
+import (
+	"fmt"
+	"time"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// DatePolicy decides what happens to an article whose publication date
+// can't be parsed.
+type DatePolicy string
+
+const (
+	// DateFail aborts the run.
+	DateFail DatePolicy = "fail"
+	// DateSkip drops the article.
+	DateSkip DatePolicy = "skip"
+	// DateOmit writes the article without a publication date.
+	DateOmit DatePolicy = "omit"
+	// DateNow substitutes the time the article was loaded.
+	DateNow DatePolicy = "now"
+)
+
+// ParseDatePolicy returns the policy named s.
+func ParseDatePolicy(s string) (DatePolicy, error) {
+	switch p := DatePolicy(s); p {
+	case DateFail, DateSkip, DateOmit, DateNow:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown date policy %q, expected fail, skip, omit or now", s)
+}
+
+// normalizeDates applies policy to the articles with an unparseable
+// publication date and returns the articles to write along with the
+// number of bad dates. Dates without a zone are read in loc and, unless
+// loc is nil, rewritten as UTC so later stages don't take them for UTC.
+// Relative dates are resolved against now.
+func normalizeDates(articles []model.Article, loc *time.Location, policy DatePolicy, now time.Time) ([]model.Article, int, error) {
+	in := loc
+	if in == nil {
+		in = time.UTC
+	}
+
+	kept := articles[:0]
+	bad := 0
+	for _, a := range articles {
+		t, err := utils.ParseDateIn(a.PublicationDate, in)
+		if err != nil {
+			// Relative dates such as "2 hours ago" are anchored to the run
+			if t, err = utils.ParseFuzzyDate(a.PublicationDate, now.In(in)); err == nil {
+				a.PublicationDate = t.UTC().Format(time.RFC3339Nano)
+			}
+		} else if loc != nil {
+			a.PublicationDate = t.UTC().Format(time.RFC3339Nano)
+		}
+		switch {
+		case err == nil:
+		case policy == DateSkip:
+			bad++
+			continue
+		case policy == DateOmit:
+			bad++
+			a.PublicationDate = ""
+		case policy == DateNow:
+			bad++
+			a.PublicationDate = now.UTC().Format(time.RFC3339Nano)
+		default:
+			return nil, 0, fmt.Errorf("article %s has an invalid publication_date %q", a.ID, a.PublicationDate)
+		}
+		kept = append(kept, a)
+	}
+	return kept, bad, nil
+}
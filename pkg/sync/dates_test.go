@@ -0,0 +1,77 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+func TestNormalizeDates(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	ist := time.FixedZone("IST", 5*60*60+30*60)
+	tests := []struct {
+		name   string
+		date   string
+		loc    *time.Location
+		policy DatePolicy
+		// want is the publication date written, unless the article is
+		// dropped
+		want    string
+		dropped bool
+		bad     int
+		wantErr bool
+	}{
+		{name: "valid date kept", date: "2024-01-01T10:00:00+05:30", policy: DateFail, want: "2024-01-01T10:00:00+05:30"},
+		{name: "no zone read in loc", date: "2024-01-01 15:30:00", loc: ist, policy: DateFail, want: "2024-01-01T10:00:00Z"},
+		{name: "zone kept over loc", date: "2024-01-01T10:00:00Z", loc: ist, policy: DateFail, want: "2024-01-01T10:00:00Z"},
+		{name: "relative date", date: "2 hours ago", policy: DateFail, want: "2024-03-01T10:00:00Z"},
+		{name: "relative date in loc", date: "3 days ago", loc: ist, policy: DateFail, want: "2024-02-27T12:00:00Z"},
+		{name: "bad date fails", date: "not a date", policy: DateFail, wantErr: true},
+		{name: "bad date skipped", date: "not a date", policy: DateSkip, dropped: true, bad: 1},
+		{name: "bad date omitted", date: "not a date", policy: DateOmit, want: "", bad: 1},
+		{name: "missing date omitted", date: "", policy: DateOmit, want: "", bad: 1},
+		{name: "bad date set to now", date: "not a date", policy: DateNow, want: "2024-03-01T12:00:00Z", bad: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			articles := []model.Article{{ID: "1", PublicationDate: tt.date}}
+			kept, bad, err := normalizeDates(articles, tt.loc, tt.policy, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %v", kept)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bad != tt.bad {
+				t.Errorf("bad is %d, want %d", bad, tt.bad)
+			}
+			if tt.dropped {
+				if len(kept) != 0 {
+					t.Errorf("expected the article to be dropped, got %v", kept)
+				}
+				return
+			}
+			if len(kept) != 1 {
+				t.Fatalf("kept %d articles, want 1", len(kept))
+			}
+			if got := kept[0].PublicationDate; got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDatePolicy(t *testing.T) {
+	for _, s := range []string{"fail", "skip", "omit", "now"} {
+		if p, err := ParseDatePolicy(s); err != nil || string(p) != s {
+			t.Errorf("ParseDatePolicy(%q) = %q, %v", s, p, err)
+		}
+	}
+	if _, err := ParseDatePolicy("ignore"); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
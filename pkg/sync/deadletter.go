@@ -0,0 +1,71 @@
+package sync
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+// DeadLetterEntry is a line of a dead letter file: the article as it was
+// written, with why and when it failed under "dead_letter". The file is
+// newline delimited JSON of articles, so it can be synced again as a
+// source, as retry-dlq does.
+type DeadLetterEntry struct {
+	model.Article
+	DeadLetter DeadLetterInfo `json:"dead_letter"`
+}
+
+// DeadLetterInfo records the failure of a dead lettered article.
+type DeadLetterInfo struct {
+	Reason   string    `json:"reason"`
+	RunID    string    `json:"run_id"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// deadLetters appends failed articles to a dead letter file, created on
+// the first failure.
+type deadLetters struct {
+	path  string
+	runID string
+	file  *os.File
+	enc   *json.Encoder
+}
+
+// add appends the articles of batch named in rejected, or every article
+// of it with reason when rejected is nil. It returns how many it added.
+func (d *deadLetters) add(batch []model.Article, rejected map[string]string, reason string) (int, error) {
+	if d.file == nil {
+		file, err := os.OpenFile(d.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return 0, err
+		}
+		d.file, d.enc = file, json.NewEncoder(file)
+	}
+
+	now := time.Now().UTC()
+	added := 0
+	for _, a := range batch {
+		why := reason
+		if rejected != nil {
+			var ok bool
+			if why, ok = rejected[a.ID]; !ok {
+				continue
+			}
+		}
+		entry := DeadLetterEntry{Article: a, DeadLetter: DeadLetterInfo{Reason: why, RunID: d.runID, FailedAt: now}}
+		if err := d.enc.Encode(entry); err != nil {
+			return added, err
+		}
+		added++
+	}
+	return added, nil
+}
+
+func (d *deadLetters) close() error {
+	if d.file == nil {
+		return nil
+	}
+	return d.file.Close()
+}
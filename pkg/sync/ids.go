@@ -0,0 +1,94 @@
+package sync
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// IDStrategy decides how the document ID of an article is derived.
+type IDStrategy string
+
+const (
+	// IDInput uses the id of the input row.
+	IDInput IDStrategy = "input"
+	// IDURL hashes the canonical form of the article URL.
+	IDURL IDStrategy = "url"
+	// IDTitleDate is a UUIDv5 of the title and publication date, in its
+	// canonical UTC form.
+	IDTitleDate IDStrategy = "uuid5"
+	// IDAuto generates a random UUID, so reruns duplicate articles.
+	IDAuto IDStrategy = "auto"
+)
+
+// idNamespace is the UUIDv5 namespace of IDTitleDate.
+var idNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://inshorts.com/news-data-syncer/articles"))
+
+// ParseIDStrategies returns the comma separated strategies in s, which
+// are tried in order until one yields an ID.
+func ParseIDStrategies(s string) ([]IDStrategy, error) {
+	var strategies []IDStrategy
+	for _, name := range strings.Split(s, ",") {
+		switch st := IDStrategy(strings.TrimSpace(name)); st {
+		case IDInput, IDURL, IDTitleDate, IDAuto:
+			strategies = append(strategies, st)
+		default:
+			return nil, fmt.Errorf("unknown id strategy %q, expected input, url, uuid5 or auto", name)
+		}
+	}
+	return strategies, nil
+}
+
+// articleID returns the ID the first strategy able to derive one gives a,
+// or "" when none can.
+func articleID(a model.Article, strategies []IDStrategy) string {
+	for _, st := range strategies {
+		switch st {
+		case IDInput:
+			if id := strings.TrimSpace(a.ID); id != "" {
+				return id
+			}
+		case IDURL:
+			if a.URL == "" {
+				continue
+			}
+			canonical, err := utils.CanonicalURL(a.URL)
+			if err != nil {
+				continue
+			}
+			return utils.ContentHash(canonical)
+		case IDTitleDate:
+			if a.Title == "" || a.PublicationDate == "" {
+				continue
+			}
+			// The instant is hashed rather than the input, so equal dates
+			// formatted differently give the same ID
+			date := a.PublicationDate
+			if t, err := utils.ParseDate(date); err == nil {
+				date = utils.ESDate(t)
+			}
+			return uuid.NewSHA1(idNamespace, []byte(a.Title+"\n"+date)).String()
+		case IDAuto:
+			return uuid.NewString()
+		}
+	}
+	return ""
+}
+
+// assignIDs sets the ID of every article from strategies and drops the
+// articles none of them could derive one for, returning their number.
+func assignIDs(articles []model.Article, strategies []IDStrategy) ([]model.Article, int) {
+	kept := articles[:0]
+	missing := 0
+	for _, a := range articles {
+		if a.ID = articleID(a, strategies); a.ID == "" {
+			missing++
+			continue
+		}
+		kept = append(kept, a)
+	}
+	return kept, missing
+}
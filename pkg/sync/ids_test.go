@@ -0,0 +1,108 @@
+package sync
+
+import (
+	"testing"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+func TestArticleID(t *testing.T) {
+	tests := []struct {
+		name       string
+		article    model.Article
+		strategies []IDStrategy
+		want       string
+	}{
+		{
+			name:       "input",
+			article:    model.Article{ID: " 42 "},
+			strategies: []IDStrategy{IDInput},
+			want:       "42",
+		},
+		{
+			name:       "no input id",
+			article:    model.Article{Title: "t"},
+			strategies: []IDStrategy{IDInput},
+			want:       "",
+		},
+		{
+			name:       "url",
+			article:    model.Article{URL: "https://example.com/a"},
+			strategies: []IDStrategy{IDURL},
+			want:       urlID(t, "https://example.com/a"),
+		},
+		{
+			name:       "falls back to url",
+			article:    model.Article{URL: "https://example.com/a"},
+			strategies: []IDStrategy{IDInput, IDURL},
+			want:       urlID(t, "https://example.com/a"),
+		},
+		{
+			name:       "input before url",
+			article:    model.Article{ID: "42", URL: "https://example.com/a"},
+			strategies: []IDStrategy{IDInput, IDURL},
+			want:       "42",
+		},
+		{
+			name:       "uuid5 without date",
+			article:    model.Article{Title: "t"},
+			strategies: []IDStrategy{IDTitleDate},
+			want:       "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := articleID(tt.article, tt.strategies); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func urlID(t *testing.T, url string) string {
+	t.Helper()
+	canonical, err := utils.CanonicalURL(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return utils.ContentHash(canonical)
+}
+
+// TestArticleIDTitleDateFormats checks uuid5 IDs depend on the instant an
+// article was published, not on how the source formats it.
+func TestArticleIDTitleDateFormats(t *testing.T) {
+	want := articleID(model.Article{Title: "Budget passed", PublicationDate: "2024-01-01T10:00:00Z"}, []IDStrategy{IDTitleDate})
+	if want == "" {
+		t.Fatal("no id derived")
+	}
+	for _, date := range []string{
+		"2024-01-01T10:00:00",
+		"2024-01-01T10:00:00.000Z",
+		"2024-01-01T15:30:00+05:30",
+		"2024-01-01 10:00:00",
+		"1704103200",
+		"1704103200000",
+	} {
+		got := articleID(model.Article{Title: "Budget passed", PublicationDate: date}, []IDStrategy{IDTitleDate})
+		if got != want {
+			t.Errorf("%s gives id %s, want %s", date, got, want)
+		}
+	}
+
+	other := articleID(model.Article{Title: "Budget passed", PublicationDate: "2024-01-01T10:00:01Z"}, []IDStrategy{IDTitleDate})
+	if other == want {
+		t.Error("different instants give the same id")
+	}
+}
+
+func TestAssignIDs(t *testing.T) {
+	articles := []model.Article{{ID: "1"}, {Title: "no id"}, {ID: "3"}}
+	kept, missing := assignIDs(articles, []IDStrategy{IDInput})
+	if missing != 1 {
+		t.Errorf("missing is %d, want 1", missing)
+	}
+	if len(kept) != 2 || kept[0].ID != "1" || kept[1].ID != "3" {
+		t.Errorf("kept %v, want articles 1 and 3", kept)
+	}
+}
@@ -0,0 +1,112 @@
+package sync
+
+import (
+	"encoding/json"
+	"slices"
+	"sort"
+	"time"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+// slowestBatches is how many of the slowest batches are kept to name
+// their largest documents.
+const slowestBatches = 5
+
+// Latency summarises the bulk writes of a run, to spot regressions in the
+// cluster or the syncer run over run.
+type Latency struct {
+	Batches int   `json:"batches"`
+	P50Ms   int64 `json:"p50_ms"`
+	P95Ms   int64 `json:"p95_ms"`
+	P99Ms   int64 `json:"p99_ms"`
+	MaxMs   int64 `json:"max_ms"`
+	// ItemsPerBatch counts batches by number of articles, e.g. "101-500".
+	ItemsPerBatch map[string]int `json:"items_per_batch"`
+	// Retries counts responses with a retryable status per status code,
+	// see sink.RetryStats.
+	Retries map[string]int `json:"retries,omitempty"`
+	// Slowest names the largest document of each of the slowest batches,
+	// the usual suspects when a batch is slow.
+	Slowest []SlowDocument `json:"slowest,omitempty"`
+}
+
+// SlowDocument is the largest document of a slow batch.
+type SlowDocument struct {
+	ID      string `json:"id"`
+	Bytes   int    `json:"bytes"`
+	BatchMs int64  `json:"batch_ms"`
+}
+
+// batchBuckets are the upper bounds of the ItemsPerBatch buckets.
+var batchBuckets = []struct {
+	max   int
+	label string
+}{
+	{10, "1-10"}, {100, "11-100"}, {500, "101-500"}, {1000, "501-1000"}, {5000, "1001-5000"},
+}
+
+// latencyRecorder collects the timings of the batches of one run.
+type latencyRecorder struct {
+	took    []time.Duration
+	items   map[string]int
+	slowest []timedBatch
+}
+
+type timedBatch struct {
+	took     time.Duration
+	articles []model.Article
+}
+
+func (l *latencyRecorder) record(took time.Duration, articles []model.Article) {
+	l.took = append(l.took, took)
+	if l.items == nil {
+		l.items = make(map[string]int)
+	}
+	label := "over-5000"
+	for _, b := range batchBuckets {
+		if len(articles) <= b.max {
+			label = b.label
+			break
+		}
+	}
+	l.items[label]++
+
+	l.slowest = append(l.slowest, timedBatch{took, articles})
+	sort.Slice(l.slowest, func(i, j int) bool { return l.slowest[i].took > l.slowest[j].took })
+	if len(l.slowest) > slowestBatches {
+		l.slowest = l.slowest[:slowestBatches]
+	}
+}
+
+// summary returns the Latency of the recorded batches, nil without any.
+func (l *latencyRecorder) summary() *Latency {
+	if len(l.took) == 0 {
+		return nil
+	}
+	took := slices.Clone(l.took)
+	slices.Sort(took)
+	percentile := func(p float64) int64 {
+		return took[int(p*float64(len(took)-1))].Milliseconds()
+	}
+	summary := &Latency{
+		Batches:       len(took),
+		P50Ms:         percentile(0.5),
+		P95Ms:         percentile(0.95),
+		P99Ms:         percentile(0.99),
+		MaxMs:         took[len(took)-1].Milliseconds(),
+		ItemsPerBatch: l.items,
+	}
+	for _, b := range l.slowest {
+		var largest SlowDocument
+		for _, a := range b.articles {
+			data, err := json.Marshal(a)
+			if err == nil && len(data) > largest.Bytes {
+				largest = SlowDocument{ID: a.ID, Bytes: len(data)}
+			}
+		}
+		largest.BatchMs = b.took.Milliseconds()
+		summary.Slowest = append(summary.Slowest, largest)
+	}
+	return summary
+}
@@ -0,0 +1,289 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	gosync "sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/elastic/go-elasticsearch/v9/esapi"
+	"github.com/rs/zerolog/log"
+)
+
+// LockIndex holds one lock document per synced index.
+const LockIndex = "inshorts-news-syncer-locks"
+
+// ErrLockHeld is returned when another instance holds a live lock.
+var ErrLockHeld = errors.New("sync lock is held by another instance")
+
+// Lock makes sure only one sync runs per index at a time.
+type Lock interface {
+	// Acquire takes the lock, returning a context that is cancelled if
+	// the lock is lost while the sync is running.
+	Acquire(ctx context.Context) (context.Context, error)
+	// Release gives up a lock taken by Acquire.
+	Release()
+}
+
+// ESLock is a Lock stored as a document in Elasticsearch. Writes use
+// optimistic concurrency control, so of two instances racing to take over
+// an expired lock only one wins. The holder renews the lock every ttl/3.
+type ESLock struct {
+	es    *elasticsearch.Client
+	id    string
+	owner string
+	ttl   time.Duration
+
+	mu          gosync.Mutex
+	seqNo       int
+	primaryTerm int
+	cancel      context.CancelFunc
+	done        chan struct{}
+}
+
+type lockDoc struct {
+	Owner      string    `json:"owner"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type lockWriteResponse struct {
+	SeqNo       int `json:"_seq_no"`
+	PrimaryTerm int `json:"_primary_term"`
+}
+
+// NewESLock returns a lock for index that expires ttl after its last renewal.
+func NewESLock(es *elasticsearch.Client, index string, ttl time.Duration) *ESLock {
+	host, _ := os.Hostname()
+	return &ESLock{
+		es:    es,
+		id:    index,
+		owner: fmt.Sprintf("%s/%d", host, os.Getpid()),
+		ttl:   ttl,
+	}
+}
+
+// Acquire implements Lock.
+func (l *ESLock) Acquire(ctx context.Context) (context.Context, error) {
+	now := time.Now().UTC()
+	doc := lockDoc{Owner: l.owner, AcquiredAt: now, ExpiresAt: now.Add(l.ttl)}
+
+	status, err := l.write(ctx, doc, nil)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusConflict {
+		if err := l.takeover(ctx, doc); err != nil {
+			return nil, err
+		}
+	}
+
+	lockCtx, cancel := context.WithCancel(ctx)
+	l.cancel = cancel
+	l.done = make(chan struct{})
+	go l.renew(lockCtx, doc.AcquiredAt)
+
+	log.Info().Caller().Msgf("acquired sync lock for %s as %s", l.id, l.owner)
+	return lockCtx, nil
+}
+
+// takeover replaces an expired lock held by another instance.
+func (l *ESLock) takeover(ctx context.Context, doc lockDoc) error {
+	current, prev, err := l.read(ctx)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return fmt.Errorf("%w: lock was released while taking it over", ErrLockHeld)
+	}
+	if time.Now().Before(current.ExpiresAt) {
+		return fmt.Errorf("%w: %s until %s", ErrLockHeld, current.Owner, current.ExpiresAt.Format(time.RFC3339))
+	}
+
+	status, err := l.write(ctx, doc, &prev)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusConflict {
+		return fmt.Errorf("%w: lost takeover race for expired lock", ErrLockHeld)
+	}
+	log.Warn().Caller().Msgf("took over expired sync lock for %s from %s", l.id, current.Owner)
+	return nil
+}
+
+// read returns the lock document and its version, or nil when there is
+// none.
+func (l *ESLock) read(ctx context.Context) (*lockDoc, lockWriteResponse, error) {
+	res, err := esapi.GetRequest{Index: LockIndex, DocumentID: l.id}.Do(ctx, l.es)
+	if err != nil {
+		return nil, lockWriteResponse{}, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, lockWriteResponse{}, nil
+	}
+	if res.IsError() {
+		return nil, lockWriteResponse{}, fmt.Errorf("failed to read sync lock: %s", res.String())
+	}
+
+	var current struct {
+		lockWriteResponse
+		Source lockDoc `json:"_source"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&current); err != nil {
+		return nil, lockWriteResponse{}, err
+	}
+	return &current.Source, current.lockWriteResponse, nil
+}
+
+// ours reports whether doc is the lock this instance acquired at
+// acquiredAt.
+func (l *ESLock) ours(doc *lockDoc, acquiredAt time.Time) bool {
+	return doc != nil && doc.Owner == l.owner && doc.AcquiredAt.Equal(acquiredAt)
+}
+
+// write creates the lock document, or replaces it when prev is given and
+// still matches. It returns the response status so callers can tell a
+// conflict apart from other failures.
+func (l *ESLock) write(ctx context.Context, doc lockDoc, prev *lockWriteResponse) (int, error) {
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return 0, err
+	}
+
+	req := esapi.IndexRequest{
+		Index:      LockIndex,
+		DocumentID: l.id,
+		Body:       bytes.NewReader(body),
+		OpType:     "create",
+		Refresh:    "true",
+	}
+	if prev != nil {
+		req.OpType = ""
+		req.IfSeqNo = &prev.SeqNo
+		req.IfPrimaryTerm = &prev.PrimaryTerm
+	}
+
+	res, err := req.Do(ctx, l.es)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusConflict {
+		return res.StatusCode, nil
+	}
+	if res.IsError() {
+		return res.StatusCode, fmt.Errorf("failed to write sync lock: %s", res.String())
+	}
+
+	var written lockWriteResponse
+	if err := json.NewDecoder(res.Body).Decode(&written); err != nil {
+		return res.StatusCode, err
+	}
+	l.mu.Lock()
+	l.seqNo, l.primaryTerm = written.SeqNo, written.PrimaryTerm
+	l.mu.Unlock()
+	return res.StatusCode, nil
+}
+
+// renew extends the lock until ctx is cancelled. It cancels the sync
+// when the lock was taken over by someone else, or when it couldn't be
+// renewed for ttl, after which another instance may take it over.
+func (l *ESLock) renew(ctx context.Context, acquiredAt time.Time) {
+	defer close(l.done)
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	renewedAt := acquiredAt
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now().UTC()
+			owned, err := l.extend(ctx, lockDoc{Owner: l.owner, AcquiredAt: acquiredAt, ExpiresAt: now.Add(l.ttl)})
+			switch {
+			case err != nil && ctx.Err() != nil:
+				return
+			case err != nil && now.Sub(renewedAt) >= l.ttl:
+				log.Error().Caller().Err(err).Msgf("sync lock for %s wasn't renewed for %v and may be taken over, cancelling sync", l.id, l.ttl)
+				l.cancel()
+				return
+			case err != nil:
+				log.Warn().Caller().Err(err).Msg("failed to renew sync lock")
+			case !owned:
+				log.Error().Caller().Msgf("sync lock for %s was taken over, cancelling sync", l.id)
+				l.cancel()
+				return
+			default:
+				renewedAt = now
+			}
+		}
+	}
+}
+
+// extend writes doc over the lock, and reports false when another
+// instance took it over. A conflict may also come from a renewal whose
+// response was lost, leaving the version held behind the document: the
+// lock is then read again and, while it is still this instance's, written
+// over once more.
+func (l *ESLock) extend(ctx context.Context, doc lockDoc) (bool, error) {
+	for {
+		l.mu.Lock()
+		prev := lockWriteResponse{SeqNo: l.seqNo, PrimaryTerm: l.primaryTerm}
+		l.mu.Unlock()
+
+		status, err := l.write(ctx, doc, &prev)
+		if err != nil {
+			return false, err
+		}
+		if status != http.StatusConflict {
+			return true, nil
+		}
+		current, version, err := l.read(ctx)
+		if err != nil {
+			return false, err
+		}
+		if !l.ours(current, doc.AcquiredAt) {
+			return false, nil
+		}
+		l.mu.Lock()
+		l.seqNo, l.primaryTerm = version.SeqNo, version.PrimaryTerm
+		l.mu.Unlock()
+	}
+}
+
+// Release stops renewal and deletes the lock if this instance still holds it.
+func (l *ESLock) Release() {
+	l.cancel()
+	<-l.done
+
+	l.mu.Lock()
+	seqNo, primaryTerm := l.seqNo, l.primaryTerm
+	l.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	res, err := esapi.DeleteRequest{
+		Index:         LockIndex,
+		DocumentID:    l.id,
+		IfSeqNo:       &seqNo,
+		IfPrimaryTerm: &primaryTerm,
+		Refresh:       "true",
+	}.Do(ctx, l.es)
+	if err != nil {
+		log.Warn().Caller().Err(err).Msg("failed to release sync lock")
+		return
+	}
+	defer res.Body.Close()
+	if res.IsError() && res.StatusCode != http.StatusConflict && res.StatusCode != http.StatusNotFound {
+		log.Warn().Caller().Msgf("failed to release sync lock: %s", res.String())
+	}
+}
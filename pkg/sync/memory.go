@@ -0,0 +1,99 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/pkg/source"
+)
+
+// ErrMemoryBudget is returned when a run can't stay within
+// Syncer.MaxMemory, before the container would be OOM killed.
+var ErrMemoryBudget = errors.New("memory budget exceeded, sync a smaller share of the source, e.g. with --limit or backfill slices")
+
+const (
+	// memoryCheckEvery is how many articles are read between checks of
+	// the heap, as reading the statistics briefly stops the world.
+	memoryCheckEvery = 1024
+	// memoryPauseLimit is how long reads wait for memory to be freed,
+	// e.g. by a concurrent sync finishing, before giving up.
+	memoryPauseLimit = 30 * time.Second
+	// minBudgetBatchSize is the smallest batch writes shrink to.
+	minBudgetBatchSize = 50
+)
+
+func heapInUse() int64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return int64(stats.HeapAlloc)
+}
+
+// budgetSource pauses reading while the heap is above 90% of budget.
+type budgetSource struct {
+	source.Source
+	budget int64
+	read   int
+}
+
+func (b *budgetSource) Next(ctx context.Context) (model.Article, error) {
+	b.read++
+	if b.read%memoryCheckEvery == 0 {
+		if err := b.wait(ctx); err != nil {
+			return model.Article{}, err
+		}
+	}
+	return b.Source.Next(ctx)
+}
+
+// wait returns once the heap is back under the high watermark, first
+// collecting garbage and then waiting up to memoryPauseLimit.
+func (b *budgetSource) wait(ctx context.Context) error {
+	high := b.budget / 10 * 9
+	if heapInUse() < high {
+		return nil
+	}
+	runtime.GC()
+	if heapInUse() < high {
+		return nil
+	}
+
+	log.Warn().Caller().Msgf("pausing reads after %d articles, the heap is above 90%% of the %d byte memory budget", b.read, b.budget)
+	deadline := time.NewTimer(memoryPauseLimit)
+	defer deadline.Stop()
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			return ErrMemoryBudget
+		case <-ticker.C:
+			runtime.GC()
+			if heapInUse() < high {
+				log.Info().Caller().Msg("resuming reads")
+				return nil
+			}
+		}
+	}
+}
+
+// fitBatch halves batchSize while the heap is above 75% of budget, as
+// every batch is encoded into a request body in memory, and doubles it
+// back up to size once the heap is under half of budget again.
+func fitBatch(batchSize, size int, budget int64) int {
+	heap := heapInUse()
+	switch {
+	case heap > budget/4*3 && batchSize > minBudgetBatchSize:
+		batchSize = max(batchSize/2, minBudgetBatchSize)
+		log.Warn().Caller().Msgf("shrinking batches to %d articles to stay within the memory budget", batchSize)
+	case heap <= budget/2 && batchSize < size:
+		batchSize = min(batchSize*2, size)
+		log.Info().Caller().Msgf("growing batches back to %d articles, the heap is under half of the memory budget", batchSize)
+	}
+	return batchSize
+}
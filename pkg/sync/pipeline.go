@@ -0,0 +1,99 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+// pipelineDepth is how many batches may wait between two stages, which
+// bounds the articles held in memory by a pipelined run.
+const pipelineDepth = 4
+
+// pipeline runs the sync as three stages connected by bounded channels:
+// decoding batches from the source, transforming them and writing them
+// to the sink, where they are encoded into bulk requests. A stage that
+// fails cancels the others.
+func (r *run) pipeline(ctx context.Context) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	src, err := r.open(ctx)
+	if err != nil {
+		log.Error().Caller().Err(err).Msgf("error while loading articles from %s", r.Source)
+		return err
+	}
+	defer src.Close()
+
+	decoded := make(chan []model.Article, pipelineDepth)
+	transformed := make(chan []model.Article, pipelineDepth)
+	var wg sync.WaitGroup
+
+	// send hands batch to the next stage unless the run was cancelled.
+	send := func(ch chan<- []model.Article, batch []model.Article) bool {
+		select {
+		case ch <- batch:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer close(decoded)
+		batchSize := r.batchSize()
+		for {
+			batch := make([]model.Article, 0, batchSize)
+			for len(batch) < batchSize {
+				a, err := src.Next(ctx)
+				if errors.Is(err, io.EOF) {
+					if len(batch) > 0 {
+						r.report.Loaded += len(batch)
+						send(decoded, batch)
+					}
+					return
+				}
+				if err != nil {
+					log.Error().Caller().Err(err).Msgf("error while loading articles from %s", r.Source)
+					cancel(err)
+					return
+				}
+				batch = append(batch, a)
+			}
+			r.report.Loaded += len(batch)
+			if !send(decoded, batch) {
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		defer close(transformed)
+		for batch := range decoded {
+			batch, err := r.transform(ctx, batch)
+			if err != nil {
+				cancel(err)
+				return
+			}
+			if len(batch) > 0 && !send(transformed, batch) {
+				return
+			}
+		}
+	}()
+
+	for batch := range transformed {
+		if err := r.write(ctx, batch); err != nil {
+			cancel(err)
+			break
+		}
+	}
+	// Stages blocked on a send observe the cancellation
+	wg.Wait()
+	return context.Cause(ctx)
+}
@@ -0,0 +1,72 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+// Ingest transforms and writes articles pushed to the syncer rather than
+// loaded from Source, as a run does: their dates are normalised, the
+// source registry, ID strategies, run stamp, access fields and enrichers
+// are applied and they are written to the sink or the sinks of their
+// routes. As pushed articles are no subset of a source, the window,
+// shard and sample don't apply. sourceFile is stored as their
+// source_file. Unlike Run it neither locks, prunes nor notifies. When
+// it fails, the caller is to retry or dead letter the articles, see
+// DeadLetterPushed: only those the sink rejected are dead lettered.
+func (s *Syncer) Ingest(ctx context.Context, articles []model.Article, sourceFile string) (*Report, error) {
+	pushed := *s
+	pushed.Source = sourceFile
+	pushed.Since, pushed.Until = time.Time{}, time.Time{}
+	pushed.SampleRate, pushed.Limit, pushed.Shard = 0, 0, Shard{}
+	pushed.Fixup, pushed.Pipeline = nil, false
+
+	report := newReport()
+	report.Job = s.Name
+	report.Loaded = len(articles)
+	r := &run{Syncer: &pushed, report: report, sourceCounts: make(map[string]int), requeued: true}
+	if s.DeadLetter != "" {
+		r.deadLetters = &deadLetters{path: s.DeadLetter, runID: report.RunID}
+		defer r.deadLetters.close()
+	}
+
+	articles, err := r.transform(ctx, articles)
+	if err == nil {
+		err = r.write(ctx, articles)
+	}
+	report.finish(err)
+	return report, err
+}
+
+// CheckPushed tells why Ingest would fail or skip a: it has no title, a
+// publication date OnBadDate rejects or none of IDStrategies derives an
+// ID for it.
+func (s *Syncer) CheckPushed(a model.Article) error {
+	if a.Title == "" {
+		return errors.New("title is required")
+	}
+	normalized, _, err := normalizeDates([]model.Article{a}, s.Timezone, s.datePolicy(), time.Now())
+	if err != nil || len(normalized) == 0 {
+		return fmt.Errorf("invalid publication_date %q", a.PublicationDate)
+	}
+	if articleID(normalized[0], s.idStrategies()) == "" {
+		return errors.New("id is required")
+	}
+	return nil
+}
+
+// DeadLetterPushed appends pushed articles that couldn't be written to
+// DeadLetter, as failed with reason in run runID, and returns how many it
+// appended.
+func (s *Syncer) DeadLetterPushed(articles []model.Article, runID, reason string) (int, error) {
+	d := &deadLetters{path: s.DeadLetter, runID: runID}
+	added, err := d.add(articles, nil, reason)
+	if closeErr := d.close(); err == nil {
+		err = closeErr
+	}
+	return added, err
+}
@@ -0,0 +1,157 @@
+package sync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// SourceRegistry configures news sources by source_name, e.g.
+//
+//	{
+//	  "PTI": {"trust": 0.9, "default_category": ["national"]},
+//	  "Some Aggregator": {"trust": 0.3, "max_articles_per_run": 200},
+//	  "Reuters": {"access": {"license": "reuters", "visibility": "subscribers"}, "license_days": 30},
+//	  "Spam Wire": {"enabled": false}
+//	}
+//
+// Names are matched case insensitively. Unlisted sources are synced
+// without a trust score.
+type SourceRegistry map[string]SourceConfig
+
+// SourceConfig is the registry entry of one source.
+type SourceConfig struct {
+	// Trust between 0 and 1 is written to source_trust for ranking.
+	Trust *float64 `json:"trust"`
+	// DefaultCategory is used for articles without a category.
+	DefaultCategory []string `json:"default_category"`
+	// Enabled is true unless set to false, which skips the source.
+	Enabled *bool `json:"enabled"`
+	// MaxArticlesPerRun caps the articles synced from the source per run.
+	// Zero means no limit.
+	MaxArticlesPerRun int `json:"max_articles_per_run"`
+	// LicenseDays is how many days after publication the articles of the
+	// source may be served: they are written with that expires_at and
+	// purged once it passes. Zero means no limit.
+	LicenseDays int `json:"license_days"`
+	// Access overrides the access fields of the articles of the source,
+	// see Syncer.InjectAccess.
+	Access map[string]string `json:"access"`
+}
+
+// LoadSourceRegistry reads a registry from a JSON file.
+func LoadSourceRegistry(path string) (SourceRegistry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw SourceRegistry
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	registry := make(SourceRegistry, len(raw))
+	for name, source := range raw {
+		if source.Trust != nil && (*source.Trust < 0 || *source.Trust > 1) {
+			return nil, fmt.Errorf("trust of source %q must be between 0 and 1", name)
+		}
+		if source.MaxArticlesPerRun < 0 {
+			return nil, fmt.Errorf("max_articles_per_run of source %q must not be negative", name)
+		}
+		if source.LicenseDays < 0 {
+			return nil, fmt.Errorf("license_days of source %q must not be negative", name)
+		}
+		if err := checkAccessFields(source.Access); err != nil {
+			return nil, fmt.Errorf("source %q: %w", name, err)
+		}
+		key := strings.ToLower(strings.TrimSpace(name))
+		if _, ok := registry[key]; ok {
+			return nil, fmt.Errorf("source %q is listed twice", name)
+		}
+		registry[key] = source
+	}
+	if len(registry) == 0 {
+		return nil, errors.New("no sources in registry")
+	}
+	return registry, nil
+}
+
+// apply drops the articles of disabled sources and those beyond a
+// source's per run limit, and fills in the trust score and default
+// category of the rest. counts holds the articles kept per source so far
+// in the run. It returns the articles to write and how many were dropped
+// for each reason.
+func (r SourceRegistry) apply(articles []model.Article, counts map[string]int) (kept []model.Article, disabled, limited int) {
+	kept = articles[:0]
+	for _, a := range articles {
+		key := strings.ToLower(strings.TrimSpace(a.SourceName))
+		source, ok := r[key]
+		if !ok {
+			kept = append(kept, a)
+			continue
+		}
+		if source.Enabled != nil && !*source.Enabled {
+			disabled++
+			continue
+		}
+		if source.MaxArticlesPerRun > 0 && counts[key] >= source.MaxArticlesPerRun {
+			limited++
+			continue
+		}
+		counts[key]++
+
+		if source.Trust != nil {
+			trust := *source.Trust
+			a.SourceTrust = &trust
+		}
+		if len(a.Category) == 0 && len(source.DefaultCategory) > 0 {
+			a.Category = append([]string(nil), source.DefaultCategory...)
+		}
+		if source.LicenseDays > 0 {
+			a.ExpiresAt = licenseExpiry(a, source.LicenseDays)
+		}
+		kept = append(kept, a)
+	}
+	return kept, disabled, limited
+}
+
+// hasAccess reports whether any source sets access fields.
+func (r SourceRegistry) hasAccess() bool {
+	for _, source := range r {
+		if len(source.Access) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Licensed reports whether any source has a license window, whose
+// expired articles need purging.
+func (r SourceRegistry) Licensed() bool {
+	for _, source := range r {
+		if source.LicenseDays > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// licenseExpiry returns when the license of a, granted for days from its
+// publication, runs out, or the expires_at of the input when earlier.
+// Articles without a publication date are licensed from now.
+func licenseExpiry(a model.Article, days int) string {
+	published, err := utils.ParseDate(a.PublicationDate)
+	if err != nil {
+		published = time.Now()
+	}
+	expiry := published.AddDate(0, 0, days)
+	if given, err := utils.ParseDate(a.ExpiresAt); err == nil && given.Before(expiry) {
+		expiry = given
+	}
+	return utils.ESDate(expiry)
+}
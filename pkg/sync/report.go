@@ -0,0 +1,158 @@
+package sync
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/sink"
+)
+
+// Report summarises a single sync run.
+type Report struct {
+	// RunID identifies the run, and is stored with every article it writes.
+	RunID string `json:"run_id"`
+	// Job is the name of the syncer, if it has one.
+	Job        string    `json:"job,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Loaded     int       `json:"loaded"`
+	Indexed    int       `json:"indexed"`
+	Failed     int       `json:"failed"`
+	// BadDates counts unparseable publication dates, see Syncer.OnBadDate.
+	BadDates int `json:"bad_dates"`
+	// MissingIDs counts articles skipped for want of an ID, see
+	// Syncer.IDStrategies.
+	MissingIDs int `json:"missing_ids"`
+	// OutsideWindow counts articles published outside Syncer.Since and
+	// Syncer.Until.
+	OutsideWindow int `json:"outside_window,omitempty"`
+	// DisabledSources and OverSourceLimit count articles skipped by the
+	// source registry, see Syncer.Sources.
+	DisabledSources int `json:"disabled_sources"`
+	OverSourceLimit int `json:"over_source_limit"`
+	// Unsampled counts articles left out by Syncer.SampleRate and
+	// Syncer.Limit.
+	Unsampled int `json:"unsampled,omitempty"`
+	// OtherShards counts articles left to the other instances, see
+	// Syncer.Shard.
+	OtherShards int `json:"other_shards,omitempty"`
+	// DeadLettered counts articles appended to Syncer.DeadLetter.
+	DeadLettered int `json:"dead_lettered,omitempty"`
+	// Pruned counts articles soft deleted by Syncer.Prune.
+	Pruned int `json:"pruned,omitempty"`
+	// Expired counts articles deleted past their retention or license by
+	// Syncer.Expire.
+	Expired int `json:"expired,omitempty"`
+	// Related counts articles whose related articles Syncer.Relate
+	// stored.
+	Related int    `json:"related,omitempty"`
+	Error   string `json:"error,omitempty"`
+	// Sinks breaks the outcome down per destination when writing to several.
+	Sinks []sink.Stats `json:"sinks,omitempty"`
+	// Consistent is set with Sinks and tells whether all of them got the same documents.
+	Consistent *bool `json:"consistent,omitempty"`
+	// Latency summarises the batch writes, nil when nothing was written.
+	Latency *Latency `json:"latency,omitempty"`
+}
+
+// Notifier is told the outcome of runs, e.g. to post them to a chat.
+type Notifier interface {
+	NotifyRun(ctx context.Context, r *Report) error
+}
+
+// OK reports whether the run completed without errors or failed documents.
+func (r *Report) OK() bool {
+	return r.Error == "" && r.Failed == 0 && (r.Consistent == nil || *r.Consistent)
+}
+
+// setSinks records the per destination outcome of a fan out write.
+func (r *Report) setSinks(stats []sink.Stats) {
+	consistent := sink.Consistent(stats)
+	r.Sinks = stats
+	r.Consistent = &consistent
+}
+
+func newReport() *Report {
+	now := time.Now().UTC()
+	return &Report{RunID: newRunID(now), StartedAt: now}
+}
+
+// newRunID returns a unique, time ordered run ID such as
+// "20240310T120000Z-1a2b3c4d".
+func newRunID(now time.Time) string {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return now.Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
+
+// finish records the end of the run and its error, if any.
+func (r *Report) finish(err error) {
+	r.FinishedAt = time.Now().UTC()
+	r.DurationMs = r.FinishedAt.Sub(r.StartedAt).Milliseconds()
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// Log writes the report as a single structured log line.
+func (r *Report) Log() {
+	event := log.Info()
+	if !r.OK() {
+		event = log.Error()
+	}
+	if r.Job != "" {
+		event = event.Str("job", r.Job)
+	}
+	event.Caller().
+		Str("run_id", r.RunID).
+		Time("started_at", r.StartedAt).
+		Int64("duration_ms", r.DurationMs).
+		Int("loaded", r.Loaded).
+		Int("indexed", r.Indexed).
+		Int("bad_dates", r.BadDates).
+		Int("missing_ids", r.MissingIDs).
+		Int("outside_window", r.OutsideWindow).
+		Int("disabled_sources", r.DisabledSources).
+		Int("over_source_limit", r.OverSourceLimit).
+		Int("unsampled", r.Unsampled).
+		Int("other_shards", r.OtherShards).
+		Int("failed", r.Failed).
+		Int("dead_lettered", r.DeadLettered).
+		Str("error", r.Error).
+		Msg("sync run finished")
+
+	if l := r.Latency; l != nil {
+		event := log.Info().Caller().
+			Int("batches", l.Batches).
+			Int64("p50_ms", l.P50Ms).
+			Int64("p95_ms", l.P95Ms).
+			Int64("p99_ms", l.P99Ms).
+			Int64("max_ms", l.MaxMs).
+			Interface("items_per_batch", l.ItemsPerBatch).
+			Interface("slowest", l.Slowest)
+		if len(l.Retries) > 0 {
+			event = event.Interface("retries", l.Retries)
+		}
+		event.Msg("batch latency")
+	}
+
+	for _, s := range r.Sinks {
+		event := log.Info()
+		if s.Failed > 0 || s.Error != "" {
+			event = log.Warn()
+		}
+		event.Caller().
+			Str("sink", s.Sink).
+			Int("indexed", s.Indexed).
+			Int("failed", s.Failed).
+			Str("error", s.Error).
+			Msg("sink write finished")
+	}
+	if r.Consistent != nil && !*r.Consistent {
+		log.Error().Caller().Msg("sinks are inconsistent, not every destination received every article")
+	}
+}
@@ -0,0 +1,164 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/enrich"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/pkg/sink"
+)
+
+// CategoryRoutes give the articles of some categories their own index
+// and enrichment within one run, e.g.
+//
+//	[
+//	  {"category": "sports", "index": "news-sports", "skip_enrichers": ["llm_summary"]},
+//	  {"category": "finance", "enrichers": ["entities"]}
+//	]
+//
+// An article takes the first route matching one of its categories, case
+// insensitively. Enrichers listed in the enrichers of some route only
+// run on the articles of those routes; the others run on every article
+// except those of routes skipping them. Enrichers are named as in
+// enrich.Enricher.Name.
+type CategoryRoutes []CategoryRoute
+
+// CategoryRoute is one rule of CategoryRoutes.
+type CategoryRoute struct {
+	Category string `json:"category"`
+	// Index is written to instead of the index of the run, empty keeps it.
+	Index         string   `json:"index"`
+	Enrichers     []string `json:"enrichers"`
+	SkipEnrichers []string `json:"skip_enrichers"`
+}
+
+// LoadCategoryRoutes reads routes from a JSON file.
+func LoadCategoryRoutes(path string) (CategoryRoutes, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var routes CategoryRoutes
+	if err := json.Unmarshal(data, &routes); err != nil {
+		return nil, err
+	}
+	if len(routes) == 0 {
+		return nil, errors.New("no category routes defined")
+	}
+	seen := make(map[string]bool)
+	for i, route := range routes {
+		key := strings.ToLower(strings.TrimSpace(route.Category))
+		if key == "" {
+			return nil, fmt.Errorf("route %d has no category", i+1)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("category %q is routed twice", route.Category)
+		}
+		seen[key] = true
+		for _, name := range route.Enrichers {
+			if slices.Contains(route.SkipEnrichers, name) {
+				return nil, fmt.Errorf("route of %q both runs and skips enricher %s", route.Category, name)
+			}
+		}
+	}
+	return routes, nil
+}
+
+// Indices returns the indices routed to, in order.
+func (c CategoryRoutes) Indices() []string {
+	var indices []string
+	for _, route := range c {
+		if route.Index != "" && !slices.Contains(indices, route.Index) {
+			indices = append(indices, route.Index)
+		}
+	}
+	return indices
+}
+
+// Check returns an error naming a route enricher that isn't in enrichers.
+func (c CategoryRoutes) Check(enrichers []enrich.Enricher) error {
+	for _, route := range c {
+		for _, name := range slices.Concat(route.Enrichers, route.SkipEnrichers) {
+			if !slices.ContainsFunc(enrichers, func(e enrich.Enricher) bool { return e.Name() == name }) {
+				return fmt.Errorf("route of %q names enricher %s, which isn't enabled", route.Category, name)
+			}
+		}
+	}
+	return nil
+}
+
+// match returns the route of a, nil when it has none.
+func (c CategoryRoutes) match(a *model.Article) *CategoryRoute {
+	for i := range c {
+		for _, category := range a.Category {
+			if strings.EqualFold(strings.TrimSpace(category), strings.TrimSpace(c[i].Category)) {
+				return &c[i]
+			}
+		}
+	}
+	return nil
+}
+
+// runs reports whether the enricher name runs on the articles of route,
+// nil for unrouted articles.
+func (c CategoryRoutes) runs(route *CategoryRoute, name string) bool {
+	if route != nil && slices.Contains(route.Enrichers, name) {
+		return true
+	}
+	if route != nil && slices.Contains(route.SkipEnrichers, name) {
+		return false
+	}
+	// Enrichers some route asks for are limited to those routes
+	return !slices.ContainsFunc(c, func(r CategoryRoute) bool { return slices.Contains(r.Enrichers, name) })
+}
+
+// enrich runs the enrichers on the articles of the routes they apply to.
+// Articles are matched again before each enricher, so categories
+// assigned by an earlier one are routed.
+func (r *run) enrich(ctx context.Context, articles []model.Article) error {
+	if len(r.Routes) == 0 {
+		return enrich.Run(ctx, r.Enrichers, articles)
+	}
+	for _, e := range r.Enrichers {
+		var picked []int
+		for i := range articles {
+			if r.Routes.runs(r.Routes.match(&articles[i]), e.Name()) {
+				picked = append(picked, i)
+			}
+		}
+		if len(picked) == len(articles) {
+			if err := enrich.Run(ctx, []enrich.Enricher{e}, articles); err != nil {
+				return err
+			}
+			continue
+		}
+		if len(picked) == 0 {
+			continue
+		}
+		group := make([]model.Article, len(picked))
+		for j, i := range picked {
+			group[j] = articles[i]
+		}
+		if err := enrich.Run(ctx, []enrich.Enricher{e}, group); err != nil {
+			return err
+		}
+		for j, i := range picked {
+			articles[i] = group[j]
+		}
+	}
+	return nil
+}
+
+// sinkFor returns the sink a is written to.
+func (r *run) sinkFor(a *model.Article) sink.Sink {
+	if route := r.Routes.match(a); route != nil && route.Index != "" {
+		return r.RouteSinks[route.Index]
+	}
+	return r.Sink
+}
@@ -0,0 +1,67 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+// ParseSampleRate parses a sample rate given as a percentage such as
+// "1%" or a fraction such as "0.01".
+func ParseSampleRate(value string) (float64, error) {
+	number, percent := strings.CutSuffix(strings.TrimSpace(value), "%")
+	rate, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sample rate %q", value)
+	}
+	if percent {
+		rate /= 100
+	}
+	if rate <= 0 || rate > 1 {
+		return 0, errors.New("sample rate must be above 0% and at most 100%")
+	}
+	return rate, nil
+}
+
+// sampleKey places an article on [0, 1) by the hash of its ID, so the
+// same articles are picked by every run and from every dump.
+func sampleKey(a model.Article) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(a.ID))
+	return float64(h.Sum64()) / (math.MaxUint64 + 1.0)
+}
+
+// sample keeps the articles whose key is below rate, then at most limit
+// of them with the lowest keys, in their original order. A rate of zero
+// keeps all and a limit of zero keeps any number. It returns the kept
+// articles and the number dropped.
+func sample(articles []model.Article, rate float64, limit int) ([]model.Article, int) {
+	type keyed struct {
+		index int
+		key   float64
+	}
+	picked := make([]keyed, 0, len(articles))
+	for i, a := range articles {
+		key := sampleKey(a)
+		if rate == 0 || key < rate {
+			picked = append(picked, keyed{i, key})
+		}
+	}
+	if limit > 0 && len(picked) > limit {
+		sort.Slice(picked, func(i, j int) bool { return picked[i].key < picked[j].key })
+		picked = picked[:limit]
+		sort.Slice(picked, func(i, j int) bool { return picked[i].index < picked[j].index })
+	}
+
+	kept := make([]model.Article, 0, len(picked))
+	for _, p := range picked {
+		kept = append(kept, articles[p.index])
+	}
+	return kept, len(articles) - len(kept)
+}
@@ -0,0 +1,70 @@
+package sync
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+)
+
+// Shard is the part of the input one of Count syncer instances sharing
+// it processes, e.g. the pods of a StatefulSet splitting a backfill
+// without coordinating. Index counts from zero. The zero Shard is the
+// whole input.
+type Shard struct {
+	Index int
+	Count int
+}
+
+// ParseShard parses a shard given as "i/n", e.g. "2/4" for the third of
+// four instances.
+func ParseShard(value string) (Shard, error) {
+	index, count, ok := strings.Cut(value, "/")
+	if !ok {
+		return Shard{}, fmt.Errorf("invalid shard %q, expected i/n such as 0/4", value)
+	}
+	var s Shard
+	var err error
+	if s.Index, err = strconv.Atoi(strings.TrimSpace(index)); err != nil {
+		return Shard{}, fmt.Errorf("invalid shard %q, expected i/n such as 0/4", value)
+	}
+	if s.Count, err = strconv.Atoi(strings.TrimSpace(count)); err != nil {
+		return Shard{}, fmt.Errorf("invalid shard %q, expected i/n such as 0/4", value)
+	}
+	if s.Count < 1 || s.Index < 0 || s.Index >= s.Count {
+		return Shard{}, fmt.Errorf("invalid shard %q, i must be between 0 and n-1", value)
+	}
+	return s, nil
+}
+
+// String returns s as ParseShard takes it.
+func (s Shard) String() string {
+	return fmt.Sprintf("%d/%d", s.Index, s.Count)
+}
+
+// Owns reports whether a belongs to the shard, by the hash of its ID so
+// every instance agrees without talking to the others.
+func (s Shard) Owns(a model.Article) bool {
+	if s.Count <= 1 {
+		return true
+	}
+	// The remainder depends on the low bits of the hash while sampleKey
+	// goes by the high ones, so sampling a shard keeps its share
+	h := fnv.New64a()
+	h.Write([]byte(a.ID))
+	return h.Sum64()%uint64(s.Count) == uint64(s.Index)
+}
+
+// shard keeps the articles s owns, in order, and returns how many it left
+// to the other shards.
+func (s Shard) shard(articles []model.Article) ([]model.Article, int) {
+	kept := articles[:0]
+	for _, a := range articles {
+		if s.Owns(a) {
+			kept = append(kept, a)
+		}
+	}
+	return kept, len(articles) - len(kept)
+}
@@ -0,0 +1,513 @@
+// Package sync orchestrates a sync run: it loads articles from a source,
+// enriches them and writes them to the sink, optionally guarded by a lock.
+// Importers usually alias it to avoid clashing with the standard library.
+package sync
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"inshorts.com/inshorts-news-data-syncer/pkg/enrich"
+	"inshorts.com/inshorts-news-data-syncer/pkg/model"
+	"inshorts.com/inshorts-news-data-syncer/pkg/sink"
+	"inshorts.com/inshorts-news-data-syncer/pkg/source"
+	"inshorts.com/inshorts-news-data-syncer/utils"
+)
+
+// Syncer holds everything a sync run needs.
+type Syncer struct {
+	// Name optionally identifies the run in its report, e.g. the job.
+	Name string
+	// Source is the URI articles are loaded from, see source.Open.
+	Source string
+	// Shape optionally maps the input objects of Source onto articles.
+	Shape source.Shape
+	Sink  sink.Sink
+	// Routes optionally give the articles of some categories their own
+	// index and enrichment, see CategoryRoutes.
+	Routes CategoryRoutes
+	// RouteSinks write the articles of routes with an index, by index.
+	RouteSinks map[string]sink.Sink
+	// BatchSize is the number of articles per WriteBatch, sink.DefaultBulkSize when zero.
+	BatchSize int
+	Enrichers []enrich.Enricher
+	// Timezone is the zone of source timestamps that carry none. Nil
+	// means UTC.
+	Timezone *time.Location
+	// OnBadDate handles unparseable publication dates, DateFail when empty.
+	OnBadDate DatePolicy
+	// Since and Until optionally restrict the run to articles published
+	// in [Since, Until), e.g. one slice of a backfill.
+	Since, Until time.Time
+	// Sources optionally configures news sources by name. Nil syncs
+	// every source as is.
+	Sources SourceRegistry
+	// IDStrategies derive article IDs, tried in order. Empty means
+	// IDInput. Articles left without an ID are skipped.
+	IDStrategies []IDStrategy
+	// SampleRate and Limit reduce the run to a deterministic subset, e.g.
+	// to seed a staging cluster: the articles whose ID hashes below
+	// SampleRate, at most Limit of them. Zero disables either.
+	SampleRate float64
+	Limit      int
+	// Shard restricts the run to the articles one of several instances
+	// syncing the same source owns, by the hash of their ID. The zero
+	// Shard syncs every article.
+	Shard Shard
+	// MaxMemory is the heap budget in bytes. When set, writes shrink
+	// their batches while the heap nears it and, in a Pipeline run, reads
+	// pause; a run that still can't fit fails with ErrMemoryBudget. Runs
+	// that aren't pipelined hold the whole source in memory, so reads
+	// aren't paused there. Zero disables the budget.
+	MaxMemory int64
+	// Pipeline reads, transforms and writes batches concurrently, so
+	// slow enrichment doesn't hold up reading the source or bulk
+	// requests. Limit then keeps the first articles sampled, and
+	// Preflight vets each batch rather than the whole run. Run wide
+	// enrichers, see enrich.RunWide, fail a pipelined run.
+	Pipeline bool
+	// Access are the document level security fields set on every
+	// article, e.g. {"visibility": "public"}, which search roles filter
+	// on. Sources can override them, see InjectAccess.
+	Access map[string]string
+	// Tenant optionally tags every article with the brand it is synced
+	// for.
+	Tenant string
+	// DeadLetter is a file rejected articles are appended to, see
+	// DeadLetterEntry. Empty disables dead lettering.
+	DeadLetter string
+	// Fixup optionally corrects each loaded article, e.g. when retrying
+	// dead letters. An error fails the run.
+	Fixup func(a *model.Article) error
+	// Retries optionally counts the retried requests of the cluster
+	// clients for the report.
+	Retries *sink.RetryStats
+	// Prune optionally soft deletes the articles of the destination the
+	// run runID didn't write, returning how many. It's called only after
+	// a complete run of the whole source.
+	Prune func(ctx context.Context, runID string) (int, error)
+	// Expire optionally deletes the articles of the destination past
+	// their retention or license after every successful run, returning
+	// how many.
+	Expire func(ctx context.Context) (int, error)
+	// Relate optionally stores the related articles of those the run
+	// runID wrote after every successful run, returning for how many.
+	// Failing to doesn't fail the run.
+	Relate func(ctx context.Context, runID string) (int, error)
+	// Notifier is optionally told the outcome of every run.
+	Notifier Notifier
+	// Lock is nil when distributed locking is disabled.
+	Lock Lock
+	// Preflight optionally vets the enriched articles before they are
+	// written. An error aborts the run.
+	Preflight func(ctx context.Context, articles []model.Article) error
+}
+
+// Run performs a single sync: it prepares the sink, loads the articles,
+// enriches them and writes them to the sink.
+func (s *Syncer) Run(ctx context.Context) *Report {
+	report := newReport()
+	report.Job = s.Name
+
+	if s.Lock != nil {
+		lockCtx, err := s.Lock.Acquire(ctx)
+		if err != nil {
+			log.Error().Caller().Err(err).Msg("failed to acquire sync lock")
+			report.finish(err)
+			s.notify(ctx, report)
+			return report
+		}
+		defer s.Lock.Release()
+		ctx = lockCtx
+	}
+
+	// Batches are enriched on their own in a pipelined run
+	if names := enrich.RunWide(s.Enrichers); s.Pipeline && len(names) > 0 {
+		err := fmt.Errorf("pipelined runs enrich batch by batch, which the %s enrichers don't support as they need every article of the run", strings.Join(names, ", "))
+		log.Error().Caller().Err(err).Msg("invalid sync configuration")
+		report.finish(err)
+		s.notify(ctx, report)
+		return report
+	}
+
+	// Create index mapping before inserting data
+	s.Prepare(ctx)
+
+	// Enrichers outlive runs in daemon mode, their cost caps don't
+	enrich.StartRun(s.Enrichers)
+
+	r := &run{Syncer: s, report: report, sourceCounts: make(map[string]int)}
+	if s.DeadLetter != "" {
+		r.deadLetters = &deadLetters{path: s.DeadLetter, runID: report.RunID}
+		defer r.deadLetters.close()
+	}
+	var err error
+	if s.Pipeline {
+		err = r.pipeline(ctx)
+	} else {
+		err = r.all(ctx)
+	}
+	// Stats accumulate across batches until taken
+	if reporter, ok := s.Sink.(sink.StatsReporter); ok && r.wrote {
+		report.setSinks(reporter.TakeStats())
+	}
+	if report.Latency = r.latency.summary(); report.Latency != nil && s.Retries != nil {
+		report.Latency.Retries = s.Retries.Take()
+	}
+	if err == nil && s.Prune != nil {
+		err = r.prune(ctx)
+	}
+	if err == nil && s.Expire != nil {
+		report.Expired, err = s.Expire(ctx)
+		if err != nil {
+			log.Error().Caller().Err(err).Msg("error while expiring articles")
+		}
+	}
+	if err == nil && s.Relate != nil && report.Indexed > 0 {
+		var relateErr error
+		if report.Related, relateErr = s.Relate(ctx, report.RunID); relateErr != nil {
+			log.Warn().Caller().Err(relateErr).Msg("error while storing related articles")
+		}
+	}
+	report.finish(err)
+	s.notify(ctx, report)
+	return report
+}
+
+// Prepare readies the sink and the sinks of the routes, e.g. creates
+// their index mappings. Failures are logged, as writes may still succeed.
+func (s *Syncer) Prepare(ctx context.Context) {
+	opts := enrich.IndexOptionsFor(s.Enrichers)
+	if err := s.Sink.Prepare(ctx, opts); err != nil {
+		log.Error().Caller().Err(err).Msgf("error while preparing %s sink", s.Sink.Name())
+	}
+	for _, index := range s.Routes.Indices() {
+		routed := s.RouteSinks[index]
+		if err := routed.Prepare(ctx, opts); err != nil {
+			log.Error().Caller().Err(err).Msgf("error while preparing %s sink of %s", routed.Name(), index)
+		}
+	}
+}
+
+// notifyTimeout bounds telling the Notifier, which happens even when the
+// run was cancelled.
+const notifyTimeout = 30 * time.Second
+
+func (s *Syncer) notify(ctx context.Context, report *Report) {
+	if s.Notifier == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+	defer cancel()
+	if err := s.Notifier.NotifyRun(ctx, report); err != nil {
+		log.Error().Caller().Err(err).Msg("error while sending run notification")
+	}
+}
+
+// run is the state of one Run shared by its stages.
+type run struct {
+	*Syncer
+	report *Report
+	// sourceCounts and sampled carry the per source and sample limits
+	// across the batches of a pipelined run.
+	sourceCounts map[string]int
+	sampled      int
+	// wrote is set once articles were passed to the sink.
+	wrote       bool
+	latency     latencyRecorder
+	deadLetters *deadLetters
+	// requeued is set when the caller retries the articles of a failed
+	// run, so only those the sink rejected are dead lettered.
+	requeued bool
+}
+
+// prune soft deletes what the run didn't write, unless it may have left
+// out articles still in the source.
+func (r *run) prune(ctx context.Context) error {
+	report := r.report
+	switch {
+	case !r.Since.IsZero() || !r.Until.IsZero() || r.SampleRate > 0 || r.Limit > 0 || r.Shard.Count > 1:
+		log.Warn().Caller().Msg("not pruning after syncing a subset of the source")
+		return nil
+	case report.Failed > 0 || report.OverSourceLimit > 0 || report.MissingIDs > 0:
+		log.Warn().Caller().Msg("not pruning as some articles of the source weren't written")
+		return nil
+	case report.Indexed == 0:
+		// An empty source more likely means a broken feed than no news
+		log.Warn().Caller().Msg("not pruning after writing no articles")
+		return nil
+	}
+	pruned, err := r.Prune(ctx, report.RunID)
+	report.Pruned = pruned
+	if err != nil {
+		log.Error().Caller().Err(err).Msg("error while pruning articles")
+		return err
+	}
+	return nil
+}
+
+// all loads every article, transforms them and writes them in batches.
+func (r *run) all(ctx context.Context) error {
+	articles, err := r.load(ctx)
+	if err != nil {
+		log.Error().Caller().Err(err).Msgf("error while loading articles from %s", r.Source)
+		return err
+	}
+	r.report.Loaded = len(articles)
+
+	if articles, err = r.transform(ctx, articles); err != nil {
+		return err
+	}
+	return r.write(ctx, articles)
+}
+
+// transform readies loaded articles for the sink: it normalises their
+// dates, applies the window, source registry, ID strategies and sample,
+// stamps them with the run and enriches them. Counts are added to the
+// report.
+func (r *run) transform(ctx context.Context, articles []model.Article) ([]model.Article, error) {
+	report := r.report
+	if r.Fixup != nil {
+		for i := range articles {
+			if err := r.Fixup(&articles[i]); err != nil {
+				log.Error().Caller().Err(err).Msgf("error while fixing up article %s", articles[i].ID)
+				return nil, err
+			}
+		}
+	}
+
+	articles, badDates, err := normalizeDates(articles, r.Timezone, r.datePolicy(), report.StartedAt)
+	report.BadDates += badDates
+	if err != nil {
+		log.Error().Caller().Err(err).Msg("error while parsing publication dates")
+		return nil, err
+	}
+
+	if !r.Since.IsZero() || !r.Until.IsZero() {
+		var outside int
+		articles, outside = r.window(articles)
+		report.OutsideWindow += outside
+	}
+
+	if r.Sources != nil {
+		var disabled, limited int
+		articles, disabled, limited = r.Sources.apply(articles, r.sourceCounts)
+		report.DisabledSources += disabled
+		report.OverSourceLimit += limited
+		if disabled > 0 {
+			log.Info().Caller().Msgf("skipping %d articles from disabled sources", disabled)
+		}
+		if limited > 0 {
+			log.Warn().Caller().Msgf("skipping %d articles over their source's per run limit", limited)
+		}
+	}
+
+	// IDs are derived after normalisation so uuid5 IDs don't depend on
+	// how the source formats dates
+	articles, missing := assignIDs(articles, r.idStrategies())
+	report.MissingIDs += missing
+	if missing > 0 {
+		log.Warn().Caller().Msgf("skipping %d articles without an id", missing)
+	}
+
+	// Sharding and sampling go by ID, so they follow assignIDs
+	if r.Shard.Count > 1 {
+		var others int
+		articles, others = r.Shard.shard(articles)
+		report.OtherShards += others
+		log.Info().Caller().Msgf("syncing %d articles of shard %s, leaving %d to the other shards", len(articles), r.Shard, others)
+	}
+	if r.SampleRate > 0 || r.Limit > 0 {
+		var unsampled int
+		articles, unsampled = r.sample(articles)
+		report.Unsampled += unsampled
+		log.Info().Caller().Msgf("syncing a sample of %d articles, skipping %d", len(articles), unsampled)
+	}
+
+	ingestedAt := utils.ESDate(report.StartedAt)
+	for i := range articles {
+		articles[i].IngestedAt = ingestedAt
+		articles[i].SyncRunID = report.RunID
+		articles[i].SourceFile = r.Source
+		if r.Tenant != "" {
+			articles[i].Tenant = r.Tenant
+		}
+	}
+	r.InjectAccess(articles)
+
+	// Run optional enrichment stages before indexing
+	if err := r.enrich(ctx, articles); err != nil {
+		log.Error().Caller().Err(err).Msg("error while enriching articles")
+		return nil, err
+	}
+
+	if r.Preflight != nil {
+		if err := r.Preflight(ctx, articles); err != nil {
+			log.Error().Caller().Err(err).Msg("preflight check failed, not writing articles")
+			return nil, err
+		}
+	}
+	return articles, nil
+}
+
+// sample applies SampleRate and what is left of Limit.
+func (r *run) sample(articles []model.Article) ([]model.Article, int) {
+	limit := 0
+	if r.Limit > 0 {
+		limit = r.Limit - r.sampled
+		if limit <= 0 {
+			return articles[:0], len(articles)
+		}
+	}
+	kept, unsampled := sample(articles, r.SampleRate, limit)
+	r.sampled += len(kept)
+	return kept, unsampled
+}
+
+// datePolicy returns OnBadDate or its default.
+func (s *Syncer) datePolicy() DatePolicy {
+	if s.OnBadDate == "" {
+		return DateFail
+	}
+	return s.OnBadDate
+}
+
+// idStrategies returns IDStrategies or their default.
+func (s *Syncer) idStrategies() []IDStrategy {
+	if len(s.IDStrategies) == 0 {
+		return []IDStrategy{IDInput}
+	}
+	return s.IDStrategies
+}
+
+// batchSize returns BatchSize or its default.
+func (s *Syncer) batchSize() int {
+	if s.BatchSize == 0 {
+		return sink.DefaultBulkSize
+	}
+	return s.BatchSize
+}
+
+// write passes articles to the sink, or the sinks of their routes, in
+// batches, timing each, and adds the outcome to the report. The first
+// batch that fails outright stops the write.
+func (r *run) write(ctx context.Context, articles []model.Article) error {
+	r.wrote = true
+	if len(r.RouteSinks) == 0 {
+		return r.writeTo(ctx, r.Sink, articles)
+	}
+	var sinks []sink.Sink
+	groups := make(map[sink.Sink][]model.Article)
+	for i := range articles {
+		s := r.sinkFor(&articles[i])
+		if _, ok := groups[s]; !ok {
+			sinks = append(sinks, s)
+		}
+		groups[s] = append(groups[s], articles[i])
+	}
+	for _, s := range sinks {
+		if err := r.writeTo(ctx, s, groups[s]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *run) writeTo(ctx context.Context, out sink.Sink, articles []model.Article) error {
+	size := r.batchSize()
+	batchSize := size
+	for start := 0; start < len(articles); {
+		if r.MaxMemory > 0 {
+			batchSize = fitBatch(batchSize, size, r.MaxMemory)
+		}
+		end := min(start+batchSize, len(articles))
+		began := time.Now()
+		result, err := sink.Write(ctx, out, articles[start:end], batchSize)
+		r.latency.record(time.Since(began), articles[start:end])
+		r.report.Indexed += result.Indexed
+		r.report.Failed += result.Failed
+		// Requeued batches are retried whole, only rejections are final
+		batchErr := err
+		if r.requeued {
+			batchErr = nil
+		}
+		if r.deadLetters != nil && (batchErr != nil || result.Failed > 0) {
+			r.deadLetter(out, articles[start:end], result, batchErr)
+		}
+		if err != nil {
+			log.Error().Caller().Err(err).Msgf("error while writing articles to %s sink", out.Name())
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+// deadLetter appends the failed articles of batch to the dead letter
+// file: all of them when the batch failed with err, otherwise those the
+// sink rejected.
+func (r *run) deadLetter(out sink.Sink, batch []model.Article, result sink.Result, err error) {
+	var rejected map[string]string
+	reason := ""
+	if err != nil {
+		reason = err.Error()
+	} else if rejected = result.Rejected; rejected == nil {
+		log.Warn().Caller().Msgf("%s doesn't name its %d rejected documents, they can't be dead lettered", out.Name(), result.Failed)
+		return
+	}
+	added, dlqErr := r.deadLetters.add(batch, rejected, reason)
+	r.report.DeadLettered += added
+	if dlqErr != nil {
+		log.Error().Caller().Err(dlqErr).Msgf("error while writing dead letters to %s", r.DeadLetter)
+	}
+}
+
+// window returns the articles published between Since and Until and the
+// number of others, including those without a date.
+func (s *Syncer) window(articles []model.Article) ([]model.Article, int) {
+	kept := articles[:0]
+	outside := 0
+	for _, a := range articles {
+		// Dates were normalised, so they parse
+		t, err := utils.ParseDate(a.PublicationDate)
+		if err != nil || (!s.Since.IsZero() && t.Before(s.Since)) || (!s.Until.IsZero() && !t.Before(s.Until)) {
+			outside++
+			continue
+		}
+		kept = append(kept, a)
+	}
+	return kept, outside
+}
+
+func (s *Syncer) load(ctx context.Context) ([]model.Article, error) {
+	src, err := s.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	return source.ReadAll(ctx, src)
+}
+
+// open opens Source, reshaped and within the memory budget as
+// configured.
+func (s *Syncer) open(ctx context.Context) (source.Source, error) {
+	src, err := source.Open(ctx, s.Source)
+	if err != nil {
+		return nil, err
+	}
+	if !s.Shape.IsZero() {
+		reshaped, err := source.Reshape(src, s.Shape)
+		if err != nil {
+			src.Close()
+			return nil, err
+		}
+		src = reshaped
+	}
+	if s.MaxMemory > 0 && s.Pipeline {
+		src = &budgetSource{Source: src, budget: s.MaxMemory}
+	}
+	return src, nil
+}
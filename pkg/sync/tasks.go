@@ -0,0 +1,500 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/elastic/go-elasticsearch/v9/esapi"
+	"github.com/rs/zerolog/log"
+)
+
+// TaskIndex holds one task document per slice of a coordinated job.
+const TaskIndex = "inshorts-news-syncer-tasks"
+
+const taskMapping = `{
+  "mappings": {
+    "dynamic": "strict",
+    "properties": {
+      "job":          { "type": "keyword" },
+      "slice":        { "type": "keyword" },
+      "plan":         { "type": "keyword" },
+      "status":       { "type": "keyword" },
+      "owner":        { "type": "keyword" },
+      "attempts":     { "type": "integer" },
+      "claimed_at":   { "type": "date" },
+      "expires_at":   { "type": "date" },
+      "completed_at": { "type": "date" },
+      "error":        { "type": "text", "index": false }
+    }
+  }
+}`
+
+// Statuses of a task. A failed task is claimed again like a pending one
+// until it was attempted TaskQueue.maxAttempts times, when it is
+// abandoned.
+const (
+	taskPending   = "pending"
+	taskRunning   = "running"
+	taskDone      = "done"
+	taskFailed    = "failed"
+	taskAbandoned = "abandoned"
+)
+
+// taskPageSize is how many tasks are read per search request.
+const taskPageSize = 1000
+
+// ErrTasksAbandoned is returned once every slice is done or abandoned,
+// when some were abandoned.
+var ErrTasksAbandoned = errors.New("slices were abandoned after failing too often")
+
+// ErrTaskLost is returned when another worker claimed the slice being
+// worked on after its lease expired.
+var ErrTaskLost = errors.New("task was claimed by another worker")
+
+// TaskQueue splits a job into slices recorded as task documents in
+// TaskIndex, so several instances share the work: each claims a slice
+// nobody holds, renews its lease every ttl/3 while working on it and marks
+// it done. The slice of a worker that stopped renewing is claimed again
+// once the lease expires, so a job resumes where it was left off however
+// its workers stop. Writes use optimistic concurrency control, as ESLock
+// does, so of two workers claiming a slice only one wins. A slice claimed
+// maxAttempts times without getting done is abandoned.
+type TaskQueue struct {
+	es          *elasticsearch.Client
+	job         string
+	owner       string
+	ttl         time.Duration
+	maxAttempts int
+}
+
+type taskDoc struct {
+	Job         string    `json:"job"`
+	Slice       string    `json:"slice"`
+	Plan        string    `json:"plan"`
+	Status      string    `json:"status"`
+	Owner       string    `json:"owner,omitempty"`
+	Attempts    int       `json:"attempts"`
+	ClaimedAt   time.Time `json:"claimed_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CompletedAt time.Time `json:"completed_at"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// task is a task document as last read or written by this worker.
+type task struct {
+	doc taskDoc
+	lockWriteResponse
+}
+
+// NewTaskQueue returns the queue of job, whose leases expire ttl after
+// their last renewal and whose slices are abandoned after maxAttempts
+// claims. Workers of one job must give the same job name.
+func NewTaskQueue(es *elasticsearch.Client, job string, ttl time.Duration, maxAttempts int) *TaskQueue {
+	host, _ := os.Hostname()
+	return &TaskQueue{
+		es:          es,
+		job:         job,
+		owner:       fmt.Sprintf("%s/%d", host, os.Getpid()),
+		ttl:         ttl,
+		maxAttempts: maxAttempts,
+	}
+}
+
+// Plan records a pending task for every slice of the job not recorded
+// yet, so whichever worker starts first plans the job and the others join
+// it. plan describes how the job was split, e.g. its date range; a job
+// already planned differently is refused rather than mixed up.
+func (q *TaskQueue) Plan(ctx context.Context, plan string, slices []string) error {
+	if err := q.prepare(ctx); err != nil {
+		return err
+	}
+	tasks, err := q.tasks(ctx)
+	if err != nil {
+		return err
+	}
+	recorded := map[string]bool{}
+	for _, t := range tasks {
+		if t.doc.Plan != plan {
+			return fmt.Errorf("job %s was planned as %s, not %s", q.job, t.doc.Plan, plan)
+		}
+		recorded[t.doc.Slice] = true
+	}
+
+	var created int
+	for _, slice := range slices {
+		if recorded[slice] {
+			continue
+		}
+		t := &task{doc: taskDoc{Job: q.job, Slice: slice, Plan: plan, Status: taskPending}}
+		status, err := q.write(ctx, t, false)
+		if err != nil {
+			return err
+		}
+		// A conflict means another worker planned the slice meanwhile
+		if status != http.StatusConflict {
+			created++
+		}
+	}
+	log.Info().Caller().Msgf("job %s has %d slices, %d of them newly planned", q.job, len(slices), created)
+	return nil
+}
+
+// prepare creates the task index unless it exists.
+func (q *TaskQueue) prepare(ctx context.Context) error {
+	exists, err := q.es.Indices.Exists([]string{TaskIndex}, q.es.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	exists.Body.Close()
+	if exists.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	res, err := q.es.Indices.Create(TaskIndex, q.es.Indices.Create.WithBody(strings.NewReader(taskMapping)), q.es.Indices.Create.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	// Workers starting together race to create it
+	if res.IsError() && !strings.Contains(res.String(), "resource_already_exists_exception") {
+		return fmt.Errorf("failed to create task index %s: %s", TaskIndex, res.String())
+	}
+	return nil
+}
+
+// tasks returns the tasks of the job by slice, paging through them with
+// search_after.
+func (q *TaskQueue) tasks(ctx context.Context) ([]*task, error) {
+	var tasks []*task
+	for {
+		query := map[string]interface{}{
+			"size":  taskPageSize,
+			"query": map[string]interface{}{"term": map[string]interface{}{"job": q.job}},
+			"sort":  []interface{}{map[string]interface{}{"slice": "asc"}},
+		}
+		if len(tasks) > 0 {
+			query["search_after"] = []interface{}{tasks[len(tasks)-1].doc.Slice}
+		}
+		page, err := q.taskPage(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, page...)
+		if len(page) < taskPageSize {
+			return tasks, nil
+		}
+	}
+}
+
+// taskPage returns the tasks query finds.
+func (q *TaskQueue) taskPage(ctx context.Context, query map[string]interface{}) ([]*task, error) {
+	body, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+	seqNo := true
+	res, err := esapi.SearchRequest{
+		Index:            []string{TaskIndex},
+		Body:             bytes.NewReader(body),
+		SeqNoPrimaryTerm: &seqNo,
+	}.Do(ctx, q.es)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, fmt.Errorf("failed to read tasks of job %s: %s", q.job, res.String())
+	}
+
+	var resp struct {
+		Hits struct {
+			Hits []struct {
+				lockWriteResponse
+				Source taskDoc `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, err
+	}
+	tasks := make([]*task, 0, len(resp.Hits.Hits))
+	for _, h := range resp.Hits.Hits {
+		tasks = append(tasks, &task{doc: h.Source, lockWriteResponse: h.lockWriteResponse})
+	}
+	return tasks, nil
+}
+
+// write creates the document of t, or replaces it when replace is set and
+// it is still as t was read. It returns the response status so callers
+// can tell a conflict apart from other failures.
+func (q *TaskQueue) write(ctx context.Context, t *task, replace bool) (int, error) {
+	body, err := json.Marshal(t.doc)
+	if err != nil {
+		return 0, err
+	}
+
+	req := esapi.IndexRequest{
+		Index:      TaskIndex,
+		DocumentID: q.job + ":" + t.doc.Slice,
+		Body:       bytes.NewReader(body),
+		OpType:     "create",
+		Refresh:    "true",
+	}
+	if replace {
+		req.OpType = ""
+		req.IfSeqNo = &t.SeqNo
+		req.IfPrimaryTerm = &t.PrimaryTerm
+	}
+
+	res, err := req.Do(ctx, q.es)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusConflict {
+		return res.StatusCode, nil
+	}
+	if res.IsError() {
+		return res.StatusCode, fmt.Errorf("failed to write task %s of job %s: %s", t.doc.Slice, q.job, res.String())
+	}
+	if err := json.NewDecoder(res.Body).Decode(&t.lockWriteResponse); err != nil {
+		return res.StatusCode, err
+	}
+	return res.StatusCode, nil
+}
+
+// Work claims the slices of the job and runs do on them until every slice
+// is done or abandoned, waiting for those other workers hold. It stops at
+// the first slice do fails on, which is then left for the other workers
+// to retry, and returns its error. When slices were abandoned it returns
+// ErrTasksAbandoned.
+func (q *TaskQueue) Work(ctx context.Context, do func(ctx context.Context, slice string) error) error {
+	for {
+		tasks, err := q.tasks(ctx)
+		if err != nil {
+			return err
+		}
+		t, held, abandoned, err := q.claim(ctx, tasks)
+		if err != nil {
+			return err
+		}
+		if t == nil {
+			if held == 0 && len(abandoned) > 0 {
+				return fmt.Errorf("%d slices of job %s, %s: %w", len(abandoned), q.job, strings.Join(abandoned, ", "), ErrTasksAbandoned)
+			}
+			if held == 0 {
+				log.Info().Caller().Msgf("all %d slices of job %s are done", len(tasks), q.job)
+				return nil
+			}
+			log.Info().Caller().Msgf("waiting for %d slices of job %s held by other workers", held, q.job)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(q.ttl / 3):
+			}
+			continue
+		}
+		if err := q.run(ctx, t, do); err != nil {
+			return err
+		}
+	}
+}
+
+// claim takes the first of tasks that is neither done, abandoned nor held
+// under a live lease, abandoning those claimed maxAttempts times on the
+// way. Without one it returns how many slices others hold and which were
+// abandoned.
+func (q *TaskQueue) claim(ctx context.Context, tasks []*task) (*task, int, []string, error) {
+	var held int
+	var abandoned []string
+	now := time.Now().UTC()
+	for _, t := range tasks {
+		switch {
+		case t.doc.Status == taskDone:
+			continue
+		case t.doc.Status == taskAbandoned:
+			abandoned = append(abandoned, t.doc.Slice)
+			continue
+		case t.doc.Status == taskRunning && now.Before(t.doc.ExpiresAt):
+			held++
+			continue
+		case q.maxAttempts > 0 && t.doc.Attempts >= q.maxAttempts:
+			if err := q.abandon(ctx, t); err != nil {
+				return nil, 0, nil, err
+			}
+			abandoned = append(abandoned, t.doc.Slice)
+			continue
+		}
+		previous := t.doc.Owner
+		expired := t.doc.Status == taskRunning
+		t.doc.Status = taskRunning
+		t.doc.Owner = q.owner
+		t.doc.Attempts++
+		t.doc.ClaimedAt = now
+		t.doc.ExpiresAt = now.Add(q.ttl)
+		status, err := q.write(ctx, t, true)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		if status == http.StatusConflict {
+			held++
+			continue
+		}
+		if expired {
+			log.Warn().Caller().Msgf("took over slice %s of job %s from %s after its lease expired", t.doc.Slice, q.job, previous)
+		}
+		return t, 0, nil, nil
+	}
+	return nil, held, abandoned, nil
+}
+
+// abandon records that t failed too often to be claimed again. Another
+// worker abandoning it meanwhile is fine, one claiming it isn't possible
+// as its attempts are used up.
+func (q *TaskQueue) abandon(ctx context.Context, t *task) error {
+	t.doc.Status = taskAbandoned
+	t.doc.Owner = ""
+	t.doc.ExpiresAt = time.Time{}
+	if _, err := q.write(ctx, t, true); err != nil {
+		return err
+	}
+	log.Error().Caller().Msgf("abandoning slice %s of job %s after %d attempts, last error: %s", t.doc.Slice, q.job, t.doc.Attempts, t.doc.Error)
+	return nil
+}
+
+// run runs do on the slice of t while renewing its lease, then records
+// whether it is done.
+func (q *TaskQueue) run(ctx context.Context, t *task, do func(ctx context.Context, slice string) error) error {
+	slice := t.doc.Slice
+	log.Info().Caller().Msgf("claimed slice %s of job %s, attempt %d", slice, q.job, t.doc.Attempts)
+
+	// Renewals use ctx rather than taskCtx, so a renewal in flight when do
+	// returns completes and t is left as Elasticsearch has it
+	var lost bool
+	taskCtx, cancel := context.WithCancel(ctx)
+	stop := make(chan struct{})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		ticker := time.NewTicker(q.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				t.doc.ExpiresAt = time.Now().UTC().Add(q.ttl)
+				owned, err := q.replace(ctx, t)
+				if err != nil {
+					log.Warn().Caller().Err(err).Msgf("failed to renew slice %s of job %s", slice, q.job)
+					continue
+				}
+				if !owned {
+					log.Error().Caller().Msgf("slice %s of job %s was claimed by another worker, cancelling it", slice, q.job)
+					lost = true
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+	err := do(taskCtx, slice)
+	close(stop)
+	<-renewed
+	cancel()
+
+	if lost {
+		return fmt.Errorf("slice %s of job %s: %w", slice, q.job, ErrTaskLost)
+	}
+	if ctx.Err() != nil {
+		// Stopping leaves the slice to expire rather than count as failed
+		return ctx.Err()
+	}
+
+	// Recording the outcome mustn't fail for want of the cancelled context
+	writeCtx, cancelWrite := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelWrite()
+	t.doc.ExpiresAt = time.Time{}
+	if err != nil {
+		t.doc.Status = taskFailed
+		t.doc.Error = err.Error()
+	} else {
+		t.doc.Status = taskDone
+		t.doc.Error = ""
+		t.doc.CompletedAt = time.Now().UTC()
+	}
+	owned, writeErr := q.replace(writeCtx, t)
+	switch {
+	case writeErr != nil:
+		log.Error().Caller().Err(writeErr).Msgf("failed to record slice %s of job %s as %s", slice, q.job, t.doc.Status)
+	case !owned:
+		writeErr = fmt.Errorf("slice %s of job %s: %w before it was recorded as %s", slice, q.job, ErrTaskLost, t.doc.Status)
+		log.Warn().Caller().Err(writeErr).Send()
+	}
+	if err != nil {
+		return fmt.Errorf("slice %s of job %s: %w", slice, q.job, err)
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	log.Info().Caller().Msgf("slice %s of job %s is done", slice, q.job)
+	return nil
+}
+
+// replace writes t over its document, and reports false when another
+// worker claimed the slice since. A conflict may also come from a write
+// of this worker whose response was lost, leaving t behind the document:
+// the document is then read again and, when it is still this worker's
+// claim, written over once more.
+func (q *TaskQueue) replace(ctx context.Context, t *task) (bool, error) {
+	for {
+		status, err := q.write(ctx, t, true)
+		if err != nil {
+			return false, err
+		}
+		if status != http.StatusConflict {
+			return true, nil
+		}
+		current, err := q.get(ctx, t.doc.Slice)
+		if err != nil {
+			return false, err
+		}
+		if current == nil || current.doc.Owner != q.owner || !current.doc.ClaimedAt.Equal(t.doc.ClaimedAt) {
+			return false, nil
+		}
+		t.lockWriteResponse = current.lockWriteResponse
+	}
+}
+
+// get reads the task of slice, returning nil when it doesn't exist.
+func (q *TaskQueue) get(ctx context.Context, slice string) (*task, error) {
+	res, err := esapi.GetRequest{Index: TaskIndex, DocumentID: q.job + ":" + slice}.Do(ctx, q.es)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("failed to read task %s of job %s: %s", slice, q.job, res.String())
+	}
+
+	var current struct {
+		lockWriteResponse
+		Source taskDoc `json:"_source"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&current); err != nil {
+		return nil, err
+	}
+	return &task{doc: current.Source, lockWriteResponse: current.lockWriteResponse}, nil
+}
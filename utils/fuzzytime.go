@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	agoPattern = regexp.MustCompile(`^(\d+|an?|one)\s*(s|sec|secs|second|seconds|m|min|mins|minute|minutes|h|hr|hrs|hour|hours|d|day|days|w|wk|wks|week|weeks|mo|month|months|y|yr|yrs|year|years)\s+ago$`)
+	dayPattern = regexp.MustCompile(`^(today|yesterday)(?:[,\s]+(?:at\s+)?(.+))?$`)
+)
+
+// clockLayouts are the times of day accepted after "today" or "yesterday".
+var clockLayouts = []string{"3:04 PM", "3:04PM", "3:04 pm", "3:04pm", "15:04", "3 PM", "3PM", "3pm"}
+
+// ParseFuzzyDate parses relative dates as found on some feeds, such as
+// "just now", "2 hours ago", "an hour ago" or "Yesterday, 5:30 PM",
+// relative to anchor, normally the time the feed was fetched. Times of
+// day are read in anchor's location.
+func ParseFuzzyDate(input string, anchor time.Time) (time.Time, error) {
+	s := strings.ToLower(strings.Join(strings.Fields(input), " "))
+	if s == "now" || s == "just now" {
+		return anchor, nil
+	}
+
+	if m := agoPattern.FindStringSubmatch(s); m != nil {
+		n := 1
+		if m[1] != "a" && m[1] != "an" && m[1] != "one" {
+			n, _ = strconv.Atoi(m[1])
+		}
+		switch unit := strings.TrimSuffix(m[2], "s"); unit {
+		case "", "sec", "second":
+			return anchor.Add(-time.Duration(n) * time.Second), nil
+		case "m", "min", "minute":
+			return anchor.Add(-time.Duration(n) * time.Minute), nil
+		case "h", "hr", "hour":
+			return anchor.Add(-time.Duration(n) * time.Hour), nil
+		case "d", "day":
+			return anchor.AddDate(0, 0, -n), nil
+		case "w", "wk", "week":
+			return anchor.AddDate(0, 0, -7*n), nil
+		case "mo", "month":
+			return anchor.AddDate(0, -n, 0), nil
+		case "y", "yr", "year":
+			return anchor.AddDate(-n, 0, 0), nil
+		}
+	}
+
+	if m := dayPattern.FindStringSubmatch(s); m != nil {
+		day := anchor
+		if m[1] == "yesterday" {
+			day = anchor.AddDate(0, 0, -1)
+		}
+		if m[2] == "" {
+			return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, anchor.Location()), nil
+		}
+		for _, layout := range clockLayouts {
+			if clock, err := time.Parse(layout, m[2]); err == nil {
+				return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, anchor.Location()), nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised relative date %q", input)
+}
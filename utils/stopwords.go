@@ -0,0 +1,32 @@
+package utils
+
+// stopwords are common English words that carry no topical meaning.
+var stopwords = toSet(
+	"a", "about", "above", "after", "again", "against", "all", "also", "am", "an", "and", "any", "are",
+	"as", "at", "be", "because", "been", "before", "being", "below", "between", "both", "but", "by",
+	"can", "could", "did", "do", "does", "doing", "down", "during", "each", "even", "few", "for", "from",
+	"further", "had", "has", "have", "having", "he", "her", "here", "hers", "herself", "him", "himself",
+	"his", "how", "i", "if", "in", "into", "is", "it", "its", "itself", "just", "last", "like", "made",
+	"make", "many", "may", "me", "more", "most", "much", "must", "my", "myself", "new", "no", "nor",
+	"not", "now", "of", "off", "on", "once", "one", "only", "or", "other", "our", "ours", "ourselves",
+	"out", "over", "own", "said", "same", "says", "she", "should", "since", "so", "some", "such",
+	"than", "that", "the", "their", "theirs", "them", "themselves", "then", "there", "these", "they",
+	"this", "those", "through", "to", "too", "two", "under", "until", "up", "upon", "us", "very", "was",
+	"we", "were", "what", "when", "where", "which", "while", "who", "whom", "why", "will", "with",
+	"would", "year", "years", "you", "your", "yours", "yourself", "yourselves", "amid", "per", "via",
+	"get", "got", "according", "added", "told", "reportedly", "week", "day", "days", "first", "however",
+)
+
+// IsStopword reports whether the lowercased word is an English stopword.
+func IsStopword(word string) bool {
+	_, ok := stopwords[word]
+	return ok
+}
+
+func toSet(words ...string) map[string]struct{} {
+	set := make(map[string]struct{}, len(words))
+	for _, w := range words {
+		set[w] = struct{}{}
+	}
+	return set
+}
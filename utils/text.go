@@ -0,0 +1,14 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokenize lowercases s and splits it into words, dropping punctuation.
+// Apostrophes inside words are kept so "don't" stays a single token.
+func Tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+}
@@ -1,21 +1,80 @@
 package utils
 
-import "time"
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const outputLayout = "2006-01-02T15:04:05.000Z"
+
+// dateLayouts are tried in order.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	time.DateOnly,
+	time.RFC1123Z,
+	time.RFC1123,
+}
 
-const (
-	inputLayout  = "2006-01-02T15:04:05"
-	outputLayout = "2006-01-02T15:04:05.000Z"
+var (
+	extraLayoutsMu sync.RWMutex
+	extraLayouts   []string
 )
 
-// NormalizeToESDate converts
-// "yyyy-MM-dd'T'HH:mm:ss"
-// → "yyyy-MM-dd'T'HH:mm:ss.SSS'Z'"
+// AddDateLayouts registers Go time layouts tried after the built in ones.
+func AddDateLayouts(layouts ...string) {
+	extraLayoutsMu.Lock()
+	defer extraLayoutsMu.Unlock()
+	extraLayouts = append(extraLayouts, layouts...)
+}
+
+// ParseDate parses input with the first matching layout. Integers are
+// read as unix seconds, or milliseconds when too large to be seconds.
+func ParseDate(input string) (time.Time, error) {
+	return ParseDateIn(input, time.UTC)
+}
+
+// ParseDateIn is like ParseDate but reads dates without a zone in loc.
+func ParseDateIn(input string, loc *time.Location) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, input, loc); err == nil {
+			return t, nil
+		}
+	}
+	extraLayoutsMu.RLock()
+	defer extraLayoutsMu.RUnlock()
+	for _, layout := range extraLayouts {
+		if t, err := time.ParseInLocation(layout, input, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	if n, err := strconv.ParseInt(input, 10, 64); err == nil {
+		// 1e11 seconds is the year 5138, so larger values are millis
+		if n >= 1e11 || n <= -1e11 {
+			return time.UnixMilli(n), nil
+		}
+		return time.Unix(n, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognised date %q", input)
+}
+
+// NormalizeToESDate converts a date in any format ParseDate accepts to
+// "yyyy-MM-dd'T'HH:mm:ss.SSS'Z'".
 func NormalizeToESDate(input string) (string, error) {
-	t, err := time.Parse(inputLayout, input)
+	t, err := ParseDate(input)
 	if err != nil {
 		return "", err
 	}
 
-	// Force UTC and format for Elasticsearch
-	return t.UTC().Format(outputLayout), nil
+	return ESDate(t), nil
+}
+
+// ESDate formats t as "yyyy-MM-dd'T'HH:mm:ss.SSS'Z'", in UTC.
+func ESDate(t time.Time) string {
+	return t.UTC().Format(outputLayout)
 }
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// trackingParams are query parameters that identify a campaign or click
+// rather than the resource. Parameters starting with "utm_" are always removed.
+var trackingParams = toSet(
+	"fbclid", "gclid", "dclid", "msclkid", "igshid", "mc_cid", "mc_eid", "_ga", "ref_src", "yclid",
+)
+
+// CanonicalURL normalises raw so the same article always yields the same
+// string: protocol-relative URLs get https, scheme and host are lowercased,
+// default ports and fragments are dropped, tracking parameters plus any
+// extraParams are removed and the remaining query is sorted.
+func CanonicalURL(raw string, extraParams ...string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "//") {
+		raw = "https:" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("url %q is not absolute", raw)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	if port := u.Port(); port != "" && !(u.Scheme == "http" && port == "80") && !(u.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	u.Host = host
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	q := u.Query()
+	for key := range q {
+		lower := strings.ToLower(key)
+		if strings.HasPrefix(lower, "utm_") {
+			q.Del(key)
+			continue
+		}
+		if _, ok := trackingParams[lower]; ok {
+			q.Del(key)
+			continue
+		}
+		for _, p := range extraParams {
+			if strings.EqualFold(p, key) {
+				q.Del(key)
+			}
+		}
+	}
+	// Encode sorts by key, giving a stable order.
+	u.RawQuery = q.Encode()
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u.String(), nil
+}